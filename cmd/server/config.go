@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/urfave/cli"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+)
+
+const (
+	// envPrefix starts the environment variable of every flag, e.g. DOCNOGEN_MONGOADDR for --mongoaddr
+	envPrefix = "DOCNOGEN_"
+	// redacted replaces the values of the secret settings in the help and the logs
+	redacted = common.Redacted
+	// configMetadataKey holds the *configLoader in the metadata of the app
+	configMetadataKey = "config"
+)
+
+// secretSettings are never printed, and can be read from the file named by their <name>file setting
+var secretSettings = []string{"mongoauthpassword"}
+
+// reloadableSettings are applied again when the config file changes, the others need a restart
+var reloadableSettings = []string{"ratelimit", "rateburst", "ratelimitmode", "ratelimitmaxdelay", "ratelimitfile", "addressratelimit", "addressrateburst"}
+
+func isSecret(name string) bool {
+	return containsString(secretSettings, name)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// secretFileFlags returns the <name>file flag of each secret setting
+func secretFileFlags() []cli.Flag {
+	var flags []cli.Flag
+	for _, name := range secretSettings {
+		flags = append(flags, cli.StringFlag{
+			Name:  name + "file",
+			Usage: fmt.Sprintf("File holding the value of --%s, which then stays out of the command line", name),
+		})
+	}
+	return flags
+}
+
+// withEnvVars gives every flag its DOCNOGEN_ environment variable
+func withEnvVars(flags []cli.Flag) []cli.Flag {
+	for i, flag := range flags {
+		env := envPrefix + strings.ToUpper(flag.GetName())
+		switch f := flag.(type) {
+		case cli.StringFlag:
+			f.EnvVar = env
+			flags[i] = f
+		case cli.StringSliceFlag:
+			f.EnvVar = env
+			flags[i] = f
+		case cli.IntFlag:
+			f.EnvVar = env
+			flags[i] = f
+		case cli.Float64Flag:
+			f.EnvVar = env
+			flags[i] = f
+		case cli.DurationFlag:
+			f.EnvVar = env
+			flags[i] = f
+		case cli.BoolFlag:
+			f.EnvVar = env
+			flags[i] = f
+		}
+	}
+	return flags
+}
+
+// redactedFlagStringer leaves the default of the secret flags out of the help
+func redactedFlagStringer(stringer cli.FlagStringFunc) cli.FlagStringFunc {
+	return func(flag cli.Flag) string {
+		if f, ok := flag.(cli.StringFlag); ok && isSecret(f.Name) && f.Value != "" {
+			f.Value = redacted
+			return stringer(f)
+		}
+		return stringer(flag)
+	}
+}
+
+// settingFlags returns the global flags but help and version
+func settingFlags(c *cli.Context) []cli.Flag {
+	var flags []cli.Flag
+	for _, flag := range c.App.Flags {
+		if flag != cli.HelpFlag && flag != cli.VersionFlag {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// flagValue returns the value of a global flag as it would be written on the command line, lists joined by commas
+func flagValue(c *cli.Context, flag cli.Flag) string {
+	name := flag.GetName()
+	switch flag.(type) {
+	case cli.StringSliceFlag:
+		return strings.Join(c.GlobalStringSlice(name), ",")
+	case cli.IntFlag:
+		return strconv.Itoa(c.GlobalInt(name))
+	case cli.Float64Flag:
+		return strconv.FormatFloat(c.GlobalFloat64(name), 'g', -1, 64)
+	case cli.DurationFlag:
+		return c.GlobalDuration(name).String()
+	case cli.BoolFlag:
+		return strconv.FormatBool(c.GlobalBool(name))
+	}
+	return c.GlobalString(name)
+}
+
+// configLoader layers the config file under the flags and environment variables
+type configLoader struct {
+	file string
+	// explicit are the settings of a flag or environment variable, which the config file does not override
+	explicit map[string]bool
+	// defaults are the values of the settings before the config file was applied
+	defaults map[string]string
+	// sources tell where each setting comes from: flag or env, file or default. reload updates them from the
+	// watcher goroutine, so they are read and written under mu
+	mu      sync.Mutex
+	sources map[string]string
+	// values are the settings of the config file last applied
+	values  common.Settings
+	modTime time.Time
+	// current holds the *cli.Context of the reloadable settings last applied, which reload replaces
+	current atomic.Value
+}
+
+// loadConfig applies the config file and the secret files to the global flags, before any command runs
+func loadConfig(c *cli.Context) error {
+	l := &configLoader{
+		file:     c.GlobalString("config"),
+		explicit: make(map[string]bool),
+		defaults: make(map[string]string),
+		sources:  make(map[string]string),
+	}
+	for _, flag := range settingFlags(c) {
+		name := flag.GetName()
+		l.explicit[name] = c.IsSet(name)
+		l.defaults[name] = flagValue(c, flag)
+		l.sources[name] = "default"
+		if l.explicit[name] {
+			l.sources[name] = "flag or env"
+		}
+	}
+
+	if l.file != "" {
+		settings, modTime, err := l.read()
+		if err != nil {
+			return err
+		}
+		for name, values := range settings {
+			if _, known := l.defaults[name]; !known || name == "config" {
+				return fmt.Errorf("Config file %s has unknown setting %s", l.file, name)
+			}
+			if l.explicit[name] {
+				continue
+			}
+			for _, value := range values {
+				if err := c.GlobalSet(name, value); err != nil {
+					return fmt.Errorf("Config file %s has invalid %s: %s", l.file, name, err.Error())
+				}
+			}
+			l.sources[name] = "file"
+		}
+		l.values, l.modTime = settings, modTime
+	}
+	values := make(map[string][]string)
+	for _, name := range reloadableSettings {
+		values[name] = []string{flagValue(c, settingFlag(c, name))}
+	}
+	current, err := reloadableContext(c, values)
+	if err != nil {
+		return err
+	}
+	l.current.Store(current)
+
+	for _, name := range secretSettings {
+		file := c.GlobalString(name + "file")
+		if file == "" {
+			continue
+		}
+		if c.GlobalString(name) != "" {
+			return fmt.Errorf("--%s and --%sfile are both set", name, name)
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("Error reading %s file %s Error=%s", name, file, err.Error())
+		}
+		if err := c.GlobalSet(name, strings.TrimRight(string(data), "\r\n")); err != nil {
+			return err
+		}
+		l.sources[name] = "file " + file
+	}
+
+	c.App.Metadata[configMetadataKey] = l
+	return nil
+}
+
+// settingFlag returns the global flag of a setting
+func settingFlag(c *cli.Context, name string) cli.Flag {
+	for _, flag := range settingFlags(c) {
+		if flag.GetName() == name {
+			return flag
+		}
+	}
+	return nil
+}
+
+// reloadableContext parses the values of the reloadable settings into a context of their own, apart from the global
+// flags the other goroutines read
+func reloadableContext(c *cli.Context, values map[string][]string) (*cli.Context, error) {
+	set := flag.NewFlagSet("reload", flag.ContinueOnError)
+	for _, name := range reloadableSettings {
+		settingFlag(c, name).Apply(set)
+		for _, value := range values[name] {
+			if err := set.Set(name, value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", name, err.Error())
+			}
+		}
+	}
+	return cli.NewContext(c.App, set, nil), nil
+}
+
+// currentSettings returns the reloadable settings last applied, read them with c.String and the like
+func currentSettings(c *cli.Context) *cli.Context {
+	l, _ := c.App.Metadata[configMetadataKey].(*configLoader)
+	return l.current.Load().(*cli.Context)
+}
+
+func (l *configLoader) read() (common.Settings, time.Time, error) {
+	info, err := os.Stat(l.file)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("Error reading config file %s Error=%s", l.file, err.Error())
+	}
+	settings, err := common.LoadSettingsFile(l.file)
+	return settings, info.ModTime(), err
+}
+
+// logConfig logs the value and source of every setting, secrets redacted
+func logConfig(c *cli.Context, logger log.Logger) {
+	l, _ := c.App.Metadata[configMetadataKey].(*configLoader)
+	var keyvals []interface{}
+	for _, flag := range settingFlags(c) {
+		name := flag.GetName()
+		value := flagValue(c, flag)
+		if isSecret(name) && value != "" {
+			value = redacted
+		}
+		keyvals = append(keyvals, name, value+" ("+l.source(name)+")")
+	}
+	logger.Log(keyvals...)
+}
+
+// source tells where a setting comes from
+func (l *configLoader) source(name string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sources[name]
+}
+
+// watchConfig checks the config file for changes every interval until ctx is done. The reloadable settings that no
+// flag or environment variable sets are applied again, back to their default when left out, and reload is called.
+// Changes to the other settings are only logged. reload is also called when the files named by watched settings,
+// such as --ratelimitfile, change.
+func watchConfig(ctx context.Context, c *cli.Context, interval time.Duration, watched []string, reload func() error, logger log.Logger) {
+	l, _ := c.App.Metadata[configMetadataKey].(*configLoader)
+	logger = log.With(logger, "config", l.file)
+	modTimes := func() map[string]time.Time {
+		times := make(map[string]time.Time)
+		for _, name := range watched {
+			if file := currentSettings(c).String(name); file != "" {
+				if info, err := os.Stat(file); err == nil {
+					times[file] = info.ModTime()
+				}
+			}
+		}
+		return times
+	}
+	watchedTimes := modTimes()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed := false
+		if l.file != "" {
+			info, err := os.Stat(l.file)
+			if err != nil {
+				logger.Log("reload", "failed", "err", err)
+				continue
+			}
+			if !info.ModTime().Equal(l.modTime) {
+				if err := l.reload(c, logger); err != nil {
+					logger.Log("reload", "failed", "err", err)
+					continue
+				}
+				changed = true
+			}
+		}
+		times := modTimes()
+		for file, modTime := range times {
+			if !modTime.Equal(watchedTimes[file]) {
+				changed = true
+			}
+		}
+		watchedTimes = times
+
+		if changed {
+			if err := reload(); err != nil {
+				logger.Log("reload", "failed", "err", err)
+				continue
+			}
+			logger.Log("reload", "applied")
+		}
+	}
+}
+
+// reload parses the reloadable settings of the config file again and publishes them as the current settings, the
+// global flags are left untouched
+func (l *configLoader) reload(c *cli.Context, logger log.Logger) error {
+	settings, modTime, err := l.read()
+	if err != nil {
+		return err
+	}
+	for name := range settings {
+		if _, known := l.defaults[name]; !known || name == "config" {
+			return fmt.Errorf("unknown setting %s", name)
+		}
+	}
+
+	values := make(map[string][]string)
+	sources := make(map[string]string)
+	for _, name := range reloadableSettings {
+		values[name], sources[name] = settings[name], "file"
+		if l.explicit[name] {
+			values[name], sources[name] = []string{l.defaults[name]}, l.source(name)
+		} else if values[name] == nil {
+			values[name], sources[name] = []string{l.defaults[name]}, "default"
+		}
+	}
+	current, err := reloadableContext(c, values)
+	if err != nil {
+		return err
+	}
+
+	for name := range l.defaults {
+		if l.explicit[name] || equalValues(settings[name], l.values[name]) {
+			continue
+		}
+		if !containsString(reloadableSettings, name) {
+			logger.Log("setting", name, "changed", "restart to apply")
+			continue
+		}
+		l.mu.Lock()
+		l.sources[name] = sources[name]
+		l.mu.Unlock()
+		logger.Log("setting", name, "value", strings.Join(values[name], ","))
+	}
+	l.current.Store(current)
+	l.values, l.modTime = settings, modTime
+	return nil
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/urfave/cli"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// runTestApp runs an app with some of the settings of the server, calling action with its context once the config
+// is loaded
+func runTestApp(args []string, action func(c *cli.Context)) error {
+	app := cli.NewApp()
+	app.Name = "test"
+	app.Writer = ioutil.Discard
+	app.Flags = withEnvVars(append([]cli.Flag{
+		cli.StringFlag{Name: "config"},
+		cli.StringFlag{Name: "mongoaddr", Value: "localhost:27017"},
+		cli.StringFlag{Name: "mongoauthpassword"},
+		cli.Float64Flag{Name: "ratelimit", Value: 10},
+		cli.IntFlag{Name: "rateburst", Value: 10},
+		cli.Float64Flag{Name: "addressratelimit", Value: 50},
+		cli.IntFlag{Name: "addressrateburst", Value: 100},
+		cli.StringFlag{Name: "ratelimitmode", Value: string(common.RateLimitError)},
+		cli.DurationFlag{Name: "ratelimitmaxdelay", Value: time.Second},
+		cli.StringFlag{Name: "ratelimitfile"},
+	}, secretFileFlags()...))
+	app.Metadata = make(map[string]interface{})
+	app.Before = loadConfig
+	app.Action = func(c *cli.Context) error {
+		action(c)
+		return nil
+	}
+	return app.Run(append([]string{"test"}, args...))
+}
+
+func writeTestFile(dir, name, data string) string {
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(data), 0600); err != nil {
+		panic(err)
+	}
+	return file
+}
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docnogen-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	Convey("Given a config file", t, func() {
+		config := writeTestFile(dir, "config.yaml", "mongoaddr: file-db:27017\nratelimit: 3\nrateburst: 30\naddressratelimit: 300\n")
+
+		Convey("A flag wins over the environment, the environment over the file and the file over the default", func() {
+			os.Setenv("DOCNOGEN_RATELIMIT", "2")
+			os.Setenv("DOCNOGEN_RATEBURST", "20")
+			defer os.Unsetenv("DOCNOGEN_RATELIMIT")
+			defer os.Unsetenv("DOCNOGEN_RATEBURST")
+
+			var l *configLoader
+			err := runTestApp([]string{"--config", config, "--ratelimit", "1"}, func(c *cli.Context) {
+				l = c.App.Metadata[configMetadataKey].(*configLoader)
+				So(c.GlobalFloat64("ratelimit"), ShouldEqual, 1)
+				So(c.GlobalInt("rateburst"), ShouldEqual, 20)
+				So(c.GlobalFloat64("addressratelimit"), ShouldEqual, 300)
+				So(c.GlobalInt("addressrateburst"), ShouldEqual, 100)
+				So(c.GlobalString("mongoaddr"), ShouldEqual, "file-db:27017")
+
+				current := currentSettings(c)
+				So(current.Float64("ratelimit"), ShouldEqual, 1)
+				So(current.Int("rateburst"), ShouldEqual, 20)
+				So(current.Float64("addressratelimit"), ShouldEqual, 300)
+				So(current.Int("addressrateburst"), ShouldEqual, 100)
+			})
+			So(err, ShouldBeNil)
+			So(l.source("ratelimit"), ShouldEqual, "flag or env")
+			So(l.source("rateburst"), ShouldEqual, "flag or env")
+			So(l.source("addressratelimit"), ShouldEqual, "file")
+			So(l.source("addressrateburst"), ShouldEqual, "default")
+		})
+
+		Convey("An unknown setting in the file fails", func() {
+			config := writeTestFile(dir, "unknown.yaml", "mongoport: 27017\n")
+			err := runTestApp([]string{"--config", config}, func(c *cli.Context) {})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unknown setting mongoport")
+		})
+
+		Convey("A reload applies the reloadable settings and only logs the others", func() {
+			var logged []string
+			logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+				logged = append(logged, fmt.Sprint(keyvals...))
+				return nil
+			})
+
+			err := runTestApp([]string{"--config", config, "--addressrateburst", "5"}, func(c *cli.Context) {
+				l := c.App.Metadata[configMetadataKey].(*configLoader)
+				writeTestFile(dir, "config.yaml", "mongoaddr: other-db:27017\nrateburst: 40\naddressratelimit: 300\naddressrateburst: 400\n")
+				So(l.reload(c, logger), ShouldBeNil)
+
+				current := currentSettings(c)
+				So(current.Int("rateburst"), ShouldEqual, 40)
+				So(l.source("rateburst"), ShouldEqual, "file")
+
+				Convey("A setting removed from the file falls back to its default", func() {
+					So(current.Float64("ratelimit"), ShouldEqual, 10)
+					So(l.source("ratelimit"), ShouldEqual, "default")
+				})
+
+				Convey("A setting of a flag is not overridden", func() {
+					So(current.Int("addressrateburst"), ShouldEqual, 5)
+					So(l.source("addressrateburst"), ShouldEqual, "flag or env")
+				})
+
+				Convey("A setting that is not reloadable keeps its value and is logged", func() {
+					So(c.GlobalString("mongoaddr"), ShouldEqual, "file-db:27017")
+					So(l.source("mongoaddr"), ShouldEqual, "file")
+					So(logged, ShouldContain, "settingmongoaddrchangedrestart to apply")
+				})
+
+				Convey("Only the changed settings are logged", func() {
+					So(logged, ShouldContain, "settingrateburstvalue40")
+					So(logged, ShouldContain, "settingratelimitvalue10")
+					So(logged, ShouldHaveLength, 3)
+				})
+			})
+			So(err, ShouldBeNil)
+		})
+	})
+
+	Convey("Given a password file", t, func() {
+		file := writeTestFile(dir, "password", "s3cret\n")
+
+		Convey("The password is read from it, without the trailing newline", func() {
+			var l *configLoader
+			err := runTestApp([]string{"--mongoauthpasswordfile", file}, func(c *cli.Context) {
+				l = c.App.Metadata[configMetadataKey].(*configLoader)
+				So(c.GlobalString("mongoauthpassword"), ShouldEqual, "s3cret")
+			})
+			So(err, ShouldBeNil)
+			So(l.source("mongoauthpassword"), ShouldEqual, "file "+file)
+		})
+
+		Convey("The password file can be named in the environment", func() {
+			os.Setenv("DOCNOGEN_MONGOAUTHPASSWORDFILE", file)
+			defer os.Unsetenv("DOCNOGEN_MONGOAUTHPASSWORDFILE")
+			err := runTestApp(nil, func(c *cli.Context) {
+				So(c.GlobalString("mongoauthpassword"), ShouldEqual, "s3cret")
+			})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Setting the password as well fails", func() {
+			err := runTestApp([]string{"--mongoauthpassword", "other", "--mongoauthpasswordfile", file}, func(c *cli.Context) {})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "--mongoauthpassword and --mongoauthpasswordfile are both set")
+		})
+
+		Convey("A missing password file fails", func() {
+			err := runTestApp([]string{"--mongoauthpasswordfile", filepath.Join(dir, "missing")}, func(c *cli.Context) {})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "Error reading mongoauthpassword file")
+		})
+	})
+}
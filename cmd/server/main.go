@@ -2,26 +2,39 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	stdLog "log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/gorilla/handlers"
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/urfave/cli"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 
+	"github.com/howlun/go-kit-documentnogen/common"
 	docnogensvc "github.com/howlun/go-kit-documentnogen/services/docnogen"
 	docnogenendpoints "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/endpoints"
+	docnogenopenapi "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/openapi"
 	docnogenpb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
 	docnogengrpctransport "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/transports/grpc"
 	docnogenhttptransport "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/transports/http"
+	docnogenresttransport "github.com/howlun/go-kit-documentnogen/services/docnogen/transports/rest"
 
 	docnogenmodel "github.com/howlun/go-kit-documentnogen/services/docnogen/models"
 
@@ -33,6 +46,12 @@ import (
 	"github.com/rs/cors"
 )
 
+// set at build time, see the Makefile
+var (
+	gitCommit = "unknown"
+	buildTime = "unknown"
+)
+
 func main() {
 	app := cli.NewApp()
 	app.Name = "docnogen-server"
@@ -54,6 +73,15 @@ func main() {
 				Value:  &cli.StringSlice{"127.0.0.1:9092"},
 			},
 		*/
+		cli.StringFlag{
+			Name:  "config",
+			Usage: "YAML (.yaml, .yml) or TOML (.toml) file of settings named as the flags, which flags and DOCNOGEN_ environment variables override",
+		},
+		cli.DurationFlag{
+			Name:  "configreloadinterval",
+			Value: 10 * time.Second,
+			Usage: "How often the config and rate limit files are checked for changes, the rate limits are applied again without a restart",
+		},
 		cli.StringFlag{
 			Name:  "httpaddr",
 			Value: ":12000",
@@ -64,6 +92,10 @@ func main() {
 			Value: ":13000",
 			Usage: "GRPC Server Address",
 		},
+		cli.StringFlag{
+			Name:  "adminaddr",
+			Usage: "Admin Http Server Address of /admin/circuitbreakers, which should only be reachable by operators. Without it the admin handlers are served on --httpaddr, and only with authentication on",
+		},
 		cli.StringFlag{
 			Name:  "mongoaddr",
 			Value: "localhost:27017",
@@ -84,12 +116,345 @@ func main() {
 			Value: "",
 			Usage: "Mongo DB Auth Password",
 		},
+		cli.StringFlag{
+			Name:  "mongolayout",
+			Value: docnogenmodel.LayoutCollection,
+			Usage: "Mongo layout of the counters: collection (one collection per organization) or shared (one collection for all organizations)",
+		},
+		cli.IntFlag{
+			Name:  "retrymaxattempts",
+			Value: common.DefaultRetryPolicy.MaxAttempts,
+			Usage: "Maximum attempts to generate a document number when concurrent updates conflict",
+		},
+		cli.DurationFlag{
+			Name:  "retryinitialbackoff",
+			Value: common.DefaultRetryPolicy.InitialBackoff,
+			Usage: "Wait before the first retry, doubled on every further retry",
+		},
+		cli.DurationFlag{
+			Name:  "retrymaxbackoff",
+			Value: common.DefaultRetryPolicy.MaxBackoff,
+			Usage: "Maximum wait between retries",
+		},
+		cli.StringFlag{
+			Name:  "httptlscert",
+			Usage: "PEM certificate file of the HTTP listener, which serves HTTPS when set",
+		},
+		cli.StringFlag{
+			Name:  "httptlskey",
+			Usage: "PEM key file of the HTTP listener certificate",
+		},
+		cli.StringFlag{
+			Name:  "httptlsca",
+			Usage: "PEM CA file verifying the client certificates of the HTTP listener",
+		},
+		cli.BoolFlag{
+			Name:  "httptlsverifyclient",
+			Usage: "Require HTTP clients to present a certificate signed by --httptlsca",
+		},
+		cli.StringFlag{
+			Name:  "grpctlscert",
+			Usage: "PEM certificate file of the gRPC listener, which serves TLS when set",
+		},
+		cli.StringFlag{
+			Name:  "grpctlskey",
+			Usage: "PEM key file of the gRPC listener certificate",
+		},
+		cli.StringFlag{
+			Name:  "grpctlsca",
+			Usage: "PEM CA file verifying the client certificates of the gRPC listener",
+		},
+		cli.BoolFlag{
+			Name:  "grpctlsverifyclient",
+			Usage: "Require gRPC clients to present a certificate signed by --grpctlsca",
+		},
+		cli.DurationFlag{
+			Name:  "tlsreloadinterval",
+			Value: 10 * time.Second,
+			Usage: "How often the certificate, key and CA files are checked for changes, on a handshake",
+		},
+		cli.StringSliceFlag{
+			Name:  "jwthskeyfile",
+			Usage: "File with an HMAC secret verifying HS256, HS384 and HS512 tokens, can be repeated",
+		},
+		cli.StringSliceFlag{
+			Name:  "jwtrsakeyfile",
+			Usage: "PEM RSA public key file verifying RS256, RS384 and RS512 tokens, can be repeated",
+		},
+		cli.StringSliceFlag{
+			Name:  "jwtjwksfile",
+			Usage: "JSON Web Key Set file with RSA and oct keys verifying tokens by kid, can be repeated",
+		},
+		cli.StringFlag{
+			Name:  "jwtissuer",
+			Usage: "Issuer (iss) the tokens must have",
+		},
+		cli.StringFlag{
+			Name:  "jwtaudience",
+			Usage: "Audience (aud) the tokens must have",
+		},
+		cli.StringFlag{
+			Name:  "jwtorgclaim",
+			Value: common.DefaultOrgClaim,
+			Usage: "Claim listing the organizations a token may use, \"*\" allows all of them",
+		},
+		cli.StringFlag{
+			Name:  "jwtroleclaim",
+			Value: common.DefaultRoleClaim,
+			Usage: "Claim listing the roles of a token",
+		},
+		cli.StringSliceFlag{
+			Name:  "jwtdefaultroles",
+			Usage: "Roles of a token without the role claim, none by default; admin cannot be one of them",
+		},
+		cli.BoolFlag{
+			Name:  "apikeyauth",
+			Usage: "Accept the API keys made with the apikey command or CreateAPIKey, in the X-API-Key header",
+		},
+		cli.DurationFlag{
+			Name:  "apikeycachettl",
+			Value: 30 * time.Second,
+			Usage: "How long an API key is cached, a revoked key may still be accepted for that long",
+		},
+		cli.Float64Flag{
+			Name:  "ratelimit",
+			Value: 10,
+			Usage: "Requests per second allowed for each method and client, 0 does not limit them",
+		},
+		cli.IntFlag{
+			Name:  "rateburst",
+			Value: 10,
+			Usage: "Requests allowed at once for each method and client",
+		},
+		cli.Float64Flag{
+			Name:  "addressratelimit",
+			Value: 50,
+			Usage: "Requests per second allowed for each peer address before authentication, 0 does not limit them",
+		},
+		cli.IntFlag{
+			Name:  "addressrateburst",
+			Value: 100,
+			Usage: "Requests allowed at once for each peer address before authentication",
+		},
+		cli.StringFlag{
+			Name:  "ratelimitmode",
+			Value: string(common.RateLimitError),
+			Usage: "What happens to requests over their limit, unless they choose with X-RateLimit-Mode: error (RESOURCE_EXHAUSTED) or delay",
+		},
+		cli.DurationFlag{
+			Name:  "ratelimitmaxdelay",
+			Value: time.Second,
+			Usage: "Longest wait of a delayed request, a longer one fails as in error mode",
+		},
+		cli.StringFlag{
+			Name:  "ratelimitfile",
+			Usage: "JSON file of rate limit rules by method, org and client, tried in order before the default one",
+		},
+		cli.StringFlag{
+			Name:  "circuitbreaker",
+			Value: common.CircuitBreakerGobreaker,
+			Usage: "Circuit breaker of every method: gobreaker or hystrix, which needs --circuitbreakerfile with a timeout in every rule",
+		},
+		cli.StringFlag{
+			Name:  "circuitbreakerfile",
+			Usage: "JSON file of circuit breaker settings by method, the first rule matching a method applies",
+		},
+		cli.StringFlag{
+			Name:  "traceexporter",
+			Value: "none",
+			Usage: "Where the spans of the requests go: none, stdout, file (--tracefile) or otlp (--traceotlpendpoint)",
+		},
+		cli.StringFlag{
+			Name:  "tracefile",
+			Value: "log/trace.jsonl",
+			Usage: "File the spans are appended to as JSON lines, with --traceexporter file",
+		},
+		cli.StringFlag{
+			Name:  "traceotlpendpoint",
+			Value: "http://localhost:4318/v1/traces",
+			Usage: "OTLP/HTTP traces endpoint of an OpenTelemetry collector, with --traceexporter otlp",
+		},
+		cli.Float64Flag{
+			Name:  "tracesampleratio",
+			Value: 1,
+			Usage: "Share of the traces started here that are recorded, 0 to 1; the caller decides for the traces it continues",
+		},
+		cli.BoolFlag{
+			Name:  "grpcreflection",
+			Usage: "Register the gRPC server reflection service, for tools like grpcurl",
+		},
+		cli.DurationFlag{
+			Name:  "healthinterval",
+			Value: 5 * time.Second,
+			Usage: "Interval of the Mongo checks behind the gRPC health service, every check and every /readyz check times out after the interval too",
+		},
+		cli.DurationFlag{
+			Name:  "shutdowntimeout",
+			Value: 30 * time.Second,
+			Usage: "Time the servers get to finish the in-flight requests on SIGINT or SIGTERM before they are closed",
+		},
+		cli.StringFlag{
+			Name:  "servicemiddlewares",
+			Value: "instrumenting",
+			Usage: "Comma separated service middlewares, the first outermost: logging and instrumenting",
+		},
+		cli.StringFlag{
+			Name:  "endpointmiddlewares",
+			Value: "instrumenting,logging,addressratelimit,auth,ratelimit,circuitbreaker",
+			Usage: "Comma separated endpoint middlewares, the first outermost: instrumenting, logging, addressratelimit, auth, ratelimit and circuitbreaker",
+		},
+		cli.StringFlag{
+			Name:  "loglevel",
+			Value: "info",
+			Usage: "Least level logged: debug, info, warn or error; debug logs the steps of every request",
+		},
+		cli.StringFlag{
+			Name:  "logformat",
+			Value: "logfmt",
+			Usage: "Format of the log: logfmt or json",
+		},
+		cli.StringSliceFlag{
+			Name:  "logredact",
+			Usage: "Log key or variable map name whose values are logged as [REDACTED], case insensitive, repeatable",
+		},
 		cli.StringFlag{
 			Name:  "httplog",
 			Value: "log/http.log",
 			Usage: "HTTP log directory and filename",
 		},
 	}
+	app.Flags = withEnvVars(append(app.Flags, secretFileFlags()...))
+	cli.FlagStringer = redactedFlagStringer(cli.FlagStringer)
+	app.Metadata = make(map[string]interface{})
+	app.Before = loadConfig
+	app.Commands = []cli.Command{
+		{
+			Name:      "export",
+			Usage:     "Export the counters of an organization",
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "org",
+					Usage: "Organization Code to export",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Value: "json",
+					Usage: "Dump format: json or csv",
+				},
+				cli.StringFlag{
+					Name:  "out",
+					Usage: "Output file (default: stdout)",
+				},
+			},
+			Action: runExport,
+		},
+		{
+			Name:      "import",
+			Usage:     "Import the counters of an organization from an export",
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "org",
+					Usage: "Organization Code to import into",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Value: "json",
+					Usage: "Dump format: json or csv",
+				},
+				cli.StringFlag{
+					Name:  "in",
+					Usage: "Input file (default: stdin)",
+				},
+				cli.StringFlag{
+					Name:  "mode",
+					Value: "merge",
+					Usage: "Import mode: merge (keep counters not in the dump) or overwrite (delete counters not in the dump)",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Report the changes without writing them",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "Allow NextSeqNo to move backwards",
+				},
+			},
+			Action: runImport,
+		},
+		{
+			Name:      "migrate-layout",
+			Usage:     "Move the counters of every organization collection into the shared collection, one organization at a time, while serving",
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "drop-source",
+					Usage: "Drop the organization collections once moved",
+				},
+				cli.DurationFlag{
+					Name:  "request-wait",
+					Value: 10 * time.Second,
+					Usage: "How long to wait, once, for the servers to create new organizations in the shared layout, longer than the slowest request",
+				},
+			},
+			Action: runMigrateLayout,
+		},
+		{
+			Name:  "apikey",
+			Usage: "Create, list and revoke API keys",
+			Subcommands: []cli.Command{
+				{
+					Name:      "create",
+					Usage:     "Create an API key, it is only printed once",
+					ArgsUsage: " ",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "name",
+							Usage: "Name of the key, who or what uses it",
+						},
+						cli.StringSliceFlag{
+							Name:  "org",
+							Usage: "Organization Code the key may use, \"*\" for all of them, can be repeated",
+						},
+						cli.StringSliceFlag{
+							Name:  "role",
+							Usage: "Role of the key: peek, issue, consume, void or admin, can be repeated",
+						},
+						cli.DurationFlag{
+							Name:  "expires",
+							Usage: "Time after which the key expires (default: never)",
+						},
+					},
+					Action: runCreateAPIKey,
+				},
+				{
+					Name:      "list",
+					Usage:     "List the API keys",
+					ArgsUsage: " ",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "org",
+							Usage: "Only the keys of this Organization Code (default: all keys)",
+						},
+					},
+					Action: runListAPIKeys,
+				},
+				{
+					Name:      "revoke",
+					Usage:     "Revoke an API key",
+					ArgsUsage: " ",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "id",
+							Usage: "Id of the key to revoke",
+						},
+					},
+					Action: runRevokeAPIKey,
+				},
+			},
+		},
+	}
 	app.Action = runMain
 	err := app.Run(os.Args)
 	if err != nil {
@@ -103,14 +468,118 @@ func runMain(c *cli.Context) error {
 
 	mux := http.NewServeMux()
 	ctx := context.Background()
-	errc := make(chan error)
-	s := grpc.NewServer()
+	// one slot per sender, so that none blocks once the first error is received
+	errc := make(chan error, 3)
+	healthServer := health.NewServer()
+	probeCtx, stopProbe := context.WithCancel(ctx)
+	defer stopProbe()
 	var logger log.Logger
 	{
-		logger = log.NewLogfmtLogger(os.Stdout)
+		switch c.String("logformat") {
+		case "logfmt":
+			logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stdout))
+		case "json":
+			logger = log.NewJSONLogger(log.NewSyncWriter(os.Stdout))
+		default:
+			stdLog.Fatal(fmt.Errorf("--logformat must be logfmt or json, not %s", c.String("logformat")))
+		}
+		level, err := common.ParseLogLevel(c.String("loglevel"))
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		logger = common.NewRedactingLogger(logger, c.StringSlice("logredact"))
+		logger = common.NewLevelFilter(logger, level)
 		logger = log.With(logger, "ts", log.DefaultTimestampUTC)
 		logger = log.With(logger, "caller", log.DefaultCaller)
 	}
+	logConfig(c, logger)
+	var httpTLS, grpcTLS *tls.Config
+	{
+		var err error
+		httpTLS, err = listenerTLSConfig(c, "http", logger)
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		grpcTLS, err = listenerTLSConfig(c, "grpc", logger)
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+	}
+	var authenticators []common.Authenticator
+	{
+		verifier, err := jwtVerifier(c)
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		if verifier != nil {
+			authenticators = append(authenticators, verifier)
+			logger.Log("auth", "jwt", "orgclaim", c.String("jwtorgclaim"), "roleclaim", c.String("jwtroleclaim"), "defaultroles", strings.Join(c.StringSlice("jwtdefaultroles"), ","))
+		}
+	}
+	var limiter, addressLimiter *common.RateLimiter
+	{
+		settings := currentSettings(c)
+		rules, err := rateLimitRules(settings)
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		limiter, err = common.NewRateLimiter(rules)
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		addressLimiter, err = common.NewRateLimiter(addressRateLimitRules(settings))
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		logger.Log("ratelimit", settings.Float64("ratelimit"), "burst", settings.Int("rateburst"), "mode", settings.String("ratelimitmode"), "rules", len(rules)-1, "addressratelimit", settings.Float64("addressratelimit"), "addressburst", settings.Int("addressrateburst"))
+	}
+	go watchConfig(probeCtx, c, c.Duration("configreloadinterval"), []string{"ratelimitfile"}, func() error {
+		settings := currentSettings(c)
+		rules, err := rateLimitRules(settings)
+		if err != nil {
+			return err
+		}
+		if err := limiter.SetRules(rules); err != nil {
+			return err
+		}
+		if err := addressLimiter.SetRules(addressRateLimitRules(settings)); err != nil {
+			return err
+		}
+		logger.Log("ratelimit", settings.Float64("ratelimit"), "burst", settings.Int("rateburst"), "mode", settings.String("ratelimitmode"), "rules", len(rules)-1, "addressratelimit", settings.Float64("addressratelimit"), "addressburst", settings.Int("addressrateburst"))
+		return nil
+	}, logger)
+	var tracer *common.Tracer
+	{
+		var err error
+		tracer, err = newTracer(c, logger)
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		if tracer != nil {
+			opentracing.SetGlobalTracer(tracer)
+		}
+	}
+	var panics metrics.Counter
+	{
+		panics = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "howlun",
+			Subsystem: "docnogen",
+			Name:      "panics_recovered_total",
+			Help:      "Panics of request handlers turned into INTERNAL errors.",
+		}, []string{"transport", "method"})
+	}
+	var s *grpc.Server
+	{
+		// a panicking handler fails its request instead of the server
+		options := []grpc.ServerOption{
+			grpc.UnaryInterceptor(common.RecoveryGRPCUnaryInterceptor(log.With(logger, "transport", "gRPC"), panics)),
+			grpc.StreamInterceptor(common.RecoveryGRPCStreamInterceptor(log.With(logger, "transport", "gRPC"), panics)),
+		}
+		if grpcTLS != nil {
+			options = append(options, grpc.Creds(credentials.NewTLS(grpcTLS)))
+		}
+		s = grpc.NewServer(options...)
+	}
 	/*
 		var kafkaSyncProducer sarama.SyncProducer
 		{
@@ -136,7 +605,93 @@ func runMain(c *cli.Context) error {
 			Help:      "Request duration in seconds.",
 		}, []string{"method", "success"})
 	}
+	var retry docnogensvc.RetryConfig
+	{
+		retry.Policy = common.DefaultRetryPolicy
+		retry.Policy.MaxAttempts = c.Int("retrymaxattempts")
+		retry.Policy.InitialBackoff = c.Duration("retryinitialbackoff")
+		retry.Policy.MaxBackoff = c.Duration("retrymaxbackoff")
+		// Service-level metrics.
+		retry.Retries = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "howlun",
+			Subsystem: "docnogen",
+			Name:      "conflict_retries_total",
+			Help:      "Retries after a concurrency update conflict.",
+		}, []string{"method"})
+		retry.Exhausted = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "howlun",
+			Subsystem: "docnogen",
+			Name:      "contention_failures_total",
+			Help:      "Requests given up on after the maximum retries.",
+		}, []string{"method"})
+	}
+	var instrumenting docnogensvc.Middleware
+	{
+		// Business metrics of the numbers handed out.
+		instrumenting = docnogensvc.InstrumentingMiddleware(
+			prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "howlun",
+				Subsystem: "docnogen",
+				Name:      "numbers_total",
+				Help:      "Document numbers issued, peeked and consumed.",
+			}, []string{"action", "org", "doc_code"}),
+			prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+				Namespace: "howlun",
+				Subsystem: "docnogen",
+				Name:      "bulk_size",
+				Help:      "Numbers issued by each successful GenerateBulkDocNoFormat.",
+				Buckets:   []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000},
+			}, []string{"org"}),
+			prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+				Namespace: "howlun",
+				Subsystem: "docnogen",
+				Name:      "next_seq_no",
+				Help:      "NextSeqNo of the counter of each org and doc code last used.",
+			}, []string{"org", "doc_code"}),
+			prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+				Namespace: "howlun",
+				Subsystem: "docnogen",
+				Name:      "remaining_capacity",
+				Help:      "Numbers the counter of each org and doc code last used can still issue before the pad width or the max value is exceeded.",
+			}, []string{"org", "doc_code", "limit"}),
+		)
+	}
+	var breakers *common.CircuitBreakers
+	{
+		var rules []common.CircuitBreakerRule
+		var err error
+		if c.String("circuitbreakerfile") != "" {
+			rules, err = common.LoadCircuitBreakerRules(c.String("circuitbreakerfile"))
+			if err != nil {
+				stdLog.Fatal(err)
+			}
+		}
+		breakers, err = common.NewCircuitBreakers(c.String("circuitbreaker"), rules, logger,
+			prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+				Namespace: "howlun",
+				Subsystem: "docnogen",
+				Name:      "circuit_breaker_state",
+				Help:      "State of the circuit breaker of each method: 0 closed, 1 half-open, 2 open.",
+			}, []string{"method"}),
+			prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "howlun",
+				Subsystem: "docnogen",
+				Name:      "circuit_breaker_state_changes_total",
+				Help:      "State changes of the circuit breakers.",
+			}, []string{"method", "from", "to"}),
+		)
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		logger.Log("circuitbreaker", c.String("circuitbreaker"), "rules", len(rules))
+	}
 	mux.Handle("/metrics", promhttp.Handler())
+	var probes *common.Probes
+	adminMux := http.NewServeMux()
+	var adminServer *http.Server
+	if c.String("adminaddr") != "" {
+		adminServer = &http.Server{Addr: c.String("adminaddr"), Handler: common.NewRecoveryHTTPHandler(adminMux, logger, panics)}
+	}
 
 	{
 		dbclient := docnogenmodel.NewDBClient(c.String("mongoaddr"), c.String("mongodbname"), c.String("mongoauthusername"), c.String("mongoauthpassword"))
@@ -146,15 +701,103 @@ func runMain(c *cli.Context) error {
 		}
 		defer dbclient.Close()
 
-		docNoRepo := docnogenmodel.NewDocNoRepository(dbclient)
+		// bring the Mongo schema (indexes, de-duplicated documents) to the version this build expects
+		from, to, err := docnogenmodel.NewSchemaMigrator(dbclient).Migrate()
+		if err != nil {
+			stdLog.Fatal(fmt.Errorf("Failed to migrate Mongo schema: %s", err.Error()))
+		}
+		logger.Log("schema_from", from, "schema_to", to)
+
+		docNoRepo, err := docnogenmodel.NewDocNoRepositoryWithLayout(dbclient, c.String("mongolayout"))
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		docNoRepo = docnogenmodel.NewTracingDocNoRepository(docNoRepo, "mongodb")
+		logger.Log("mongolayout", c.String("mongolayout"))
+
+		// readiness follows Mongo and its schema version, liveness only the process
+		probes = common.NewProbes(common.BuildInfo{Version: c.App.Version, GitCommit: gitCommit, BuildTime: buildTime}, c.Duration("healthinterval"),
+			common.ReadinessCheck{Name: "mongo", Check: dbclient.Ping},
+			common.ReadinessCheck{Name: "schema", Check: func(ctx context.Context) error { return docnogenmodel.CheckSchemaVersion(ctx, dbclient) }},
+		)
+		probes.RegisterHandlers(mux)
+
+		apiKeyRepo := docnogenmodel.NewTracingAPIKeyRepository(docnogenmodel.NewAPIKeyRepository(dbclient), "mongodb")
+		if c.Bool("apikeyauth") {
+			authenticators = append(authenticators, docnogensvc.NewAPIKeyAuthenticator(apiKeyRepo, c.Duration("apikeycachettl")))
+			logger.Log("auth", "apikey", "cachettl", c.Duration("apikeycachettl"))
+		}
+		// requests should be authenticated before they are rate limited by client, so auth goes before ratelimit, and
+		// limited by address before, so addressratelimit goes before auth
+		endpointMiddlewares := docnogenendpoints.DefaultMiddlewares(logger, duration)
+		endpointMiddlewares["addressratelimit"] = addressLimiter.AddressMiddleware()
+		endpointMiddlewares["ratelimit"] = limiter.Middleware()
+		endpointMiddlewares["circuitbreaker"] = breakers.Middleware()
+		endpointMiddlewares["auth"] = nil
+		if len(authenticators) > 0 {
+			endpointMiddlewares["auth"] = common.NewAuthMiddleware(docnogensvc.Permissions, authenticators...)
+		} else {
+			logger.Log("auth", "disabled, no --jwthskeyfile, --jwtrsakeyfile, --jwtjwksfile or --apikeyauth")
+		}
+		// the breaker states are for admins, like the API keys
+		adminMiddlewares := []common.MethodMiddleware{common.TraceMethodMiddleware("addressratelimit", endpointMiddlewares["addressratelimit"])}
+		if auth := endpointMiddlewares["auth"]; auth != nil {
+			adminMiddlewares = append(adminMiddlewares, common.TraceMethodMiddleware("auth", auth))
+		}
+		// without authentication anyone reaching --httpaddr could read them, so they need a listener of their own
+		switch {
+		case adminServer != nil:
+			adminMux.Handle("/admin/circuitbreakers", common.NewCircuitBreakersHTTPHandler(breakers, logger, adminMiddlewares...))
+		case endpointMiddlewares["auth"] != nil:
+			mux.Handle("/admin/circuitbreakers", common.NewCircuitBreakersHTTPHandler(breakers, logger, adminMiddlewares...))
+		default:
+			logger.Log("admin", "disabled, no authentication and no --adminaddr")
+		}
+		endpointChain, err := endpointMiddlewares.Chain(common.ParseChain(c.String("endpointmiddlewares")))
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		serviceMiddlewares := docnogensvc.Middlewares{
+			"logging":       docnogensvc.LoggingMiddleware(log.With(logger, "layer", "service")),
+			"instrumenting": instrumenting,
+		}
+		serviceChain, err := serviceMiddlewares.Chain(common.ParseChain(c.String("servicemiddlewares")))
+		if err != nil {
+			stdLog.Fatal(err)
+		}
+		logger.Log("servicemiddlewares", c.String("servicemiddlewares"), "endpointmiddlewares", c.String("endpointmiddlewares"))
 
 		docNoFormatterSvc := docnogensvc.NewDocnoformatterService()
-		svc := docnogensvc.NewDocnogenService(docNoRepo, docNoFormatterSvc)
-		endpoints := docnogenendpoints.MakeEndpoints(svc, logger, duration)
+		var svc docnogenpb.DocNoGenServiceServer
+		svc = docnogensvc.NewDocnogenServiceWithAPIKeys(docNoRepo, docNoFormatterSvc, retry, apiKeyRepo)
+		svc = serviceChain(svc)
+		endpoints := docnogenendpoints.MakeEndpoints(svc, endpointChain)
 		srv := docnogengrpctransport.MakeGRPCServer(ctx, endpoints, logger)
 		docnogenpb.RegisterDocNoGenServiceServer(s, srv)
 		docnogenhttptransport.RegisterHandlers(ctx, svc, mux, endpoints, logger)
+		docnogenresttransport.RegisterHandlers(mux, endpoints, logger)
+		if err := docnogenopenapi.RegisterHandlers(mux, logger, docnogenresttransport.OpenAPIPaths); err != nil {
+			stdLog.Fatal(err)
+		}
+
+		// the health of the server and of every service follows Mongo
+		services := []string{""}
+		for name := range s.GetServiceInfo() {
+			services = append(services, name)
+		}
+		go common.ProbeHealth(probeCtx, healthServer, services, c.Duration("healthinterval"), c.Duration("healthinterval"), dbclient.Ping, func(err error) {
+			if err != nil {
+				logger.Log("health", healthpb.HealthCheckResponse_NOT_SERVING, "err", err)
+				return
+			}
+			logger.Log("health", healthpb.HealthCheckResponse_SERVING)
+		})
+	}
+	healthpb.RegisterHealthServer(s, healthServer)
+	if c.Bool("grpcreflection") {
+		reflection.Register(s)
 	}
+	httpServer := &http.Server{Addr: c.String("httpaddr"), TLSConfig: httpTLS}
 
 	// start servers
 	go func() {
@@ -165,25 +808,45 @@ func runMain(c *cli.Context) error {
 
 	go func() {
 		logger := log.With(logger, "transport", "HTTP")
-		logger.Log("addr", c.String("httpaddr"))
+		logger.Log("addr", c.String("httpaddr"), "tls", httpTLS != nil)
 
 		// http log writer
 		err := ensureDir(c.String("httplog"))
 		if err != nil {
 			errc <- err
+			return
 		}
 		httpLogFile, err := os.OpenFile(c.String("httplog"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
 		if err != nil {
 			errc <- err
+			return
 		}
 
 		// enable cors
 		cr := cors.AllowAll()
 
 		// gorilla/handlers LoggingHandler is used for logging HTTP requests in the Apache Common Log Format
-		errc <- http.ListenAndServe(c.String("httpaddr"), cr.Handler(handlers.LoggingHandler(httpLogFile, mux)))
+		httpServer.Handler = cr.Handler(handlers.LoggingHandler(httpLogFile, common.NewRecoveryHTTPHandler(mux, logger, panics)))
+		if httpTLS != nil {
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			errc <- err
+		}
 	}()
 
+	if adminServer != nil {
+		go func() {
+			logger := log.With(logger, "transport", "admin HTTP")
+			logger.Log("addr", c.String("adminaddr"))
+			if err := adminServer.ListenAndServe(); err != http.ErrServerClosed {
+				errc <- err
+			}
+		}()
+	}
+
 	go func() {
 		logger := log.With(logger, "transport", "gRPC")
 		ln, err := net.Listen("tcp", c.String("grpcaddr"))
@@ -191,14 +854,401 @@ func runMain(c *cli.Context) error {
 			errc <- err
 			return
 		}
-		logger.Log("addr", c.String("grpcaddr"))
+		logger.Log("addr", c.String("grpcaddr"), "tls", grpcTLS != nil)
 		errc <- s.Serve(ln)
 	}()
 
 	logger.Log("exit", <-errc)
+	stopProbe()
+	shutdown(httpServer, adminServer, s, healthServer, probes, c.Duration("shutdowntimeout"), logger)
+	if tracer != nil {
+		// the spans of the last requests are exported before exiting
+		ctx, cancel := context.WithTimeout(context.Background(), c.Duration("shutdowntimeout"))
+		defer cancel()
+		if err := tracer.Shutdown(ctx); err != nil {
+			logger.Log("tracing", "shutdown", "err", err)
+		}
+	}
 	return nil
 }
 
+// listenerTLSConfig returns the TLS config of the listener from the flags starting with prefix, or nil for plaintext
+func listenerTLSConfig(c *cli.Context, prefix string, logger log.Logger) (*tls.Config, error) {
+	files := common.TLSFiles{
+		CertFile:     c.String(prefix + "tlscert"),
+		KeyFile:      c.String(prefix + "tlskey"),
+		CAFile:       c.String(prefix + "tlsca"),
+		VerifyClient: c.Bool(prefix + "tlsverifyclient"),
+	}
+	if !files.Enabled() {
+		if files.CAFile != "" || files.VerifyClient {
+			return nil, fmt.Errorf("--%stlsca and --%stlsverifyclient need --%stlscert and --%stlskey", prefix, prefix, prefix, prefix)
+		}
+		return nil, nil
+	}
+	logger = log.With(logger, "listener", prefix)
+	config, err := common.NewServerTLSConfig(files, c.Duration("tlsreloadinterval"), func(err error) {
+		if err != nil {
+			logger.Log("tls", "reload failed, keeping the previous certificate", "err", err)
+			return
+		}
+		logger.Log("tls", "certificate reloaded")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to set up TLS of the %s listener: %s", prefix, err.Error())
+	}
+	return config, nil
+}
+
+// jwtVerifier returns the verifier of the tokens from the jwt flags, or nil when no key is configured
+func jwtVerifier(c *cli.Context) (*common.JWTVerifier, error) {
+	keys := &common.JWTKeys{}
+	for _, file := range c.StringSlice("jwthskeyfile") {
+		if err := keys.AddHMACFile(file); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range c.StringSlice("jwtrsakeyfile") {
+		if err := keys.AddRSAFile(file); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range c.StringSlice("jwtjwksfile") {
+		if err := keys.AddJWKSFile(file); err != nil {
+			return nil, err
+		}
+	}
+	if keys.Len() == 0 {
+		if len(c.StringSlice("jwtjwksfile")) > 0 {
+			return nil, errors.New("--jwtjwksfile has no signing key")
+		}
+		return nil, nil
+	}
+	// a token without the role claim must not be an admin
+	known := map[string]bool{}
+	for _, role := range docnogensvc.Roles {
+		known[role] = role != docnogensvc.RoleAdmin
+	}
+	for _, role := range c.StringSlice("jwtdefaultroles") {
+		if !known[role] {
+			return nil, fmt.Errorf("--jwtdefaultroles cannot grant %s, use %s, %s, %s or %s", role, docnogensvc.RolePeek, docnogensvc.RoleIssue, docnogensvc.RoleConsume, docnogensvc.RoleVoid)
+		}
+	}
+	return common.NewJWTVerifier(keys, common.JWTOptions{
+		Issuer:       c.String("jwtissuer"),
+		Audience:     c.String("jwtaudience"),
+		OrgClaim:     c.String("jwtorgclaim"),
+		RoleClaim:    c.String("jwtroleclaim"),
+		DefaultRoles: c.StringSlice("jwtdefaultroles"),
+	}), nil
+}
+
+// rateLimitRules returns, from the current settings, the rules of --ratelimitfile followed by the default rule of the ratelimit flags, which has a
+// bucket per method and client
+func rateLimitRules(c *cli.Context) ([]common.RateLimitRule, error) {
+	var rules []common.RateLimitRule
+	if c.String("ratelimitfile") != "" {
+		var err error
+		rules, err = common.LoadRateLimitRules(c.String("ratelimitfile"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range rules {
+		if rules[i].MaxDelay == 0 {
+			rules[i].MaxDelay = common.Duration(c.Duration("ratelimitmaxdelay"))
+		}
+	}
+	return append(rules, common.RateLimitRule{
+		Per:      []string{common.RateLimitPerMethod, common.RateLimitPerClient},
+		Rate:     c.Float64("ratelimit"),
+		Burst:    c.Int("rateburst"),
+		Mode:     common.RateLimitMode(c.String("ratelimitmode")),
+		MaxDelay: common.Duration(c.Duration("ratelimitmaxdelay")),
+	}), nil
+}
+
+// addressRateLimitRules returns, from the current settings, the rule of the addressratelimit flags, which has a bucket per peer address
+func addressRateLimitRules(c *cli.Context) []common.RateLimitRule {
+	return []common.RateLimitRule{{
+		Per:   []string{common.RateLimitPerClient},
+		Rate:  c.Float64("addressratelimit"),
+		Burst: c.Int("addressrateburst"),
+		Mode:  common.RateLimitError,
+	}}
+}
+
+// newTracer returns the tracer of --traceexporter, or nil when it is none
+func newTracer(c *cli.Context, logger log.Logger) (*common.Tracer, error) {
+	var exporter common.SpanExporter
+	switch c.String("traceexporter") {
+	case "none", "":
+		return nil, nil
+	case "stdout":
+		exporter = common.NewWriterSpanExporter(os.Stdout)
+	case "file":
+		if err := ensureDir(c.String("tracefile")); err != nil {
+			return nil, err
+		}
+		var err error
+		exporter, err = common.NewFileSpanExporter(c.String("tracefile"))
+		if err != nil {
+			return nil, err
+		}
+	case "otlp":
+		exporter = common.NewOTLPSpanExporter(c.String("traceotlpendpoint"), nil, nil)
+	default:
+		return nil, fmt.Errorf("--traceexporter must be none, stdout, file or otlp, not %s", c.String("traceexporter"))
+	}
+	ratio := c.Float64("tracesampleratio")
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("--tracesampleratio must be between 0 and 1, not %g", ratio)
+	}
+	logger = log.With(logger, "tracing", c.String("traceexporter"))
+	logger.Log("sampleratio", ratio)
+	return common.NewTracer("docnogen", exporter, ratio, func(err error) {
+		logger.Log("export", "failed", "err", err)
+	}), nil
+}
+
+// shutdown stops both servers accepting new requests and waits up to timeout for the in-flight ones, then closes
+// what is left. The health service reports NOT_SERVING and /readyz 503 meanwhile, so that balancers stop sending requests.
+func shutdown(httpServer, adminServer *http.Server, grpcServer *grpc.Server, healthServer *health.Server, probes *common.Probes, timeout time.Duration, logger log.Logger) {
+	healthServer.Shutdown()
+	probes.Shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	shutdownHTTP := func(server *http.Server, transport string) {
+		defer wg.Done()
+		logger := log.With(logger, "transport", transport)
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Log("shutdown", "forced", "err", err)
+			server.Close()
+			return
+		}
+		logger.Log("shutdown", "drained")
+	}
+	wg.Add(2)
+	go shutdownHTTP(httpServer, "HTTP")
+	if adminServer != nil {
+		wg.Add(1)
+		go shutdownHTTP(adminServer, "admin HTTP")
+	}
+	go func() {
+		defer wg.Done()
+		logger := log.With(logger, "transport", "gRPC")
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			logger.Log("shutdown", "drained")
+		case <-ctx.Done():
+			logger.Log("shutdown", "forced", "err", ctx.Err())
+			grpcServer.Stop()
+		}
+	}()
+	wg.Wait()
+}
+
+func runExport(c *cli.Context) error {
+	svc, closeFn, err := dialService(c)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	out, err := svc.Export(context.Background(), &docnogenpb.ExportRequest{
+		OrgCode: c.String("org"),
+		Format:  c.String("format"),
+	})
+	if err != nil {
+		return err
+	}
+	if !out.Ok {
+		return fmt.Errorf("Export failed: %s", out.ErrorMessage)
+	}
+
+	if c.String("out") == "" {
+		_, err = fmt.Fprint(os.Stdout, out.Result.Data)
+		return err
+	}
+	if err := ensureDir(c.String("out")); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(c.String("out"), []byte(out.Result.Data), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d counters of %s to %s\n", out.Result.CounterCount, c.String("org"), c.String("out"))
+	return nil
+}
+
+func runImport(c *cli.Context) error {
+	mode, ok := docnogenpb.ImportMode_value[strings.ToUpper(c.String("mode"))]
+	if !ok {
+		return fmt.Errorf("Import mode %s is not supported, use merge or overwrite", c.String("mode"))
+	}
+
+	var data []byte
+	var err error
+	if c.String("in") == "" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(c.String("in"))
+	}
+	if err != nil {
+		return err
+	}
+
+	svc, closeFn, err := dialService(c)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	out, err := svc.Import(context.Background(), &docnogenpb.ImportRequest{
+		OrgCode: c.String("org"),
+		Format:  c.String("format"),
+		Data:    string(data),
+		Mode:    docnogenpb.ImportMode(mode),
+		DryRun:  c.Bool("dry-run"),
+		Force:   c.Bool("force"),
+	})
+	if err != nil {
+		return err
+	}
+
+	if out.Result != nil {
+		for _, change := range out.Result.Changes {
+			// a failed import lists what it wrote before failing
+			status := ""
+			switch {
+			case change.Failed:
+				status = " (failed)"
+			case change.Applied && !out.Ok:
+				status = " (written)"
+			}
+			fmt.Printf("%-9s DocCode=%s Path=%s NextSeqNo %d -> %d%s\n", change.Action, change.DocCode, change.Path, change.FromSeqNo, change.ToSeqNo, status)
+		}
+		fmt.Printf("created=%d updated=%d unchanged=%d deleted=%d dryRun=%v\n", out.Result.Created, out.Result.Updated, out.Result.Unchanged, out.Result.Deleted, out.Result.DryRun)
+	}
+	if !out.Ok {
+		return fmt.Errorf("Import failed: %s", out.ErrorMessage)
+	}
+	return nil
+}
+
+func runMigrateLayout(c *cli.Context) error {
+	dbclient := docnogenmodel.NewDBClient(c.GlobalString("mongoaddr"), c.GlobalString("mongodbname"), c.GlobalString("mongoauthusername"), c.GlobalString("mongoauthpassword"))
+	err := dbclient.DialWithInfo()
+	if err != nil {
+		return fmt.Errorf("Failed to establish connection to Mongo Server: %s", err.Error())
+	}
+	defer dbclient.Close()
+
+	result, err := docnogenmodel.MigrateToSharedLayout(dbclient, c.Bool("drop-source"), c.Duration("request-wait"))
+	if result != nil {
+		fmt.Printf("orgs=%d created=%d advanced=%d unchanged=%d dropped=%d\n", result.Orgs, result.Created, result.Advanced, result.Unchanged, result.Dropped)
+	}
+	return err
+}
+
+func runCreateAPIKey(c *cli.Context) error {
+	var expiresAt int64
+	if c.Duration("expires") > 0 {
+		expiresAt = time.Now().Add(c.Duration("expires")).Unix()
+	}
+
+	svc, closeFn, err := dialService(c)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	out, err := svc.CreateAPIKey(context.Background(), &docnogenpb.CreateAPIKeyRequest{
+		Name:      c.String("name"),
+		OrgCodes:  c.StringSlice("org"),
+		Roles:     c.StringSlice("role"),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	if !out.Ok {
+		return fmt.Errorf("Creating API key failed: %s", out.ErrorMessage)
+	}
+	fmt.Fprintf(os.Stderr, "created API key %s, it is not shown again\n", out.Result.ApiKey.Id)
+	fmt.Println(out.Result.Key)
+	return nil
+}
+
+func runListAPIKeys(c *cli.Context) error {
+	svc, closeFn, err := dialService(c)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	out, err := svc.ListAPIKeys(context.Background(), &docnogenpb.ListAPIKeysRequest{OrgCode: c.String("org")})
+	if err != nil {
+		return err
+	}
+	if !out.Ok {
+		return fmt.Errorf("Listing API keys failed: %s", out.ErrorMessage)
+	}
+	for _, key := range out.Result.ApiKeys {
+		status := "active"
+		if key.RevokedAt != 0 {
+			status = "revoked " + time.Unix(key.RevokedAt, 0).UTC().Format(time.RFC3339)
+		} else if key.ExpiresAt != 0 && key.ExpiresAt <= time.Now().Unix() {
+			status = "expired " + time.Unix(key.ExpiresAt, 0).UTC().Format(time.RFC3339)
+		}
+		fmt.Printf("%s Name=%s Orgs=%s Roles=%s Created=%s Status=%s\n", key.Id, key.Name, strings.Join(key.OrgCodes, ","), strings.Join(key.Roles, ","),
+			time.Unix(key.CreatedAt, 0).UTC().Format(time.RFC3339), status)
+	}
+	return nil
+}
+
+func runRevokeAPIKey(c *cli.Context) error {
+	svc, closeFn, err := dialService(c)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	out, err := svc.RevokeAPIKey(context.Background(), &docnogenpb.RevokeAPIKeyRequest{Id: c.String("id")})
+	if err != nil {
+		return err
+	}
+	if !out.Ok {
+		return fmt.Errorf("Revoking API key failed: %s", out.ErrorMessage)
+	}
+	fmt.Fprintf(os.Stderr, "revoked API key %s\n", out.Result.ApiKey.Id)
+	return nil
+}
+
+// dialService connects to Mongo with the global flags and returns the service for the CLI subcommands
+func dialService(c *cli.Context) (docnogenpb.DocNoGenServiceServer, func(), error) {
+	dbclient := docnogenmodel.NewDBClient(c.GlobalString("mongoaddr"), c.GlobalString("mongodbname"), c.GlobalString("mongoauthusername"), c.GlobalString("mongoauthpassword"))
+	err := dbclient.DialWithInfo()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to establish connection to Mongo Server: %s", err.Error())
+	}
+
+	docNoRepo, err := docnogenmodel.NewDocNoRepositoryWithLayout(dbclient, c.GlobalString("mongolayout"))
+	if err != nil {
+		dbclient.Close()
+		return nil, nil, err
+	}
+	docNoFormatterSvc := docnogensvc.NewDocnoformatterService()
+	apiKeyRepo := docnogenmodel.NewAPIKeyRepository(dbclient)
+	return docnogensvc.NewDocnogenServiceWithAPIKeys(docNoRepo, docNoFormatterSvc, docnogensvc.RetryConfig{Policy: common.DefaultRetryPolicy}, apiKeyRepo), dbclient.Close, nil
+}
+
 func ensureDir(fileName string) error {
 	dirName := filepath.Dir(fileName)
 	if _, serr := os.Stat(dirName); serr != nil {
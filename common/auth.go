@@ -0,0 +1,188 @@
+package common
+
+import (
+	"context"
+	stdhttp "net/http"
+	"strings"
+
+	stdjwt "github.com/dgrijalva/jwt-go"
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/endpoint"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc/metadata"
+)
+
+// MethodMiddleware wraps the endpoint of a service method, method is its name as in the proto file
+type MethodMiddleware func(method string, next endpoint.Endpoint) endpoint.Endpoint
+
+type authContextKey int
+
+const (
+	// APIKeyContextKey holds the API key of a request, put there by APIKeyHTTPToContext or APIKeyGRPCToContext
+	APIKeyContextKey authContextKey = iota
+	// PrincipalContextKey holds the *Principal of an authenticated request
+	PrincipalContextKey
+)
+
+const (
+	// APIKeyHeader carries the API key over HTTP, and in lower case over gRPC
+	APIKeyHeader   = "X-API-Key"
+	apiKeyMetadata = "x-api-key"
+
+	// AllOrgs and AllRoles, in Principal.Orgs and Principal.Roles, grant every organization and every role
+	AllOrgs  = "*"
+	AllRoles = "*"
+)
+
+// Principal is who a request is authenticated as, and what it may do
+type Principal struct {
+	// Subject names the principal in logs, the sub of a token or the id of an API key
+	Subject string
+	Orgs    []string
+	Roles   []string
+	// Claims are the claims of a token, nil for an API key
+	Claims stdjwt.MapClaims
+}
+
+// HasOrg tells whether the principal may act for orgCode
+func (p *Principal) HasOrg(orgCode string) bool {
+	return containsOrAll(p.Orgs, orgCode, AllOrgs)
+}
+
+// HasRole tells whether the principal has role
+func (p *Principal) HasRole(role string) bool {
+	return containsOrAll(p.Roles, role, AllRoles)
+}
+
+func (p *Principal) hasAnyRole(roles []string) bool {
+	for _, role := range roles {
+		if p.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFromContext returns the principal of an authenticated request, or nil
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(PrincipalContextKey).(*Principal)
+	return p
+}
+
+// Authenticator recognizes one kind of credentials
+type Authenticator interface {
+	// Authenticate returns the principal of the credentials in ctx, nil without error when ctx has none of its kind,
+	// and an UNAUTHENTICATED error when they are not valid
+	Authenticate(ctx context.Context) (*Principal, error)
+}
+
+// Permission is what a method needs from a principal
+type Permission struct {
+	// Roles allowed to call the method, any one of them is enough
+	Roles []string
+	// AllOrgsWithoutOrg makes a request naming no organization need a principal for every organization, for methods
+	// acting across organizations. Otherwise such requests are left to validation.
+	AllOrgsWithoutOrg bool
+}
+
+// NewAuthMiddleware authenticates each request with the first authenticator finding credentials, then checks the
+// principal has the permission of the method and every organization the request names: its orgCode or orgCodes.
+// Methods without a permission are denied. The principal is put in the context, the claims of a token too.
+func NewAuthMiddleware(permissions map[string]Permission, authenticators ...Authenticator) MethodMiddleware {
+	return func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+		permission, known := permissions[method]
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			var principal *Principal
+			for _, a := range authenticators {
+				p, err := a.Authenticate(ctx)
+				if err != nil {
+					return nil, err
+				}
+				if p != nil {
+					principal = p
+					break
+				}
+			}
+			if principal == nil {
+				return nil, Errorf(ReasonUnauthenticated, "%s needs a bearer token or an API key", method)
+			}
+
+			if !known {
+				return nil, Errorf(ReasonPermissionDenied, "%s has no permission defined", method)
+			}
+			if !principal.hasAnyRole(permission.Roles) {
+				return nil, Errorf(ReasonPermissionDenied, "%s needs role %s", method, strings.Join(permission.Roles, " or "))
+			}
+			orgs := requestOrgs(request)
+			if len(orgs) == 0 && permission.AllOrgsWithoutOrg {
+				orgs = []string{AllOrgs}
+			}
+			for _, org := range orgs {
+				if !principal.HasOrg(org) {
+					if org == AllOrgs {
+						return nil, Errorf(ReasonPermissionDenied, "%s without organization is only allowed for every organization", method)
+					}
+					return nil, Errorf(ReasonPermissionDenied, "Not allowed for organization %s", org)
+				}
+			}
+
+			ctx = context.WithValue(ctx, PrincipalContextKey, principal)
+			if principal.Claims != nil {
+				ctx = context.WithValue(ctx, kitjwt.JWTClaimsContextKey, principal.Claims)
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// requestOrgs returns the organizations a request names
+func requestOrgs(request interface{}) []string {
+	var orgs []string
+	if r, ok := request.(interface{ GetOrgCode() string }); ok && r.GetOrgCode() != "" {
+		orgs = append(orgs, r.GetOrgCode())
+	}
+	if r, ok := request.(interface{ GetOrgCodes() []string }); ok {
+		orgs = append(orgs, r.GetOrgCodes()...)
+	}
+	return orgs
+}
+
+func containsOrAll(values []string, value string, all string) bool {
+	for _, v := range values {
+		if v == value || v == all {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyHTTPToContext moves the API key from the X-API-Key header to the context
+func APIKeyHTTPToContext() httptransport.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		if key := r.Header.Get(APIKeyHeader); key != "" {
+			return context.WithValue(ctx, APIKeyContextKey, key)
+		}
+		return ctx
+	}
+}
+
+// APIKeyGRPCToContext moves the API key from the x-api-key metadata to the context
+func APIKeyGRPCToContext() grpctransport.ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		if keys := md[apiKeyMetadata]; len(keys) > 0 && keys[0] != "" {
+			return context.WithValue(ctx, APIKeyContextKey, keys[0])
+		}
+		return ctx
+	}
+}
+
+// APIKeyContextToGRPC moves the API key from the context to the x-api-key metadata, for clients
+func APIKeyContextToGRPC() grpctransport.ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if key, ok := ctx.Value(APIKeyContextKey).(string); ok && key != "" {
+			(*md)[apiKeyMetadata] = []string{key}
+		}
+		return ctx
+	}
+}
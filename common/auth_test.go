@@ -0,0 +1,113 @@
+package common
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc/metadata"
+)
+
+type authRequest struct {
+	OrgCode  string
+	OrgCodes []string
+}
+
+func (r *authRequest) GetOrgCode() string {
+	return r.OrgCode
+}
+
+func (r *authRequest) GetOrgCodes() []string {
+	return r.OrgCodes
+}
+
+// keyAuthenticator knows the API keys of its map
+type keyAuthenticator map[string]*Principal
+
+func (a keyAuthenticator) Authenticate(ctx context.Context) (*Principal, error) {
+	key, ok := ctx.Value(APIKeyContextKey).(string)
+	if !ok {
+		return nil, nil
+	}
+	if p, ok := a[key]; ok {
+		return p, nil
+	}
+	return nil, Errorf(ReasonUnauthenticated, "Invalid API key")
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	authenticator := keyAuthenticator{
+		"reporting": {Subject: "reporting", Orgs: []string{"ORG1"}, Roles: []string{"peek"}},
+		"platform":  {Subject: "platform", Orgs: []string{AllOrgs}, Roles: []string{"admin"}},
+		"org-admin": {Subject: "org-admin", Orgs: []string{"ORG1", "ORG2"}, Roles: []string{"admin"}},
+	}
+	permissions := map[string]Permission{
+		"GetNextDocNo":        {Roles: []string{"peek"}},
+		"GenerateDocNoFormat": {Roles: []string{"issue"}},
+		"CreateAPIKey":        {Roles: []string{"admin"}, AllOrgsWithoutOrg: true},
+	}
+	call := func(key string, method string, request interface{}) (*Principal, error) {
+		var got *Principal
+		next := func(ctx context.Context, request interface{}) (interface{}, error) {
+			got = PrincipalFromContext(ctx)
+			return nil, nil
+		}
+		ctx := context.Background()
+		if key != "" {
+			ctx = context.WithValue(ctx, APIKeyContextKey, key)
+		}
+		_, err := NewAuthMiddleware(permissions, authenticator)(method, next)(ctx, request)
+		return got, err
+	}
+
+	Convey("Requests are authenticated", t, func() {
+		_, err := call("", "GetNextDocNo", &authRequest{OrgCode: "ORG1"})
+		So(ReasonOf(err), ShouldEqual, ReasonUnauthenticated)
+		_, err = call("unknown", "GetNextDocNo", &authRequest{OrgCode: "ORG1"})
+		So(ReasonOf(err), ShouldEqual, ReasonUnauthenticated)
+
+		p, err := call("reporting", "GetNextDocNo", &authRequest{OrgCode: "ORG1"})
+		So(err, ShouldBeNil)
+		So(p.Subject, ShouldEqual, "reporting")
+	})
+
+	Convey("Methods need the role of their permission", t, func() {
+		_, err := call("reporting", "GenerateDocNoFormat", &authRequest{OrgCode: "ORG1"})
+		So(ReasonOf(err), ShouldEqual, ReasonPermissionDenied)
+		_, err = call("platform", "Unknown", &authRequest{OrgCode: "ORG1"})
+		So(ReasonOf(err), ShouldEqual, ReasonPermissionDenied)
+	})
+
+	Convey("Every organization of a request must be allowed", t, func() {
+		_, err := call("reporting", "GetNextDocNo", &authRequest{OrgCode: "ORG2"})
+		So(ReasonOf(err), ShouldEqual, ReasonPermissionDenied)
+		_, err = call("org-admin", "CreateAPIKey", &authRequest{OrgCodes: []string{"ORG1", "ORG2"}})
+		So(err, ShouldBeNil)
+		_, err = call("org-admin", "CreateAPIKey", &authRequest{OrgCodes: []string{"ORG1", "ORG3"}})
+		So(ReasonOf(err), ShouldEqual, ReasonPermissionDenied)
+		_, err = call("platform", "CreateAPIKey", &authRequest{OrgCodes: []string{"ORG3"}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Requests without organization need every organization only when the permission says so", t, func() {
+		_, err := call("reporting", "GetNextDocNo", &authRequest{})
+		So(err, ShouldBeNil)
+		_, err = call("org-admin", "CreateAPIKey", &authRequest{})
+		So(ReasonOf(err), ShouldEqual, ReasonPermissionDenied)
+		_, err = call("platform", "CreateAPIKey", &authRequest{})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("The transports move the API key to the context and back", t, func() {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-API-Key", "reporting")
+		So(APIKeyHTTPToContext()(context.Background(), r).Value(APIKeyContextKey), ShouldEqual, "reporting")
+
+		md := metadata.MD{}
+		ctx := context.WithValue(context.Background(), APIKeyContextKey, "platform")
+		APIKeyContextToGRPC()(ctx, &md)
+		So(APIKeyGRPCToContext()(context.Background(), md).Value(APIKeyContextKey), ShouldEqual, "platform")
+		So(APIKeyGRPCToContext()(context.Background(), metadata.MD{}).Value(APIKeyContextKey), ShouldBeNil)
+	})
+}
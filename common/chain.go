@@ -0,0 +1,73 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// MethodMiddlewares names the endpoint middlewares a chain can be built from. A name mapped to nil is known but
+// disabled, e.g. auth without any authenticator, and is left out of the chains naming it.
+type MethodMiddlewares map[string]MethodMiddleware
+
+// Chain returns the middleware applying the ones named, the first outermost, i.e. in the order a request goes through
+// them. Each runs in a span of its name. Unknown or repeated names are an error.
+func (m MethodMiddlewares) Chain(names []string) (MethodMiddleware, error) {
+	enabled := make(map[string]bool, len(m))
+	for name, mw := range m {
+		enabled[name] = mw != nil
+	}
+	names, err := ChainNames("endpoint", names, enabled)
+	if err != nil {
+		return nil, err
+	}
+	chain := make([]MethodMiddleware, 0, len(names))
+	for _, name := range names {
+		chain = append(chain, TraceMethodMiddleware(name, m[name]))
+	}
+	return func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+		for i := len(chain) - 1; i >= 0; i-- {
+			next = chain[i](method, next)
+		}
+		return next
+	}, nil
+}
+
+// ChainNames checks the names of a chain of kind middlewares, e.g. endpoint or service, against the known ones, each
+// telling if it is enabled, and returns the enabled ones in order. Unknown or repeated names are an error.
+func ChainNames(kind string, names []string, known map[string]bool) ([]string, error) {
+	chain := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		enabled, ok := known[name]
+		if !ok {
+			all := make([]string, 0, len(known))
+			for name := range known {
+				all = append(all, name)
+			}
+			sort.Strings(all)
+			return nil, fmt.Errorf("unknown %s middleware %q, not one of %s", kind, name, strings.Join(all, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s middleware %q is in the chain twice", kind, name)
+		}
+		seen[name] = true
+		if enabled {
+			chain = append(chain, name)
+		}
+	}
+	return chain, nil
+}
+
+// ParseChain splits a comma separated list of middleware names, ignoring blanks
+func ParseChain(s string) []string {
+	names := []string{}
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
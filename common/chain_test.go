@@ -0,0 +1,57 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// recordingMiddleware appends its name and the method to calls when a request goes through it
+func recordingMiddleware(name string, calls *[]string) MethodMiddleware {
+	return func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			*calls = append(*calls, name+":"+method)
+			return next(ctx, request)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	Convey("Given named endpoint middlewares", t, func() {
+		calls := []string{}
+		middlewares := MethodMiddlewares{
+			"audit":    recordingMiddleware("audit", &calls),
+			"validate": recordingMiddleware("validate", &calls),
+			"auth":     nil,
+		}
+		service := func(ctx context.Context, request interface{}) (interface{}, error) {
+			calls = append(calls, "service")
+			return nil, nil
+		}
+
+		Convey("the chain runs them in the order named, skipping the disabled ones", func() {
+			chain, err := middlewares.Chain(ParseChain(" validate, auth,,audit "))
+			So(err, ShouldBeNil)
+			_, err = chain("GetNextDocNo", service)(context.Background(), nil)
+			So(err, ShouldBeNil)
+			So(calls, ShouldResemble, []string{"validate:GetNextDocNo", "audit:GetNextDocNo", "service"})
+		})
+
+		Convey("an empty chain calls the endpoint directly", func() {
+			chain, err := middlewares.Chain(ParseChain(""))
+			So(err, ShouldBeNil)
+			chain("GetNextDocNo", service)(context.Background(), nil)
+			So(calls, ShouldResemble, []string{"service"})
+		})
+
+		Convey("unknown and repeated names are refused", func() {
+			_, err := middlewares.Chain([]string{"audit", "cache"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "audit, auth, validate")
+			_, err = middlewares.Chain([]string{"audit", "audit"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,393 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	stdhttp "net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/sony/gobreaker"
+)
+
+const (
+	// The implementations of the circuit breakers
+	CircuitBreakerGobreaker = "gobreaker"
+	CircuitBreakerHystrix   = "hystrix"
+
+	// The states of a breaker, hystrix has no half-open one
+	CircuitClosed   = "closed"
+	CircuitHalfOpen = "half-open"
+	CircuitOpen     = "open"
+
+	// CircuitBreakersMethod is the method of the endpoint listing the breakers, for its permission
+	CircuitBreakersMethod = "CircuitBreakers"
+)
+
+// circuitStateValues are the values of the state gauge
+var circuitStateValues = map[string]float64{CircuitClosed: 0, CircuitHalfOpen: 1, CircuitOpen: 2}
+
+// CircuitBreakerRule configures the breaker of the methods matching Method, empty or "*" matching any. Zero values
+// keep the defaults of the implementation.
+type CircuitBreakerRule struct {
+	Method string `json:"method,omitempty"`
+	// OpenTimeout is how long the breaker stays open before letting a request test the method: the Timeout of
+	// gobreaker (60s), the SleepWindow of hystrix (5s)
+	OpenTimeout Duration `json:"openTimeout,omitempty"`
+
+	// gobreaker: MaxRequests let through half-open (1), Interval at which a closed breaker clears its counts (never),
+	// and the breaker trips after more than ConsecutiveFailures failures in a row (5)
+	MaxRequests         uint32   `json:"maxRequests,omitempty"`
+	Interval            Duration `json:"interval,omitempty"`
+	ConsecutiveFailures uint32   `json:"consecutiveFailures,omitempty"`
+
+	// hystrix: Timeout of a request, which every rule must set, MaxConcurrentRequests (10), and the
+	// ErrorPercentThreshold tripping the breaker (50) once it saw RequestVolumeThreshold requests (20) in 10s. A
+	// request timing out is answered at once and its context cancelled.
+	Timeout                Duration `json:"timeout,omitempty"`
+	MaxConcurrentRequests  int      `json:"maxConcurrentRequests,omitempty"`
+	RequestVolumeThreshold int      `json:"requestVolumeThreshold,omitempty"`
+	ErrorPercentThreshold  int      `json:"errorPercentThreshold,omitempty"`
+}
+
+func (r CircuitBreakerRule) validate() error {
+	if r.OpenTimeout < 0 || r.Interval < 0 || r.Timeout < 0 {
+		return fmt.Errorf("durations must not be negative")
+	}
+	if r.MaxConcurrentRequests < 0 || r.RequestVolumeThreshold < 0 {
+		return fmt.Errorf("maxConcurrentRequests and requestVolumeThreshold must not be negative")
+	}
+	if r.ErrorPercentThreshold < 0 || r.ErrorPercentThreshold > 100 {
+		return fmt.Errorf("errorPercentThreshold %d is not a percentage", r.ErrorPercentThreshold)
+	}
+	return nil
+}
+
+// LoadCircuitBreakerRules reads the rules of a JSON file of the form {"rules": [...]}
+func LoadCircuitBreakerRules(file string) ([]CircuitBreakerRule, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading circuit breaker file %s Error=%s", file, err.Error())
+	}
+	var config struct {
+		Rules []CircuitBreakerRule `json:"rules"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("Error parsing circuit breaker file %s Error=%s", file, err.Error())
+	}
+	return config.Rules, nil
+}
+
+// circuitBreaker is the breaker of one method
+type circuitBreaker interface {
+	execute(ctx context.Context, request interface{}, next endpoint.Endpoint) (interface{}, error)
+	state() string
+}
+
+// CircuitBreakers gives every method its own breaker, configured by the first of its rules matching the method.
+// Besides errors, responses failing with STORAGE_UNAVAILABLE or INTERNAL count as failures; the other failed
+// responses are the caller's doing and count as successes. Requests refused by a breaker fail with UNAVAILABLE.
+type CircuitBreakers struct {
+	implementation string
+	rules          []CircuitBreakerRule
+	logger         log.Logger
+	state          metrics.Gauge
+	changes        metrics.Counter
+
+	mtx      sync.Mutex
+	breakers map[string]circuitBreaker
+}
+
+// NewCircuitBreakers returns breakers of implementation, gobreaker or hystrix. hystrix needs a rule matching every
+// method, and a Timeout in each rule. State changes are logged at warn level, counted by changes with labels
+// "method", "from" and "to", and set in state with label "method": 0 closed, 1 half-open, 2 open. Either metric may
+// be nil.
+func NewCircuitBreakers(implementation string, rules []CircuitBreakerRule, logger log.Logger, state metrics.Gauge, changes metrics.Counter) (*CircuitBreakers, error) {
+	if implementation != CircuitBreakerGobreaker && implementation != CircuitBreakerHystrix {
+		return nil, fmt.Errorf("circuit breaker %s is not supported, use %s or %s", implementation, CircuitBreakerGobreaker, CircuitBreakerHystrix)
+	}
+	matchesAll := false
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("circuit breaker rule %d: %s", i, err.Error())
+		}
+		if implementation == CircuitBreakerHystrix && rule.Timeout == 0 {
+			return nil, fmt.Errorf("circuit breaker rule %d: hystrix needs a timeout", i)
+		}
+		matchesAll = matchesAll || matchesOrAny(rule.Method, "")
+	}
+	if implementation == CircuitBreakerHystrix && !matchesAll {
+		return nil, fmt.Errorf("hystrix needs a circuit breaker rule with a timeout for every method, \"*\"")
+	}
+	return &CircuitBreakers{
+		implementation: implementation,
+		rules:          rules,
+		logger:         logger,
+		state:          state,
+		changes:        changes,
+		breakers:       make(map[string]circuitBreaker),
+	}, nil
+}
+
+// Implementation returns gobreaker or hystrix
+func (b *CircuitBreakers) Implementation() string {
+	return b.implementation
+}
+
+func (b *CircuitBreakers) rule(method string) CircuitBreakerRule {
+	for _, rule := range b.rules {
+		if matchesOrAny(rule.Method, method) {
+			return rule
+		}
+	}
+	return CircuitBreakerRule{}
+}
+
+// breaker returns the breaker of method, made on first use
+func (b *CircuitBreakers) breaker(method string) circuitBreaker {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if breaker, ok := b.breakers[method]; ok {
+		return breaker
+	}
+	rule := b.rule(method)
+	var breaker circuitBreaker
+	if b.implementation == CircuitBreakerHystrix {
+		breaker = newHystrixBreaker(method, rule, b.stateChanged)
+	} else {
+		breaker = newGobreakerBreaker(method, rule, b.stateChanged)
+	}
+	b.breakers[method] = breaker
+	if b.state != nil {
+		b.state.With("method", method).Set(circuitStateValues[CircuitClosed])
+	}
+	return breaker
+}
+
+func (b *CircuitBreakers) stateChanged(method, from, to string) {
+	LogWarn(b.logger).Log("circuitbreaker", b.implementation, "method", method, "from", from, "to", to)
+	if b.changes != nil {
+		b.changes.With("method", method, "from", from, "to", to).Add(1)
+	}
+	if b.state != nil {
+		b.state.With("method", method).Set(circuitStateValues[to])
+	}
+}
+
+// Middleware runs each request through the breaker of its method
+func (b *CircuitBreakers) Middleware() MethodMiddleware {
+	return func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+		breaker := b.breaker(method)
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			return breaker.execute(ctx, request, next)
+		}
+	}
+}
+
+// CircuitBreakerState is the state of the breaker of a method
+type CircuitBreakerState struct {
+	Method string `json:"method"`
+	State  string `json:"state"`
+}
+
+// States returns the state of every breaker, by method
+func (b *CircuitBreakers) States() []CircuitBreakerState {
+	b.mtx.Lock()
+	breakers := make(map[string]circuitBreaker, len(b.breakers))
+	for method, breaker := range b.breakers {
+		breakers[method] = breaker
+	}
+	b.mtx.Unlock()
+
+	states := make([]CircuitBreakerState, 0, len(breakers))
+	for method, breaker := range breakers {
+		states = append(states, CircuitBreakerState{Method: method, State: breaker.state()})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Method < states[j].Method })
+	return states
+}
+
+// errFailedResponse tells a breaker the response failed, the response itself is still returned
+var errFailedResponse = errors.New("failed response")
+
+// call runs next, turning a response failing with STORAGE_UNAVAILABLE or INTERNAL into errFailedResponse
+func call(ctx context.Context, request interface{}, next endpoint.Endpoint) (interface{}, error) {
+	response, err := next(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	if e := ResponseError(response); e != nil && (e.Reason == ReasonStorageUnavailable || e.Reason == ReasonInternal) {
+		return response, errFailedResponse
+	}
+	return response, nil
+}
+
+type gobreakerBreaker struct {
+	method string
+	cb     *gobreaker.CircuitBreaker
+}
+
+func newGobreakerBreaker(method string, rule CircuitBreakerRule, onStateChange func(method, from, to string)) *gobreakerBreaker {
+	settings := gobreaker.Settings{
+		Name:        method,
+		MaxRequests: rule.MaxRequests,
+		Interval:    time.Duration(rule.Interval),
+		Timeout:     time.Duration(rule.OpenTimeout),
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			onStateChange(name, from.String(), to.String())
+		},
+	}
+	if rule.ConsecutiveFailures > 0 {
+		settings.ReadyToTrip = func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures > rule.ConsecutiveFailures
+		}
+	}
+	return &gobreakerBreaker{method: method, cb: gobreaker.NewCircuitBreaker(settings)}
+}
+
+func (b *gobreakerBreaker) execute(ctx context.Context, request interface{}, next endpoint.Endpoint) (interface{}, error) {
+	var response interface{}
+	_, err := b.cb.Execute(func() (interface{}, error) {
+		var err error
+		response, err = call(ctx, request, next)
+		return nil, err
+	})
+	switch err {
+	case errFailedResponse:
+		return response, nil
+	case gobreaker.ErrOpenState, gobreaker.ErrTooManyRequests:
+		return nil, Errorf(ReasonUnavailable, "%s is unavailable, its circuit breaker is %s", b.method, b.cb.State())
+	}
+	return response, err
+}
+
+func (b *gobreakerBreaker) state() string {
+	return b.cb.State().String()
+}
+
+// hystrixBreaker follows the state of the hystrix circuit of its method, which has no callback, after every request
+type hystrixBreaker struct {
+	method        string
+	timeout       time.Duration
+	onStateChange func(method, from, to string)
+
+	mtx  sync.Mutex
+	open bool
+}
+
+func newHystrixBreaker(method string, rule CircuitBreakerRule, onStateChange func(method, from, to string)) *hystrixBreaker {
+	hystrix.ConfigureCommand(method, hystrix.CommandConfig{
+		Timeout:                int(time.Duration(rule.Timeout) / time.Millisecond),
+		MaxConcurrentRequests:  rule.MaxConcurrentRequests,
+		RequestVolumeThreshold: rule.RequestVolumeThreshold,
+		SleepWindow:            int(time.Duration(rule.OpenTimeout) / time.Millisecond),
+		ErrorPercentThreshold:  rule.ErrorPercentThreshold,
+	})
+	return &hystrixBreaker{method: method, timeout: time.Duration(rule.Timeout), onStateChange: onStateChange}
+}
+
+func (b *hystrixBreaker) execute(ctx context.Context, request interface{}, next endpoint.Endpoint) (interface{}, error) {
+	// the request is cancelled when hystrix stops waiting for it, rather than going on unseen
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	var response interface{}
+	err := hystrix.DoC(ctx, b.method, func(ctx context.Context) error {
+		var err error
+		response, err = call(ctx, request, next)
+		return err
+	}, nil)
+	b.state()
+	if ctx.Err() != nil {
+		// past the timeout or cancelled, even if the run function returned as it passed
+		return nil, b.contextError(ctx.Err())
+	}
+	switch err {
+	case nil:
+		return response, nil
+	case errFailedResponse:
+		// the run function has returned, so response is written
+		return response, nil
+	case hystrix.ErrCircuitOpen, hystrix.ErrMaxConcurrency:
+		return nil, Errorf(ReasonUnavailable, "%s is unavailable, %s", b.method, err.Error())
+	case hystrix.ErrTimeout:
+		return nil, Errorf(ReasonDeadlineExceeded, "%s did not answer in time, %s", b.method, err.Error())
+	case context.DeadlineExceeded, context.Canceled:
+		return nil, b.contextError(err)
+	}
+	// the run function may still be running, on a context error, and writing response
+	return nil, err
+}
+
+// contextError returns the error of a request stopped by its context, CANCELLED or DEADLINE_EXCEEDED
+func (b *hystrixBreaker) contextError(err error) error {
+	if err == context.Canceled {
+		return Errorf(ReasonCancelled, "%s was cancelled", b.method)
+	}
+	return Errorf(ReasonDeadlineExceeded, "%s did not answer in time, %s", b.method, err.Error())
+}
+
+// state reads the circuit, reporting a change since the last read
+func (b *hystrixBreaker) state() string {
+	open := false
+	if circuit, _, err := hystrix.GetCircuit(b.method); err == nil {
+		open = circuit.IsOpen()
+	}
+	b.mtx.Lock()
+	changed := open != b.open
+	b.open = open
+	b.mtx.Unlock()
+
+	from, to := CircuitClosed, CircuitOpen
+	if !open {
+		from, to = to, from
+	}
+	if changed {
+		b.onStateChange(b.method, from, to)
+	}
+	return to
+}
+
+// NewCircuitBreakersHTTPHandler serves the implementation and the states of the breakers as JSON on GET, through the
+// middlewares given, e.g. the authentication with the permission of CircuitBreakersMethod
+func NewCircuitBreakersHTTPHandler(b *CircuitBreakers, logger log.Logger, middlewares ...MethodMiddleware) stdhttp.Handler {
+	var e endpoint.Endpoint = func(ctx context.Context, request interface{}) (interface{}, error) {
+		return struct {
+			Implementation string                `json:"implementation"`
+			Breakers       []CircuitBreakerState `json:"breakers"`
+		}{b.Implementation(), b.States()}, nil
+	}
+	e = TraceEndpoint("service", CircuitBreakersMethod)(e)
+	for _, middleware := range middlewares {
+		e = middleware(CircuitBreakersMethod, e)
+	}
+	return httptransport.NewServer(e,
+		func(_ context.Context, r *stdhttp.Request) (interface{}, error) {
+			if r.Method != stdhttp.MethodGet {
+				return nil, Errorf(ReasonInvalidArgument, "Method %s is not allowed, use GET", r.Method)
+			}
+			return nil, nil
+		},
+		httptransport.EncodeJSONResponse,
+		httptransport.ServerErrorEncoder(func(ctx context.Context, err error, w stdhttp.ResponseWriter) {
+			SetRequestIDHeader(ctx, w.Header())
+			WriteHTTPError(w, err)
+		}),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, TraceHTTPToContext(), RequestIDHTTPToContext(logger), kitjwt.HTTPToContext(), APIKeyHTTPToContext()),
+		httptransport.ServerAfter(RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(TraceHTTPFinalizer()),
+		httptransport.ServerErrorLogger(logger),
+	)
+}
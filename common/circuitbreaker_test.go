@@ -0,0 +1,203 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testGauge keeps the last value set under every label set
+type testGauge struct {
+	labels []string
+	values map[string]float64
+}
+
+func (g testGauge) With(labelValues ...string) metrics.Gauge {
+	return testGauge{labels: append(append([]string{}, g.labels...), labelValues...), values: g.values}
+}
+
+func (g testGauge) Set(value float64) { g.values[strings.Join(g.labels, ",")] = value }
+func (g testGauge) Add(delta float64) { g.values[strings.Join(g.labels, ",")] += delta }
+
+// failingEndpoint answers with a response failing with the reason it is set to, or succeeds when it is empty
+func failingEndpoint(reason *Reason) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		if *reason == "" {
+			return &failedTestResponse{}, nil
+		}
+		return &failedTestResponse{reason: string(*reason)}, nil
+	}
+}
+
+func TestCircuitBreakers(t *testing.T) {
+	Convey("Given gobreaker breakers tripping after 2 failures in a row", t, func() {
+		var buf bytes.Buffer
+		state, changes := testGauge{values: map[string]float64{}}, testCounter{values: map[string]float64{}}
+		breakers, err := NewCircuitBreakers(CircuitBreakerGobreaker, []CircuitBreakerRule{
+			{Method: "GetNextDocNo", ConsecutiveFailures: 2, OpenTimeout: Duration(50 * time.Millisecond)},
+			{Method: "*", ConsecutiveFailures: 100},
+		}, log.NewLogfmtLogger(&buf), state, changes)
+		So(err, ShouldBeNil)
+		reason := ReasonStorageUnavailable
+		e := breakers.Middleware()("GetNextDocNo", failingEndpoint(&reason))
+		breakers.Middleware()("Export", failingEndpoint(&reason))
+		So(state.values["method,GetNextDocNo"], ShouldEqual, 0)
+
+		Convey("failed responses are returned until it opens, then requests fail with UNAVAILABLE", func() {
+			for i := 0; i < 3; i++ {
+				response, err := e(context.Background(), nil)
+				So(err, ShouldBeNil)
+				So(ResponseError(response).Reason, ShouldEqual, ReasonStorageUnavailable)
+			}
+			_, err := e(context.Background(), nil)
+			So(ReasonOf(err), ShouldEqual, ReasonUnavailable)
+			So(breakers.States(), ShouldResemble, []CircuitBreakerState{{"Export", CircuitClosed}, {"GetNextDocNo", CircuitOpen}})
+			So(state.values["method,GetNextDocNo"], ShouldEqual, 2)
+			So(changes.values["method,GetNextDocNo,from,closed,to,open"], ShouldEqual, 1)
+			So(buf.String(), ShouldContainSubstring, "level=warn circuitbreaker=gobreaker method=GetNextDocNo from=closed to=open")
+
+			Convey("and closes again once a test request succeeds", func() {
+				time.Sleep(60 * time.Millisecond)
+				So(breakers.States()[1].State, ShouldEqual, CircuitHalfOpen)
+				reason = ""
+				response, err := e(context.Background(), nil)
+				So(err, ShouldBeNil)
+				So(ResponseError(response), ShouldBeNil)
+				So(breakers.States()[1].State, ShouldEqual, CircuitClosed)
+				So(state.values["method,GetNextDocNo"], ShouldEqual, 0)
+				So(changes.values["method,GetNextDocNo,from,half-open,to,closed"], ShouldEqual, 1)
+			})
+		})
+
+		Convey("failures of the caller do not trip it", func() {
+			reason = ReasonInvalidArgument
+			for i := 0; i < 5; i++ {
+				_, err := e(context.Background(), nil)
+				So(err, ShouldBeNil)
+			}
+			So(breakers.States()[1].State, ShouldEqual, CircuitClosed)
+		})
+	})
+
+	Convey("Given hystrix breakers", t, func() {
+		state, changes := testGauge{values: map[string]float64{}}, testCounter{values: map[string]float64{}}
+		breakers, err := NewCircuitBreakers(CircuitBreakerHystrix, []CircuitBreakerRule{
+			{Method: "*", Timeout: Duration(time.Second), RequestVolumeThreshold: 2, ErrorPercentThreshold: 50, OpenTimeout: Duration(time.Minute)},
+		}, log.NewNopLogger(), state, changes)
+		So(err, ShouldBeNil)
+		reason := ReasonInternal
+		e := breakers.Middleware()("HystrixTestMethod", failingEndpoint(&reason))
+
+		Convey("failing requests open the circuit, then requests fail with UNAVAILABLE", func() {
+			deadline := time.Now().Add(5 * time.Second)
+			for err == nil && time.Now().Before(deadline) {
+				var response interface{}
+				response, err = e(context.Background(), nil)
+				if err == nil {
+					So(ResponseError(response).Reason, ShouldEqual, ReasonInternal)
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+			So(ReasonOf(err), ShouldEqual, ReasonUnavailable)
+			So(breakers.States(), ShouldResemble, []CircuitBreakerState{{"HystrixTestMethod", CircuitOpen}})
+			So(state.values["method,HystrixTestMethod"], ShouldEqual, 2)
+			So(changes.values["method,HystrixTestMethod,from,closed,to,open"], ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given a hystrix breaker and a request blocking until its context is done", t, func() {
+		breakers, err := NewCircuitBreakers(CircuitBreakerHystrix, []CircuitBreakerRule{
+			{Method: "*", Timeout: Duration(time.Minute)},
+		}, log.NewNopLogger(), nil, nil)
+		So(err, ShouldBeNil)
+		started, stopped := make(chan struct{}), make(chan struct{})
+		e := breakers.Middleware()("HystrixBlockingMethod", func(ctx context.Context, request interface{}) (interface{}, error) {
+			close(started)
+			<-ctx.Done()
+			close(stopped)
+			// answering as the request is stopped
+			return "late", nil
+		})
+
+		Convey("cancelling the request stops it, without a response", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-started
+				cancel()
+			}()
+			response, err := e(ctx, nil)
+			So(response, ShouldBeNil)
+			So(ReasonOf(err), ShouldEqual, ReasonCancelled)
+			<-stopped
+		})
+
+		Convey("a request past its deadline fails with DEADLINE_EXCEEDED, without a response", func() {
+			ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+			defer cancel()
+			response, err := e(ctx, nil)
+			So(response, ShouldBeNil)
+			So(ReasonOf(err), ShouldEqual, ReasonDeadlineExceeded)
+		})
+	})
+
+	Convey("Unknown implementations and invalid rules are refused", t, func() {
+		_, err := NewCircuitBreakers("resilience4j", nil, log.NewNopLogger(), nil, nil)
+		So(err, ShouldNotBeNil)
+		_, err = NewCircuitBreakers(CircuitBreakerHystrix, []CircuitBreakerRule{{ErrorPercentThreshold: 150}}, log.NewNopLogger(), nil, nil)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("hystrix needs a timeout in every rule, and a rule of every method", t, func() {
+		_, err := NewCircuitBreakers(CircuitBreakerHystrix, nil, log.NewNopLogger(), nil, nil)
+		So(err, ShouldNotBeNil)
+		_, err = NewCircuitBreakers(CircuitBreakerHystrix, []CircuitBreakerRule{{Method: "*"}}, log.NewNopLogger(), nil, nil)
+		So(err, ShouldNotBeNil)
+		_, err = NewCircuitBreakers(CircuitBreakerHystrix, []CircuitBreakerRule{
+			{Method: "GetNextDocNo", Timeout: Duration(time.Second)},
+		}, log.NewNopLogger(), nil, nil)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("The admin endpoint lists the breakers through its middlewares", t, func() {
+		breakers, _ := NewCircuitBreakers(CircuitBreakerGobreaker, nil, log.NewNopLogger(), nil, nil)
+		breakers.Middleware()("GetNextDocNo", nil)
+		deny := func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+			return func(ctx context.Context, request interface{}) (interface{}, error) {
+				if PrincipalFromContext(ctx) == nil && method == CircuitBreakersMethod {
+					return nil, NewError(ReasonPermissionDenied, "no principal")
+				}
+				return next(ctx, request)
+			}
+		}
+
+		w := httptest.NewRecorder()
+		NewCircuitBreakersHTTPHandler(breakers, log.NewNopLogger()).ServeHTTP(w, httptest.NewRequest("GET", "/admin/circuitbreakers", nil))
+		So(w.Code, ShouldEqual, http.StatusOK)
+		var body struct {
+			Implementation string
+			Breakers       []CircuitBreakerState
+		}
+		So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+		So(body.Implementation, ShouldEqual, CircuitBreakerGobreaker)
+		So(body.Breakers, ShouldResemble, []CircuitBreakerState{{"GetNextDocNo", CircuitClosed}})
+
+		w = httptest.NewRecorder()
+		NewCircuitBreakersHTTPHandler(breakers, log.NewNopLogger()).ServeHTTP(w, httptest.NewRequest("POST", "/admin/circuitbreakers", nil))
+		So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+		w = httptest.NewRecorder()
+		NewCircuitBreakersHTTPHandler(breakers, log.NewNopLogger(), deny).ServeHTTP(w, httptest.NewRequest("GET", "/admin/circuitbreakers", nil))
+		So(w.Code, ShouldEqual, http.StatusForbidden)
+		So(w.Header().Get(RequestIDHeader), ShouldNotBeEmpty)
+	})
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Settings are the values of a config file by setting name, a list has one value per item
+type Settings map[string][]string
+
+var settingNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// LoadSettingsFile reads a YAML (.yaml, .yml) or TOML (.toml) file of settings. Only the flat form of both is
+// supported: a value or a list of values per name, as the flags of the server are.
+func LoadSettingsFile(file string) (Settings, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading config file %s Error=%s", file, err.Error())
+	}
+	var settings Settings
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		settings, err = ParseYAMLSettings(data)
+	case ".toml":
+		settings, err = ParseTOMLSettings(data)
+	default:
+		return nil, fmt.Errorf("Config file %s is not .yaml, .yml or .toml", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing config file %s Error=%s", file, err.Error())
+	}
+	return settings, nil
+}
+
+// ParseYAMLSettings parses a mapping of names to scalars or lists of scalars, keeping the text of the scalars
+func ParseYAMLSettings(data []byte) (Settings, error) {
+	var values map[string]yamlSettingValues
+	if err := yaml.UnmarshalStrict(data, &values); err != nil {
+		return nil, err
+	}
+	settings := Settings{}
+	for name, items := range values {
+		if !settingNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%q is not a setting name", name)
+		}
+		// a null value is not set
+		if items != nil {
+			settings[name] = items
+		}
+	}
+	return settings, nil
+}
+
+// yamlSettingValues reads a scalar as a list of one value, nested values are refused
+type yamlSettingValues []string
+
+func (v *yamlSettingValues) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var items []string
+	if err := unmarshal(&items); err == nil {
+		*v = items
+		return nil
+	}
+	var scalar string
+	if err := unmarshal(&scalar); err != nil {
+		return errors.New("nested values are not supported")
+	}
+	*v = []string{scalar}
+	return nil
+}
+
+// ParseTOMLSettings parses names set to values or arrays of values; tables are not supported
+func ParseTOMLSettings(data []byte) (Settings, error) {
+	var values map[string]interface{}
+	if _, err := toml.Decode(string(data), &values); err != nil {
+		return nil, err
+	}
+	settings := Settings{}
+	for name, value := range values {
+		if !settingNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%q is not a setting name", name)
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value}
+		}
+		settings[name] = make([]string, 0, len(items))
+		for _, item := range items {
+			scalar, err := tomlScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err.Error())
+			}
+			settings[name] = append(settings[name], scalar)
+		}
+	}
+	return settings, nil
+}
+
+func tomlScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	}
+	return "", errors.New("tables and nested arrays are not supported")
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSettings(t *testing.T) {
+	expected := Settings{
+		"mongoaddr":    {"prod-db:27017"},
+		"jwtissuer":    {"https://auth.example.com/#main"},
+		"ratelimit":    {"12.5"},
+		"rateburst":    {"1000"},
+		"apikeyauth":   {"true"},
+		"jwthskeyfile": {"/etc/docnogen/a.key", "/etc/docnogen/b.key"},
+		"jwtaudience":  {"it's docnogen"},
+	}
+
+	Convey("YAML settings are read with their lists and quotes", t, func() {
+		settings, err := ParseYAMLSettings([]byte(`---
+# docnogen
+mongoaddr: prod-db:27017
+jwtissuer: "https://auth.example.com/#main" # the issuer
+ratelimit: 12.5
+rateburst: 1000
+apikeyauth: true
+jwthskeyfile:
+  - /etc/docnogen/a.key
+  - '/etc/docnogen/b.key'
+jwtaudience: 'it''s docnogen'
+jwtrsakeyfile: ~
+jwtjwksfile:
+`))
+		So(err, ShouldBeNil)
+		So(settings, ShouldResemble, expected)
+
+		settings, err = ParseYAMLSettings([]byte("jwthskeyfile: [/etc/docnogen/a.key, \"/etc/docnogen/b.key\"]\njwtjwksfile:\n- /etc/jwks.json\n"))
+		So(err, ShouldBeNil)
+		So(settings["jwthskeyfile"], ShouldResemble, expected["jwthskeyfile"])
+		So(settings["jwtjwksfile"], ShouldResemble, []string{"/etc/jwks.json"})
+	})
+
+	Convey("TOML settings are read with their arrays and quotes", t, func() {
+		settings, err := ParseTOMLSettings([]byte(`# docnogen
+mongoaddr = "prod-db:27017"
+jwtissuer = "https://auth.example.com/#main" # the issuer
+ratelimit = 12.5
+rateburst = 1_000
+apikeyauth = true
+jwthskeyfile = [
+  "/etc/docnogen/a.key", # first
+  '/etc/docnogen/b.key',
+]
+jwtaudience = "it's docnogen"
+`))
+		So(err, ShouldBeNil)
+		So(settings, ShouldResemble, expected)
+	})
+
+	Convey("Nested values, duplicates and broken lines are refused", t, func() {
+		for _, yaml := range []string{"mongo:\n  addr: db\n", "a: 1\na: 2\n", "just text\n", "a: {b: 1}\n", "a: [1, 2\n", "a: \"open\n", "a b: 1\n"} {
+			_, err := ParseYAMLSettings([]byte(yaml))
+			So(err, ShouldNotBeNil)
+		}
+		for _, toml := range []string{"[mongo]\naddr = \"db\"\n", "a = 1\na = 2\n", "a\n", "a = \n", "a = [1, [2]]\n", "a = {b = 1}\n", "a = [1,\n"} {
+			_, err := ParseTOMLSettings([]byte(toml))
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Files are read by their extension", t, func() {
+		dir, err := ioutil.TempDir("", "config")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		for name, data := range map[string]string{"a.yml": "ratelimit: 5\n", "a.toml": "ratelimit = 5\n"} {
+			file := filepath.Join(dir, name)
+			ioutil.WriteFile(file, []byte(data), 0600)
+			settings, err := LoadSettingsFile(file)
+			So(err, ShouldBeNil)
+			So(settings, ShouldResemble, Settings{"ratelimit": {"5"}})
+		}
+		file := filepath.Join(dir, "a.ini")
+		ioutil.WriteFile(file, []byte("ratelimit=5\n"), 0600)
+		_, err = LoadSettingsFile(file)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -1,7 +1,245 @@
 package common
 
-import "errors"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ErrorCodeContention is returned when an update keeps failing with ConcurrencyUpdateError until the retries run out
+	ErrorCodeContention = 409
+	// ErrorCodeCancelled is returned when the caller cancelled the request
+	ErrorCodeCancelled = 499
+	// ErrorCodeDeadlineExceeded is returned when the deadline of the request passed
+	ErrorCodeDeadlineExceeded = 504
+)
 
 var (
-	ConcurrencyUpdateError = errors.New("Concurrency update error: record timestamp has changed")
+	ConcurrencyUpdateError error = &Error{Reason: ReasonConcurrencyConflict, Message: "Concurrency update error: record has changed"}
 )
+
+// Reason is the stable, machine readable cause of an error, it is returned as errorReason in the responses.
+// Reasons are never renamed, new ones may be added.
+type Reason string
+
+const (
+	// ReasonInvalidArgument means the request is malformed or fails validation
+	ReasonInvalidArgument Reason = "INVALID_ARGUMENT"
+	// ReasonConcurrencyConflict means the counter changed since the caller read it
+	ReasonConcurrencyConflict Reason = "CONCURRENCY_CONFLICT"
+	// ReasonContention means the retries on concurrency conflicts ran out
+	ReasonContention Reason = "CONTENTION"
+	// ReasonNotFound means the counter or API key does not exist
+	ReasonNotFound Reason = "NOT_FOUND"
+	// ReasonFailedPrecondition means the request is valid but the current state does not allow it
+	ReasonFailedPrecondition Reason = "FAILED_PRECONDITION"
+	// ReasonStorageUnavailable means the database could not be reached or failed
+	ReasonStorageUnavailable Reason = "STORAGE_UNAVAILABLE"
+	// ReasonInternal means the service itself is broken
+	ReasonInternal Reason = "INTERNAL"
+	// ReasonCancelled means the caller cancelled the request
+	ReasonCancelled Reason = "CANCELLED"
+	// ReasonDeadlineExceeded means the deadline of the request passed
+	ReasonDeadlineExceeded Reason = "DEADLINE_EXCEEDED"
+	// ReasonUnsupportedMediaType means the Content-Type of an HTTP request is not supported
+	ReasonUnsupportedMediaType Reason = "UNSUPPORTED_MEDIA_TYPE"
+	// ReasonNotAcceptable means none of the types in the Accept header of an HTTP request can be returned
+	ReasonNotAcceptable Reason = "NOT_ACCEPTABLE"
+	// ReasonMethodNotAllowed means the route of an HTTP request does not take its method
+	ReasonMethodNotAllowed Reason = "METHOD_NOT_ALLOWED"
+	// ReasonUnauthenticated means the request has no valid credentials
+	ReasonUnauthenticated Reason = "UNAUTHENTICATED"
+	// ReasonPermissionDenied means the credentials of the request do not allow it
+	ReasonPermissionDenied Reason = "PERMISSION_DENIED"
+	// ReasonResourceExhausted means the request is over its rate limit
+	ReasonResourceExhausted Reason = "RESOURCE_EXHAUSTED"
+	// ReasonUnavailable means the circuit breaker of the method is open or over its concurrency limit
+	ReasonUnavailable Reason = "UNAVAILABLE"
+)
+
+// GRPCCode returns the gRPC status code of the reason
+func (r Reason) GRPCCode() codes.Code {
+	switch r {
+	case "":
+		return codes.OK
+	case ReasonInvalidArgument, ReasonUnsupportedMediaType, ReasonNotAcceptable, ReasonMethodNotAllowed:
+		return codes.InvalidArgument
+	case ReasonConcurrencyConflict, ReasonContention:
+		return codes.Aborted
+	case ReasonNotFound:
+		return codes.NotFound
+	case ReasonFailedPrecondition:
+		return codes.FailedPrecondition
+	case ReasonStorageUnavailable, ReasonUnavailable:
+		return codes.Unavailable
+	case ReasonCancelled:
+		return codes.Canceled
+	case ReasonDeadlineExceeded:
+		return codes.DeadlineExceeded
+	case ReasonUnauthenticated:
+		return codes.Unauthenticated
+	case ReasonPermissionDenied:
+		return codes.PermissionDenied
+	case ReasonResourceExhausted:
+		return codes.ResourceExhausted
+	}
+	return codes.Internal
+}
+
+// HTTPStatus returns the HTTP status of the reason
+func (r Reason) HTTPStatus() int {
+	switch r {
+	case "":
+		return http.StatusOK
+	case ReasonInvalidArgument:
+		return http.StatusBadRequest
+	case ReasonUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case ReasonNotAcceptable:
+		return http.StatusNotAcceptable
+	case ReasonMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case ReasonConcurrencyConflict, ReasonContention:
+		return http.StatusConflict
+	case ReasonNotFound:
+		return http.StatusNotFound
+	case ReasonFailedPrecondition:
+		return http.StatusConflict
+	case ReasonStorageUnavailable, ReasonUnavailable:
+		return http.StatusServiceUnavailable
+	case ReasonCancelled:
+		return ErrorCodeCancelled
+	case ReasonDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case ReasonUnauthenticated:
+		return http.StatusUnauthorized
+	case ReasonPermissionDenied:
+		return http.StatusForbidden
+	case ReasonResourceExhausted:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an error with a Reason
+type Error struct {
+	Reason  Reason
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// GRPCStatus lets grpc return the error with the code of its reason
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(e.Reason.GRPCCode(), e.Message)
+}
+
+func NewError(reason Reason, message string) *Error {
+	return &Error{Reason: reason, Message: message}
+}
+
+func Errorf(reason Reason, format string, a ...interface{}) *Error {
+	return &Error{Reason: reason, Message: fmt.Sprintf(format, a...)}
+}
+
+// ReasonOf returns the reason of err, errors without one are INTERNAL
+func ReasonOf(err error) Reason {
+	if err == nil {
+		return ""
+	}
+	if e, ok := err.(*Error); ok {
+		return e.Reason
+	}
+	switch err {
+	case context.Canceled:
+		return ReasonCancelled
+	case context.DeadlineExceeded:
+		return ReasonDeadlineExceeded
+	}
+	return ReasonInternal
+}
+
+// ReasonOfCode returns the reason of a legacy error code, for responses that do not set errorReason
+func ReasonOfCode(code int32) Reason {
+	switch code {
+	case 0:
+		return ""
+	case 400:
+		return ReasonInvalidArgument
+	case ErrorCodeContention:
+		return ReasonContention
+	case ErrorCodeCancelled:
+		return ReasonCancelled
+	case ErrorCodeDeadlineExceeded:
+		return ReasonDeadlineExceeded
+	}
+	return ReasonInternal
+}
+
+// ContextErrorCode returns the error code for an error of a done context
+func ContextErrorCode(err error) int32 {
+	if err == context.DeadlineExceeded {
+		return ErrorCodeDeadlineExceeded
+	}
+	return ErrorCodeCancelled
+}
+
+// failedResponse is implemented by the responses, which report failures in their body
+type failedResponse interface {
+	GetOk() bool
+	GetErrorCode() int32
+	GetErrorMessage() string
+	GetErrorReason() string
+}
+
+// ResponseError returns the error reported in the body of a failed response, or nil
+func ResponseError(response interface{}) *Error {
+	r, ok := response.(failedResponse)
+	if !ok || r.GetOk() {
+		return nil
+	}
+	reason := Reason(r.GetErrorReason())
+	if reason == "" {
+		reason = ReasonOfCode(r.GetErrorCode())
+		if reason == "" {
+			reason = ReasonInternal
+		}
+	}
+	return &Error{Reason: reason, Message: r.GetErrorMessage()}
+}
+
+// ResponseStatus returns the gRPC status error of a failed response, with the response itself attached as a detail
+// so that the legacy body fields still reach the client. It returns nil when the response did not fail.
+func ResponseStatus(response proto.Message) error {
+	e := ResponseError(response)
+	if e == nil {
+		return nil
+	}
+	st := e.GRPCStatus()
+	if withDetails, err := st.WithDetails(response); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// WriteHTTPError writes err with the status of its reason and the body of the errors raised before the service, for
+// the handlers outside of the generated transports
+func WriteHTTPError(w http.ResponseWriter, err error) {
+	reason := ReasonOf(err)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(reason.HTTPStatus())
+	json.NewEncoder(w).Encode(struct {
+		Error        string `json:"error"`
+		ErrorCode    int32  `json:"errorCode"`
+		ErrorMessage string `json:"errorMessage"`
+		ErrorReason  string `json:"errorReason"`
+	}{err.Error(), int32(reason.HTTPStatus()), err.Error(), string(reason)})
+}
@@ -0,0 +1,160 @@
+package common
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+var (
+	// jsonMarshaler follows the proto3 JSON mapping, writing every field, with its default value when unset
+	jsonMarshaler = jsonpb.Marshaler{EmitDefaults: true}
+	// jsonUnmarshaler rejects unknown fields
+	jsonUnmarshaler = jsonpb.Unmarshaler{}
+)
+
+// mediaTypes maps the accepted spellings of each content type
+var mediaTypes = map[string]string{
+	ContentTypeJSON:           ContentTypeJSON,
+	ContentTypeProtobuf:       ContentTypeProtobuf,
+	"application/protobuf":    ContentTypeProtobuf,
+	"application/x-protobuf3": ContentTypeProtobuf,
+}
+
+// DecodeHTTPRequest decodes the body of r into msg as JSON or protobuf, following its Content-Type; without one the
+// body is JSON. It also checks that the response can be encoded in a type of the Accept header.
+func DecodeHTTPRequest(r *http.Request, msg proto.Message) error {
+	if _, err := NegotiateContentType(r.Header.Get("Accept")); err != nil {
+		return err
+	}
+
+	contentType := ContentTypeJSON
+	if header := r.Header.Get("Content-Type"); header != "" {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil || mediaTypes[mediaType] == "" {
+			return Errorf(ReasonUnsupportedMediaType, "Content-Type %s is not supported, use %s or %s", header, ContentTypeJSON, ContentTypeProtobuf)
+		}
+		contentType = mediaTypes[mediaType]
+	}
+
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return Errorf(ReasonInvalidArgument, "Error reading request body: %s", err.Error())
+		}
+	}
+	if contentType == ContentTypeProtobuf {
+		if err := proto.Unmarshal(body, msg); err != nil {
+			return Errorf(ReasonInvalidArgument, "Invalid protobuf body: %s", err.Error())
+		}
+		return nil
+	}
+	return UnmarshalProtoJSON(body, msg)
+}
+
+// MarshalProtoJSON encodes a generated message with the proto3 JSON mapping: 64 bit integers are strings, enums are
+// their names and fields with their default value are written too
+func MarshalProtoJSON(msg proto.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jsonMarshaler.Marshal(&buf, msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalProtoJSON decodes data into a generated message with the proto3 JSON mapping. Fields may be named by
+// their JSON or proto name, 64 bit integers may be numbers or strings and enums names or numbers. An empty body is
+// an empty message, unknown fields are rejected with an INVALID_ARGUMENT error.
+func UnmarshalProtoJSON(data []byte, msg proto.Message) error {
+	msg.Reset()
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := jsonUnmarshaler.Unmarshal(bytes.NewReader(data), msg); err != nil {
+		return Errorf(ReasonInvalidArgument, "Invalid JSON body: %s", err.Error())
+	}
+	return nil
+}
+
+// EncodeHTTPResponse writes msg with status, as JSON or protobuf following the accept header
+func EncodeHTTPResponse(w http.ResponseWriter, accept string, status int, msg proto.Message) error {
+	contentType, err := NegotiateContentType(accept)
+	if err != nil {
+		// the request was checked when decoded, fall back to JSON
+		contentType = ContentTypeJSON
+	}
+
+	var body []byte
+	if contentType == ContentTypeProtobuf {
+		body, err = proto.Marshal(msg)
+	} else {
+		body, err = MarshalProtoJSON(msg)
+	}
+	if err != nil {
+		return err
+	}
+
+	if contentType == ContentTypeJSON {
+		w.Header().Set("Content-Type", ContentTypeJSON+"; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	return err
+}
+
+// NegotiateContentType picks the response content type from an Accept header: the supported type with the highest
+// quality, the first one listed on a tie. An empty header or a wildcard gives JSON.
+func NegotiateContentType(accept string) (string, error) {
+	if strings.TrimSpace(accept) == "" {
+		return ContentTypeJSON, nil
+	}
+
+	type candidate struct {
+		contentType string
+		quality     float64
+		order       int
+	}
+	var candidates []candidate
+	for i, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		switch {
+		case mediaTypes[mediaType] != "":
+			candidates = append(candidates, candidate{mediaTypes[mediaType], quality, i})
+		case mediaType == "*/*" || mediaType == "application/*":
+			candidates = append(candidates, candidate{ContentTypeJSON, quality, i})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", Errorf(ReasonNotAcceptable, "Accept %s is not supported, use %s or %s", accept, ContentTypeJSON, ContentTypeProtobuf)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	return candidates[0].contentType, nil
+}
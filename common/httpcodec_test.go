@@ -0,0 +1,114 @@
+package common
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	. "github.com/smartystreets/goconvey/convey"
+
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+)
+
+func Test_ProtoJSON(t *testing.T) {
+	Convey("Marshalling follows the proto3 JSON mapping", t, func() {
+		out, err := MarshalProtoJSON(&pb.ConsumeDocNoResponse{Ok: true, Result: &pb.ConsumeDocNoResponse_Result{NextSeqNo: 2, Version: 3}})
+		So(err, ShouldBeNil)
+		So(string(out), ShouldEqual, `{"ok":true,"errorCode":0,"errorMessage":"","errorReason":"","violations":[],"result":{"nextSeqNo":2,"recordTimestamp":"0","version":"3"}}`)
+
+		out, err = MarshalProtoJSON(&pb.ImportRequest{OrgCode: "MAT", Mode: pb.ImportMode_OVERWRITE})
+		So(err, ShouldBeNil)
+		So(string(out), ShouldContainSubstring, `"orgCode":"MAT"`)
+		So(string(out), ShouldContainSubstring, `"mode":"OVERWRITE"`)
+
+		Convey("and a failed response keeps ok", func() {
+			out, err := MarshalProtoJSON(&pb.ConsumeDocNoResponse{ErrorCode: 409})
+			So(err, ShouldBeNil)
+			So(string(out), ShouldStartWith, `{"ok":false,"errorCode":409,`)
+		})
+	})
+
+	Convey("Unmarshalling", t, func() {
+		Convey("takes 64 bit integers as strings or numbers and enums as names or numbers", func() {
+			var in pb.ConsumeDocNoRequest
+			So(UnmarshalProtoJSON([]byte(`{"docCode":"AP","curSeqNo":2,"version":"3","recordTimestamp":4}`), &in), ShouldBeNil)
+			So(in.Version, ShouldEqual, 3)
+			So(in.RecordTimestamp, ShouldEqual, 4)
+
+			var imp pb.ImportRequest
+			So(UnmarshalProtoJSON([]byte(`{"mode":"OVERWRITE"}`), &imp), ShouldBeNil)
+			So(imp.Mode, ShouldEqual, pb.ImportMode_OVERWRITE)
+			So(UnmarshalProtoJSON([]byte(`{"mode":1}`), &imp), ShouldBeNil)
+			So(imp.Mode, ShouldEqual, pb.ImportMode_OVERWRITE)
+		})
+
+		Convey("round trips maps and nested messages", func() {
+			in := &pb.GenerateBulkDocNoFormatResponse{Ok: true, Results: []*pb.GenerateBulkDocNoFormatResponse_Result{{DocNoString: "AP1", Version: 1}}}
+			data, _ := MarshalProtoJSON(in)
+			var out pb.GenerateBulkDocNoFormatResponse
+			So(UnmarshalProtoJSON(data, &out), ShouldBeNil)
+			So(proto.Equal(&out, in), ShouldBeTrue)
+
+			var req pb.GetNextDocNoRequest
+			So(UnmarshalProtoJSON([]byte(`{"variableMap":{"BRANCH":"HQ"}}`), &req), ShouldBeNil)
+			So(req.VariableMap["BRANCH"], ShouldEqual, "HQ")
+		})
+
+		Convey("rejects unknown fields and wrong types", func() {
+			var in pb.GetNextDocNoRequest
+			err := UnmarshalProtoJSON([]byte(`{"docCode":"AP","colour":"red"}`), &in)
+			So(ReasonOf(err), ShouldEqual, ReasonInvalidArgument)
+			So(err.Error(), ShouldContainSubstring, "colour")
+
+			err = UnmarshalProtoJSON([]byte(`{"docCode":1}`), &in)
+			So(ReasonOf(err), ShouldEqual, ReasonInvalidArgument)
+
+			var imp pb.ImportRequest
+			err = UnmarshalProtoJSON([]byte(`{"mode":"REPLACE"}`), &imp)
+			So(ReasonOf(err), ShouldEqual, ReasonInvalidArgument)
+		})
+	})
+}
+
+func Test_ContentNegotiation(t *testing.T) {
+	Convey("The response type follows Accept", t, func() {
+		for accept, expected := range map[string]string{
+			"":                       ContentTypeJSON,
+			"*/*":                    ContentTypeJSON,
+			"application/x-protobuf": ContentTypeProtobuf,
+			"application/json;q=0.5, application/protobuf":   ContentTypeProtobuf,
+			"application/x-protobuf;q=0.1, application/json": ContentTypeJSON,
+		} {
+			contentType, err := NegotiateContentType(accept)
+			So(err, ShouldBeNil)
+			So(contentType, ShouldEqual, expected)
+		}
+
+		_, err := NegotiateContentType("text/html")
+		So(ReasonOf(err), ShouldEqual, ReasonNotAcceptable)
+	})
+
+	Convey("The request body follows Content-Type", t, func() {
+		body, _ := proto.Marshal(&pb.GetNextDocNoRequest{DocCode: "AP"})
+		r := httptest.NewRequest("POST", "/GetNextDocNo", strings.NewReader(string(body)))
+		r.Header.Set("Content-Type", "application/x-protobuf")
+		var in pb.GetNextDocNoRequest
+		So(DecodeHTTPRequest(r, &in), ShouldBeNil)
+		So(in.DocCode, ShouldEqual, "AP")
+
+		r = httptest.NewRequest("POST", "/GetNextDocNo", strings.NewReader("<xml/>"))
+		r.Header.Set("Content-Type", "text/xml")
+		So(ReasonOf(DecodeHTTPRequest(r, &in)), ShouldEqual, ReasonUnsupportedMediaType)
+
+		Convey("and the response can be protobuf", func() {
+			w := httptest.NewRecorder()
+			So(EncodeHTTPResponse(w, "application/x-protobuf", 201, &pb.GetNextDocNoResponse{Ok: true}), ShouldBeNil)
+			So(w.Code, ShouldEqual, 201)
+			So(w.Header().Get("Content-Type"), ShouldEqual, ContentTypeProtobuf)
+			var out pb.GetNextDocNoResponse
+			So(proto.Unmarshal(w.Body.Bytes(), &out), ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+		})
+	})
+}
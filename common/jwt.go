@@ -0,0 +1,262 @@
+package common
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	stdjwt "github.com/dgrijalva/jwt-go"
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+)
+
+const (
+	// DefaultOrgClaim is the claim listing the organizations a token is for
+	DefaultOrgClaim = "orgs"
+	// DefaultRoleClaim is the claim listing the roles of a token
+	DefaultRoleClaim = "roles"
+)
+
+// jwtKey is a key verifying tokens, HMAC keys are []byte and RSA keys *rsa.PublicKey
+type jwtKey struct {
+	kid string
+	key interface{}
+}
+
+// JWTKeys are the keys that tokens may be signed with
+type JWTKeys struct {
+	keys []jwtKey
+}
+
+// Len returns the number of keys
+func (k *JWTKeys) Len() int {
+	return len(k.keys)
+}
+
+// AddHMACFile adds the secret in file for HS256, HS384 and HS512 tokens, surrounding white space is not part of it
+func (k *JWTKeys) AddHMACFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("Error reading HMAC key file %s Error=%s", file, err.Error())
+	}
+	secret := []byte(strings.TrimSpace(string(data)))
+	if len(secret) == 0 {
+		return fmt.Errorf("HMAC key file %s is empty", file)
+	}
+	k.keys = append(k.keys, jwtKey{key: secret})
+	return nil
+}
+
+// AddRSAFile adds the PEM RSA public key in file for RS256, RS384 and RS512 tokens
+func (k *JWTKeys) AddRSAFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("Error reading RSA key file %s Error=%s", file, err.Error())
+	}
+	key, err := stdjwt.ParseRSAPublicKeyFromPEM(data)
+	if err != nil {
+		return fmt.Errorf("Error parsing RSA key file %s Error=%s", file, err.Error())
+	}
+	k.keys = append(k.keys, jwtKey{key: key})
+	return nil
+}
+
+// AddJWKSFile adds the RSA and symmetric (oct) keys of the JSON Web Key Set in file, keys for encryption are skipped
+func (k *JWTKeys) AddJWKSFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("Error reading JWKS file %s Error=%s", file, err.Error())
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("Error parsing JWKS file %s Error=%s", file, err.Error())
+	}
+
+	for i, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, nErr := base64.RawURLEncoding.DecodeString(strings.TrimRight(jwk.N, "="))
+			e, eErr := base64.RawURLEncoding.DecodeString(strings.TrimRight(jwk.E, "="))
+			if nErr != nil || eErr != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+				return fmt.Errorf("Key %d of JWKS file %s is not a valid RSA key", i, file)
+			}
+			key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			k.keys = append(k.keys, jwtKey{kid: jwk.Kid, key: key})
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(jwk.K, "="))
+			if err != nil || len(secret) == 0 {
+				return fmt.Errorf("Key %d of JWKS file %s is not a valid symmetric key", i, file)
+			}
+			k.keys = append(k.keys, jwtKey{kid: jwk.Kid, key: secret})
+		default:
+			return fmt.Errorf("Key %d of JWKS file %s has key type %s, only RSA and oct are supported", i, file, jwk.Kty)
+		}
+	}
+	return nil
+}
+
+// candidates returns the keys that may verify token: the key named by its kid, otherwise every key of its algorithm
+func (k *JWTKeys) candidates(token *stdjwt.Token) []interface{} {
+	var hmac bool
+	switch token.Method.(type) {
+	case *stdjwt.SigningMethodHMAC:
+		hmac = true
+	case *stdjwt.SigningMethodRSA:
+	default:
+		return nil
+	}
+
+	var keys []interface{}
+	kid, _ := token.Header["kid"].(string)
+	for _, key := range k.keys {
+		if _, isHMAC := key.key.([]byte); isHMAC != hmac {
+			continue
+		}
+		if kid != "" && key.kid == kid {
+			return []interface{}{key.key}
+		}
+		keys = append(keys, key.key)
+	}
+	return keys
+}
+
+// JWTOptions are the checks of a token beyond its signature, exp and nbf
+type JWTOptions struct {
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+	// OrgClaim lists the organizations of the token, a string or an array of strings; "*" allows every organization
+	OrgClaim string
+	// RoleClaim lists the roles of the token the same way
+	RoleClaim string
+	// DefaultRoles are the roles of a token without RoleClaim, none when empty
+	DefaultRoles []string
+}
+
+// JWTVerifier verifies tokens, it authenticates requests by their bearer token
+type JWTVerifier struct {
+	keys    *JWTKeys
+	options JWTOptions
+	parser  *stdjwt.Parser
+}
+
+func NewJWTVerifier(keys *JWTKeys, options JWTOptions) *JWTVerifier {
+	if options.OrgClaim == "" {
+		options.OrgClaim = DefaultOrgClaim
+	}
+	if options.RoleClaim == "" {
+		options.RoleClaim = DefaultRoleClaim
+	}
+	return &JWTVerifier{
+		keys:    keys,
+		options: options,
+		parser: &stdjwt.Parser{ValidMethods: []string{
+			stdjwt.SigningMethodHS256.Alg(), stdjwt.SigningMethodHS384.Alg(), stdjwt.SigningMethodHS512.Alg(),
+			stdjwt.SigningMethodRS256.Alg(), stdjwt.SigningMethodRS384.Alg(), stdjwt.SigningMethodRS512.Alg(),
+		}},
+	}
+}
+
+// Verify checks the signature and claims of tokenString, returning an UNAUTHENTICATED error when they are not valid
+func (v *JWTVerifier) Verify(tokenString string) (stdjwt.MapClaims, error) {
+	var keys []interface{}
+	keyFunc := func(token *stdjwt.Token) (interface{}, error) {
+		if keys == nil {
+			keys = v.keys.candidates(token)
+			if len(keys) == 0 {
+				return nil, fmt.Errorf("no key for algorithm %s", token.Method.Alg())
+			}
+		}
+		return keys[0], nil
+	}
+
+	// every candidate key is tried in turn when the token does not name its key
+	var lastErr error
+	for {
+		claims := stdjwt.MapClaims{}
+		_, err := v.parser.ParseWithClaims(tokenString, claims, keyFunc)
+		if err == nil {
+			return claims, v.checkClaims(claims)
+		}
+		lastErr = err
+		if e, ok := err.(*stdjwt.ValidationError); !ok || e.Errors != stdjwt.ValidationErrorSignatureInvalid || len(keys) <= 1 {
+			break
+		}
+		keys = keys[1:]
+	}
+	return nil, Errorf(ReasonUnauthenticated, "Invalid token: %s", lastErr.Error())
+}
+
+func (v *JWTVerifier) checkClaims(claims stdjwt.MapClaims) error {
+	if v.options.Issuer != "" && !claims.VerifyIssuer(v.options.Issuer, true) {
+		return Errorf(ReasonUnauthenticated, "Invalid token: issuer is not %s", v.options.Issuer)
+	}
+	if v.options.Audience != "" && !verifyAudience(claims["aud"], v.options.Audience) {
+		return Errorf(ReasonUnauthenticated, "Invalid token: audience is not %s", v.options.Audience)
+	}
+	return nil
+}
+
+// verifyAudience accepts aud as a string or an array of strings, jwt-go only knows the string form
+func verifyAudience(aud interface{}, audience string) bool {
+	for _, a := range claimStrings(aud) {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate verifies the bearer token the transports put in the context
+func (v *JWTVerifier) Authenticate(ctx context.Context) (*Principal, error) {
+	tokenString, ok := ctx.Value(kitjwt.JWTTokenContextKey).(string)
+	if !ok || tokenString == "" {
+		return nil, nil
+	}
+	claims, err := v.Verify(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	principal := &Principal{
+		Orgs:   claimStrings(claims[v.options.OrgClaim]),
+		Roles:  v.options.DefaultRoles,
+		Claims: claims,
+	}
+	principal.Subject, _ = claims["sub"].(string)
+	if roles, ok := claims[v.options.RoleClaim]; ok {
+		principal.Roles = claimStrings(roles)
+	}
+	return principal, nil
+}
+
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	stdjwt "github.com/dgrijalva/jwt-go"
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type orgRequest struct {
+	OrgCode string
+}
+
+func (r *orgRequest) GetOrgCode() string {
+	return r.OrgCode
+}
+
+func signToken(t *testing.T, method stdjwt.SigningMethod, key interface{}, kid string, claims stdjwt.MapClaims) string {
+	token := stdjwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWTVerifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, data []byte) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaFile := write("rsa.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	hsFile := write("hs.key", []byte("secret\n"))
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(jwksKey.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(jwksKey.E)).Bytes())},
+		{"kty": "oct", "kid": "oct-1", "k": base64.RawURLEncoding.EncodeToString([]byte("jwks secret"))},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}})
+	jwksFile := write("jwks.json", jwks)
+
+	keys := &JWTKeys{}
+	for _, err := range []error{keys.AddHMACFile(hsFile), keys.AddRSAFile(rsaFile), keys.AddJWKSFile(jwksFile)} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	verifier := NewJWTVerifier(keys, JWTOptions{Issuer: "issuer", Audience: "docnogen"})
+
+	claims := func(orgs interface{}) stdjwt.MapClaims {
+		return stdjwt.MapClaims{"iss": "issuer", "aud": []string{"docnogen"}, "exp": time.Now().Add(time.Hour).Unix(), "orgs": orgs, "roles": "peek"}
+	}
+	call := func(token string, orgCode string) (stdjwt.MapClaims, error) {
+		var got stdjwt.MapClaims
+		next := func(ctx context.Context, request interface{}) (interface{}, error) {
+			got, _ = ctx.Value(kitjwt.JWTClaimsContextKey).(stdjwt.MapClaims)
+			return nil, nil
+		}
+		ctx := context.Background()
+		if token != "" {
+			ctx = context.WithValue(ctx, kitjwt.JWTTokenContextKey, token)
+		}
+		permissions := map[string]Permission{"GetNextDocNo": {Roles: []string{"peek"}}}
+		_, err := NewAuthMiddleware(permissions, verifier)("GetNextDocNo", next)(ctx, &orgRequest{OrgCode: orgCode})
+		return got, err
+	}
+
+	Convey("Loading keys", t, func() {
+		So(keys.Len(), ShouldEqual, 4)
+		So((&JWTKeys{}).AddRSAFile(hsFile), ShouldNotBeNil)
+		bad := write("bad.json", []byte(`{"keys":[{"kty":"EC","kid":"ec-1"}]}`))
+		So((&JWTKeys{}).AddJWKSFile(bad), ShouldNotBeNil)
+	})
+
+	Convey("Tokens signed with every kind of key are verified", t, func() {
+		for _, token := range []string{
+			signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", claims("ORG1")),
+			signToken(t, stdjwt.SigningMethodRS256, rsaKey, "", claims("ORG1")),
+			signToken(t, stdjwt.SigningMethodRS512, jwksKey, "rsa-1", claims("ORG1")),
+			signToken(t, stdjwt.SigningMethodHS384, []byte("jwks secret"), "oct-1", claims("ORG1")),
+			signToken(t, stdjwt.SigningMethodHS256, []byte("jwks secret"), "", claims("ORG1")),
+		} {
+			got, err := call(token, "ORG1")
+			So(err, ShouldBeNil)
+			So(got["orgs"], ShouldEqual, "ORG1")
+		}
+	})
+
+	Convey("Missing, forged and expired tokens are UNAUTHENTICATED", t, func() {
+		forger, _ := rsa.GenerateKey(rand.Reader, 1024)
+		expired := claims("ORG1")
+		expired["exp"] = time.Now().Add(-time.Minute).Unix()
+		wrongIssuer := claims("ORG1")
+		wrongIssuer["iss"] = "other"
+		wrongAudience := claims("ORG1")
+		wrongAudience["aud"] = "other"
+		for _, token := range []string{
+			"",
+			"not a token",
+			signToken(t, stdjwt.SigningMethodHS256, []byte("guess"), "", claims("ORG1")),
+			signToken(t, stdjwt.SigningMethodRS256, forger, "", claims("ORG1")),
+			signToken(t, stdjwt.SigningMethodRS256, rsaKey, "rsa-1", claims("ORG1")),
+			signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", expired),
+			signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", wrongIssuer),
+			signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", wrongAudience),
+			signToken(t, stdjwt.SigningMethodNone, stdjwt.UnsafeAllowNoneSignatureType, "", claims("ORG1")),
+		} {
+			_, err := call(token, "ORG1")
+			So(ReasonOf(err), ShouldEqual, ReasonUnauthenticated)
+		}
+	})
+
+	Convey("Requests are authorized by the orgs claim", t, func() {
+		token := signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", claims([]string{"ORG1", "ORG2"}))
+		_, err := call(token, "ORG2")
+		So(err, ShouldBeNil)
+		_, err = call(token, "ORG3")
+		So(ReasonOf(err), ShouldEqual, ReasonPermissionDenied)
+		_, err = call(token, "")
+		So(err, ShouldBeNil)
+
+		_, err = call(signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", claims(nil)), "ORG1")
+		So(ReasonOf(err), ShouldEqual, ReasonPermissionDenied)
+
+		_, err = call(signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", claims("*")), "ORG3")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Tokens have the roles of the roles claim, the default roles without it", t, func() {
+		withRoles := claims("ORG1")
+		withRoles["roles"] = []string{"issue"}
+		_, err := call(signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", withRoles), "ORG1")
+		So(ReasonOf(err), ShouldEqual, ReasonPermissionDenied)
+
+		withRoles["roles"] = "peek"
+		got, err := call(signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", withRoles), "ORG1")
+		So(err, ShouldBeNil)
+		So(got["roles"], ShouldEqual, "peek")
+
+		withoutRoles := claims("ORG1")
+		delete(withoutRoles, "roles")
+		_, err = call(signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", withoutRoles), "ORG1")
+		So(ReasonOf(err), ShouldEqual, ReasonPermissionDenied)
+
+		verifier = NewJWTVerifier(keys, JWTOptions{Issuer: "issuer", Audience: "docnogen", DefaultRoles: []string{"peek"}})
+		_, err = call(signToken(t, stdjwt.SigningMethodHS256, []byte("secret"), "", withoutRoles), "ORG1")
+		So(err, ShouldBeNil)
+	})
+}
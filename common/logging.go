@@ -0,0 +1,243 @@
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	stdhttp "net/http"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// LogLevel orders the log entries by importance, entries below the level of NewLevelFilter are dropped
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// LevelKey is the key of the level of a log entry, an entry without one is at LevelInfo
+const LevelKey = "level"
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return logLevelNames[l]
+}
+
+// ParseLogLevel reads debug, info, warn or error
+func ParseLogLevel(s string) (LogLevel, error) {
+	for i, name := range logLevelNames {
+		if strings.EqualFold(s, name) {
+			return LogLevel(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("log level must be debug, info, warn or error, not %s", s)
+}
+
+// LogDebug, LogInfo, LogWarn and LogError return logger with the level of its entries
+func LogDebug(logger log.Logger) log.Logger { return log.WithPrefix(logger, LevelKey, LevelDebug) }
+func LogInfo(logger log.Logger) log.Logger  { return log.WithPrefix(logger, LevelKey, LevelInfo) }
+func LogWarn(logger log.Logger) log.Logger  { return log.WithPrefix(logger, LevelKey, LevelWarn) }
+func LogError(logger log.Logger) log.Logger { return log.WithPrefix(logger, LevelKey, LevelError) }
+
+type levelFilter struct {
+	next log.Logger
+	min  LogLevel
+}
+
+// NewLevelFilter drops the entries of next below min
+func NewLevelFilter(next log.Logger, min LogLevel) log.Logger {
+	return &levelFilter{next: next, min: min}
+}
+
+func (l *levelFilter) Log(keyvals ...interface{}) error {
+	level := LevelInfo
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] == LevelKey {
+			if lv, ok := keyvals[i+1].(LogLevel); ok {
+				level = lv
+			}
+			break
+		}
+	}
+	if level < l.min {
+		return nil
+	}
+	return l.next.Log(keyvals...)
+}
+
+type redactingLogger struct {
+	next      log.Logger
+	sensitive map[string]bool
+}
+
+// NewRedactingLogger replaces the values logged under the sensitive keys by [REDACTED], and so the values of the
+// sensitive keys of the variable maps logged, case insensitive
+func NewRedactingLogger(next log.Logger, sensitive []string) log.Logger {
+	if len(sensitive) == 0 {
+		return next
+	}
+	l := &redactingLogger{next: next, sensitive: make(map[string]bool)}
+	for _, key := range sensitive {
+		l.sensitive[strings.ToLower(strings.TrimSpace(key))] = true
+	}
+	return l
+}
+
+func (l *redactingLogger) Log(keyvals ...interface{}) error {
+	redacted := make([]interface{}, len(keyvals))
+	copy(redacted, keyvals)
+	for i := 0; i+1 < len(redacted); i += 2 {
+		if key, ok := redacted[i].(string); ok && l.sensitive[strings.ToLower(key)] {
+			redacted[i+1] = Redacted
+			continue
+		}
+		if values, ok := redacted[i+1].(map[string]string); ok {
+			redacted[i+1] = l.redactMap(values)
+		}
+	}
+	return l.next.Log(redacted...)
+}
+
+func (l *redactingLogger) redactMap(values map[string]string) map[string]string {
+	copied := make(map[string]string, len(values))
+	for key, value := range values {
+		if l.sensitive[strings.ToLower(key)] {
+			value = Redacted
+		}
+		copied[key] = value
+	}
+	return copied
+}
+
+// Redacted replaces the sensitive values in the logs
+const Redacted = "[REDACTED]"
+
+// RequestIDHeader carries the ID of a request over HTTP, and in lower case in the gRPC metadata. A request without
+// one, or with one longer than maxRequestIDLength or not printable, gets a new ID.
+const (
+	RequestIDHeader    = "X-Request-Id"
+	requestIDMetadata  = "x-request-id"
+	maxRequestIDLength = 128
+)
+
+type requestIDContextKey struct{}
+type loggerContextKey struct{}
+
+// NewRequestID returns a random request ID
+func NewRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID sets the ID of the request in ctx, and the logger of the request: logger with its request_id, and
+// its trace_id when traced
+func WithRequestID(ctx context.Context, id string, logger log.Logger) context.Context {
+	logger = log.With(logger, "request_id", id)
+	if traceID, ok := TraceIDFromContext(ctx); ok {
+		logger = log.With(logger, "trace_id", traceID)
+	}
+	ctx = context.WithValue(ctx, requestIDContextKey{}, id)
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// RequestIDFromContext returns the ID of the request, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// LoggerFromContext returns the logger of the request set by WithRequestID, or a logger discarding everything
+func LoggerFromContext(ctx context.Context) log.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(log.Logger); ok {
+		return logger
+	}
+	return log.NewNopLogger()
+}
+
+// RequestIDHTTPToContext takes the ID of the request from its X-Request-Id header, or generates one, and gives the
+// request a logger from logger
+func RequestIDHTTPToContext(logger log.Logger) httptransport.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		return WithRequestID(ctx, id, logger)
+	}
+}
+
+// SetRequestIDHeader returns the ID of the request in h
+func SetRequestIDHeader(ctx context.Context, h stdhttp.Header) {
+	if id := RequestIDFromContext(ctx); id != "" {
+		h.Set(RequestIDHeader, id)
+	}
+}
+
+// RequestIDHTTPHeader returns the ID of the request in the X-Request-Id header of the response
+func RequestIDHTTPHeader() httptransport.ServerResponseFunc {
+	return func(ctx context.Context, w stdhttp.ResponseWriter) context.Context {
+		SetRequestIDHeader(ctx, w.Header())
+		return ctx
+	}
+}
+
+// RequestIDGRPCToContext takes the ID of the request from its x-request-id metadata, or generates one, and gives the
+// request a logger from logger
+func RequestIDGRPCToContext(logger log.Logger) grpctransport.ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		id := ""
+		if ids := md[requestIDMetadata]; len(ids) > 0 {
+			id = ids[0]
+		}
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		return WithRequestID(ctx, id, logger)
+	}
+}
+
+// RequestIDGRPCHeader returns the ID of the request in the x-request-id response metadata, failed or not
+func RequestIDGRPCHeader() grpctransport.ServerFinalizerFunc {
+	return func(ctx context.Context, err error) {
+		if id := RequestIDFromContext(ctx); id != "" {
+			grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id))
+		}
+	}
+}
+
+// RequestIDContextToGRPC sends the request ID of ctx in the x-request-id metadata, for clients
+func RequestIDContextToGRPC() grpctransport.ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if id := RequestIDFromContext(ctx); id != "" {
+			(*md)[requestIDMetadata] = []string{id}
+		}
+		return ctx
+	}
+}
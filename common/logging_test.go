@@ -0,0 +1,94 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestLogging(t *testing.T) {
+	Convey("Log levels are parsed and filtered", t, func() {
+		level, err := ParseLogLevel("WARN")
+		So(err, ShouldBeNil)
+		So(level, ShouldEqual, LevelWarn)
+		_, err = ParseLogLevel("verbose")
+		So(err, ShouldNotBeNil)
+
+		var buf bytes.Buffer
+		logger := NewLevelFilter(log.NewLogfmtLogger(&buf), LevelInfo)
+		LogDebug(logger).Log("msg", "dropped")
+		LogInfo(logger).Log("msg", "kept")
+		LogError(logger).Log("msg", "failed")
+		logger.Log("msg", "no level")
+		So(buf.String(), ShouldNotContainSubstring, "dropped")
+		So(buf.String(), ShouldContainSubstring, "level=info msg=kept")
+		So(buf.String(), ShouldContainSubstring, "level=error msg=failed")
+		So(buf.String(), ShouldContainSubstring, "msg=\"no level\"")
+	})
+
+	Convey("Sensitive keys and variables are redacted, whatever the case", t, func() {
+		var buf bytes.Buffer
+		logger := NewRedactingLogger(log.NewJSONLogger(&buf), []string{"Customer_IC", "apikey"})
+		variables := map[string]string{"customer_ic": "800101-14-5555", "BRANCH": "HQ"}
+		logger.Log("msg", "formatted", "APIKey", "secret", "variableMap", variables)
+
+		var entry map[string]interface{}
+		So(json.Unmarshal(buf.Bytes(), &entry), ShouldBeNil)
+		So(entry["APIKey"], ShouldEqual, Redacted)
+		So(entry["variableMap"], ShouldResemble, map[string]interface{}{"customer_ic": Redacted, "BRANCH": "HQ"})
+		So(variables["customer_ic"], ShouldEqual, "800101-14-5555")
+	})
+
+	Convey("Given a request over HTTP", t, func() {
+		var buf bytes.Buffer
+		logger := log.NewLogfmtLogger(&buf)
+		r := httptest.NewRequest("POST", "/GetNextDocNo", nil)
+
+		Convey("its X-Request-Id is kept and returned", func() {
+			r.Header.Set(RequestIDHeader, "abc-123")
+			ctx := RequestIDHTTPToContext(logger)(context.Background(), r)
+			So(RequestIDFromContext(ctx), ShouldEqual, "abc-123")
+			w := httptest.NewRecorder()
+			RequestIDHTTPHeader()(ctx, w)
+			So(w.Header().Get(RequestIDHeader), ShouldEqual, "abc-123")
+
+			LoggerFromContext(ctx).Log("msg", "issued")
+			So(buf.String(), ShouldContainSubstring, "request_id=abc-123 msg=issued")
+		})
+
+		Convey("a missing or invalid X-Request-Id is replaced", func() {
+			ctx := RequestIDHTTPToContext(logger)(context.Background(), r)
+			So(len(RequestIDFromContext(ctx)), ShouldEqual, 32)
+			r.Header.Set(RequestIDHeader, "has space")
+			ctx = RequestIDHTTPToContext(logger)(context.Background(), r)
+			So(RequestIDFromContext(ctx), ShouldNotEqual, "has space")
+			r.Header.Set(RequestIDHeader, strings.Repeat("a", maxRequestIDLength+1))
+			ctx = RequestIDHTTPToContext(logger)(context.Background(), r)
+			So(len(RequestIDFromContext(ctx)), ShouldEqual, 32)
+		})
+	})
+
+	Convey("The request ID goes from the gRPC client to the server", t, func() {
+		ctx := WithRequestID(context.Background(), "abc-123", log.NewNopLogger())
+		md := metadata.MD{}
+		RequestIDContextToGRPC()(ctx, &md)
+		So(md[requestIDMetadata], ShouldResemble, []string{"abc-123"})
+
+		ctx = RequestIDGRPCToContext(log.NewNopLogger())(context.Background(), md)
+		So(RequestIDFromContext(ctx), ShouldEqual, "abc-123")
+		ctx = RequestIDGRPCToContext(log.NewNopLogger())(context.Background(), metadata.MD{})
+		So(RequestIDFromContext(ctx), ShouldNotBeEmpty)
+	})
+
+	Convey("Without a request the logger discards everything", t, func() {
+		So(RequestIDFromContext(context.Background()), ShouldEqual, "")
+		So(LoggerFromContext(context.Background()).Log("msg", "lost"), ShouldBeNil)
+	})
+}
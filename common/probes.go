@@ -0,0 +1,142 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// BuildInfo identifies the running build, GitCommit and BuildTime are set with -ldflags at build time
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildTime string `json:"buildTime"`
+}
+
+// ReadinessCheck is a dependency the server needs to serve requests, Check returns why it is not usable
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Probes serves /healthz, /readyz and /version for load balancers and orchestrators
+type Probes struct {
+	info    BuildInfo
+	checks  []ReadinessCheck
+	timeout time.Duration
+
+	mu       sync.Mutex
+	shutdown bool
+}
+
+// NewProbes returns probes running checks for readiness, each check is given timeout to finish
+func NewProbes(info BuildInfo, timeout time.Duration, checks ...ReadinessCheck) *Probes {
+	return &Probes{
+		info:    info,
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Shutdown makes the server not ready any more, so that no new requests are sent while it drains
+func (p *Probes) Shutdown() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.shutdown = true
+}
+
+// RegisterHandlers mounts the probes on mux
+func (p *Probes) RegisterHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", p.Healthz)
+	mux.HandleFunc("/readyz", p.Readyz)
+	mux.HandleFunc("/version", p.Version)
+}
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Healthz reports the process is alive, it checks nothing else so that a failing dependency does not get the
+// server restarted
+func (p *Probes) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, http.StatusOK, readiness{Status: "ok"})
+}
+
+// Readyz runs every check and reports 503 when one fails or the server is shutting down
+func (p *Probes) Readyz(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	shutdown := p.shutdown
+	p.mu.Unlock()
+	if shutdown {
+		writeProbe(w, http.StatusServiceUnavailable, readiness{Status: "shutting down"})
+		return
+	}
+
+	out := readiness{Status: "ready", Checks: map[string]string{}}
+	status := http.StatusOK
+	for _, check := range p.checks {
+		ctx, cancel := context.WithTimeout(r.Context(), p.timeout)
+		err := check.Check(ctx)
+		cancel()
+		if err != nil {
+			out.Checks[check.Name] = err.Error()
+			out.Status = "not ready"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		out.Checks[check.Name] = "ok"
+	}
+	writeProbe(w, status, out)
+}
+
+// Version reports the build
+func (p *Probes) Version(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, http.StatusOK, p.info)
+}
+
+func writeProbe(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// ProbeHealth runs check every interval until ctx is done and sets the status of the services in server to SERVING
+// when it passes and NOT_SERVING when it fails. Every check gets timeout to finish. onChange, if not nil, is called
+// when the result changes, with the error of the failed check or nil.
+func ProbeHealth(ctx context.Context, server *health.Server, services []string, interval time.Duration, timeout time.Duration, check func(ctx context.Context) error, onChange func(err error)) {
+	first := true
+	var lastErr error
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := check(checkCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		servingStatus := healthpb.HealthCheckResponse_SERVING
+		if err != nil {
+			servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		for _, service := range services {
+			server.SetServingStatus(service, servingStatus)
+		}
+		if onChange != nil && (first || (err == nil) != (lastErr == nil)) {
+			onChange(err)
+		}
+		first, lastErr = false, err
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
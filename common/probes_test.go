@@ -0,0 +1,90 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func Test_Probes(t *testing.T) {
+	Convey("Given probes with a passing and a failing check", t, func() {
+		var dbErr error
+		probes := NewProbes(BuildInfo{Version: "1.3.0", GitCommit: "abc1234", BuildTime: "2026-10-19T10:00:00Z"}, time.Second,
+			ReadinessCheck{Name: "mongo", Check: func(ctx context.Context) error { return dbErr }},
+			ReadinessCheck{Name: "schema", Check: func(ctx context.Context) error { return nil }},
+		)
+		mux := http.NewServeMux()
+		probes.RegisterHandlers(mux)
+		get := func(path string) (int, map[string]interface{}) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			var body map[string]interface{}
+			So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+			return w.Code, body
+		}
+
+		Convey("/healthz and /version always answer", func() {
+			dbErr = errors.New("no reachable servers")
+			code, body := get("/healthz")
+			So(code, ShouldEqual, http.StatusOK)
+			So(body["status"], ShouldEqual, "ok")
+
+			code, body = get("/version")
+			So(code, ShouldEqual, http.StatusOK)
+			So(body, ShouldResemble, map[string]interface{}{"version": "1.3.0", "gitCommit": "abc1234", "buildTime": "2026-10-19T10:00:00Z"})
+		})
+
+		Convey("/readyz reports every check", func() {
+			code, body := get("/readyz")
+			So(code, ShouldEqual, http.StatusOK)
+			So(body["status"], ShouldEqual, "ready")
+			So(body["checks"], ShouldResemble, map[string]interface{}{"mongo": "ok", "schema": "ok"})
+
+			dbErr = errors.New("no reachable servers")
+			code, body = get("/readyz")
+			So(code, ShouldEqual, http.StatusServiceUnavailable)
+			So(body["status"], ShouldEqual, "not ready")
+			So(body["checks"], ShouldResemble, map[string]interface{}{"mongo": "no reachable servers", "schema": "ok"})
+		})
+
+		Convey("/readyz is 503 once shutting down", func() {
+			probes.Shutdown()
+			code, body := get("/readyz")
+			So(code, ShouldEqual, http.StatusServiceUnavailable)
+			So(body["status"], ShouldEqual, "shutting down")
+		})
+	})
+}
+
+func Test_ProbeHealth(t *testing.T) {
+	Convey("The health service follows the check", t, func() {
+		s := health.NewServer()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		failing := make(chan error, 1)
+		failing <- errors.New("no reachable servers")
+		changes := make(chan error, 10)
+		check := func(ctx context.Context) error {
+			select {
+			case err := <-failing:
+				return err
+			default:
+				return nil
+			}
+		}
+		go ProbeHealth(ctx, s, []string{"", "svc"}, time.Millisecond, time.Second, check, func(err error) { changes <- err })
+
+		So((<-changes).Error(), ShouldEqual, "no reachable servers")
+		So(<-changes, ShouldBeNil)
+		out, _ := s.Check(ctx, &healthpb.HealthCheckRequest{Service: "svc"})
+		So(out.Status, ShouldEqual, healthpb.HealthCheckResponse_SERVING)
+	})
+}
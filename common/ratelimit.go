@@ -0,0 +1,475 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	stdhttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// RateLimitMode is what happens to a request over its limit
+type RateLimitMode string
+
+const (
+	// RateLimitError fails the request with RESOURCE_EXHAUSTED at once
+	RateLimitError RateLimitMode = "error"
+	// RateLimitDelay holds the request until the bucket has room, up to the MaxDelay of the rule
+	RateLimitDelay RateLimitMode = "delay"
+)
+
+const (
+	// RateLimitModeHeader lets a client choose the mode of its request over HTTP, and in lower case over gRPC
+	RateLimitModeHeader   = "X-RateLimit-Mode"
+	rateLimitModeMetadata = "x-ratelimit-mode"
+
+	// The headers of the bucket a request was counted in, lower case in the gRPC response metadata
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+
+	// The keys a rule can count by
+	RateLimitPerMethod = "method"
+	RateLimitPerOrg    = "org"
+	RateLimitPerClient = "client"
+
+	// idle buckets are dropped once they would be full again, checked at most every rateLimitSweepInterval
+	rateLimitSweepInterval = time.Minute
+)
+
+// Duration is a time.Duration written as "1s" or "250ms" in JSON
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"1s\": %s", err.Error())
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// RateLimitRule limits the requests matching its Method, Org and Client, empty or "*" matching any. Every distinct
+// combination of the keys in Per gets its own bucket of Burst requests, refilled at Rate per second.
+type RateLimitRule struct {
+	Method string `json:"method,omitempty"`
+	Org    string `json:"org,omitempty"`
+	// Client is the subject of the principal: the sub of a token, or apikey:<id>; addr:<ip> for a request without one
+	Client string `json:"client,omitempty"`
+	// Per lists the keys counted apart, method, org and client; all of them when empty
+	Per []string `json:"per,omitempty"`
+	// Rate is in requests per second, 0 does not limit the matching requests
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst,omitempty"`
+	// Mode applies when the request does not choose one, error by default
+	Mode RateLimitMode `json:"mode,omitempty"`
+	// MaxDelay bounds the wait of a delayed request, a longer wait fails as in error mode
+	MaxDelay Duration `json:"maxDelay,omitempty"`
+}
+
+func (r RateLimitRule) matches(method, org, client string) bool {
+	return matchesOrAny(r.Method, method) && matchesOrAny(r.Org, org) && matchesOrAny(r.Client, client)
+}
+
+func matchesOrAny(pattern, value string) bool {
+	return pattern == "" || pattern == "*" || pattern == value
+}
+
+// bucketKey returns the key of the bucket of a request in the rule numbered i
+func (r RateLimitRule) bucketKey(i int, method, org, client string) string {
+	key := strconv.Itoa(i)
+	for _, per := range []string{RateLimitPerMethod, RateLimitPerOrg, RateLimitPerClient} {
+		value := ""
+		if r.counts(per) {
+			switch per {
+			case RateLimitPerMethod:
+				value = method
+			case RateLimitPerOrg:
+				value = org
+			case RateLimitPerClient:
+				value = client
+			}
+		}
+		key += "\x00" + value
+	}
+	return key
+}
+
+// counts tells whether the rule has a bucket per value of the key per
+func (r RateLimitRule) counts(per string) bool {
+	if len(r.Per) == 0 {
+		return true
+	}
+	for _, p := range r.Per {
+		if p == per {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RateLimitRule) validate() error {
+	if r.Rate < 0 {
+		return fmt.Errorf("rate %v is negative", r.Rate)
+	}
+	if r.Rate > 0 && r.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1")
+	}
+	if r.Mode != "" && r.Mode != RateLimitError && r.Mode != RateLimitDelay {
+		return fmt.Errorf("mode %s is not supported, use error or delay", r.Mode)
+	}
+	for _, per := range r.Per {
+		if per != RateLimitPerMethod && per != RateLimitPerOrg && per != RateLimitPerClient {
+			return fmt.Errorf("per %s is not supported, use method, org or client", per)
+		}
+	}
+	return nil
+}
+
+// LoadRateLimitRules reads the rules of a JSON file of the form {"rules": [...]}
+func LoadRateLimitRules(file string) ([]RateLimitRule, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading rate limit file %s Error=%s", file, err.Error())
+	}
+	var config struct {
+		Rules []RateLimitRule `json:"rules"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("Error parsing rate limit file %s Error=%s", file, err.Error())
+	}
+	return config.Rules, nil
+}
+
+// RateLimitStatus is the bucket a request was counted in, returned to the client as headers
+type RateLimitStatus struct {
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until a refused request would be allowed, 0 when it was allowed
+	RetryAfter time.Duration
+}
+
+// rateLimitState is put in the context by the transports, so that the middleware knows the mode the client chose
+// and the transports the status to return
+type rateLimitState struct {
+	mode   RateLimitMode
+	status *RateLimitStatus
+}
+
+type rateLimitContextKey struct{}
+
+// tokenBucket holds up to burst tokens, a request takes one; tokens go negative for the requests waiting
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter applies the first of its rules matching each request
+type RateLimiter struct {
+	now func() time.Time
+
+	mtx       sync.Mutex
+	rules     []RateLimitRule
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter returns a limiter of rules, tried in order
+func NewRateLimiter(rules []RateLimitRule) (*RateLimiter, error) {
+	l := &RateLimiter{now: time.Now, buckets: make(map[string]*tokenBucket)}
+	if err := l.SetRules(rules); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// SetRules replaces the rules, the buckets start over
+func (l *RateLimiter) SetRules(rules []RateLimitRule) error {
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("Rate limit rule %d: %s", i, err.Error())
+		}
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.rules = append([]RateLimitRule(nil), rules...)
+	l.buckets = make(map[string]*tokenBucket)
+	return nil
+}
+
+// take counts a request in its bucket. It returns the wait before the request may go on, or an error with the
+// status filled in when it is refused.
+func (l *RateLimiter) take(method, org, client string, mode RateLimitMode) (time.Duration, *RateLimitStatus, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	i, rule := -1, RateLimitRule{}
+	for j := range l.rules {
+		if l.rules[j].matches(method, org, client) {
+			i, rule = j, l.rules[j]
+			break
+		}
+	}
+	if i < 0 || rule.Rate == 0 {
+		return 0, nil, nil
+	}
+	if mode == "" {
+		mode = rule.Mode
+	}
+	maxDelay := time.Duration(0)
+	if mode == RateLimitDelay {
+		maxDelay = time.Duration(rule.MaxDelay)
+	}
+
+	now := l.now()
+	l.sweep(now)
+	key := rule.bucketKey(i, method, org, client)
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(rule.Burst), last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(float64(rule.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*rule.Rate)
+	bucket.last = now
+
+	status := &RateLimitStatus{Limit: rule.Burst}
+	bucket.tokens--
+	wait := rateDuration(-bucket.tokens, rule.Rate)
+	if wait > maxDelay {
+		bucket.tokens++
+		status.RetryAfter = rateDuration(1-bucket.tokens, rule.Rate)
+		status.Reset = rateDuration(float64(rule.Burst)-bucket.tokens, rule.Rate)
+		return 0, status, Errorf(ReasonResourceExhausted, "Rate limit of %s exceeded, retry in %s", method, status.RetryAfter)
+	}
+	if bucket.tokens > 0 {
+		status.Remaining = int(bucket.tokens)
+	}
+	status.Reset = rateDuration(float64(rule.Burst)-bucket.tokens, rule.Rate)
+	return wait, status, nil
+}
+
+// giveBack returns the token of a request that gave up waiting
+func (l *RateLimiter) giveBack(method, org, client string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for i, rule := range l.rules {
+		if rule.matches(method, org, client) {
+			if bucket, ok := l.buckets[rule.bucketKey(i, method, org, client)]; ok {
+				bucket.tokens++
+			}
+			return
+		}
+	}
+}
+
+// sweep drops the buckets that are full again, so that one bucket per org and client does not pile up
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		if bucket.tokens >= 0 && now.Sub(bucket.last) >= rateLimitSweepInterval {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func rateDuration(tokens float64, rate float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
+
+// Middleware limits each request by its method, the organization it names and the subject of its principal, so it
+// goes after the authentication middleware; a request without a principal is counted by the address of its peer.
+// Requests over their limit fail with RESOURCE_EXHAUSTED, or wait in delay mode.
+func (l *RateLimiter) Middleware() MethodMiddleware {
+	return l.middleware(func(ctx context.Context) string {
+		if principal := PrincipalFromContext(ctx); principal != nil {
+			return principal.Subject
+		}
+		return peerClient(ctx)
+	})
+}
+
+// AddressMiddleware limits each request by its method, the organization it names and the address of its peer, as
+// client, whoever it is authenticated as. It goes before the authentication middleware, so that it also bounds the
+// requests with wrong credentials.
+func (l *RateLimiter) AddressMiddleware() MethodMiddleware {
+	return l.middleware(peerClient)
+}
+
+func (l *RateLimiter) middleware(clientOf func(ctx context.Context) string) MethodMiddleware {
+	return func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			org := ""
+			if orgs := requestOrgs(request); len(orgs) > 0 {
+				org = orgs[0]
+			}
+			client := clientOf(ctx)
+			state, _ := ctx.Value(rateLimitContextKey{}).(*rateLimitState)
+			mode := RateLimitMode("")
+			if state != nil {
+				mode = state.mode
+			}
+
+			wait, status, err := l.take(method, org, client, mode)
+			if state != nil {
+				state.status = status
+			}
+			if err != nil {
+				return nil, err
+			}
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					l.giveBack(method, org, client)
+					return nil, ctx.Err()
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// peerClient returns the client of a request by the address of its peer, addr:<ip>, or "" when the transport did not
+// tell it
+func peerClient(ctx context.Context) string {
+	addr := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	} else if remote, ok := ctx.Value(httptransport.ContextKeyRequestRemoteAddr).(string); ok {
+		addr = remote
+	}
+	if addr == "" {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return "addr:" + addr
+}
+
+func parseRateLimitMode(value string) RateLimitMode {
+	switch mode := RateLimitMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case RateLimitError, RateLimitDelay:
+		return mode
+	}
+	return ""
+}
+
+// RateLimitHTTPToContext reads the mode chosen in the X-RateLimit-Mode header, and makes room for the status
+func RateLimitHTTPToContext() httptransport.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		return context.WithValue(ctx, rateLimitContextKey{}, &rateLimitState{mode: parseRateLimitMode(r.Header.Get(RateLimitModeHeader))})
+	}
+}
+
+// RateLimitGRPCToContext reads the mode chosen in the x-ratelimit-mode metadata, and makes room for the status
+func RateLimitGRPCToContext() grpctransport.ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		mode := RateLimitMode("")
+		if modes := md[rateLimitModeMetadata]; len(modes) > 0 {
+			mode = parseRateLimitMode(modes[0])
+		}
+		return context.WithValue(ctx, rateLimitContextKey{}, &rateLimitState{mode: mode})
+	}
+}
+
+// WithRateLimitMode chooses the mode of the calls made with ctx, for clients
+func WithRateLimitMode(ctx context.Context, mode RateLimitMode) context.Context {
+	return context.WithValue(ctx, rateLimitContextKey{}, &rateLimitState{mode: mode})
+}
+
+// RateLimitContextToGRPC sends the mode chosen with WithRateLimitMode in the x-ratelimit-mode metadata, for clients
+func RateLimitContextToGRPC() grpctransport.ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if state, ok := ctx.Value(rateLimitContextKey{}).(*rateLimitState); ok && state.mode != "" {
+			(*md)[rateLimitModeMetadata] = []string{string(state.mode)}
+		}
+		return ctx
+	}
+}
+
+// rateLimitHeaders returns the headers of the status of the request in ctx, nil when it was not limited
+func rateLimitHeaders(ctx context.Context) map[string]string {
+	state, ok := ctx.Value(rateLimitContextKey{}).(*rateLimitState)
+	if !ok || state.status == nil {
+		return nil
+	}
+	headers := map[string]string{
+		RateLimitLimitHeader:     strconv.Itoa(state.status.Limit),
+		RateLimitRemainingHeader: strconv.Itoa(state.status.Remaining),
+		RateLimitResetHeader:     strconv.Itoa(ceilSeconds(state.status.Reset)),
+	}
+	if state.status.RetryAfter > 0 {
+		headers[RetryAfterHeader] = strconv.Itoa(ceilSeconds(state.status.RetryAfter))
+	}
+	return headers
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// SetRateLimitHeaders sets the rate limit headers of the request in ctx on h, for the HTTP error encoders
+func SetRateLimitHeaders(ctx context.Context, h stdhttp.Header) {
+	for name, value := range rateLimitHeaders(ctx) {
+		h.Set(name, value)
+	}
+}
+
+// RateLimitHTTPHeaders sets the rate limit headers on successful HTTP responses
+func RateLimitHTTPHeaders() httptransport.ServerResponseFunc {
+	return func(ctx context.Context, w stdhttp.ResponseWriter) context.Context {
+		SetRateLimitHeaders(ctx, w.Header())
+		return ctx
+	}
+}
+
+// RateLimitGRPCHeaders sets the rate limit headers in the response metadata, failed or not
+func RateLimitGRPCHeaders() grpctransport.ServerFinalizerFunc {
+	return func(ctx context.Context, err error) {
+		headers := rateLimitHeaders(ctx)
+		if len(headers) == 0 {
+			return
+		}
+		md := metadata.MD{}
+		for name, value := range headers {
+			md[strings.ToLower(name)] = []string{value}
+		}
+		grpc.SetHeader(ctx, md)
+	}
+}
@@ -0,0 +1,164 @@
+package common
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestRateLimiter(t *testing.T) {
+	next := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	newLimiter := func(rules ...RateLimitRule) (*RateLimiter, *time.Time) {
+		limiter, err := NewRateLimiter(rules)
+		So(err, ShouldBeNil)
+		now := time.Unix(1500000000, 0)
+		limiter.now = func() time.Time { return now }
+		return limiter, &now
+	}
+	call := func(limiter *RateLimiter, ctx context.Context, method string, org string) error {
+		_, err := limiter.Middleware()(method, next)(ctx, &orgRequest{OrgCode: org})
+		return err
+	}
+
+	Convey("A bucket allows its burst, then RESOURCE_EXHAUSTED until it refills", t, func() {
+		limiter, now := newLimiter(RateLimitRule{Rate: 2, Burst: 3})
+		ctx := context.Background()
+		for i := 0; i < 3; i++ {
+			So(call(limiter, ctx, "GetNextDocNo", "MAT"), ShouldBeNil)
+		}
+		err := call(limiter, ctx, "GetNextDocNo", "MAT")
+		So(ReasonOf(err), ShouldEqual, ReasonResourceExhausted)
+		So(ReasonResourceExhausted.GRPCCode(), ShouldEqual, codes.ResourceExhausted)
+		So(ReasonResourceExhausted.HTTPStatus(), ShouldEqual, http.StatusTooManyRequests)
+
+		*now = now.Add(500 * time.Millisecond)
+		So(call(limiter, ctx, "GetNextDocNo", "MAT"), ShouldBeNil)
+		So(ReasonOf(call(limiter, ctx, "GetNextDocNo", "MAT")), ShouldEqual, ReasonResourceExhausted)
+	})
+
+	Convey("Methods, organizations and clients have their own buckets", t, func() {
+		limiter, _ := newLimiter(RateLimitRule{Rate: 1, Burst: 1})
+		alice := context.WithValue(context.Background(), PrincipalContextKey, &Principal{Subject: "alice"})
+		bob := context.WithValue(context.Background(), PrincipalContextKey, &Principal{Subject: "bob"})
+		So(call(limiter, alice, "GetNextDocNo", "MAT"), ShouldBeNil)
+		So(ReasonOf(call(limiter, alice, "GetNextDocNo", "MAT")), ShouldEqual, ReasonResourceExhausted)
+		So(call(limiter, alice, "GetNextDocNo", "OTHER"), ShouldBeNil)
+		So(call(limiter, alice, "GenerateDocNoFormat", "MAT"), ShouldBeNil)
+		So(call(limiter, bob, "GetNextDocNo", "MAT"), ShouldBeNil)
+	})
+
+	Convey("Requests without a principal are counted by the address of their peer", t, func() {
+		limiter, _ := newLimiter(RateLimitRule{Per: []string{RateLimitPerMethod, RateLimitPerClient}, Rate: 1, Burst: 1})
+		first := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4000}})
+		samePeer := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4001}})
+		otherPeer := context.WithValue(context.Background(), httptransport.ContextKeyRequestRemoteAddr, "10.0.0.2:4000")
+		So(call(limiter, first, "GetNextDocNo", "MAT"), ShouldBeNil)
+		So(ReasonOf(call(limiter, samePeer, "GetNextDocNo", "OTHER")), ShouldEqual, ReasonResourceExhausted)
+		So(call(limiter, otherPeer, "GetNextDocNo", "MAT"), ShouldBeNil)
+
+		Convey("and by address whoever they are before authentication", func() {
+			alice := context.WithValue(first, PrincipalContextKey, &Principal{Subject: "alice"})
+			So(call(limiter, alice, "GetNextDocNo", "MAT"), ShouldBeNil)
+			_, err := limiter.AddressMiddleware()("GetNextDocNo", next)(alice, &orgRequest{OrgCode: "MAT"})
+			So(ReasonOf(err), ShouldEqual, ReasonResourceExhausted)
+		})
+	})
+
+	Convey("The first matching rule applies, and can share a bucket or not limit at all", t, func() {
+		limiter, _ := newLimiter(
+			RateLimitRule{Org: "VIP", Rate: 0},
+			RateLimitRule{Org: "NOISY", Per: []string{RateLimitPerOrg}, Rate: 1, Burst: 1},
+			RateLimitRule{Rate: 1, Burst: 1},
+		)
+		ctx := context.Background()
+		for i := 0; i < 5; i++ {
+			So(call(limiter, ctx, "GetNextDocNo", "VIP"), ShouldBeNil)
+		}
+		So(call(limiter, ctx, "GetNextDocNo", "NOISY"), ShouldBeNil)
+		So(ReasonOf(call(limiter, ctx, "GenerateDocNoFormat", "NOISY")), ShouldEqual, ReasonResourceExhausted)
+	})
+
+	Convey("In delay mode a request waits for the bucket, up to the max delay", t, func() {
+		limiter, err := NewRateLimiter([]RateLimitRule{{Rate: 50, Burst: 1, Mode: RateLimitDelay, MaxDelay: Duration(100 * time.Millisecond)}})
+		So(err, ShouldBeNil)
+		ctx := context.Background()
+		So(call(limiter, ctx, "GetNextDocNo", "MAT"), ShouldBeNil)
+		begin := time.Now()
+		So(call(limiter, ctx, "GetNextDocNo", "MAT"), ShouldBeNil)
+		So(time.Since(begin), ShouldBeGreaterThanOrEqualTo, 10*time.Millisecond)
+
+		Convey("unless the client chooses error mode", func() {
+			r := httptest.NewRequest("POST", "/GetNextDocNo", nil)
+			r.Header.Set(RateLimitModeHeader, "error")
+			ctx := RateLimitHTTPToContext()(context.Background(), r)
+			So(ReasonOf(call(limiter, ctx, "GetNextDocNo", "MAT")), ShouldEqual, ReasonResourceExhausted)
+		})
+
+		Convey("a cancelled request gives its place back", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			So(ReasonOf(call(limiter, ctx, "GetNextDocNo", "MAT")), ShouldEqual, ReasonDeadlineExceeded)
+		})
+	})
+
+	Convey("The status of the bucket is returned as headers", t, func() {
+		limiter, _ := newLimiter(RateLimitRule{Rate: 1, Burst: 2})
+		ctx := RateLimitHTTPToContext()(context.Background(), httptest.NewRequest("POST", "/GetNextDocNo", nil))
+		So(call(limiter, ctx, "GetNextDocNo", "MAT"), ShouldBeNil)
+		w := httptest.NewRecorder()
+		RateLimitHTTPHeaders()(ctx, w)
+		So(w.Header().Get(RateLimitLimitHeader), ShouldEqual, "2")
+		So(w.Header().Get(RateLimitRemainingHeader), ShouldEqual, "1")
+		So(w.Header().Get(RateLimitResetHeader), ShouldEqual, "1")
+		So(w.Header().Get(RetryAfterHeader), ShouldEqual, "")
+
+		call(limiter, ctx, "GetNextDocNo", "MAT")
+		So(ReasonOf(call(limiter, ctx, "GetNextDocNo", "MAT")), ShouldEqual, ReasonResourceExhausted)
+		h := http.Header{}
+		SetRateLimitHeaders(ctx, h)
+		So(h.Get(RateLimitRemainingHeader), ShouldEqual, "0")
+		So(h.Get(RetryAfterHeader), ShouldEqual, "1")
+	})
+
+	Convey("Clients choose the mode in the gRPC metadata", t, func() {
+		md := metadata.MD{}
+		RateLimitContextToGRPC()(WithRateLimitMode(context.Background(), RateLimitDelay), &md)
+		So(md[rateLimitModeMetadata], ShouldResemble, []string{"delay"})
+		ctx := RateLimitGRPCToContext()(context.Background(), md)
+		So(ctx.Value(rateLimitContextKey{}).(*rateLimitState).mode, ShouldEqual, RateLimitDelay)
+	})
+
+	Convey("Rules are read from JSON and checked", t, func() {
+		dir, err := ioutil.TempDir("", "ratelimit")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "rules.json")
+		ioutil.WriteFile(file, []byte(`{"rules":[{"org":"MAT","rate":5,"burst":5,"mode":"delay","maxDelay":"250ms"}]}`), 0600)
+		rules, err := LoadRateLimitRules(file)
+		So(err, ShouldBeNil)
+		So(rules, ShouldResemble, []RateLimitRule{{Org: "MAT", Rate: 5, Burst: 5, Mode: RateLimitDelay, MaxDelay: Duration(250 * time.Millisecond)}})
+
+		ioutil.WriteFile(file, []byte(`{"rules":[{"orgs":"MAT","rate":5}]}`), 0600)
+		_, err = LoadRateLimitRules(file)
+		So(err, ShouldNotBeNil)
+
+		for _, rule := range []RateLimitRule{{Rate: -1}, {Rate: 1}, {Rate: 1, Burst: 1, Mode: "drop"}, {Rate: 1, Burst: 1, Per: []string{"path"}}} {
+			_, err := NewRateLimiter([]RateLimitRule{rule})
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	stdhttp "net/http"
+	"runtime/debug"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// panicMessage is returned to the caller instead of the panic, which stays in the logs
+const panicMessage = "internal error"
+
+// RecoveryGRPCUnaryInterceptor turns a panic of a unary call into an INTERNAL error, logged at error level with the
+// stack and the request ID, and counted by panics with labels "transport" ("grpc") and "method". A request without a
+// valid x-request-id gets one, so that the transport logs the same ID.
+func RecoveryGRPCUnaryInterceptor(logger log.Logger, panics metrics.Counter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, id := requestIDToIncomingContext(ctx)
+		defer func() {
+			if p := recover(); p != nil {
+				recovered(logger, panics, "grpc", info.FullMethod, id, p)
+				resp, err = nil, NewError(ReasonInternal, panicMessage)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryGRPCStreamInterceptor turns a panic of a streaming call into an INTERNAL error, as
+// RecoveryGRPCUnaryInterceptor
+func RecoveryGRPCStreamInterceptor(logger log.Logger, panics metrics.Counter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		id := ""
+		if md, ok := metadata.FromIncomingContext(ss.Context()); ok && len(md[requestIDMetadata]) > 0 {
+			id = md[requestIDMetadata][0]
+		}
+		defer func() {
+			if p := recover(); p != nil {
+				recovered(logger, panics, "grpc", info.FullMethod, id, p)
+				err = NewError(ReasonInternal, panicMessage)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// requestIDToIncomingContext returns the request ID of the incoming metadata, after setting a new one when it is
+// missing or invalid
+func requestIDToIncomingContext(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md[requestIDMetadata]; len(ids) > 0 && validRequestID(ids[0]) {
+		return ctx, ids[0]
+	}
+	id := NewRequestID()
+	md = md.Copy()
+	md[requestIDMetadata] = []string{id}
+	return metadata.NewIncomingContext(ctx, md), id
+}
+
+// NewRecoveryHTTPHandler turns a panic of next into a 500 INTERNAL error with the JSON body of the failed requests,
+// logged with its path and counted as RecoveryGRPCUnaryInterceptor with labels "transport" and "method" both "http",
+// since paths embed the values of the REST routes. The http.ErrAbortHandler panic is left to net/http, and a response
+// already started is only logged.
+func NewRecoveryHTTPHandler(next stdhttp.Handler, logger log.Logger, panics metrics.Counter) stdhttp.Handler {
+	return stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = NewRequestID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		rw := &startedResponseWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == stdhttp.ErrAbortHandler {
+				panic(p)
+			}
+			recovered(log.With(logger, "path", r.URL.Path), panics, "http", "http", id, p)
+			if rw.started {
+				return
+			}
+			w.Header().Set(RequestIDHeader, id)
+			WriteHTTPError(w, NewError(ReasonInternal, panicMessage))
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// startedResponseWriter remembers whether the response was started
+type startedResponseWriter struct {
+	stdhttp.ResponseWriter
+	started bool
+}
+
+func (w *startedResponseWriter) WriteHeader(code int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *startedResponseWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets the handlers streaming their response flush it
+func (w *startedResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(stdhttp.Flusher); ok {
+		w.started = true
+		f.Flush()
+	}
+}
+
+func recovered(logger log.Logger, panics metrics.Counter, transport, method, id string, p interface{}) {
+	if panics != nil {
+		panics.With("transport", transport, "method", method).Add(1)
+	}
+	LogError(logger).Log("method", method, "request_id", id, "panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+}
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testCounter sums what is added under every label set
+type testCounter struct {
+	labels []string
+	values map[string]float64
+}
+
+func (c testCounter) With(labelValues ...string) metrics.Counter {
+	return testCounter{labels: append(append([]string{}, c.labels...), labelValues...), values: c.values}
+}
+
+func (c testCounter) Add(delta float64) { c.values[strings.Join(c.labels, ",")] += delta }
+
+func TestRecovery(t *testing.T) {
+	Convey("Given a panicking handler", t, func() {
+		var buf bytes.Buffer
+		logger := log.NewLogfmtLogger(&buf)
+		panics := testCounter{values: map[string]float64{}}
+
+		Convey("a gRPC call fails with INTERNAL, logged with its stack and request ID", func() {
+			interceptor := RecoveryGRPCUnaryInterceptor(logger, panics)
+			info := &grpc.UnaryServerInfo{FullMethod: "/docnogen.DocNoGenService/GetNextDocNo"}
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDMetadata, "abc-123"))
+			var handled string
+			resp, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				handled = RequestIDFromContext(RequestIDGRPCToContext(logger)(ctx, metadataOf(ctx)))
+				var request *struct{ OrgCode string }
+				return request.OrgCode, nil
+			})
+			So(resp, ShouldBeNil)
+			So(status.Code(err), ShouldEqual, codes.Internal)
+			So(err.Error(), ShouldNotContainSubstring, "nil pointer")
+			So(handled, ShouldEqual, "abc-123")
+			So(panics.values["transport,grpc,method,/docnogen.DocNoGenService/GetNextDocNo"], ShouldEqual, 1)
+			So(buf.String(), ShouldContainSubstring, "level=error")
+			So(buf.String(), ShouldContainSubstring, "request_id=abc-123")
+			So(buf.String(), ShouldContainSubstring, "nil pointer dereference")
+			So(buf.String(), ShouldContainSubstring, "recovery_test.go")
+		})
+
+		Convey("a gRPC call without a request ID is given one before the handler", func() {
+			interceptor := RecoveryGRPCUnaryInterceptor(logger, panics)
+			var handled string
+			_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/m"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				handled = RequestIDFromContext(RequestIDGRPCToContext(logger)(ctx, metadataOf(ctx)))
+				panic("broken")
+			})
+			So(status.Code(err), ShouldEqual, codes.Internal)
+			So(handled, ShouldNotBeEmpty)
+			So(buf.String(), ShouldContainSubstring, "request_id="+handled)
+		})
+
+		Convey("a gRPC stream fails with INTERNAL", func() {
+			interceptor := RecoveryGRPCStreamInterceptor(logger, panics)
+			err := interceptor(nil, testServerStream{}, &grpc.StreamServerInfo{FullMethod: "/m"}, func(srv interface{}, ss grpc.ServerStream) error {
+				panic("broken")
+			})
+			So(status.Code(err), ShouldEqual, codes.Internal)
+			So(panics.values["transport,grpc,method,/m"], ShouldEqual, 1)
+		})
+
+		Convey("an HTTP request gets a 500 with the JSON body of failed requests and its request ID", func() {
+			handler := NewRecoveryHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("broken")
+			}), logger, panics)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("POST", "/GetNextDocNo", nil))
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(w.Header().Get(RequestIDHeader), ShouldNotBeEmpty)
+			var body map[string]interface{}
+			So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+			So(body["errorReason"], ShouldEqual, string(ReasonInternal))
+			So(body["errorMessage"], ShouldEqual, "internal error")
+			So(panics.values["transport,http,method,http"], ShouldEqual, 1)
+			So(buf.String(), ShouldContainSubstring, "path=/GetNextDocNo")
+			So(buf.String(), ShouldContainSubstring, "request_id="+w.Header().Get(RequestIDHeader))
+		})
+
+		Convey("an HTTP response already started is left as it is", func() {
+			handler := NewRecoveryHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				panic("broken")
+			}), logger, panics)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/Export", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.Len(), ShouldEqual, 0)
+			So(panics.values["transport,http,method,http"], ShouldEqual, 1)
+		})
+
+		Convey("http.ErrAbortHandler is not recovered", func() {
+			handler := NewRecoveryHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic(http.ErrAbortHandler)
+			}), logger, panics)
+			So(func() { handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)) }, ShouldPanicWith, http.ErrAbortHandler)
+		})
+	})
+}
+
+// testServerStream is a stream without metadata
+type testServerStream struct{ grpc.ServerStream }
+
+func (testServerStream) Context() context.Context { return context.Background() }
+
+func metadataOf(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return md
+}
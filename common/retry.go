@@ -0,0 +1,60 @@
+package common
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy bounds the retries of an update that failed with ConcurrencyUpdateError.
+// The wait before attempt n+1 is InitialBackoff * Multiplier^(n-1), capped at MaxBackoff,
+// of which a random fraction up to Jitter is taken off so that competing callers spread out.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64 // 0 to 1
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    10,
+	InitialBackoff: 5 * time.Millisecond,
+	MaxBackoff:     500 * time.Millisecond,
+	Multiplier:     2,
+	Jitter:         0.5,
+}
+
+// Backoff returns the wait after the given failed attempt, attempts count from 1
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	jitter := math.Min(math.Max(p.Jitter, 0), 1)
+	backoff -= backoff * jitter * rand.Float64()
+	return time.Duration(backoff)
+}
+
+// Wait sleeps for the backoff of the given failed attempt, it returns ctx.Err() if ctx is done first
+func (p RetryPolicy) Wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.Backoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,217 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSFiles names the PEM files of a TLS listener or client
+type TLSFiles struct {
+	CertFile string
+	KeyFile  string
+	// CAFile verifies the peer: the client certificates on a listener, the server certificate on a client
+	CAFile string
+	// VerifyClient requires the clients of a listener to present a certificate signed by CAFile
+	VerifyClient bool
+}
+
+// Enabled tells whether a certificate is configured
+func (f TLSFiles) Enabled() bool {
+	return f.CertFile != "" || f.KeyFile != ""
+}
+
+// NewServerTLSConfig returns the TLS config of a listener. With VerifyClient every client must present a certificate
+// signed by CAFile, with only CAFile a certificate is verified when a client presents one.
+// The certificate, key and CA are reloaded when their files change, which is checked on a handshake at most every
+// interval; onReload, if not nil, is called with the result of every reload. A failed reload keeps the previous files.
+func NewServerTLSConfig(files TLSFiles, interval time.Duration, onReload func(err error)) (*tls.Config, error) {
+	if files.CertFile == "" || files.KeyFile == "" {
+		return nil, errors.New("TLS needs both a certificate and a key file")
+	}
+	if files.VerifyClient && files.CAFile == "" {
+		return nil, errors.New("Verifying client certificates needs a CA file")
+	}
+	r := &certReloader{files: files, interval: interval, onReload: onReload}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+	}
+	if files.CAFile != "" {
+		// the CA pool may change, so the chain is verified here rather than with a fixed ClientCAs
+		config.ClientAuth = tls.RequestClientCert
+		if files.VerifyClient {
+			config.ClientAuth = tls.RequireAnyClientCert
+		}
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return nil
+			}
+			_, pool := r.current()
+			return verifyChain(rawCerts, pool, x509.ExtKeyUsageClientAuth)
+		}
+	}
+	return config, nil
+}
+
+// NewClientTLSConfig returns the TLS config of a client. CAFile verifies the server, the system roots are used when
+// it is empty, and serverName overrides the host name that is verified. CertFile and KeyFile, when set, are presented
+// for mutual TLS and reloaded like those of a listener; the CA is read once.
+func NewClientTLSConfig(files TLSFiles, serverName string, interval time.Duration, onReload func(err error)) (*tls.Config, error) {
+	if (files.CertFile == "") != (files.KeyFile == "") {
+		return nil, errors.New("A client certificate needs both a certificate and a key file")
+	}
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+	if files.CAFile != "" {
+		pool, err := loadCAFile(files.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if files.CertFile != "" {
+		r := &certReloader{files: TLSFiles{CertFile: files.CertFile, KeyFile: files.KeyFile}, interval: interval, onReload: onReload}
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		}
+	}
+	return config, nil
+}
+
+// certReloader holds the certificate and CA pool of files, and loads them again once the files have changed
+type certReloader struct {
+	files    TLSFiles
+	interval time.Duration
+	onReload func(err error)
+
+	mu       sync.Mutex
+	checked  time.Time
+	modTimes []time.Time
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+}
+
+// current returns the certificate and CA pool, reloaded first if the files changed
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checked) >= r.interval {
+		r.checked = time.Now()
+		if modTimes, err := r.stat(); err != nil || !sameTimes(modTimes, r.modTimes) {
+			if err == nil {
+				err = r.loadLocked()
+			}
+			if r.onReload != nil {
+				r.onReload(err)
+			}
+		}
+	}
+	return r.cert, r.pool
+}
+
+func (r *certReloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked = time.Now()
+	return r.loadLocked()
+}
+
+func (r *certReloader) loadLocked() error {
+	// stat first, so that a file written while loading is loaded again on the next check
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+	if err != nil {
+		return fmt.Errorf("Error loading certificate %s and key %s Error=%s", r.files.CertFile, r.files.KeyFile, err.Error())
+	}
+	var pool *x509.CertPool
+	if r.files.CAFile != "" {
+		if pool, err = loadCAFile(r.files.CAFile); err != nil {
+			return err
+		}
+	}
+	r.cert, r.pool, r.modTimes = &cert, pool, modTimes
+	return nil
+}
+
+func (r *certReloader) stat() ([]time.Time, error) {
+	var modTimes []time.Time
+	for _, name := range []string{r.files.CertFile, r.files.KeyFile, r.files.CAFile} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s Error=%s", name, err.Error())
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+func sameTimes(a []time.Time, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func loadCAFile(name string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading CA file %s Error=%s", name, err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA file %s has no PEM certificate", name)
+	}
+	return pool, nil
+}
+
+// verifyChain verifies the peer certificate rawCerts[0] against roots, the other certificates being intermediates
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool, usage x509.ExtKeyUsage) error {
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("Bad peer certificate: %s", err.Error())
+		}
+		certs = append(certs, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	return err
+}
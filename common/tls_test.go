@@ -0,0 +1,166 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert returns a certificate signed by parent, or self signed CA when parent is nil
+func newTestCert(t *testing.T, name string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = nil
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key}
+}
+
+// write writes the certificate and key PEM files into dir, with the given modification time
+func (c *testCert) write(t *testing.T, dir string, name string, modTime time.Time) (certFile string, keyFile string) {
+	certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(certFile, modTime, modTime)
+	os.Chtimes(keyFile, modTime, modTime)
+	return certFile, keyFile
+}
+
+func Test_TLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docnogen-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Now().Add(-time.Minute)
+	ca := newTestCert(t, "ca", nil, 0)
+	caFile, _ := ca.write(t, dir, "ca", start)
+	otherCA := newTestCert(t, "other-ca", nil, 0)
+	serverCertFile, serverKeyFile := newTestCert(t, "server", ca, x509.ExtKeyUsageServerAuth).write(t, dir, "server", start)
+	clientCertFile, clientKeyFile := newTestCert(t, "client", ca, x509.ExtKeyUsageClientAuth).write(t, dir, "client", start)
+	strangerCertFile, strangerKeyFile := newTestCert(t, "stranger", otherCA, x509.ExtKeyUsageClientAuth).write(t, dir, "stranger", start)
+
+	serve := func(files TLSFiles) string {
+		config, err := NewServerTLSConfig(files, 0, nil)
+		So(err, ShouldBeNil)
+		ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+		So(err, ShouldBeNil)
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})}
+		go server.Serve(ln)
+		Reset(func() { server.Close() })
+		return "https://" + ln.Addr().String()
+	}
+	get := func(url string, client TLSFiles) (*tls.ConnectionState, error) {
+		config, err := NewClientTLSConfig(client, "localhost", 0, nil)
+		So(err, ShouldBeNil)
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: config}, Timeout: 5 * time.Second}
+		resp, err := httpClient.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		return resp.TLS, nil
+	}
+
+	Convey("A TLS listener", t, func() {
+		url := serve(TLSFiles{CertFile: serverCertFile, KeyFile: serverKeyFile})
+
+		Convey("is trusted by clients of its CA only", func() {
+			_, err := get(url, TLSFiles{CAFile: caFile})
+			So(err, ShouldBeNil)
+			_, err = get(url, TLSFiles{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("A listener verifying client certificates", t, func() {
+		url := serve(TLSFiles{CertFile: serverCertFile, KeyFile: serverKeyFile, CAFile: caFile, VerifyClient: true})
+
+		Convey("accepts a client certificate signed by its CA", func() {
+			_, err := get(url, TLSFiles{CAFile: caFile, CertFile: clientCertFile, KeyFile: clientKeyFile})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("refuses clients without certificate or with one of another CA", func() {
+			_, err := get(url, TLSFiles{CAFile: caFile})
+			So(err, ShouldNotBeNil)
+			_, err = get(url, TLSFiles{CAFile: caFile, CertFile: strangerCertFile, KeyFile: strangerKeyFile})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("A listener reloads its certificate when the files change", t, func() {
+		url := serve(TLSFiles{CertFile: serverCertFile, KeyFile: serverKeyFile})
+		state, err := get(url, TLSFiles{CAFile: caFile})
+		So(err, ShouldBeNil)
+		So(state.PeerCertificates[0].Subject.CommonName, ShouldEqual, "server")
+
+		renewed := newTestCert(t, "renewed", ca, x509.ExtKeyUsageServerAuth)
+		renewed.write(t, dir, "server", start.Add(time.Second))
+		state, err = get(url, TLSFiles{CAFile: caFile})
+		So(err, ShouldBeNil)
+		So(state.PeerCertificates[0].Subject.CommonName, ShouldEqual, "renewed")
+	})
+
+	Convey("Bad settings are refused", t, func() {
+		_, err := NewServerTLSConfig(TLSFiles{CertFile: serverCertFile}, 0, nil)
+		So(err, ShouldNotBeNil)
+		_, err = NewServerTLSConfig(TLSFiles{CertFile: serverCertFile, KeyFile: serverKeyFile, VerifyClient: true}, 0, nil)
+		So(err, ShouldNotBeNil)
+		_, err = NewServerTLSConfig(TLSFiles{CertFile: serverCertFile, KeyFile: clientKeyFile}, 0, nil)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,561 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	stdhttp "net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	kitopentracing "github.com/go-kit/kit/tracing/opentracing"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	httptransport "github.com/go-kit/kit/transport/http"
+	basictracer "github.com/opentracing/basictracer-go"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Spans are those of the opentracing global tracer, a no-op one until opentracing.SetGlobalTracer is given a Tracer.
+// The span context of a request is read from and written to its ot-tracer-* headers or metadata by the tracer.
+
+const (
+	traceBatchSize     = 512
+	traceQueueSize     = 2048
+	traceFlushInterval = 5 * time.Second
+)
+
+// SpanExporter sends the finished spans of service to a tracing backend
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, service string, spans []basictracer.RawSpan) error
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer is the opentracing tracer of basictracer, exporting the sampled spans in batches
+type Tracer struct {
+	opentracing.Tracer
+	serviceName string
+	exporter    SpanExporter
+	onError     func(error)
+
+	queue   chan basictracer.RawSpan
+	flush   chan chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewTracer samples sampleRatio (0 to 1) of the traces started here, traces continued from a caller follow its
+// choice, and exports the sampled spans with exporter. onError is called with the errors of the exporter.
+func NewTracer(serviceName string, exporter SpanExporter, sampleRatio float64, onError func(error)) *Tracer {
+	if onError == nil {
+		onError = func(error) {}
+	}
+	t := &Tracer{
+		serviceName: serviceName,
+		exporter:    exporter,
+		onError:     onError,
+		queue:       make(chan basictracer.RawSpan, traceQueueSize),
+		flush:       make(chan chan struct{}),
+		stop:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	options := basictracer.DefaultOptions()
+	options.ShouldSample = RatioSampler(sampleRatio)
+	options.TrimUnsampledSpans = true
+	options.Recorder = spanRecorderFunc(t.enqueue)
+	t.Tracer = basictracer.NewWithOptions(options)
+	go t.run()
+	return t
+}
+
+// RatioSampler samples ratio (0 to 1) of the trace IDs, which are random
+func RatioSampler(ratio float64) func(traceID uint64) bool {
+	return func(traceID uint64) bool {
+		if ratio >= 1 {
+			return true
+		}
+		return float64(traceID) < ratio*math.MaxUint64
+	}
+}
+
+type spanRecorderFunc func(basictracer.RawSpan)
+
+func (f spanRecorderFunc) RecordSpan(span basictracer.RawSpan) { f(span) }
+
+func (t *Tracer) enqueue(span basictracer.RawSpan) {
+	if !span.Context.Sampled {
+		return
+	}
+	select {
+	case t.queue <- span:
+	default:
+		// the exporter cannot keep up, the span is dropped rather than slowing requests down
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+	var batch []basictracer.RawSpan
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), traceFlushInterval)
+		if err := t.exporter.ExportSpans(ctx, t.serviceName, batch); err != nil {
+			t.onError(err)
+		}
+		cancel()
+		batch = nil
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-t.queue:
+				batch = append(batch, span)
+				if len(batch) >= traceBatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) >= traceBatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case done := <-t.flush:
+			drain()
+			close(done)
+		case <-t.stop:
+			drain()
+			return
+		}
+	}
+}
+
+// Flush exports the spans finished so far
+func (t *Tracer) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case t.flush <- done:
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the spans finished so far and shuts the exporter down, spans finished later are dropped
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.once.Do(func() { close(t.stop) })
+	select {
+	case <-t.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+// TraceIDFromContext returns the trace ID of the span of ctx, when it is traced by a Tracer
+func TraceIDFromContext(ctx context.Context) (string, bool) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return "", false
+	}
+	sc, ok := span.Context().(basictracer.SpanContext)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%016x", sc.TraceID), true
+}
+
+// FinishSpan marks span failed by err, if not nil, then finishes it
+func FinishSpan(span opentracing.Span, err error) {
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(otlog.String("event", "error"), otlog.String("message", err.Error()))
+	}
+	span.Finish()
+}
+
+// LogSpanEvent logs the event name on the span of ctx, if any, with key value pairs
+func LogSpanEvent(ctx context.Context, name string, keyvals ...interface{}) {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.LogKV(append([]interface{}{"event", name}, keyvals...)...)
+	}
+}
+
+// TraceEndpoint returns an endpoint middleware starting a span named name around next, with tag "method".
+// The span fails with the error returned, or the error of a failed response.
+func TraceEndpoint(name, method string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			span, ctx := opentracing.StartSpanFromContext(ctx, name)
+			span.SetTag("method", method)
+			defer func() {
+				if e := ResponseError(response); err == nil && e != nil {
+					span.SetTag("error.reason", string(e.Reason))
+					FinishSpan(span, e)
+					return
+				}
+				FinishSpan(span, err)
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+// TraceMethodMiddleware returns middleware with a span named name around it
+func TraceMethodMiddleware(name string, middleware MethodMiddleware) MethodMiddleware {
+	return func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+		return TraceEndpoint(name, method)(middleware(method, next))
+	}
+}
+
+// TraceHTTPToContext starts the server span of an HTTP request, continuing the trace of its headers.
+// TraceHTTPFinalizer finishes it.
+func TraceHTTPToContext() httptransport.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		return kitopentracing.HTTPToContext(opentracing.GlobalTracer(), "HTTP "+r.Method+" "+r.URL.Path, log.NewNopLogger())(ctx, r)
+	}
+}
+
+// TraceHTTPFinalizer finishes the server span of an HTTP request with its status code, failed from 500
+func TraceHTTPFinalizer() httptransport.ServerFinalizerFunc {
+	return func(ctx context.Context, code int, r *stdhttp.Request) {
+		span := opentracing.SpanFromContext(ctx)
+		if span == nil {
+			return
+		}
+		ext.HTTPStatusCode.Set(span, uint16(code))
+		var err error
+		if code >= stdhttp.StatusInternalServerError {
+			err = fmt.Errorf("%s", stdhttp.StatusText(code))
+		}
+		FinishSpan(span, err)
+	}
+}
+
+// TraceGRPCToContext starts the server span of a gRPC request, continuing the trace of its metadata.
+// TraceGRPCFinalizer finishes it.
+func TraceGRPCToContext() grpctransport.ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		method, _ := grpc.Method(ctx)
+		ctx = kitopentracing.GRPCToContext(opentracing.GlobalTracer(), "gRPC "+strings.TrimPrefix(method, "/"), log.NewNopLogger())(ctx, md)
+		span := opentracing.SpanFromContext(ctx)
+		ext.Component.Set(span, "grpc")
+		span.SetTag("rpc.method", method)
+		return ctx
+	}
+}
+
+// TraceGRPCFinalizer finishes the server span of a gRPC request with its status code
+func TraceGRPCFinalizer() grpctransport.ServerFinalizerFunc {
+	return func(ctx context.Context, err error) {
+		span := opentracing.SpanFromContext(ctx)
+		if span == nil {
+			return
+		}
+		span.SetTag("rpc.grpc.status_code", status.Code(err).String())
+		FinishSpan(span, err)
+	}
+}
+
+// TraceContextToGRPC sends the span context of ctx in the metadata, for clients
+func TraceContextToGRPC() grpctransport.ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		return kitopentracing.ContextToGRPC(opentracing.GlobalTracer(), log.NewNopLogger())(ctx, md)
+	}
+}
+
+// exportedSpan is a span with its tags and logs as strings, and its kind and error read from them
+type exportedSpan struct {
+	basictracer.RawSpan
+	kind       string
+	attributes map[string]string
+	events     []spanEventJSON
+	err        string
+}
+
+func exportSpan(span basictracer.RawSpan) exportedSpan {
+	e := exportedSpan{RawSpan: span, kind: "internal", attributes: map[string]string{}}
+	failed := false
+	for key, value := range span.Tags {
+		switch key {
+		case string(ext.SpanKind):
+			e.kind = fmt.Sprint(value)
+		case string(ext.Error):
+			failed = value == true
+		default:
+			e.attributes[key] = fmt.Sprint(value)
+		}
+	}
+	for _, record := range span.Logs {
+		event := spanEventJSON{Time: record.Timestamp, Attributes: map[string]string{}}
+		for _, field := range record.Fields {
+			if field.Key() == "event" {
+				event.Name = field.Value().(string)
+			} else {
+				event.Attributes[field.Key()] = fmt.Sprint(field.Value())
+			}
+		}
+		if event.Name == "error" {
+			e.err = event.Attributes["message"]
+			continue
+		}
+		e.events = append(e.events, event)
+	}
+	if failed && e.err == "" {
+		e.err = "error"
+	}
+	return e
+}
+
+// spanJSON is a span as written by the writer exporter, one JSON object per line
+type spanJSON struct {
+	TraceID    string            `json:"traceId"`
+	SpanID     string            `json:"spanId"`
+	ParentID   string            `json:"parentSpanId,omitempty"`
+	Service    string            `json:"service"`
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	Start      time.Time         `json:"start"`
+	Duration   string            `json:"duration"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Events     []spanEventJSON   `json:"events,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+type spanEventJSON struct {
+	Name       string            `json:"name"`
+	Time       time.Time         `json:"time"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+type writerSpanExporter struct {
+	mtx    sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewWriterSpanExporter writes every span to w as a line of JSON, such as os.Stdout
+func NewWriterSpanExporter(w io.Writer) SpanExporter {
+	return &writerSpanExporter{w: w}
+}
+
+// NewFileSpanExporter appends every span to file as a line of JSON
+func NewFileSpanExporter(file string) (SpanExporter, error) {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening trace file %s Error=%s", file, err.Error())
+	}
+	return &writerSpanExporter{w: f, closer: f}, nil
+}
+
+func (e *writerSpanExporter) ExportSpans(_ context.Context, service string, spans []basictracer.RawSpan) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, raw := range spans {
+		span := exportSpan(raw)
+		s := spanJSON{
+			TraceID:    fmt.Sprintf("%016x", span.Context.TraceID),
+			SpanID:     fmt.Sprintf("%016x", span.Context.SpanID),
+			Service:    service,
+			Name:       span.Operation,
+			Kind:       span.kind,
+			Start:      span.Start,
+			Duration:   span.Duration.String(),
+			Attributes: span.attributes,
+			Events:     span.events,
+			Error:      span.err,
+		}
+		if span.ParentSpanID != 0 {
+			s.ParentID = fmt.Sprintf("%016x", span.ParentSpanID)
+		}
+		if err := encoder.Encode(s); err != nil {
+			return err
+		}
+	}
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+func (e *writerSpanExporter) Shutdown(context.Context) error {
+	if e.closer != nil {
+		return e.closer.Close()
+	}
+	return nil
+}
+
+type otlpSpanExporter struct {
+	url     string
+	headers map[string]string
+	client  *stdhttp.Client
+}
+
+// NewOTLPSpanExporter posts the spans to url, an OTLP/HTTP traces endpoint such as
+// http://localhost:4318/v1/traces of an OpenTelemetry collector, in the JSON encoding, with headers
+func NewOTLPSpanExporter(url string, headers map[string]string, client *stdhttp.Client) SpanExporter {
+	if client == nil {
+		client = &stdhttp.Client{Timeout: traceFlushInterval}
+	}
+	return &otlpSpanExporter{url: url, headers: headers, client: client}
+}
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func otlpAttributes(attributes map[string]string) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(attributes))
+	for key, value := range attributes {
+		kv := otlpKeyValue{Key: key}
+		kv.Value.StringValue = value
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpKind returns the OTLP value of an opentracing span.kind
+func otlpKind(kind string) int {
+	switch kind {
+	case string(ext.SpanKindRPCServerEnum):
+		return 2
+	case string(ext.SpanKindRPCClientEnum):
+		return 3
+	}
+	return 1
+}
+
+func (e *otlpSpanExporter) ExportSpans(ctx context.Context, service string, spans []basictracer.RawSpan) error {
+	type event struct {
+		TimeUnixNano string         `json:"timeUnixNano"`
+		Name         string         `json:"name"`
+		Attributes   []otlpKeyValue `json:"attributes"`
+	}
+	type span struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes"`
+		Events            []event        `json:"events,omitempty"`
+		Status            struct {
+			Code    int    `json:"code,omitempty"`
+			Message string `json:"message,omitempty"`
+		} `json:"status"`
+	}
+	type scopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []span `json:"spans"`
+	}
+	type resourceSpans struct {
+		Resource struct {
+			Attributes []otlpKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+
+	rs := &resourceSpans{ScopeSpans: []scopeSpans{{}}}
+	rs.Resource.Attributes = otlpAttributes(map[string]string{"service.name": service})
+	rs.ScopeSpans[0].Scope.Name = service
+	for _, raw := range spans {
+		s := exportSpan(raw)
+		// the 64 bit IDs of the tracer are padded to the 128 bit trace IDs of OTLP
+		o := span{
+			TraceID:           fmt.Sprintf("%032x", s.Context.TraceID),
+			SpanID:            fmt.Sprintf("%016x", s.Context.SpanID),
+			Name:              s.Operation,
+			Kind:              otlpKind(s.kind),
+			StartTimeUnixNano: otlpTime(s.Start),
+			EndTimeUnixNano:   otlpTime(s.Start.Add(s.Duration)),
+			Attributes:        otlpAttributes(s.attributes),
+		}
+		if s.ParentSpanID != 0 {
+			o.ParentSpanID = fmt.Sprintf("%016x", s.ParentSpanID)
+		}
+		for _, e := range s.events {
+			o.Events = append(o.Events, event{TimeUnixNano: otlpTime(e.Time), Name: e.Name, Attributes: otlpAttributes(e.Attributes)})
+		}
+		if s.err != "" {
+			// STATUS_CODE_ERROR
+			o.Status.Code, o.Status.Message = 2, s.err
+		}
+		rs.ScopeSpans[0].Spans = append(rs.ScopeSpans[0].Spans, o)
+	}
+	request := struct {
+		ResourceSpans []*resourceSpans `json:"resourceSpans"`
+	}{[]*resourceSpans{rs}}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := stdhttp.NewRequest("POST", e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error exporting %d spans to %s Error=%s", len(spans), e.url, err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Error exporting %d spans to %s Status=%s %s", len(spans), e.url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (e *otlpSpanExporter) Shutdown(context.Context) error {
+	return nil
+}
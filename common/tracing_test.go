@@ -0,0 +1,203 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	basictracer "github.com/opentracing/basictracer-go"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc/metadata"
+)
+
+// memorySpanExporter keeps the spans exported
+type memorySpanExporter struct {
+	mtx   sync.Mutex
+	spans []basictracer.RawSpan
+}
+
+func (e *memorySpanExporter) ExportSpans(_ context.Context, _ string, spans []basictracer.RawSpan) error {
+	e.mtx.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mtx.Unlock()
+	return nil
+}
+
+func (e *memorySpanExporter) Shutdown(context.Context) error { return nil }
+
+func (e *memorySpanExporter) byName() map[string]exportedSpan {
+	spans := map[string]exportedSpan{}
+	for _, span := range e.spans {
+		spans[span.Operation] = exportSpan(span)
+	}
+	return spans
+}
+
+func spanContextOf(span opentracing.Span) basictracer.SpanContext {
+	return span.Context().(basictracer.SpanContext)
+}
+
+func TestTracing(t *testing.T) {
+	Convey("Without tracer spans do nothing", t, func() {
+		e := TraceEndpoint("service", "GetNextDocNo")(func(ctx context.Context, request interface{}) (interface{}, error) {
+			LogSpanEvent(ctx, "retry", "attempt", 1)
+			_, traced := TraceIDFromContext(ctx)
+			return traced, nil
+		})
+		traced, err := e(context.Background(), nil)
+		So(err, ShouldBeNil)
+		So(traced, ShouldBeFalse)
+		LogSpanEvent(context.Background(), "retry")
+	})
+
+	Convey("Given a tracer", t, func() {
+		exporter := &memorySpanExporter{}
+		tracer := NewTracer("docnogen", exporter, 1, nil)
+		opentracing.SetGlobalTracer(tracer)
+		defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+		defer tracer.Shutdown(context.Background())
+
+		Convey("the spans of a request continue the trace of its caller and nest by middleware", func() {
+			caller := tracer.StartSpan("caller")
+			r := httptest.NewRequest("POST", "/GetNextDocNo", nil)
+			So(tracer.Inject(caller.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header)), ShouldBeNil)
+			ctx := TraceHTTPToContext()(context.Background(), r)
+			traceID, traced := TraceIDFromContext(ctx)
+			So(traced, ShouldBeTrue)
+			So(traceID, ShouldEqual, fmt.Sprintf("%016x", spanContextOf(caller).TraceID))
+
+			middleware := TraceMethodMiddleware("ratelimit", func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+				return next
+			})
+			e := middleware("GetNextDocNo", TraceEndpoint("service", "GetNextDocNo")(func(ctx context.Context, request interface{}) (interface{}, error) {
+				LogSpanEvent(ctx, "retry", "attempt", 1)
+				return &failedTestResponse{reason: string(ReasonNotFound)}, nil
+			}))
+			_, err := e(ctx, nil)
+			So(err, ShouldBeNil)
+			TraceHTTPFinalizer()(ctx, http.StatusNotFound, r)
+			So(tracer.Flush(context.Background()), ShouldBeNil)
+
+			spans := exporter.byName()
+			So(len(spans), ShouldEqual, 3)
+			server, ratelimit, service := spans["HTTP POST /GetNextDocNo"], spans["ratelimit"], spans["service"]
+			So(server.kind, ShouldEqual, "server")
+			So(server.Context.TraceID, ShouldEqual, spanContextOf(caller).TraceID)
+			So(server.ParentSpanID, ShouldEqual, spanContextOf(caller).SpanID)
+			So(server.attributes["http.status_code"], ShouldEqual, "404")
+			So(server.err, ShouldEqual, "")
+			So(ratelimit.ParentSpanID, ShouldEqual, server.Context.SpanID)
+			So(ratelimit.attributes["method"], ShouldEqual, "GetNextDocNo")
+			So(service.ParentSpanID, ShouldEqual, ratelimit.Context.SpanID)
+			So(service.err, ShouldNotEqual, "")
+			So(service.attributes["error.reason"], ShouldEqual, string(ReasonNotFound))
+			So(service.events[0].Name, ShouldEqual, "retry")
+			So(service.events[0].Attributes["attempt"], ShouldEqual, "1")
+			So(service.Duration, ShouldBeGreaterThanOrEqualTo, 0)
+		})
+
+		Convey("the gRPC client sends the current span in the metadata", func() {
+			span := tracer.StartSpan("client")
+			md := metadata.MD{}
+			TraceContextToGRPC()(opentracing.ContextWithSpan(context.Background(), span), &md)
+			So(md["ot-tracer-spanid"], ShouldResemble, []string{fmt.Sprintf("%x", spanContextOf(span).SpanID)})
+
+			ctx := TraceGRPCToContext()(context.Background(), md)
+			server := spanContextOf(opentracing.SpanFromContext(ctx))
+			So(server.TraceID, ShouldEqual, spanContextOf(span).TraceID)
+			So(server.SpanID, ShouldNotEqual, spanContextOf(span).SpanID)
+		})
+
+		Convey("a trace the caller did not sample is not recorded, but still propagated", func() {
+			r := httptest.NewRequest("POST", "/GetNextDocNo", nil)
+			r.Header.Set("Ot-Tracer-Traceid", "af7651916cd43dd8")
+			r.Header.Set("Ot-Tracer-Spanid", "b7ad6b7169203331")
+			r.Header.Set("Ot-Tracer-Sampled", "false")
+			ctx := TraceHTTPToContext()(context.Background(), r)
+			md := metadata.MD{}
+			TraceContextToGRPC()(ctx, &md)
+			So(md["ot-tracer-traceid"], ShouldResemble, []string{"af7651916cd43dd8"})
+			So(md["ot-tracer-sampled"], ShouldResemble, []string{"false"})
+			TraceHTTPFinalizer()(ctx, http.StatusOK, r)
+			So(tracer.Flush(context.Background()), ShouldBeNil)
+			So(exporter.spans, ShouldBeEmpty)
+		})
+	})
+
+	Convey("The traces started here are sampled by ratio", t, func() {
+		exporter := &memorySpanExporter{}
+		tracer := NewTracer("docnogen", exporter, 0, nil)
+		tracer.StartSpan("op").Finish()
+		So(tracer.Shutdown(context.Background()), ShouldBeNil)
+		So(exporter.spans, ShouldBeEmpty)
+
+		sample := RatioSampler(0.25)
+		So(sample(0), ShouldBeTrue)
+		So(sample(math.MaxUint64/8), ShouldBeTrue)
+		So(sample(math.MaxUint64/2), ShouldBeFalse)
+		So(RatioSampler(1)(math.MaxUint64), ShouldBeTrue)
+	})
+
+	Convey("The writer exporter writes a line of JSON per span", t, func() {
+		var buf bytes.Buffer
+		tracer := NewTracer("docnogen", NewWriterSpanExporter(&buf), 1, nil)
+		parent := tracer.StartSpan("parent")
+		child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()))
+		FinishSpan(child, errors.New("conflict"))
+		parent.Finish()
+		So(tracer.Shutdown(context.Background()), ShouldBeNil)
+
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		So(len(lines), ShouldEqual, 2)
+		var span map[string]interface{}
+		So(json.Unmarshal(lines[0], &span), ShouldBeNil)
+		So(span["name"], ShouldEqual, "child")
+		So(span["parentSpanId"], ShouldEqual, fmt.Sprintf("%016x", spanContextOf(parent).SpanID))
+		So(span["error"], ShouldEqual, "conflict")
+		So(span["service"], ShouldEqual, "docnogen")
+	})
+
+	Convey("The OTLP exporter posts the spans as OTLP JSON", t, func() {
+		var body map[string]interface{}
+		var contentType string
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(data, &body)
+			contentType = r.Header.Get("Content-Type")
+		}))
+		defer collector.Close()
+		tracer := NewTracer("docnogen", NewOTLPSpanExporter(collector.URL+"/v1/traces", nil, nil), 1, nil)
+		span := tracer.StartSpan("op", ext.SpanKindRPCServer)
+		span.Finish()
+		So(tracer.Shutdown(context.Background()), ShouldBeNil)
+
+		So(contentType, ShouldEqual, "application/json")
+		resourceSpans := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		scopeSpans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})
+		exported := scopeSpans["spans"].([]interface{})[0].(map[string]interface{})
+		So(exported["name"], ShouldEqual, "op")
+		So(exported["traceId"], ShouldEqual, fmt.Sprintf("%032x", spanContextOf(span).TraceID))
+		So(exported["kind"], ShouldEqual, 2)
+	})
+}
+
+// failedTestResponse is a response body as the services return, failed unless its reason is empty
+type failedTestResponse struct {
+	reason string
+}
+
+func (r *failedTestResponse) GetOk() bool             { return r.reason == "" }
+func (r *failedTestResponse) GetErrorCode() int32     { return 404 }
+func (r *failedTestResponse) GetErrorMessage() string { return "not found" }
+func (r *failedTestResponse) GetErrorReason() string  { return r.reason }
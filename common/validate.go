@@ -0,0 +1,174 @@
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Violation is a request field failing one of its rules
+type Violation struct {
+	Field       string
+	Description string
+}
+
+type Violations []Violation
+
+// Err returns nil when there are no violations, or an INVALID_ARGUMENT error listing all of them
+func (vs Violations) Err() error {
+	if len(vs) == 0 {
+		return nil
+	}
+	descriptions := make([]string, len(vs))
+	for i, v := range vs {
+		descriptions[i] = v.Description
+	}
+	return NewError(ReasonInvalidArgument, strings.Join(descriptions, "; "))
+}
+
+// Rule checks a field of a request, it returns the description of the violation or ""
+type Rule struct {
+	Field string
+	Check func(in interface{}, value reflect.Value) string
+}
+
+// Rules declares the validation of a request type, fields are named as in the proto file
+type Rules []Rule
+
+// Validate checks in, a pointer to a generated request, against every rule and returns all violations in rule order
+func (rs Rules) Validate(in interface{}) Violations {
+	var violations Violations
+	for _, r := range rs {
+		value, err := protoField(in, r.Field)
+		if err != nil {
+			violations = append(violations, Violation{Field: r.Field, Description: err.Error()})
+			continue
+		}
+		if description := r.Check(in, value); description != "" {
+			violations = append(violations, Violation{Field: r.Field, Description: description})
+		}
+	}
+	return violations
+}
+
+// Required is violated by the zero value of the field
+func Required(field string, description string) Rule {
+	return Rule{Field: field, Check: func(in interface{}, value reflect.Value) string {
+		if isZero(value) {
+			return description
+		}
+		return ""
+	}}
+}
+
+// RequiredWithout is violated when both the field and its alternative have their zero value
+func RequiredWithout(field string, alternative string, description string) Rule {
+	return Rule{Field: field, Check: func(in interface{}, value reflect.Value) string {
+		if !isZero(value) {
+			return ""
+		}
+		if alt, err := protoField(in, alternative); err == nil && !isZero(alt) {
+			return ""
+		}
+		return description
+	}}
+}
+
+// Between is violated by an integer field outside [min, max]
+func Between(field string, min int64, max int64, description string) Rule {
+	return Rule{Field: field, Check: func(in interface{}, value reflect.Value) string {
+		var n int64
+		switch value.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = value.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = int64(value.Uint())
+		default:
+			return fmt.Sprintf("Field %s is not an integer", field)
+		}
+		if n < min || n > max {
+			return description
+		}
+		return ""
+	}}
+}
+
+// Enum is violated by an enum field whose value is not in names, description is formatted with the value
+func Enum(field string, names map[int32]string, description string) Rule {
+	return Rule{Field: field, Check: func(in interface{}, value reflect.Value) string {
+		if value.Kind() != reflect.Int32 {
+			return fmt.Sprintf("Field %s is not an enum", field)
+		}
+		if _, ok := names[int32(value.Int())]; !ok {
+			return fmt.Sprintf(description, value.Int())
+		}
+		return ""
+	}}
+}
+
+// OneOf is violated by a string field, or an element of a repeated string field, not in allowed; description is
+// formatted with the value
+func OneOf(field string, allowed []string, description string) Rule {
+	return Satisfies(field, func(v string) bool {
+		for _, a := range allowed {
+			if v == a {
+				return true
+			}
+		}
+		return false
+	}, description)
+}
+
+// Satisfies is violated by a string field, or an element of a repeated string field, for which valid is false;
+// description is formatted with the value
+func Satisfies(field string, valid func(string) bool, description string) Rule {
+	return Rule{Field: field, Check: func(in interface{}, value reflect.Value) string {
+		var values []string
+		switch {
+		case value.Kind() == reflect.String:
+			values = []string{value.String()}
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < value.Len(); i++ {
+				values = append(values, value.Index(i).String())
+			}
+		default:
+			return fmt.Sprintf("Field %s is not a string", field)
+		}
+		for _, v := range values {
+			if !valid(v) {
+				return fmt.Sprintf(description, v)
+			}
+		}
+		return ""
+	}}
+}
+
+// protoField returns the field of a generated message with the given proto name
+func protoField(in interface{}, name string) (reflect.Value, error) {
+	v := reflect.ValueOf(in)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("Request is not a message")
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		for _, part := range strings.Split(t.Field(i).Tag.Get("protobuf"), ",") {
+			if part == "name="+name {
+				return v.Field(i), nil
+			}
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("Request has no field %s", name)
+}
+
+func isZero(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return value.Interface() == reflect.Zero(value.Type()).Interface()
+}
@@ -13,13 +13,18 @@ sudo chmod 755 /etc/systemd/system/docnogen-api.service
 
 echo "service moved"
 
+# commit and build time reported by /version
+cd /tmp/go-kit-documentnogen
+GIT_COMMIT=$(git rev-parse --short HEAD)
+BUILD_TIME=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+
 # copy source code to go folder
 mkdir -p /home/appadmin/go/src/github.com/howlun/go-kit-documentnogen
 cd /tmp/go-kit-documentnogen
 rm -rf /home/appadmin/go/src/github.com/howlun/go-kit-documentnogen/*
 cp -r * /home/appadmin/go/src/github.com/howlun/go-kit-documentnogen/
 cd /home/appadmin/go/src/github.com/howlun/go-kit-documentnogen/cmd/server
-/usr/local/go/bin/go build
+/usr/local/go/bin/go build -ldflags "-X main.gitCommit=$GIT_COMMIT -X main.buildTime=$BUILD_TIME"
 
 echo "building source..."
 
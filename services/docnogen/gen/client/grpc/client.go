@@ -1,6 +1,8 @@
 package docnogen_clientgrpc
 
 import (
+	"time"
+
 	context "golang.org/x/net/context"
 
 	jwt "github.com/go-kit/kit/auth/jwt"
@@ -8,23 +10,51 @@ import (
 	"github.com/go-kit/kit/log"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
+	"github.com/howlun/go-kit-documentnogen/common"
 	endpoints "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/endpoints"
 	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
 )
 
+// TLSOptions configures TLS to the server
+type TLSOptions struct {
+	// CAFile verifies the server certificate, the system roots are used when it is empty
+	CAFile string
+	// CertFile and KeyFile are the client certificate for mutual TLS, reloaded when the files change
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the host name of addr that the server certificate is verified for
+	ServerName string
+	// ReloadInterval is how often the client certificate files are checked for changes
+	ReloadInterval time.Duration
+}
+
+// Dial connects to the server at addr, over TLS when tlsOptions is not nil and in plaintext otherwise
+func Dial(addr string, tlsOptions *TLSOptions, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if tlsOptions == nil {
+		return grpc.Dial(addr, append(opts, grpc.WithInsecure())...)
+	}
+	files := common.TLSFiles{CAFile: tlsOptions.CAFile, CertFile: tlsOptions.CertFile, KeyFile: tlsOptions.KeyFile}
+	config, err := common.NewClientTLSConfig(files, tlsOptions.ServerName, tlsOptions.ReloadInterval, nil)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial(addr, append(opts, grpc.WithTransportCredentials(credentials.NewTLS(config)))...)
+}
+
 func New(conn *grpc.ClientConn, logger log.Logger) pb.DocNoGenServiceServer {
 
 	var generateBulkDocNoFormatEndpoint endpoint.Endpoint
 	{
 		generateBulkDocNoFormatEndpoint = grpctransport.NewClient(
 			conn,
-			"docnogen.DocnogenService",
+			"docnogen.DocNoGenService",
 			"GenerateBulkDocNoFormat",
 			EncodeGenerateBulkDocNoFormatRequest,
 			DecodeGenerateBulkDocNoFormatResponse,
 			pb.GenerateBulkDocNoFormatResponse{},
-			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(jwt.FromGRPCContext()))...,
+			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(common.TraceContextToGRPC(), common.RequestIDContextToGRPC(), jwt.ContextToGRPC(), common.APIKeyContextToGRPC(), common.RateLimitContextToGRPC()))...,
 		).Endpoint()
 	}
 
@@ -32,12 +62,12 @@ func New(conn *grpc.ClientConn, logger log.Logger) pb.DocNoGenServiceServer {
 	{
 		generatedocnoformatEndpoint = grpctransport.NewClient(
 			conn,
-			"docnogen.DocnogenService",
+			"docnogen.DocNoGenService",
 			"GenerateDocNoFormat",
 			EncodeGenerateDocNoFormatRequest,
 			DecodeGenerateDocNoFormatResponse,
 			pb.GenerateDocNoFormatResponse{},
-			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(jwt.FromGRPCContext()))...,
+			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(common.TraceContextToGRPC(), common.RequestIDContextToGRPC(), jwt.ContextToGRPC(), common.APIKeyContextToGRPC(), common.RateLimitContextToGRPC()))...,
 		).Endpoint()
 	}
 
@@ -45,12 +75,12 @@ func New(conn *grpc.ClientConn, logger log.Logger) pb.DocNoGenServiceServer {
 	{
 		getnextdocnoEndpoint = grpctransport.NewClient(
 			conn,
-			"docnogen.DocnogenService",
+			"docnogen.DocNoGenService",
 			"GetNextDocNo",
 			EncodeGetNextDocNoRequest,
 			DecodeGetNextDocNoResponse,
 			pb.GetNextDocNoResponse{},
-			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(jwt.FromGRPCContext()))...,
+			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(common.TraceContextToGRPC(), common.RequestIDContextToGRPC(), jwt.ContextToGRPC(), common.APIKeyContextToGRPC(), common.RateLimitContextToGRPC()))...,
 		).Endpoint()
 	}
 
@@ -58,12 +88,77 @@ func New(conn *grpc.ClientConn, logger log.Logger) pb.DocNoGenServiceServer {
 	{
 		consumedocnoEndpoint = grpctransport.NewClient(
 			conn,
-			"docnogen.DocnogenService",
+			"docnogen.DocNoGenService",
 			"ConsumeDocNo",
 			EncodeConsumeDocNoRequest,
 			DecodeConsumeDocNoResponse,
 			pb.ConsumeDocNoResponse{},
-			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(jwt.FromGRPCContext()))...,
+			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(common.TraceContextToGRPC(), common.RequestIDContextToGRPC(), jwt.ContextToGRPC(), common.APIKeyContextToGRPC(), common.RateLimitContextToGRPC()))...,
+		).Endpoint()
+	}
+
+	var exportEndpoint endpoint.Endpoint
+	{
+		exportEndpoint = grpctransport.NewClient(
+			conn,
+			"docnogen.DocNoGenService",
+			"Export",
+			EncodeExportRequest,
+			DecodeExportResponse,
+			pb.ExportResponse{},
+			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(common.TraceContextToGRPC(), common.RequestIDContextToGRPC(), jwt.ContextToGRPC(), common.APIKeyContextToGRPC(), common.RateLimitContextToGRPC()))...,
+		).Endpoint()
+	}
+
+	var importEndpoint endpoint.Endpoint
+	{
+		importEndpoint = grpctransport.NewClient(
+			conn,
+			"docnogen.DocNoGenService",
+			"Import",
+			EncodeImportRequest,
+			DecodeImportResponse,
+			pb.ImportResponse{},
+			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(common.TraceContextToGRPC(), common.RequestIDContextToGRPC(), jwt.ContextToGRPC(), common.APIKeyContextToGRPC(), common.RateLimitContextToGRPC()))...,
+		).Endpoint()
+	}
+
+	var createapikeyEndpoint endpoint.Endpoint
+	{
+		createapikeyEndpoint = grpctransport.NewClient(
+			conn,
+			"docnogen.DocNoGenService",
+			"CreateAPIKey",
+			EncodeCreateAPIKeyRequest,
+			DecodeCreateAPIKeyResponse,
+			pb.CreateAPIKeyResponse{},
+			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(common.TraceContextToGRPC(), common.RequestIDContextToGRPC(), jwt.ContextToGRPC(), common.APIKeyContextToGRPC(), common.RateLimitContextToGRPC()))...,
+		).Endpoint()
+	}
+
+	var listapikeysEndpoint endpoint.Endpoint
+	{
+		listapikeysEndpoint = grpctransport.NewClient(
+			conn,
+			"docnogen.DocNoGenService",
+			"ListAPIKeys",
+			EncodeListAPIKeysRequest,
+			DecodeListAPIKeysResponse,
+			pb.ListAPIKeysResponse{},
+			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(common.TraceContextToGRPC(), common.RequestIDContextToGRPC(), jwt.ContextToGRPC(), common.APIKeyContextToGRPC(), common.RateLimitContextToGRPC()))...,
+		).Endpoint()
+	}
+
+	var revokeapikeyEndpoint endpoint.Endpoint
+	{
+		revokeapikeyEndpoint = grpctransport.NewClient(
+			conn,
+			"docnogen.DocNoGenService",
+			"RevokeAPIKey",
+			EncodeRevokeAPIKeyRequest,
+			DecodeRevokeAPIKeyResponse,
+			pb.RevokeAPIKeyResponse{},
+			append([]grpctransport.ClientOption{}, grpctransport.ClientBefore(common.TraceContextToGRPC(), common.RequestIDContextToGRPC(), jwt.ContextToGRPC(), common.APIKeyContextToGRPC(), common.RateLimitContextToGRPC()))...,
 		).Endpoint()
 	}
 
@@ -76,6 +171,16 @@ func New(conn *grpc.ClientConn, logger log.Logger) pb.DocNoGenServiceServer {
 		GetNextDocNoEndpoint: getnextdocnoEndpoint,
 
 		ConsumeDocNoEndpoint: consumedocnoEndpoint,
+
+		ExportEndpoint: exportEndpoint,
+
+		ImportEndpoint: importEndpoint,
+
+		CreateAPIKeyEndpoint: createapikeyEndpoint,
+
+		ListAPIKeysEndpoint: listapikeysEndpoint,
+
+		RevokeAPIKeyEndpoint: revokeapikeyEndpoint,
 	}
 }
 
@@ -118,3 +223,53 @@ func DecodeConsumeDocNoResponse(_ context.Context, grpcResponse interface{}) (in
 	response := grpcResponse.(*pb.ConsumeDocNoResponse)
 	return response, nil
 }
+
+func EncodeExportRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.ExportRequest)
+	return req, nil
+}
+
+func DecodeExportResponse(_ context.Context, grpcResponse interface{}) (interface{}, error) {
+	response := grpcResponse.(*pb.ExportResponse)
+	return response, nil
+}
+
+func EncodeImportRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.ImportRequest)
+	return req, nil
+}
+
+func DecodeImportResponse(_ context.Context, grpcResponse interface{}) (interface{}, error) {
+	response := grpcResponse.(*pb.ImportResponse)
+	return response, nil
+}
+
+func EncodeCreateAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.CreateAPIKeyRequest)
+	return req, nil
+}
+
+func DecodeCreateAPIKeyResponse(_ context.Context, grpcResponse interface{}) (interface{}, error) {
+	response := grpcResponse.(*pb.CreateAPIKeyResponse)
+	return response, nil
+}
+
+func EncodeListAPIKeysRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.ListAPIKeysRequest)
+	return req, nil
+}
+
+func DecodeListAPIKeysResponse(_ context.Context, grpcResponse interface{}) (interface{}, error) {
+	response := grpcResponse.(*pb.ListAPIKeysResponse)
+	return response, nil
+}
+
+func EncodeRevokeAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.RevokeAPIKeyRequest)
+	return req, nil
+}
+
+func DecodeRevokeAPIKeyResponse(_ context.Context, grpcResponse interface{}) (interface{}, error) {
+	response := grpcResponse.(*pb.RevokeAPIKeyResponse)
+	return response, nil
+}
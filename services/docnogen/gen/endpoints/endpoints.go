@@ -2,17 +2,11 @@ package docnogen_endpoints
 
 import (
 	"fmt"
-	"time"
 
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
-	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/metrics"
-	"github.com/go-kit/kit/ratelimit"
+	"github.com/howlun/go-kit-documentnogen/common"
 	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
-	"github.com/sony/gobreaker"
 	context "golang.org/x/net/context"
-	"golang.org/x/time/rate"
 )
 
 //var _ = endpoint.Chain
@@ -30,6 +24,16 @@ type Endpoints struct {
 	GetNextDocNoEndpoint endpoint.Endpoint
 
 	ConsumeDocNoEndpoint endpoint.Endpoint
+
+	ExportEndpoint endpoint.Endpoint
+
+	ImportEndpoint endpoint.Endpoint
+
+	CreateAPIKeyEndpoint endpoint.Endpoint
+
+	ListAPIKeysEndpoint endpoint.Endpoint
+
+	RevokeAPIKeyEndpoint endpoint.Endpoint
 }
 
 func (e *Endpoints) GenerateBulkDocNoFormat(ctx context.Context, in *pb.GenerateBulkDocNoFormatRequest) (*pb.GenerateBulkDocNoFormatResponse, error) {
@@ -64,6 +68,46 @@ func (e *Endpoints) ConsumeDocNo(ctx context.Context, in *pb.ConsumeDocNoRequest
 	return out.(*pb.ConsumeDocNoResponse), err
 }
 
+func (e *Endpoints) Export(ctx context.Context, in *pb.ExportRequest) (*pb.ExportResponse, error) {
+	out, err := e.ExportEndpoint(ctx, in)
+	if err != nil {
+		return &pb.ExportResponse{}, err
+	}
+	return out.(*pb.ExportResponse), err
+}
+
+func (e *Endpoints) Import(ctx context.Context, in *pb.ImportRequest) (*pb.ImportResponse, error) {
+	out, err := e.ImportEndpoint(ctx, in)
+	if err != nil {
+		return &pb.ImportResponse{}, err
+	}
+	return out.(*pb.ImportResponse), err
+}
+
+func (e *Endpoints) CreateAPIKey(ctx context.Context, in *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyResponse, error) {
+	out, err := e.CreateAPIKeyEndpoint(ctx, in)
+	if err != nil {
+		return &pb.CreateAPIKeyResponse{}, err
+	}
+	return out.(*pb.CreateAPIKeyResponse), err
+}
+
+func (e *Endpoints) ListAPIKeys(ctx context.Context, in *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	out, err := e.ListAPIKeysEndpoint(ctx, in)
+	if err != nil {
+		return &pb.ListAPIKeysResponse{}, err
+	}
+	return out.(*pb.ListAPIKeysResponse), err
+}
+
+func (e *Endpoints) RevokeAPIKey(ctx context.Context, in *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyResponse, error) {
+	out, err := e.RevokeAPIKeyEndpoint(ctx, in)
+	if err != nil {
+		return &pb.RevokeAPIKeyResponse{}, err
+	}
+	return out.(*pb.RevokeAPIKeyResponse), err
+}
+
 func MakeGenerateBulkDocNoFormatEndpoint(svc pb.DocNoGenServiceServer) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(*pb.GenerateBulkDocNoFormatRequest)
@@ -108,42 +152,135 @@ func MakeConsumeDocNoEndpoint(svc pb.DocNoGenServiceServer) endpoint.Endpoint {
 	}
 }
 
-func MakeEndpoints(svc pb.DocNoGenServiceServer, logger log.Logger, duration metrics.Histogram) Endpoints {
+func MakeExportEndpoint(svc pb.DocNoGenServiceServer) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*pb.ExportRequest)
+		rep, err := svc.Export(ctx, req)
+		if err != nil {
+			return &pb.ExportResponse{}, err
+		}
+		return rep, nil
+	}
+}
+
+func MakeImportEndpoint(svc pb.DocNoGenServiceServer) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*pb.ImportRequest)
+		rep, err := svc.Import(ctx, req)
+		if err != nil {
+			return &pb.ImportResponse{}, err
+		}
+		return rep, nil
+	}
+}
+
+func MakeCreateAPIKeyEndpoint(svc pb.DocNoGenServiceServer) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*pb.CreateAPIKeyRequest)
+		rep, err := svc.CreateAPIKey(ctx, req)
+		if err != nil {
+			return &pb.CreateAPIKeyResponse{}, err
+		}
+		return rep, nil
+	}
+}
+
+func MakeListAPIKeysEndpoint(svc pb.DocNoGenServiceServer) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*pb.ListAPIKeysRequest)
+		rep, err := svc.ListAPIKeys(ctx, req)
+		if err != nil {
+			return &pb.ListAPIKeysResponse{}, err
+		}
+		return rep, nil
+	}
+}
+
+func MakeRevokeAPIKeyEndpoint(svc pb.DocNoGenServiceServer) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(*pb.RevokeAPIKeyRequest)
+		rep, err := svc.RevokeAPIKey(ctx, req)
+		if err != nil {
+			return &pb.RevokeAPIKeyResponse{}, err
+		}
+		return rep, nil
+	}
+}
+
+// MakeEndpoints wraps every method of svc in middlewares, the first innermost, e.g. the chain built from
+// DefaultMiddlewares.
+func MakeEndpoints(svc pb.DocNoGenServiceServer, middlewares ...common.MethodMiddleware) Endpoints {
 
 	var generateBulkDocNoFormatEndpoint endpoint.Endpoint
 	{
-		generateBulkDocNoFormatEndpoint = MakeGenerateBulkDocNoFormatEndpoint(svc)
-		generateBulkDocNoFormatEndpoint = ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 10))(generateBulkDocNoFormatEndpoint)
-		generateBulkDocNoFormatEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(generateBulkDocNoFormatEndpoint)
-		generateBulkDocNoFormatEndpoint = LoggingMiddleware(log.With(logger, "method", "GenerateBulkDocNoFormat"))(generateBulkDocNoFormatEndpoint)
-		generateBulkDocNoFormatEndpoint = InstrumentingMiddleware(duration.With("method", "GenerateBulkDocNoFormat"))(generateBulkDocNoFormatEndpoint)
+		generateBulkDocNoFormatEndpoint = common.TraceEndpoint("service", "GenerateBulkDocNoFormat")(MakeGenerateBulkDocNoFormatEndpoint(svc))
+		for _, middleware := range middlewares {
+			generateBulkDocNoFormatEndpoint = middleware("GenerateBulkDocNoFormat", generateBulkDocNoFormatEndpoint)
+		}
 	}
 
 	var generatedocnoformatEndpoint endpoint.Endpoint
 	{
-		generatedocnoformatEndpoint = MakeGenerateDocNoFormatEndpoint(svc)
-		generatedocnoformatEndpoint = ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 10))(generatedocnoformatEndpoint)
-		generatedocnoformatEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(generatedocnoformatEndpoint)
-		generatedocnoformatEndpoint = LoggingMiddleware(log.With(logger, "method", "GenerateDocNoFormat"))(generatedocnoformatEndpoint)
-		generatedocnoformatEndpoint = InstrumentingMiddleware(duration.With("method", "GenerateDocNoFormat"))(generatedocnoformatEndpoint)
+		generatedocnoformatEndpoint = common.TraceEndpoint("service", "GenerateDocNoFormat")(MakeGenerateDocNoFormatEndpoint(svc))
+		for _, middleware := range middlewares {
+			generatedocnoformatEndpoint = middleware("GenerateDocNoFormat", generatedocnoformatEndpoint)
+		}
 	}
 
 	var getnextdocnoEndpoint endpoint.Endpoint
 	{
-		getnextdocnoEndpoint = MakeGetNextDocNoEndpoint(svc)
-		getnextdocnoEndpoint = ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 10))(getnextdocnoEndpoint)
-		getnextdocnoEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(getnextdocnoEndpoint)
-		getnextdocnoEndpoint = LoggingMiddleware(log.With(logger, "method", "GetNextDocNo"))(getnextdocnoEndpoint)
-		getnextdocnoEndpoint = InstrumentingMiddleware(duration.With("method", "GetNextDocNo"))(getnextdocnoEndpoint)
+		getnextdocnoEndpoint = common.TraceEndpoint("service", "GetNextDocNo")(MakeGetNextDocNoEndpoint(svc))
+		for _, middleware := range middlewares {
+			getnextdocnoEndpoint = middleware("GetNextDocNo", getnextdocnoEndpoint)
+		}
 	}
 
 	var consumedocnoEndpoint endpoint.Endpoint
 	{
-		consumedocnoEndpoint = MakeConsumeDocNoEndpoint(svc)
-		consumedocnoEndpoint = ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 10))(consumedocnoEndpoint)
-		consumedocnoEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(consumedocnoEndpoint)
-		consumedocnoEndpoint = LoggingMiddleware(log.With(logger, "method", "ConsumeDocNo"))(consumedocnoEndpoint)
-		consumedocnoEndpoint = InstrumentingMiddleware(duration.With("method", "ConsumeDocNo"))(consumedocnoEndpoint)
+		consumedocnoEndpoint = common.TraceEndpoint("service", "ConsumeDocNo")(MakeConsumeDocNoEndpoint(svc))
+		for _, middleware := range middlewares {
+			consumedocnoEndpoint = middleware("ConsumeDocNo", consumedocnoEndpoint)
+		}
+	}
+
+	var exportEndpoint endpoint.Endpoint
+	{
+		exportEndpoint = common.TraceEndpoint("service", "Export")(MakeExportEndpoint(svc))
+		for _, middleware := range middlewares {
+			exportEndpoint = middleware("Export", exportEndpoint)
+		}
+	}
+
+	var importEndpoint endpoint.Endpoint
+	{
+		importEndpoint = common.TraceEndpoint("service", "Import")(MakeImportEndpoint(svc))
+		for _, middleware := range middlewares {
+			importEndpoint = middleware("Import", importEndpoint)
+		}
+	}
+
+	var createapikeyEndpoint endpoint.Endpoint
+	{
+		createapikeyEndpoint = common.TraceEndpoint("service", "CreateAPIKey")(MakeCreateAPIKeyEndpoint(svc))
+		for _, middleware := range middlewares {
+			createapikeyEndpoint = middleware("CreateAPIKey", createapikeyEndpoint)
+		}
+	}
+
+	var listapikeysEndpoint endpoint.Endpoint
+	{
+		listapikeysEndpoint = common.TraceEndpoint("service", "ListAPIKeys")(MakeListAPIKeysEndpoint(svc))
+		for _, middleware := range middlewares {
+			listapikeysEndpoint = middleware("ListAPIKeys", listapikeysEndpoint)
+		}
+	}
+
+	var revokeapikeyEndpoint endpoint.Endpoint
+	{
+		revokeapikeyEndpoint = common.TraceEndpoint("service", "RevokeAPIKey")(MakeRevokeAPIKeyEndpoint(svc))
+		for _, middleware := range middlewares {
+			revokeapikeyEndpoint = middleware("RevokeAPIKey", revokeapikeyEndpoint)
+		}
 	}
 
 	return Endpoints{
@@ -155,5 +292,15 @@ func MakeEndpoints(svc pb.DocNoGenServiceServer, logger log.Logger, duration met
 		GetNextDocNoEndpoint: getnextdocnoEndpoint,
 
 		ConsumeDocNoEndpoint: consumedocnoEndpoint,
+
+		ExportEndpoint: exportEndpoint,
+
+		ImportEndpoint: importEndpoint,
+
+		CreateAPIKeyEndpoint: createapikeyEndpoint,
+
+		ListAPIKeysEndpoint: listapikeysEndpoint,
+
+		RevokeAPIKeyEndpoint: revokeapikeyEndpoint,
 	}
 }
@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
+	"github.com/sony/gobreaker"
 	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
 )
 
 // InstrumentingMiddleware returns an endpoint middleware that records
@@ -28,16 +32,50 @@ func InstrumentingMiddleware(duration metrics.Histogram) endpoint.Middleware {
 }
 
 // LoggingMiddleware returns an endpoint middleware that logs the
-// duration of each invocation, and the resulting error, if any.
+// duration of each invocation with the request ID, and the resulting error, if any:
+// at error level for a transport error, warn for a failed response, info otherwise.
 func LoggingMiddleware(logger log.Logger) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 
 			defer func(begin time.Time) {
-				logger.Log("transport_error", err, "took", time.Since(begin))
+				logger := logger
+				if id := common.RequestIDFromContext(ctx); id != "" {
+					logger = log.With(logger, "request_id", id)
+				}
+				switch e := common.ResponseError(response); {
+				case err != nil:
+					common.LogError(logger).Log("transport_error", err, "took", time.Since(begin))
+				case e != nil:
+					common.LogWarn(logger).Log("reason", e.Reason, "err", e.Message, "took", time.Since(begin))
+				default:
+					common.LogInfo(logger).Log("took", time.Since(begin))
+				}
 			}(time.Now())
 			return next(ctx, request)
 
 		}
 	}
 }
+
+// CircuitBreakerMiddleware gives each method its own circuit breaker, with the default gobreaker settings.
+func CircuitBreakerMiddleware() common.MethodMiddleware {
+	return func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+		return circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(next)
+	}
+}
+
+// DefaultMiddlewares returns the endpoint middlewares of the service by name: "circuitbreaker", "logging" with the
+// method in the logger, and "instrumenting" with the method in the labels of duration. Add to it before building the
+// chain with common.MethodMiddlewares.Chain.
+func DefaultMiddlewares(logger log.Logger, duration metrics.Histogram) common.MethodMiddlewares {
+	return common.MethodMiddlewares{
+		"circuitbreaker": CircuitBreakerMiddleware(),
+		"logging": func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+			return LoggingMiddleware(log.With(logger, "method", method))(next)
+		},
+		"instrumenting": func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+			return InstrumentingMiddleware(duration.With("method", method))(next)
+		},
+	}
+}
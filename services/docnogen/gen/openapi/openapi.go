@@ -0,0 +1,715 @@
+package docnogen_openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/howlun/go-kit-documentnogen/common"
+)
+
+// Spec is the OpenAPI 3 document of the HTTP transport, generated from services/docnogen/docnogen.proto
+const Spec = `{
+  "openapi": "3.0.0",
+  "info": {
+    "title": "DocNoGenService",
+    "version": "v1"
+  },
+  "paths": {
+    "/GenerateBulkDocNoFormat": {
+      "post": {
+        "operationId": "GenerateBulkDocNoFormat",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/GenerateBulkDocNoFormatRequest"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/GenerateBulkDocNoFormatRequest"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Succeeded",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/GenerateBulkDocNoFormatResponse"}},
+              "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/GenerateBulkDocNoFormatResponse"}}
+            }
+          },
+          "default": {
+            "description": "Failed, the status and errorReason tell why",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"$ref": "#/components/schemas/GenerateBulkDocNoFormatResponse"},
+              {"$ref": "#/components/schemas/TransportError"}
+            ]}}}
+          }
+        }
+      }
+    },
+    "/GenerateDocNoFormat": {
+      "post": {
+        "operationId": "GenerateDocNoFormat",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/GenerateDocNoFormatRequest"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/GenerateDocNoFormatRequest"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Succeeded",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/GenerateDocNoFormatResponse"}},
+              "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/GenerateDocNoFormatResponse"}}
+            }
+          },
+          "default": {
+            "description": "Failed, the status and errorReason tell why",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"$ref": "#/components/schemas/GenerateDocNoFormatResponse"},
+              {"$ref": "#/components/schemas/TransportError"}
+            ]}}}
+          }
+        }
+      }
+    },
+    "/GetNextDocNo": {
+      "post": {
+        "operationId": "GetNextDocNo",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/GetNextDocNoRequest"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/GetNextDocNoRequest"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Succeeded",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/GetNextDocNoResponse"}},
+              "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/GetNextDocNoResponse"}}
+            }
+          },
+          "default": {
+            "description": "Failed, the status and errorReason tell why",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"$ref": "#/components/schemas/GetNextDocNoResponse"},
+              {"$ref": "#/components/schemas/TransportError"}
+            ]}}}
+          }
+        }
+      }
+    },
+    "/ConsumeDocNo": {
+      "post": {
+        "operationId": "ConsumeDocNo",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/ConsumeDocNoRequest"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/ConsumeDocNoRequest"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Succeeded",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/ConsumeDocNoResponse"}},
+              "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/ConsumeDocNoResponse"}}
+            }
+          },
+          "default": {
+            "description": "Failed, the status and errorReason tell why",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"$ref": "#/components/schemas/ConsumeDocNoResponse"},
+              {"$ref": "#/components/schemas/TransportError"}
+            ]}}}
+          }
+        }
+      }
+    },
+    "/Export": {
+      "post": {
+        "operationId": "Export",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/ExportRequest"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/ExportRequest"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Succeeded",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/ExportResponse"}},
+              "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/ExportResponse"}}
+            }
+          },
+          "default": {
+            "description": "Failed, the status and errorReason tell why",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"$ref": "#/components/schemas/ExportResponse"},
+              {"$ref": "#/components/schemas/TransportError"}
+            ]}}}
+          }
+        }
+      }
+    },
+    "/Import": {
+      "post": {
+        "operationId": "Import",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/ImportRequest"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/ImportRequest"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Succeeded",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/ImportResponse"}},
+              "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/ImportResponse"}}
+            }
+          },
+          "default": {
+            "description": "Failed, the status and errorReason tell why",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"$ref": "#/components/schemas/ImportResponse"},
+              {"$ref": "#/components/schemas/TransportError"}
+            ]}}}
+          }
+        }
+      }
+    },
+    "/CreateAPIKey": {
+      "post": {
+        "operationId": "CreateAPIKey",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/CreateAPIKeyRequest"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/CreateAPIKeyRequest"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Succeeded",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/CreateAPIKeyResponse"}},
+              "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/CreateAPIKeyResponse"}}
+            }
+          },
+          "default": {
+            "description": "Failed, the status and errorReason tell why",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"$ref": "#/components/schemas/CreateAPIKeyResponse"},
+              {"$ref": "#/components/schemas/TransportError"}
+            ]}}}
+          }
+        }
+      }
+    },
+    "/ListAPIKeys": {
+      "post": {
+        "operationId": "ListAPIKeys",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/ListAPIKeysRequest"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/ListAPIKeysRequest"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Succeeded",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/ListAPIKeysResponse"}},
+              "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/ListAPIKeysResponse"}}
+            }
+          },
+          "default": {
+            "description": "Failed, the status and errorReason tell why",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"$ref": "#/components/schemas/ListAPIKeysResponse"},
+              {"$ref": "#/components/schemas/TransportError"}
+            ]}}}
+          }
+        }
+      }
+    },
+    "/RevokeAPIKey": {
+      "post": {
+        "operationId": "RevokeAPIKey",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/RevokeAPIKeyRequest"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/RevokeAPIKeyRequest"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Succeeded",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/RevokeAPIKeyResponse"}},
+              "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/RevokeAPIKeyResponse"}}
+            }
+          },
+          "default": {
+            "description": "Failed, the status and errorReason tell why",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"$ref": "#/components/schemas/RevokeAPIKeyResponse"},
+              {"$ref": "#/components/schemas/TransportError"}
+            ]}}}
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "ImportMode": {
+        "type": "string",
+        "enum": ["MERGE", "OVERWRITE"],
+        "description": "The number of the value is accepted too: MERGE=0, OVERWRITE=1"
+      },
+      "Violation": {
+        "type": "object",
+        "properties": {
+          "field": {"type": "string"},
+          "description": {"type": "string"}
+        }
+      },
+      "GenerateBulkDocNoFormatRequest": {
+        "type": "object",
+        "properties": {
+          "docCode": {"type": "string"},
+          "orgCode": {"type": "string"},
+          "path": {"type": "string"},
+          "variableMap": {"type": "object", "additionalProperties": {"type": "string"}},
+          "bulkNumber": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295},
+          "customFormat": {"type": "string"}
+        }
+      },
+      "GenerateBulkDocNoFormatResponse": {
+        "type": "object",
+        "properties": {
+          "ok": {"type": "boolean"},
+          "errorCode": {"type": "integer", "format": "int32"},
+          "errorMessage": {"type": "string"},
+          "errorReason": {"type": "string"},
+          "violations": {"type": "array", "items": {"$ref": "#/components/schemas/Violation"}},
+          "results": {"type": "array", "items": {"$ref": "#/components/schemas/GenerateBulkDocNoFormatResponse.Result"}}
+        }
+      },
+      "GenerateBulkDocNoFormatResponse.Result": {
+        "type": "object",
+        "properties": {
+          "docNoString": {"type": "string"},
+          "nextSeqNo": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295},
+          "recordTimestamp": {"type": "string", "format": "int64", "deprecated": true},
+          "version": {"type": "string", "format": "int64"}
+        }
+      },
+      "GenerateDocNoFormatRequest": {
+        "type": "object",
+        "properties": {
+          "docCode": {"type": "string"},
+          "orgCode": {"type": "string"},
+          "path": {"type": "string"},
+          "variableMap": {"type": "object", "additionalProperties": {"type": "string"}},
+          "customFormat": {"type": "string"}
+        }
+      },
+      "GenerateDocNoFormatResponse": {
+        "type": "object",
+        "properties": {
+          "ok": {"type": "boolean"},
+          "errorCode": {"type": "integer", "format": "int32"},
+          "errorMessage": {"type": "string"},
+          "errorReason": {"type": "string"},
+          "violations": {"type": "array", "items": {"$ref": "#/components/schemas/Violation"}},
+          "result": {"$ref": "#/components/schemas/GenerateDocNoFormatResponse.Result"}
+        }
+      },
+      "GenerateDocNoFormatResponse.Result": {
+        "type": "object",
+        "properties": {
+          "docNoString": {"type": "string"},
+          "nextSeqNo": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295},
+          "recordTimestamp": {"type": "string", "format": "int64", "deprecated": true},
+          "version": {"type": "string", "format": "int64"}
+        }
+      },
+      "GetNextDocNoRequest": {
+        "type": "object",
+        "properties": {
+          "docCode": {"type": "string"},
+          "orgCode": {"type": "string"},
+          "path": {"type": "string"},
+          "variableMap": {"type": "object", "additionalProperties": {"type": "string"}},
+          "customFormat": {"type": "string"}
+        }
+      },
+      "GetNextDocNoResponse": {
+        "type": "object",
+        "properties": {
+          "ok": {"type": "boolean"},
+          "errorCode": {"type": "integer", "format": "int32"},
+          "errorMessage": {"type": "string"},
+          "errorReason": {"type": "string"},
+          "violations": {"type": "array", "items": {"$ref": "#/components/schemas/Violation"}},
+          "result": {"$ref": "#/components/schemas/GetNextDocNoResponse.Result"}
+        }
+      },
+      "GetNextDocNoResponse.Result": {
+        "type": "object",
+        "properties": {
+          "docNoString": {"type": "string"},
+          "nextSeqNo": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295},
+          "recordTimestamp": {"type": "string", "format": "int64", "deprecated": true},
+          "version": {"type": "string", "format": "int64"}
+        }
+      },
+      "ConsumeDocNoRequest": {
+        "type": "object",
+        "properties": {
+          "docCode": {"type": "string"},
+          "orgCode": {"type": "string"},
+          "path": {"type": "string"},
+          "curSeqNo": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295},
+          "recordTimestamp": {"type": "string", "format": "int64", "deprecated": true},
+          "version": {"type": "string", "format": "int64"}
+        }
+      },
+      "ConsumeDocNoResponse": {
+        "type": "object",
+        "properties": {
+          "ok": {"type": "boolean"},
+          "errorCode": {"type": "integer", "format": "int32"},
+          "errorMessage": {"type": "string"},
+          "errorReason": {"type": "string"},
+          "violations": {"type": "array", "items": {"$ref": "#/components/schemas/Violation"}},
+          "result": {"$ref": "#/components/schemas/ConsumeDocNoResponse.Result"}
+        }
+      },
+      "ConsumeDocNoResponse.Result": {
+        "type": "object",
+        "properties": {
+          "nextSeqNo": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295},
+          "recordTimestamp": {"type": "string", "format": "int64", "deprecated": true},
+          "version": {"type": "string", "format": "int64"}
+        }
+      },
+      "ExportRequest": {
+        "type": "object",
+        "properties": {
+          "orgCode": {"type": "string"},
+          "format": {"type": "string"}
+        }
+      },
+      "ExportResponse": {
+        "type": "object",
+        "properties": {
+          "ok": {"type": "boolean"},
+          "errorCode": {"type": "integer", "format": "int32"},
+          "errorMessage": {"type": "string"},
+          "errorReason": {"type": "string"},
+          "violations": {"type": "array", "items": {"$ref": "#/components/schemas/Violation"}},
+          "result": {"$ref": "#/components/schemas/ExportResponse.Result"}
+        }
+      },
+      "ExportResponse.Result": {
+        "type": "object",
+        "properties": {
+          "format": {"type": "string"},
+          "data": {"type": "string"},
+          "counterCount": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295}
+        }
+      },
+      "ImportRequest": {
+        "type": "object",
+        "properties": {
+          "orgCode": {"type": "string"},
+          "format": {"type": "string"},
+          "data": {"type": "string"},
+          "mode": {"$ref": "#/components/schemas/ImportMode"},
+          "dryRun": {"type": "boolean"},
+          "force": {"type": "boolean"}
+        }
+      },
+      "ImportResponse": {
+        "type": "object",
+        "properties": {
+          "ok": {"type": "boolean"},
+          "errorCode": {"type": "integer", "format": "int32"},
+          "errorMessage": {"type": "string"},
+          "errorReason": {"type": "string"},
+          "violations": {"type": "array", "items": {"$ref": "#/components/schemas/Violation"}},
+          "result": {"$ref": "#/components/schemas/ImportResponse.Result"}
+        }
+      },
+      "ImportResponse.Change": {
+        "type": "object",
+        "properties": {
+          "docCode": {"type": "string"},
+          "path": {"type": "string"},
+          "action": {"type": "string"},
+          "fromSeqNo": {"type": "string", "format": "int64"},
+          "toSeqNo": {"type": "string", "format": "int64"},
+          "applied": {"type": "boolean"},
+          "failed": {"type": "boolean"}
+        }
+      },
+      "ImportResponse.Result": {
+        "type": "object",
+        "properties": {
+          "dryRun": {"type": "boolean"},
+          "created": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295},
+          "updated": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295},
+          "unchanged": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295},
+          "deleted": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 4294967295},
+          "changes": {"type": "array", "items": {"$ref": "#/components/schemas/ImportResponse.Change"}}
+        }
+      },
+      "APIKey": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "name": {"type": "string"},
+          "orgCodes": {"type": "array", "items": {"type": "string"}},
+          "roles": {"type": "array", "items": {"type": "string"}},
+          "createdAt": {"type": "string", "format": "int64"},
+          "expiresAt": {"type": "string", "format": "int64"},
+          "revokedAt": {"type": "string", "format": "int64"}
+        }
+      },
+      "CreateAPIKeyRequest": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string"},
+          "orgCodes": {"type": "array", "items": {"type": "string"}},
+          "roles": {"type": "array", "items": {"type": "string"}},
+          "expiresAt": {"type": "string", "format": "int64"}
+        }
+      },
+      "CreateAPIKeyResponse": {
+        "type": "object",
+        "properties": {
+          "ok": {"type": "boolean"},
+          "errorCode": {"type": "integer", "format": "int32"},
+          "errorMessage": {"type": "string"},
+          "errorReason": {"type": "string"},
+          "violations": {"type": "array", "items": {"$ref": "#/components/schemas/Violation"}},
+          "result": {"$ref": "#/components/schemas/CreateAPIKeyResponse.Result"}
+        }
+      },
+      "CreateAPIKeyResponse.Result": {
+        "type": "object",
+        "properties": {
+          "key": {"type": "string"},
+          "apiKey": {"$ref": "#/components/schemas/APIKey"}
+        }
+      },
+      "ListAPIKeysRequest": {
+        "type": "object",
+        "properties": {
+          "orgCode": {"type": "string"}
+        }
+      },
+      "ListAPIKeysResponse": {
+        "type": "object",
+        "properties": {
+          "ok": {"type": "boolean"},
+          "errorCode": {"type": "integer", "format": "int32"},
+          "errorMessage": {"type": "string"},
+          "errorReason": {"type": "string"},
+          "violations": {"type": "array", "items": {"$ref": "#/components/schemas/Violation"}},
+          "result": {"$ref": "#/components/schemas/ListAPIKeysResponse.Result"}
+        }
+      },
+      "ListAPIKeysResponse.Result": {
+        "type": "object",
+        "properties": {
+          "apiKeys": {"type": "array", "items": {"$ref": "#/components/schemas/APIKey"}}
+        }
+      },
+      "RevokeAPIKeyRequest": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"}
+        }
+      },
+      "RevokeAPIKeyResponse": {
+        "type": "object",
+        "properties": {
+          "ok": {"type": "boolean"},
+          "errorCode": {"type": "integer", "format": "int32"},
+          "errorMessage": {"type": "string"},
+          "errorReason": {"type": "string"},
+          "violations": {"type": "array", "items": {"$ref": "#/components/schemas/Violation"}},
+          "result": {"$ref": "#/components/schemas/RevokeAPIKeyResponse.Result"}
+        }
+      },
+      "RevokeAPIKeyResponse.Result": {
+        "type": "object",
+        "properties": {
+          "apiKey": {"$ref": "#/components/schemas/APIKey"}
+        }
+      },
+      "TransportError": {
+        "type": "object",
+        "description": "Returned when the request cannot be decoded or the endpoint fails before the service is reached",
+        "properties": {
+          "error": {"type": "string"},
+          "errorCode": {"type": "integer", "format": "int32"},
+          "errorMessage": {"type": "string"},
+          "errorReason": {"type": "string"}
+        }
+      }
+    }
+  }
+}`
+
+// WithPaths returns Spec with the paths of routes the proto does not describe, each a JSON object of paths such as
+// the one of the REST transport
+func WithPaths(paths ...string) (string, error) {
+	var spec map[string]interface{}
+	if err := json.Unmarshal([]byte(Spec), &spec); err != nil {
+		return "", err
+	}
+	specPaths := spec["paths"].(map[string]interface{})
+	for _, p := range paths {
+		var extra map[string]interface{}
+		if err := json.Unmarshal([]byte(p), &extra); err != nil {
+			return "", fmt.Errorf("OpenAPI paths are not valid JSON: %s", err.Error())
+		}
+		for path, item := range extra {
+			if _, ok := specPaths[path]; ok {
+				return "", fmt.Errorf("OpenAPI path %s is already described", path)
+			}
+			specPaths[path] = item
+		}
+	}
+	out, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// RegisterHandlers serves Spec, with the paths given to WithPaths, at /openapi.json and the API browser at /docs
+func RegisterHandlers(mux *http.ServeMux, logger log.Logger, paths ...string) error {
+	spec, err := WithPaths(paths...)
+	if err != nil {
+		return err
+	}
+
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/openapi.json", "service", "Docnogen")
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(spec))
+	})
+
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/docs", "service", "Docnogen")
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(browserPage))
+	})
+	return nil
+}
+
+// browserPage lists the operations of /openapi.json, each with an example request that can be edited and sent
+const browserPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>DocNoGenService API</title>
+<style>
+body { font-family: sans-serif; margin: 2em; max-width: 60em; }
+details { border: 1px solid #ccc; border-radius: 4px; margin: 0.5em 0; padding: 0.5em; }
+summary { cursor: pointer; font-weight: bold; }
+textarea { width: 100%; height: 12em; font-family: monospace; }
+input { display: block; font-family: monospace; margin: 0.5em 0; }
+pre { background: #f5f5f5; padding: 0.5em; overflow: auto; }
+.method { color: #fff; background: #49cc90; padding: 0 0.4em; border-radius: 3px; text-transform: uppercase; }
+</style>
+</head>
+<body>
+<h1>DocNoGenService API</h1>
+<p>Generated from <a href="/openapi.json">/openapi.json</a></p>
+<div id="operations"></div>
+<script>
+function resolve(spec, schema) {
+  while (schema && schema["$ref"]) {
+    schema = spec.components.schemas[schema["$ref"].replace("#/components/schemas/", "")];
+  }
+  return schema || {};
+}
+
+function example(spec, schema, depth) {
+  schema = resolve(spec, schema);
+  if (depth > 5) return null;
+  if (schema.enum) return schema.enum[0];
+  switch (schema.type) {
+    case "object":
+      var out = {};
+      Object.keys(schema.properties || {}).forEach(function (name) {
+        if (!schema.properties[name].deprecated) out[name] = example(spec, schema.properties[name], depth + 1);
+      });
+      return out;
+    case "array": return [example(spec, schema.items, depth + 1)];
+    case "string": return schema.format === "int64" || schema.format === "uint64" ? "0" : "";
+    case "integer": case "number": return 0;
+    case "boolean": return false;
+  }
+  return null;
+}
+
+fetch("/openapi.json").then(function (res) { return res.json(); }).then(function (spec) {
+  var root = document.getElementById("operations");
+  Object.keys(spec.paths).forEach(function (path) {
+    Object.keys(spec.paths[path]).forEach(function (method) {
+      var op = spec.paths[path][method];
+      var el = document.createElement("details");
+      var summary = document.createElement("summary");
+      summary.innerHTML = "<span class=\"method\">" + method + "</span> " + path;
+      // the parameters of a route are filled in the URL, the request message in the body
+      var url = document.createElement("input");
+      url.value = path;
+      url.size = 80;
+      var body = null;
+      if (op.requestBody) {
+        body = document.createElement("textarea");
+        body.value = JSON.stringify(example(spec, op.requestBody.content["application/json"].schema, 0), null, 2);
+      }
+      var send = document.createElement("button");
+      send.textContent = "Send";
+      var result = document.createElement("pre");
+      send.onclick = function () {
+        var init = { method: method.toUpperCase() };
+        if (body) {
+          init.body = body.value;
+          init.headers = { "Content-Type": "application/json" };
+        }
+        fetch(url.value, init)
+          .then(function (res) { return res.text().then(function (text) { result.textContent = res.status + " " + res.statusText + "\n" + text; }); })
+          .catch(function (err) { result.textContent = err; });
+      };
+      el.appendChild(summary);
+      el.appendChild(url);
+      if (body) el.appendChild(body);
+      el.appendChild(send);
+      el.appendChild(result);
+      root.appendChild(el);
+    });
+  });
+});
+</script>
+</body>
+</html>
+`
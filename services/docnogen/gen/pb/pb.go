@@ -22,6 +22,79 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type ImportMode int32
+
+const (
+	ImportMode_MERGE     ImportMode = 0
+	ImportMode_OVERWRITE ImportMode = 1
+)
+
+var ImportMode_name = map[int32]string{
+	0: "MERGE",
+	1: "OVERWRITE",
+}
+
+var ImportMode_value = map[string]int32{
+	"MERGE":     0,
+	"OVERWRITE": 1,
+}
+
+func (x ImportMode) String() string {
+	return proto.EnumName(ImportMode_name, int32(x))
+}
+
+func (ImportMode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{0}
+}
+
+// Violation is a request field failing validation
+type Violation struct {
+	Field                string   `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Description          string   `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Violation) Reset()         { *m = Violation{} }
+func (m *Violation) String() string { return proto.CompactTextString(m) }
+func (*Violation) ProtoMessage()    {}
+func (*Violation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{0}
+}
+
+func (m *Violation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Violation.Unmarshal(m, b)
+}
+func (m *Violation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Violation.Marshal(b, m, deterministic)
+}
+func (m *Violation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Violation.Merge(m, src)
+}
+func (m *Violation) XXX_Size() int {
+	return xxx_messageInfo_Violation.Size(m)
+}
+func (m *Violation) XXX_DiscardUnknown() {
+	xxx_messageInfo_Violation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Violation proto.InternalMessageInfo
+
+func (m *Violation) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *Violation) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
 type GenerateBulkDocNoFormatRequest struct {
 	DocCode              string            `protobuf:"bytes,1,opt,name=docCode,proto3" json:"docCode,omitempty"`
 	OrgCode              string            `protobuf:"bytes,2,opt,name=orgCode,proto3" json:"orgCode,omitempty"`
@@ -38,7 +111,7 @@ func (m *GenerateBulkDocNoFormatRequest) Reset()         { *m = GenerateBulkDocN
 func (m *GenerateBulkDocNoFormatRequest) String() string { return proto.CompactTextString(m) }
 func (*GenerateBulkDocNoFormatRequest) ProtoMessage()    {}
 func (*GenerateBulkDocNoFormatRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{0}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{1}
 }
 
 func (m *GenerateBulkDocNoFormatRequest) XXX_Unmarshal(b []byte) error {
@@ -105,6 +178,8 @@ type GenerateBulkDocNoFormatResponse struct {
 	Ok                   bool                                      `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
 	ErrorCode            int32                                     `protobuf:"varint,2,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
 	ErrorMessage         string                                    `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	ErrorReason          string                                    `protobuf:"bytes,5,opt,name=errorReason,proto3" json:"errorReason,omitempty"`
+	Violations           []*Violation                              `protobuf:"bytes,6,rep,name=violations,proto3" json:"violations,omitempty"`
 	Results              []*GenerateBulkDocNoFormatResponse_Result `protobuf:"bytes,4,rep,name=results,proto3" json:"results,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}                                  `json:"-"`
 	XXX_unrecognized     []byte                                    `json:"-"`
@@ -115,7 +190,7 @@ func (m *GenerateBulkDocNoFormatResponse) Reset()         { *m = GenerateBulkDoc
 func (m *GenerateBulkDocNoFormatResponse) String() string { return proto.CompactTextString(m) }
 func (*GenerateBulkDocNoFormatResponse) ProtoMessage()    {}
 func (*GenerateBulkDocNoFormatResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{1}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{2}
 }
 
 func (m *GenerateBulkDocNoFormatResponse) XXX_Unmarshal(b []byte) error {
@@ -157,6 +232,20 @@ func (m *GenerateBulkDocNoFormatResponse) GetErrorMessage() string {
 	return ""
 }
 
+func (m *GenerateBulkDocNoFormatResponse) GetErrorReason() string {
+	if m != nil {
+		return m.ErrorReason
+	}
+	return ""
+}
+
+func (m *GenerateBulkDocNoFormatResponse) GetViolations() []*Violation {
+	if m != nil {
+		return m.Violations
+	}
+	return nil
+}
+
 func (m *GenerateBulkDocNoFormatResponse) GetResults() []*GenerateBulkDocNoFormatResponse_Result {
 	if m != nil {
 		return m.Results
@@ -167,7 +256,8 @@ func (m *GenerateBulkDocNoFormatResponse) GetResults() []*GenerateBulkDocNoForma
 type GenerateBulkDocNoFormatResponse_Result struct {
 	DocNoString          string   `protobuf:"bytes,1,opt,name=docNoString,proto3" json:"docNoString,omitempty"`
 	NextSeqNo            uint32   `protobuf:"varint,2,opt,name=nextSeqNo,proto3" json:"nextSeqNo,omitempty"`
-	RecordTimestamp      int64    `protobuf:"varint,3,opt,name=recordTimestamp,proto3" json:"recordTimestamp,omitempty"`
+	RecordTimestamp      int64    `protobuf:"varint,3,opt,name=recordTimestamp,proto3" json:"recordTimestamp,omitempty"` // Deprecated: Do not use.
+	Version              int64    `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -179,7 +269,7 @@ func (m *GenerateBulkDocNoFormatResponse_Result) Reset() {
 func (m *GenerateBulkDocNoFormatResponse_Result) String() string { return proto.CompactTextString(m) }
 func (*GenerateBulkDocNoFormatResponse_Result) ProtoMessage()    {}
 func (*GenerateBulkDocNoFormatResponse_Result) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{1, 0}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{2, 0}
 }
 
 func (m *GenerateBulkDocNoFormatResponse_Result) XXX_Unmarshal(b []byte) error {
@@ -214,6 +304,7 @@ func (m *GenerateBulkDocNoFormatResponse_Result) GetNextSeqNo() uint32 {
 	return 0
 }
 
+// Deprecated: Do not use.
 func (m *GenerateBulkDocNoFormatResponse_Result) GetRecordTimestamp() int64 {
 	if m != nil {
 		return m.RecordTimestamp
@@ -221,6 +312,13 @@ func (m *GenerateBulkDocNoFormatResponse_Result) GetRecordTimestamp() int64 {
 	return 0
 }
 
+func (m *GenerateBulkDocNoFormatResponse_Result) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 type GenerateDocNoFormatRequest struct {
 	DocCode              string            `protobuf:"bytes,1,opt,name=docCode,proto3" json:"docCode,omitempty"`
 	OrgCode              string            `protobuf:"bytes,2,opt,name=orgCode,proto3" json:"orgCode,omitempty"`
@@ -236,7 +334,7 @@ func (m *GenerateDocNoFormatRequest) Reset()         { *m = GenerateDocNoFormatR
 func (m *GenerateDocNoFormatRequest) String() string { return proto.CompactTextString(m) }
 func (*GenerateDocNoFormatRequest) ProtoMessage()    {}
 func (*GenerateDocNoFormatRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{2}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{3}
 }
 
 func (m *GenerateDocNoFormatRequest) XXX_Unmarshal(b []byte) error {
@@ -296,6 +394,8 @@ type GenerateDocNoFormatResponse struct {
 	Ok                   bool                                `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
 	ErrorCode            int32                               `protobuf:"varint,2,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
 	ErrorMessage         string                              `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	ErrorReason          string                              `protobuf:"bytes,5,opt,name=errorReason,proto3" json:"errorReason,omitempty"`
+	Violations           []*Violation                        `protobuf:"bytes,6,rep,name=violations,proto3" json:"violations,omitempty"`
 	Result               *GenerateDocNoFormatResponse_Result `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}                            `json:"-"`
 	XXX_unrecognized     []byte                              `json:"-"`
@@ -306,7 +406,7 @@ func (m *GenerateDocNoFormatResponse) Reset()         { *m = GenerateDocNoFormat
 func (m *GenerateDocNoFormatResponse) String() string { return proto.CompactTextString(m) }
 func (*GenerateDocNoFormatResponse) ProtoMessage()    {}
 func (*GenerateDocNoFormatResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{3}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{4}
 }
 
 func (m *GenerateDocNoFormatResponse) XXX_Unmarshal(b []byte) error {
@@ -348,6 +448,20 @@ func (m *GenerateDocNoFormatResponse) GetErrorMessage() string {
 	return ""
 }
 
+func (m *GenerateDocNoFormatResponse) GetErrorReason() string {
+	if m != nil {
+		return m.ErrorReason
+	}
+	return ""
+}
+
+func (m *GenerateDocNoFormatResponse) GetViolations() []*Violation {
+	if m != nil {
+		return m.Violations
+	}
+	return nil
+}
+
 func (m *GenerateDocNoFormatResponse) GetResult() *GenerateDocNoFormatResponse_Result {
 	if m != nil {
 		return m.Result
@@ -358,7 +472,8 @@ func (m *GenerateDocNoFormatResponse) GetResult() *GenerateDocNoFormatResponse_R
 type GenerateDocNoFormatResponse_Result struct {
 	DocNoString          string   `protobuf:"bytes,1,opt,name=docNoString,proto3" json:"docNoString,omitempty"`
 	NextSeqNo            uint32   `protobuf:"varint,2,opt,name=nextSeqNo,proto3" json:"nextSeqNo,omitempty"`
-	RecordTimestamp      int64    `protobuf:"varint,3,opt,name=recordTimestamp,proto3" json:"recordTimestamp,omitempty"`
+	RecordTimestamp      int64    `protobuf:"varint,3,opt,name=recordTimestamp,proto3" json:"recordTimestamp,omitempty"` // Deprecated: Do not use.
+	Version              int64    `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -368,7 +483,7 @@ func (m *GenerateDocNoFormatResponse_Result) Reset()         { *m = GenerateDocN
 func (m *GenerateDocNoFormatResponse_Result) String() string { return proto.CompactTextString(m) }
 func (*GenerateDocNoFormatResponse_Result) ProtoMessage()    {}
 func (*GenerateDocNoFormatResponse_Result) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{3, 0}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{4, 0}
 }
 
 func (m *GenerateDocNoFormatResponse_Result) XXX_Unmarshal(b []byte) error {
@@ -403,6 +518,7 @@ func (m *GenerateDocNoFormatResponse_Result) GetNextSeqNo() uint32 {
 	return 0
 }
 
+// Deprecated: Do not use.
 func (m *GenerateDocNoFormatResponse_Result) GetRecordTimestamp() int64 {
 	if m != nil {
 		return m.RecordTimestamp
@@ -410,6 +526,13 @@ func (m *GenerateDocNoFormatResponse_Result) GetRecordTimestamp() int64 {
 	return 0
 }
 
+func (m *GenerateDocNoFormatResponse_Result) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 type GetNextDocNoRequest struct {
 	DocCode              string            `protobuf:"bytes,1,opt,name=docCode,proto3" json:"docCode,omitempty"`
 	OrgCode              string            `protobuf:"bytes,2,opt,name=orgCode,proto3" json:"orgCode,omitempty"`
@@ -425,7 +548,7 @@ func (m *GetNextDocNoRequest) Reset()         { *m = GetNextDocNoRequest{} }
 func (m *GetNextDocNoRequest) String() string { return proto.CompactTextString(m) }
 func (*GetNextDocNoRequest) ProtoMessage()    {}
 func (*GetNextDocNoRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{4}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{5}
 }
 
 func (m *GetNextDocNoRequest) XXX_Unmarshal(b []byte) error {
@@ -485,6 +608,8 @@ type GetNextDocNoResponse struct {
 	Ok                   bool                         `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
 	ErrorCode            int32                        `protobuf:"varint,2,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
 	ErrorMessage         string                       `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	ErrorReason          string                       `protobuf:"bytes,5,opt,name=errorReason,proto3" json:"errorReason,omitempty"`
+	Violations           []*Violation                 `protobuf:"bytes,6,rep,name=violations,proto3" json:"violations,omitempty"`
 	Result               *GetNextDocNoResponse_Result `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
 	XXX_unrecognized     []byte                       `json:"-"`
@@ -495,7 +620,7 @@ func (m *GetNextDocNoResponse) Reset()         { *m = GetNextDocNoResponse{} }
 func (m *GetNextDocNoResponse) String() string { return proto.CompactTextString(m) }
 func (*GetNextDocNoResponse) ProtoMessage()    {}
 func (*GetNextDocNoResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{5}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{6}
 }
 
 func (m *GetNextDocNoResponse) XXX_Unmarshal(b []byte) error {
@@ -537,6 +662,20 @@ func (m *GetNextDocNoResponse) GetErrorMessage() string {
 	return ""
 }
 
+func (m *GetNextDocNoResponse) GetErrorReason() string {
+	if m != nil {
+		return m.ErrorReason
+	}
+	return ""
+}
+
+func (m *GetNextDocNoResponse) GetViolations() []*Violation {
+	if m != nil {
+		return m.Violations
+	}
+	return nil
+}
+
 func (m *GetNextDocNoResponse) GetResult() *GetNextDocNoResponse_Result {
 	if m != nil {
 		return m.Result
@@ -547,7 +686,8 @@ func (m *GetNextDocNoResponse) GetResult() *GetNextDocNoResponse_Result {
 type GetNextDocNoResponse_Result struct {
 	DocNoString          string   `protobuf:"bytes,1,opt,name=docNoString,proto3" json:"docNoString,omitempty"`
 	NextSeqNo            uint32   `protobuf:"varint,2,opt,name=nextSeqNo,proto3" json:"nextSeqNo,omitempty"`
-	RecordTimestamp      int64    `protobuf:"varint,3,opt,name=recordTimestamp,proto3" json:"recordTimestamp,omitempty"`
+	RecordTimestamp      int64    `protobuf:"varint,3,opt,name=recordTimestamp,proto3" json:"recordTimestamp,omitempty"` // Deprecated: Do not use.
+	Version              int64    `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -557,7 +697,7 @@ func (m *GetNextDocNoResponse_Result) Reset()         { *m = GetNextDocNoRespons
 func (m *GetNextDocNoResponse_Result) String() string { return proto.CompactTextString(m) }
 func (*GetNextDocNoResponse_Result) ProtoMessage()    {}
 func (*GetNextDocNoResponse_Result) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{5, 0}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{6, 0}
 }
 
 func (m *GetNextDocNoResponse_Result) XXX_Unmarshal(b []byte) error {
@@ -592,6 +732,7 @@ func (m *GetNextDocNoResponse_Result) GetNextSeqNo() uint32 {
 	return 0
 }
 
+// Deprecated: Do not use.
 func (m *GetNextDocNoResponse_Result) GetRecordTimestamp() int64 {
 	if m != nil {
 		return m.RecordTimestamp
@@ -599,12 +740,20 @@ func (m *GetNextDocNoResponse_Result) GetRecordTimestamp() int64 {
 	return 0
 }
 
+func (m *GetNextDocNoResponse_Result) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 type ConsumeDocNoRequest struct {
 	DocCode              string   `protobuf:"bytes,1,opt,name=docCode,proto3" json:"docCode,omitempty"`
 	OrgCode              string   `protobuf:"bytes,2,opt,name=orgCode,proto3" json:"orgCode,omitempty"`
 	Path                 string   `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
 	CurSeqNo             uint32   `protobuf:"varint,4,opt,name=curSeqNo,proto3" json:"curSeqNo,omitempty"`
-	RecordTimestamp      int64    `protobuf:"varint,5,opt,name=recordTimestamp,proto3" json:"recordTimestamp,omitempty"`
+	RecordTimestamp      int64    `protobuf:"varint,5,opt,name=recordTimestamp,proto3" json:"recordTimestamp,omitempty"` // Deprecated: Do not use.
+	Version              int64    `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -614,7 +763,7 @@ func (m *ConsumeDocNoRequest) Reset()         { *m = ConsumeDocNoRequest{} }
 func (m *ConsumeDocNoRequest) String() string { return proto.CompactTextString(m) }
 func (*ConsumeDocNoRequest) ProtoMessage()    {}
 func (*ConsumeDocNoRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{6}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{7}
 }
 
 func (m *ConsumeDocNoRequest) XXX_Unmarshal(b []byte) error {
@@ -663,6 +812,7 @@ func (m *ConsumeDocNoRequest) GetCurSeqNo() uint32 {
 	return 0
 }
 
+// Deprecated: Do not use.
 func (m *ConsumeDocNoRequest) GetRecordTimestamp() int64 {
 	if m != nil {
 		return m.RecordTimestamp
@@ -670,10 +820,19 @@ func (m *ConsumeDocNoRequest) GetRecordTimestamp() int64 {
 	return 0
 }
 
+func (m *ConsumeDocNoRequest) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 type ConsumeDocNoResponse struct {
 	Ok                   bool                         `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
 	ErrorCode            int32                        `protobuf:"varint,2,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
 	ErrorMessage         string                       `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	ErrorReason          string                       `protobuf:"bytes,5,opt,name=errorReason,proto3" json:"errorReason,omitempty"`
+	Violations           []*Violation                 `protobuf:"bytes,6,rep,name=violations,proto3" json:"violations,omitempty"`
 	Result               *ConsumeDocNoResponse_Result `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
 	XXX_unrecognized     []byte                       `json:"-"`
@@ -684,7 +843,7 @@ func (m *ConsumeDocNoResponse) Reset()         { *m = ConsumeDocNoResponse{} }
 func (m *ConsumeDocNoResponse) String() string { return proto.CompactTextString(m) }
 func (*ConsumeDocNoResponse) ProtoMessage()    {}
 func (*ConsumeDocNoResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{7}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{8}
 }
 
 func (m *ConsumeDocNoResponse) XXX_Unmarshal(b []byte) error {
@@ -726,6 +885,20 @@ func (m *ConsumeDocNoResponse) GetErrorMessage() string {
 	return ""
 }
 
+func (m *ConsumeDocNoResponse) GetErrorReason() string {
+	if m != nil {
+		return m.ErrorReason
+	}
+	return ""
+}
+
+func (m *ConsumeDocNoResponse) GetViolations() []*Violation {
+	if m != nil {
+		return m.Violations
+	}
+	return nil
+}
+
 func (m *ConsumeDocNoResponse) GetResult() *ConsumeDocNoResponse_Result {
 	if m != nil {
 		return m.Result
@@ -735,7 +908,8 @@ func (m *ConsumeDocNoResponse) GetResult() *ConsumeDocNoResponse_Result {
 
 type ConsumeDocNoResponse_Result struct {
 	NextSeqNo            uint32   `protobuf:"varint,1,opt,name=nextSeqNo,proto3" json:"nextSeqNo,omitempty"`
-	RecordTimestamp      int64    `protobuf:"varint,2,opt,name=recordTimestamp,proto3" json:"recordTimestamp,omitempty"`
+	RecordTimestamp      int64    `protobuf:"varint,2,opt,name=recordTimestamp,proto3" json:"recordTimestamp,omitempty"` // Deprecated: Do not use.
+	Version              int64    `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -745,7 +919,7 @@ func (m *ConsumeDocNoResponse_Result) Reset()         { *m = ConsumeDocNoRespons
 func (m *ConsumeDocNoResponse_Result) String() string { return proto.CompactTextString(m) }
 func (*ConsumeDocNoResponse_Result) ProtoMessage()    {}
 func (*ConsumeDocNoResponse_Result) Descriptor() ([]byte, []int) {
-	return fileDescriptor_fb7cc0a8d5129ab9, []int{7, 0}
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{8, 0}
 }
 
 func (m *ConsumeDocNoResponse_Result) XXX_Unmarshal(b []byte) error {
@@ -773,6 +947,7 @@ func (m *ConsumeDocNoResponse_Result) GetNextSeqNo() uint32 {
 	return 0
 }
 
+// Deprecated: Do not use.
 func (m *ConsumeDocNoResponse_Result) GetRecordTimestamp() int64 {
 	if m != nil {
 		return m.RecordTimestamp
@@ -780,181 +955,1403 @@ func (m *ConsumeDocNoResponse_Result) GetRecordTimestamp() int64 {
 	return 0
 }
 
-func init() {
-	proto.RegisterType((*GenerateBulkDocNoFormatRequest)(nil), "docnogen.GenerateBulkDocNoFormatRequest")
-	proto.RegisterMapType((map[string]string)(nil), "docnogen.GenerateBulkDocNoFormatRequest.VariableMapEntry")
-	proto.RegisterType((*GenerateBulkDocNoFormatResponse)(nil), "docnogen.GenerateBulkDocNoFormatResponse")
-	proto.RegisterType((*GenerateBulkDocNoFormatResponse_Result)(nil), "docnogen.GenerateBulkDocNoFormatResponse.Result")
-	proto.RegisterType((*GenerateDocNoFormatRequest)(nil), "docnogen.GenerateDocNoFormatRequest")
-	proto.RegisterMapType((map[string]string)(nil), "docnogen.GenerateDocNoFormatRequest.VariableMapEntry")
-	proto.RegisterType((*GenerateDocNoFormatResponse)(nil), "docnogen.GenerateDocNoFormatResponse")
-	proto.RegisterType((*GenerateDocNoFormatResponse_Result)(nil), "docnogen.GenerateDocNoFormatResponse.Result")
-	proto.RegisterType((*GetNextDocNoRequest)(nil), "docnogen.GetNextDocNoRequest")
-	proto.RegisterMapType((map[string]string)(nil), "docnogen.GetNextDocNoRequest.VariableMapEntry")
-	proto.RegisterType((*GetNextDocNoResponse)(nil), "docnogen.GetNextDocNoResponse")
-	proto.RegisterType((*GetNextDocNoResponse_Result)(nil), "docnogen.GetNextDocNoResponse.Result")
-	proto.RegisterType((*ConsumeDocNoRequest)(nil), "docnogen.ConsumeDocNoRequest")
-	proto.RegisterType((*ConsumeDocNoResponse)(nil), "docnogen.ConsumeDocNoResponse")
-	proto.RegisterType((*ConsumeDocNoResponse_Result)(nil), "docnogen.ConsumeDocNoResponse.Result")
+func (m *ConsumeDocNoResponse_Result) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
 }
 
-func init() { proto.RegisterFile("docnogen.proto", fileDescriptor_fb7cc0a8d5129ab9) }
+type ExportRequest struct {
+	OrgCode              string   `protobuf:"bytes,1,opt,name=orgCode,proto3" json:"orgCode,omitempty"`
+	Format               string   `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
 
-var fileDescriptor_fb7cc0a8d5129ab9 = []byte{
-	// 620 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x96, 0xcf, 0x8b, 0xd3, 0x40,
-	0x14, 0xc7, 0x9b, 0xf4, 0xc7, 0x76, 0x5f, 0xf7, 0x17, 0xb3, 0x05, 0x43, 0xd4, 0x5a, 0x82, 0x0b,
-	0x11, 0xa4, 0x48, 0x45, 0x50, 0x41, 0x0f, 0xee, 0x6a, 0x41, 0xd8, 0xba, 0xa4, 0xa2, 0x07, 0x4f,
-	0x69, 0xf2, 0xa8, 0xa5, 0x4d, 0x26, 0x3b, 0x99, 0x94, 0xdd, 0xbf, 0xc1, 0xa3, 0x47, 0x4f, 0xe2,
-	0xc9, 0xb3, 0x7f, 0x85, 0x7f, 0x92, 0x37, 0xc9, 0x24, 0x6d, 0x93, 0x36, 0xad, 0x11, 0xb6, 0xec,
-	0xde, 0xf2, 0xbe, 0x61, 0xde, 0x7c, 0xde, 0x7b, 0xdf, 0xcc, 0x04, 0xf6, 0x6c, 0x6a, 0xb9, 0x74,
-	0x80, 0x6e, 0xcb, 0x63, 0x94, 0x53, 0x52, 0x9d, 0xc6, 0xda, 0x6f, 0x19, 0x1a, 0x1d, 0x74, 0x91,
-	0x99, 0x1c, 0x5f, 0x05, 0xe3, 0xd1, 0x09, 0xb5, 0xba, 0xf4, 0x0d, 0x65, 0x8e, 0xc9, 0x0d, 0x3c,
-	0x0f, 0xd0, 0xe7, 0x44, 0x81, 0x2d, 0x9b, 0x5a, 0xc7, 0xd4, 0x46, 0x45, 0x6a, 0x4a, 0xfa, 0xb6,
-	0x31, 0x0d, 0xc3, 0x37, 0x94, 0x0d, 0xc4, 0x1b, 0x39, 0x7a, 0x13, 0x87, 0x84, 0x40, 0xc9, 0x33,
-	0xf9, 0x67, 0xa5, 0x28, 0x64, 0xf1, 0x4c, 0x3e, 0x41, 0x6d, 0x62, 0xb2, 0xa1, 0xd9, 0x1f, 0xe3,
-	0xa9, 0xe9, 0x29, 0xa5, 0x66, 0x51, 0xaf, 0xb5, 0x9f, 0xb5, 0x66, 0x68, 0xeb, 0x31, 0x5a, 0x1f,
-	0xe6, 0x6b, 0x5f, 0xbb, 0x9c, 0x5d, 0x1a, 0xc9, 0x6c, 0xa4, 0x01, 0xd0, 0x0f, 0xc6, 0xa3, 0x6e,
-	0xe0, 0xf4, 0x91, 0x29, 0xe5, 0xa6, 0xa4, 0xef, 0x1a, 0x09, 0x85, 0x68, 0xb0, 0x63, 0x05, 0x3e,
-	0xa7, 0x4e, 0x94, 0x54, 0xa9, 0x08, 0xb0, 0x94, 0xa6, 0xbe, 0x84, 0x83, 0xc5, 0x4d, 0xc8, 0x01,
-	0x14, 0x47, 0x78, 0x19, 0x17, 0x1e, 0x3e, 0x92, 0x3a, 0x94, 0x27, 0xe6, 0x38, 0x98, 0x96, 0x1c,
-	0x05, 0xcf, 0xe5, 0xa7, 0x92, 0xf6, 0x4b, 0x86, 0x7b, 0x2b, 0x8b, 0xf0, 0x3d, 0xea, 0xfa, 0x48,
-	0xf6, 0x40, 0xa6, 0x23, 0x91, 0xae, 0x6a, 0xc8, 0x74, 0x44, 0xee, 0xc0, 0x36, 0x32, 0x46, 0xd9,
-	0xac, 0x89, 0x65, 0x63, 0x2e, 0x84, 0xd4, 0x22, 0x38, 0x45, 0xdf, 0x37, 0x07, 0x18, 0xb7, 0x33,
-	0xa5, 0x91, 0xb7, 0xb0, 0xc5, 0xd0, 0x0f, 0xc6, 0xdc, 0x8f, 0x5b, 0xfa, 0x28, 0x47, 0x4b, 0x23,
-	0x9a, 0x96, 0x21, 0x16, 0x1a, 0xd3, 0x04, 0x2a, 0x83, 0x4a, 0x24, 0x91, 0x26, 0xd4, 0xec, 0x70,
-	0x41, 0x8f, 0xb3, 0xa1, 0x3b, 0x88, 0xeb, 0x4f, 0x4a, 0x21, 0xb9, 0x8b, 0x17, 0xbc, 0x87, 0xe7,
-	0x5d, 0x2a, 0xc8, 0x77, 0x8d, 0xb9, 0x40, 0x74, 0xd8, 0x67, 0x68, 0x51, 0x66, 0xbf, 0x1f, 0x3a,
-	0xe8, 0x73, 0xd3, 0xf1, 0x04, 0x7c, 0xd1, 0x58, 0x94, 0xb5, 0xef, 0x32, 0xa8, 0x53, 0xce, 0x0d,
-	0xba, 0xef, 0x63, 0x96, 0xfb, 0x9e, 0x2c, 0xb7, 0xea, 0xbf, 0x9d, 0xb7, 0xe8, 0xac, 0xf2, 0x06,
-	0x9c, 0xf5, 0x53, 0x86, 0xdb, 0x99, 0x80, 0x1b, 0x73, 0xd5, 0x09, 0x54, 0x22, 0x53, 0x28, 0xa5,
-	0xa6, 0xa4, 0xd7, 0xda, 0x0f, 0xff, 0xd1, 0xa9, 0xb4, 0xa1, 0xe2, 0xb5, 0xd7, 0xe2, 0xa7, 0xaf,
-	0x32, 0x1c, 0x76, 0x90, 0x77, 0xf1, 0x82, 0x0b, 0xc2, 0xab, 0x36, 0xd2, 0x59, 0x96, 0x91, 0x5a,
-	0xc9, 0xf6, 0x2c, 0xed, 0x7d, 0x03, 0x1c, 0xf4, 0x4d, 0x86, 0x7a, 0x9a, 0x6c, 0x63, 0xd6, 0x79,
-	0xb1, 0x60, 0x9d, 0xa3, 0x55, 0xbd, 0xb9, 0x39, 0x9e, 0xf9, 0x21, 0xc1, 0xe1, 0x31, 0x75, 0xfd,
-	0xc0, 0xc1, 0x8d, 0x78, 0x46, 0x85, 0xaa, 0x15, 0xb0, 0x08, 0xb3, 0x24, 0x30, 0x67, 0x71, 0x16,
-	0x65, 0x39, 0x9b, 0xf2, 0x8f, 0x04, 0xf5, 0x34, 0xe5, 0x75, 0xcc, 0x30, 0x8b, 0x60, 0x71, 0x86,
-	0x67, 0xb3, 0x19, 0xa6, 0x26, 0x24, 0xe5, 0x98, 0x90, 0x9c, 0x59, 0x7b, 0xfb, 0x4b, 0x11, 0xf6,
-	0xc5, 0x96, 0x1d, 0x74, 0x7b, 0xc8, 0x26, 0x43, 0x0b, 0x89, 0x07, 0xb7, 0x56, 0x5c, 0x70, 0x44,
-	0xcf, 0xfb, 0x5b, 0xa1, 0x3e, 0xc8, 0x7d, 0x5b, 0x6a, 0x05, 0x62, 0x87, 0x47, 0xcb, 0xd2, 0xe9,
-	0x47, 0xee, 0xe7, 0xb9, 0x46, 0xd4, 0xa3, 0x5c, 0x47, 0xa8, 0x56, 0x20, 0xef, 0x60, 0x27, 0xf9,
-	0xa1, 0x90, 0xbb, 0x6b, 0x0f, 0x17, 0xb5, 0xb1, 0xfe, 0xfb, 0x8a, 0x12, 0x26, 0xa7, 0x96, 0x4c,
-	0x98, 0xe1, 0xfa, 0x64, 0xc2, 0xac, 0x61, 0x6b, 0x85, 0x7e, 0x45, 0xfc, 0x46, 0x3e, 0xfe, 0x1b,
-	0x00, 0x00, 0xff, 0xff, 0xe8, 0x78, 0x2b, 0xbe, 0x58, 0x0a, 0x00, 0x00,
+func (m *ExportRequest) Reset()         { *m = ExportRequest{} }
+func (m *ExportRequest) String() string { return proto.CompactTextString(m) }
+func (*ExportRequest) ProtoMessage()    {}
+func (*ExportRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{9}
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *ExportRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportRequest.Unmarshal(m, b)
+}
+func (m *ExportRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportRequest.Marshal(b, m, deterministic)
+}
+func (m *ExportRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportRequest.Merge(m, src)
+}
+func (m *ExportRequest) XXX_Size() int {
+	return xxx_messageInfo_ExportRequest.Size(m)
+}
+func (m *ExportRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportRequest.DiscardUnknown(m)
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+var xxx_messageInfo_ExportRequest proto.InternalMessageInfo
 
-// DocNoGenServiceClient is the client API for DocNoGenService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type DocNoGenServiceClient interface {
-	GenerateBulkDocNoFormat(ctx context.Context, in *GenerateBulkDocNoFormatRequest, opts ...grpc.CallOption) (*GenerateBulkDocNoFormatResponse, error)
-	GenerateDocNoFormat(ctx context.Context, in *GenerateDocNoFormatRequest, opts ...grpc.CallOption) (*GenerateDocNoFormatResponse, error)
-	GetNextDocNo(ctx context.Context, in *GetNextDocNoRequest, opts ...grpc.CallOption) (*GetNextDocNoResponse, error)
-	ConsumeDocNo(ctx context.Context, in *ConsumeDocNoRequest, opts ...grpc.CallOption) (*ConsumeDocNoResponse, error)
+func (m *ExportRequest) GetOrgCode() string {
+	if m != nil {
+		return m.OrgCode
+	}
+	return ""
 }
 
-type docNoGenServiceClient struct {
-	cc *grpc.ClientConn
+func (m *ExportRequest) GetFormat() string {
+	if m != nil {
+		return m.Format
+	}
+	return ""
 }
 
-func NewDocNoGenServiceClient(cc *grpc.ClientConn) DocNoGenServiceClient {
-	return &docNoGenServiceClient{cc}
+type ExportResponse struct {
+	Ok                   bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	ErrorCode            int32                  `protobuf:"varint,2,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
+	ErrorMessage         string                 `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	ErrorReason          string                 `protobuf:"bytes,5,opt,name=errorReason,proto3" json:"errorReason,omitempty"`
+	Violations           []*Violation           `protobuf:"bytes,6,rep,name=violations,proto3" json:"violations,omitempty"`
+	Result               *ExportResponse_Result `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
 }
 
-func (c *docNoGenServiceClient) GenerateBulkDocNoFormat(ctx context.Context, in *GenerateBulkDocNoFormatRequest, opts ...grpc.CallOption) (*GenerateBulkDocNoFormatResponse, error) {
-	out := new(GenerateBulkDocNoFormatResponse)
-	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/GenerateBulkDocNoFormat", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+func (m *ExportResponse) Reset()         { *m = ExportResponse{} }
+func (m *ExportResponse) String() string { return proto.CompactTextString(m) }
+func (*ExportResponse) ProtoMessage()    {}
+func (*ExportResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{10}
 }
 
-func (c *docNoGenServiceClient) GenerateDocNoFormat(ctx context.Context, in *GenerateDocNoFormatRequest, opts ...grpc.CallOption) (*GenerateDocNoFormatResponse, error) {
-	out := new(GenerateDocNoFormatResponse)
-	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/GenerateDocNoFormat", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+func (m *ExportResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportResponse.Unmarshal(m, b)
+}
+func (m *ExportResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportResponse.Marshal(b, m, deterministic)
+}
+func (m *ExportResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportResponse.Merge(m, src)
+}
+func (m *ExportResponse) XXX_Size() int {
+	return xxx_messageInfo_ExportResponse.Size(m)
+}
+func (m *ExportResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportResponse.DiscardUnknown(m)
 }
 
-func (c *docNoGenServiceClient) GetNextDocNo(ctx context.Context, in *GetNextDocNoRequest, opts ...grpc.CallOption) (*GetNextDocNoResponse, error) {
-	out := new(GetNextDocNoResponse)
-	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/GetNextDocNo", in, out, opts...)
-	if err != nil {
-		return nil, err
+var xxx_messageInfo_ExportResponse proto.InternalMessageInfo
+
+func (m *ExportResponse) GetOk() bool {
+	if m != nil {
+		return m.Ok
 	}
-	return out, nil
+	return false
 }
 
-func (c *docNoGenServiceClient) ConsumeDocNo(ctx context.Context, in *ConsumeDocNoRequest, opts ...grpc.CallOption) (*ConsumeDocNoResponse, error) {
-	out := new(ConsumeDocNoResponse)
-	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/ConsumeDocNo", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ExportResponse) GetErrorCode() int32 {
+	if m != nil {
+		return m.ErrorCode
 	}
-	return out, nil
+	return 0
 }
 
-// DocNoGenServiceServer is the server API for DocNoGenService service.
-type DocNoGenServiceServer interface {
-	GenerateBulkDocNoFormat(context.Context, *GenerateBulkDocNoFormatRequest) (*GenerateBulkDocNoFormatResponse, error)
-	GenerateDocNoFormat(context.Context, *GenerateDocNoFormatRequest) (*GenerateDocNoFormatResponse, error)
-	GetNextDocNo(context.Context, *GetNextDocNoRequest) (*GetNextDocNoResponse, error)
-	ConsumeDocNo(context.Context, *ConsumeDocNoRequest) (*ConsumeDocNoResponse, error)
+func (m *ExportResponse) GetErrorMessage() string {
+	if m != nil {
+		return m.ErrorMessage
+	}
+	return ""
 }
 
-func RegisterDocNoGenServiceServer(s *grpc.Server, srv DocNoGenServiceServer) {
-	s.RegisterService(&_DocNoGenService_serviceDesc, srv)
+func (m *ExportResponse) GetErrorReason() string {
+	if m != nil {
+		return m.ErrorReason
+	}
+	return ""
 }
 
-func _DocNoGenService_GenerateBulkDocNoFormat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GenerateBulkDocNoFormatRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(DocNoGenServiceServer).GenerateBulkDocNoFormat(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/docnogen.DocNoGenService/GenerateBulkDocNoFormat",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(DocNoGenServiceServer).GenerateBulkDocNoFormat(ctx, req.(*GenerateBulkDocNoFormatRequest))
+func (m *ExportResponse) GetViolations() []*Violation {
+	if m != nil {
+		return m.Violations
 	}
-	return interceptor(ctx, in, info, handler)
+	return nil
 }
 
-func _DocNoGenService_GenerateDocNoFormat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GenerateDocNoFormatRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(DocNoGenServiceServer).GenerateDocNoFormat(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/docnogen.DocNoGenService/GenerateDocNoFormat",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(DocNoGenServiceServer).GenerateDocNoFormat(ctx, req.(*GenerateDocNoFormatRequest))
+func (m *ExportResponse) GetResult() *ExportResponse_Result {
+	if m != nil {
+		return m.Result
 	}
-	return interceptor(ctx, in, info, handler)
+	return nil
 }
 
-func _DocNoGenService_GetNextDocNo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetNextDocNoRequest)
+type ExportResponse_Result struct {
+	Format               string   `protobuf:"bytes,1,opt,name=format,proto3" json:"format,omitempty"`
+	Data                 string   `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	CounterCount         uint32   `protobuf:"varint,3,opt,name=counterCount,proto3" json:"counterCount,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExportResponse_Result) Reset()         { *m = ExportResponse_Result{} }
+func (m *ExportResponse_Result) String() string { return proto.CompactTextString(m) }
+func (*ExportResponse_Result) ProtoMessage()    {}
+func (*ExportResponse_Result) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{10, 0}
+}
+
+func (m *ExportResponse_Result) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportResponse_Result.Unmarshal(m, b)
+}
+func (m *ExportResponse_Result) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportResponse_Result.Marshal(b, m, deterministic)
+}
+func (m *ExportResponse_Result) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportResponse_Result.Merge(m, src)
+}
+func (m *ExportResponse_Result) XXX_Size() int {
+	return xxx_messageInfo_ExportResponse_Result.Size(m)
+}
+func (m *ExportResponse_Result) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportResponse_Result.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExportResponse_Result proto.InternalMessageInfo
+
+func (m *ExportResponse_Result) GetFormat() string {
+	if m != nil {
+		return m.Format
+	}
+	return ""
+}
+
+func (m *ExportResponse_Result) GetData() string {
+	if m != nil {
+		return m.Data
+	}
+	return ""
+}
+
+func (m *ExportResponse_Result) GetCounterCount() uint32 {
+	if m != nil {
+		return m.CounterCount
+	}
+	return 0
+}
+
+type ImportRequest struct {
+	OrgCode              string     `protobuf:"bytes,1,opt,name=orgCode,proto3" json:"orgCode,omitempty"`
+	Format               string     `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	Data                 string     `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Mode                 ImportMode `protobuf:"varint,4,opt,name=mode,proto3,enum=docnogen.ImportMode" json:"mode,omitempty"`
+	DryRun               bool       `protobuf:"varint,5,opt,name=dryRun,proto3" json:"dryRun,omitempty"`
+	Force                bool       `protobuf:"varint,6,opt,name=force,proto3" json:"force,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ImportRequest) Reset()         { *m = ImportRequest{} }
+func (m *ImportRequest) String() string { return proto.CompactTextString(m) }
+func (*ImportRequest) ProtoMessage()    {}
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{11}
+}
+
+func (m *ImportRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportRequest.Unmarshal(m, b)
+}
+func (m *ImportRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportRequest.Marshal(b, m, deterministic)
+}
+func (m *ImportRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportRequest.Merge(m, src)
+}
+func (m *ImportRequest) XXX_Size() int {
+	return xxx_messageInfo_ImportRequest.Size(m)
+}
+func (m *ImportRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportRequest proto.InternalMessageInfo
+
+func (m *ImportRequest) GetOrgCode() string {
+	if m != nil {
+		return m.OrgCode
+	}
+	return ""
+}
+
+func (m *ImportRequest) GetFormat() string {
+	if m != nil {
+		return m.Format
+	}
+	return ""
+}
+
+func (m *ImportRequest) GetData() string {
+	if m != nil {
+		return m.Data
+	}
+	return ""
+}
+
+func (m *ImportRequest) GetMode() ImportMode {
+	if m != nil {
+		return m.Mode
+	}
+	return ImportMode_MERGE
+}
+
+func (m *ImportRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+func (m *ImportRequest) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+type ImportResponse struct {
+	Ok                   bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	ErrorCode            int32                  `protobuf:"varint,2,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
+	ErrorMessage         string                 `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	ErrorReason          string                 `protobuf:"bytes,5,opt,name=errorReason,proto3" json:"errorReason,omitempty"`
+	Violations           []*Violation           `protobuf:"bytes,6,rep,name=violations,proto3" json:"violations,omitempty"`
+	Result               *ImportResponse_Result `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *ImportResponse) Reset()         { *m = ImportResponse{} }
+func (m *ImportResponse) String() string { return proto.CompactTextString(m) }
+func (*ImportResponse) ProtoMessage()    {}
+func (*ImportResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{12}
+}
+
+func (m *ImportResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportResponse.Unmarshal(m, b)
+}
+func (m *ImportResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportResponse.Marshal(b, m, deterministic)
+}
+func (m *ImportResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportResponse.Merge(m, src)
+}
+func (m *ImportResponse) XXX_Size() int {
+	return xxx_messageInfo_ImportResponse.Size(m)
+}
+func (m *ImportResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportResponse proto.InternalMessageInfo
+
+func (m *ImportResponse) GetOk() bool {
+	if m != nil {
+		return m.Ok
+	}
+	return false
+}
+
+func (m *ImportResponse) GetErrorCode() int32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *ImportResponse) GetErrorMessage() string {
+	if m != nil {
+		return m.ErrorMessage
+	}
+	return ""
+}
+
+func (m *ImportResponse) GetErrorReason() string {
+	if m != nil {
+		return m.ErrorReason
+	}
+	return ""
+}
+
+func (m *ImportResponse) GetViolations() []*Violation {
+	if m != nil {
+		return m.Violations
+	}
+	return nil
+}
+
+func (m *ImportResponse) GetResult() *ImportResponse_Result {
+	if m != nil {
+		return m.Result
+	}
+	return nil
+}
+
+type ImportResponse_Change struct {
+	DocCode              string   `protobuf:"bytes,1,opt,name=docCode,proto3" json:"docCode,omitempty"`
+	Path                 string   `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Action               string   `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`
+	FromSeqNo            int64    `protobuf:"varint,4,opt,name=fromSeqNo,proto3" json:"fromSeqNo,omitempty"`
+	ToSeqNo              int64    `protobuf:"varint,5,opt,name=toSeqNo,proto3" json:"toSeqNo,omitempty"`
+	Applied              bool     `protobuf:"varint,6,opt,name=applied,proto3" json:"applied,omitempty"`
+	Failed               bool     `protobuf:"varint,7,opt,name=failed,proto3" json:"failed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ImportResponse_Change) Reset()         { *m = ImportResponse_Change{} }
+func (m *ImportResponse_Change) String() string { return proto.CompactTextString(m) }
+func (*ImportResponse_Change) ProtoMessage()    {}
+func (*ImportResponse_Change) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{12, 0}
+}
+
+func (m *ImportResponse_Change) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportResponse_Change.Unmarshal(m, b)
+}
+func (m *ImportResponse_Change) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportResponse_Change.Marshal(b, m, deterministic)
+}
+func (m *ImportResponse_Change) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportResponse_Change.Merge(m, src)
+}
+func (m *ImportResponse_Change) XXX_Size() int {
+	return xxx_messageInfo_ImportResponse_Change.Size(m)
+}
+func (m *ImportResponse_Change) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportResponse_Change.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportResponse_Change proto.InternalMessageInfo
+
+func (m *ImportResponse_Change) GetDocCode() string {
+	if m != nil {
+		return m.DocCode
+	}
+	return ""
+}
+
+func (m *ImportResponse_Change) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *ImportResponse_Change) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *ImportResponse_Change) GetFromSeqNo() int64 {
+	if m != nil {
+		return m.FromSeqNo
+	}
+	return 0
+}
+
+func (m *ImportResponse_Change) GetToSeqNo() int64 {
+	if m != nil {
+		return m.ToSeqNo
+	}
+	return 0
+}
+
+func (m *ImportResponse_Change) GetApplied() bool {
+	if m != nil {
+		return m.Applied
+	}
+	return false
+}
+
+func (m *ImportResponse_Change) GetFailed() bool {
+	if m != nil {
+		return m.Failed
+	}
+	return false
+}
+
+type ImportResponse_Result struct {
+	DryRun               bool                     `protobuf:"varint,1,opt,name=dryRun,proto3" json:"dryRun,omitempty"`
+	Created              uint32                   `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	Updated              uint32                   `protobuf:"varint,3,opt,name=updated,proto3" json:"updated,omitempty"`
+	Unchanged            uint32                   `protobuf:"varint,4,opt,name=unchanged,proto3" json:"unchanged,omitempty"`
+	Deleted              uint32                   `protobuf:"varint,5,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Changes              []*ImportResponse_Change `protobuf:"bytes,6,rep,name=changes,proto3" json:"changes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *ImportResponse_Result) Reset()         { *m = ImportResponse_Result{} }
+func (m *ImportResponse_Result) String() string { return proto.CompactTextString(m) }
+func (*ImportResponse_Result) ProtoMessage()    {}
+func (*ImportResponse_Result) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{12, 1}
+}
+
+func (m *ImportResponse_Result) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportResponse_Result.Unmarshal(m, b)
+}
+func (m *ImportResponse_Result) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportResponse_Result.Marshal(b, m, deterministic)
+}
+func (m *ImportResponse_Result) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportResponse_Result.Merge(m, src)
+}
+func (m *ImportResponse_Result) XXX_Size() int {
+	return xxx_messageInfo_ImportResponse_Result.Size(m)
+}
+func (m *ImportResponse_Result) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportResponse_Result.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportResponse_Result proto.InternalMessageInfo
+
+func (m *ImportResponse_Result) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+func (m *ImportResponse_Result) GetCreated() uint32 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *ImportResponse_Result) GetUpdated() uint32 {
+	if m != nil {
+		return m.Updated
+	}
+	return 0
+}
+
+func (m *ImportResponse_Result) GetUnchanged() uint32 {
+	if m != nil {
+		return m.Unchanged
+	}
+	return 0
+}
+
+func (m *ImportResponse_Result) GetDeleted() uint32 {
+	if m != nil {
+		return m.Deleted
+	}
+	return 0
+}
+
+func (m *ImportResponse_Result) GetChanges() []*ImportResponse_Change {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+// APIKey describes an API key, its secret is only returned by CreateAPIKey
+type APIKey struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	OrgCodes             []string `protobuf:"bytes,3,rep,name=orgCodes,proto3" json:"orgCodes,omitempty"`
+	Roles                []string `protobuf:"bytes,4,rep,name=roles,proto3" json:"roles,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,5,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	ExpiresAt            int64    `protobuf:"varint,6,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	RevokedAt            int64    `protobuf:"varint,7,opt,name=revokedAt,proto3" json:"revokedAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *APIKey) Reset()         { *m = APIKey{} }
+func (m *APIKey) String() string { return proto.CompactTextString(m) }
+func (*APIKey) ProtoMessage()    {}
+func (*APIKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{13}
+}
+
+func (m *APIKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_APIKey.Unmarshal(m, b)
+}
+func (m *APIKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_APIKey.Marshal(b, m, deterministic)
+}
+func (m *APIKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_APIKey.Merge(m, src)
+}
+func (m *APIKey) XXX_Size() int {
+	return xxx_messageInfo_APIKey.Size(m)
+}
+func (m *APIKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_APIKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_APIKey proto.InternalMessageInfo
+
+func (m *APIKey) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *APIKey) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *APIKey) GetOrgCodes() []string {
+	if m != nil {
+		return m.OrgCodes
+	}
+	return nil
+}
+
+func (m *APIKey) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+func (m *APIKey) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *APIKey) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *APIKey) GetRevokedAt() int64 {
+	if m != nil {
+		return m.RevokedAt
+	}
+	return 0
+}
+
+type CreateAPIKeyRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	OrgCodes             []string `protobuf:"bytes,2,rep,name=orgCodes,proto3" json:"orgCodes,omitempty"`
+	Roles                []string `protobuf:"bytes,3,rep,name=roles,proto3" json:"roles,omitempty"`
+	ExpiresAt            int64    `protobuf:"varint,4,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateAPIKeyRequest) Reset()         { *m = CreateAPIKeyRequest{} }
+func (m *CreateAPIKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateAPIKeyRequest) ProtoMessage()    {}
+func (*CreateAPIKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{14}
+}
+
+func (m *CreateAPIKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateAPIKeyRequest.Unmarshal(m, b)
+}
+func (m *CreateAPIKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateAPIKeyRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateAPIKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateAPIKeyRequest.Merge(m, src)
+}
+func (m *CreateAPIKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateAPIKeyRequest.Size(m)
+}
+func (m *CreateAPIKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateAPIKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateAPIKeyRequest proto.InternalMessageInfo
+
+func (m *CreateAPIKeyRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CreateAPIKeyRequest) GetOrgCodes() []string {
+	if m != nil {
+		return m.OrgCodes
+	}
+	return nil
+}
+
+func (m *CreateAPIKeyRequest) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+func (m *CreateAPIKeyRequest) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+type CreateAPIKeyResponse struct {
+	Ok                   bool                         `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	ErrorCode            int32                        `protobuf:"varint,2,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
+	ErrorMessage         string                       `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	ErrorReason          string                       `protobuf:"bytes,5,opt,name=errorReason,proto3" json:"errorReason,omitempty"`
+	Violations           []*Violation                 `protobuf:"bytes,6,rep,name=violations,proto3" json:"violations,omitempty"`
+	Result               *CreateAPIKeyResponse_Result `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
+	XXX_unrecognized     []byte                       `json:"-"`
+	XXX_sizecache        int32                        `json:"-"`
+}
+
+func (m *CreateAPIKeyResponse) Reset()         { *m = CreateAPIKeyResponse{} }
+func (m *CreateAPIKeyResponse) String() string { return proto.CompactTextString(m) }
+func (*CreateAPIKeyResponse) ProtoMessage()    {}
+func (*CreateAPIKeyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{15}
+}
+
+func (m *CreateAPIKeyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateAPIKeyResponse.Unmarshal(m, b)
+}
+func (m *CreateAPIKeyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateAPIKeyResponse.Marshal(b, m, deterministic)
+}
+func (m *CreateAPIKeyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateAPIKeyResponse.Merge(m, src)
+}
+func (m *CreateAPIKeyResponse) XXX_Size() int {
+	return xxx_messageInfo_CreateAPIKeyResponse.Size(m)
+}
+func (m *CreateAPIKeyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateAPIKeyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateAPIKeyResponse proto.InternalMessageInfo
+
+func (m *CreateAPIKeyResponse) GetOk() bool {
+	if m != nil {
+		return m.Ok
+	}
+	return false
+}
+
+func (m *CreateAPIKeyResponse) GetErrorCode() int32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *CreateAPIKeyResponse) GetErrorMessage() string {
+	if m != nil {
+		return m.ErrorMessage
+	}
+	return ""
+}
+
+func (m *CreateAPIKeyResponse) GetErrorReason() string {
+	if m != nil {
+		return m.ErrorReason
+	}
+	return ""
+}
+
+func (m *CreateAPIKeyResponse) GetViolations() []*Violation {
+	if m != nil {
+		return m.Violations
+	}
+	return nil
+}
+
+func (m *CreateAPIKeyResponse) GetResult() *CreateAPIKeyResponse_Result {
+	if m != nil {
+		return m.Result
+	}
+	return nil
+}
+
+type CreateAPIKeyResponse_Result struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	ApiKey               *APIKey  `protobuf:"bytes,2,opt,name=apiKey,proto3" json:"apiKey,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateAPIKeyResponse_Result) Reset()         { *m = CreateAPIKeyResponse_Result{} }
+func (m *CreateAPIKeyResponse_Result) String() string { return proto.CompactTextString(m) }
+func (*CreateAPIKeyResponse_Result) ProtoMessage()    {}
+func (*CreateAPIKeyResponse_Result) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{15, 0}
+}
+
+func (m *CreateAPIKeyResponse_Result) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateAPIKeyResponse_Result.Unmarshal(m, b)
+}
+func (m *CreateAPIKeyResponse_Result) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateAPIKeyResponse_Result.Marshal(b, m, deterministic)
+}
+func (m *CreateAPIKeyResponse_Result) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateAPIKeyResponse_Result.Merge(m, src)
+}
+func (m *CreateAPIKeyResponse_Result) XXX_Size() int {
+	return xxx_messageInfo_CreateAPIKeyResponse_Result.Size(m)
+}
+func (m *CreateAPIKeyResponse_Result) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateAPIKeyResponse_Result.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateAPIKeyResponse_Result proto.InternalMessageInfo
+
+func (m *CreateAPIKeyResponse_Result) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *CreateAPIKeyResponse_Result) GetApiKey() *APIKey {
+	if m != nil {
+		return m.ApiKey
+	}
+	return nil
+}
+
+type ListAPIKeysRequest struct {
+	OrgCode              string   `protobuf:"bytes,1,opt,name=orgCode,proto3" json:"orgCode,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListAPIKeysRequest) Reset()         { *m = ListAPIKeysRequest{} }
+func (m *ListAPIKeysRequest) String() string { return proto.CompactTextString(m) }
+func (*ListAPIKeysRequest) ProtoMessage()    {}
+func (*ListAPIKeysRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{16}
+}
+
+func (m *ListAPIKeysRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAPIKeysRequest.Unmarshal(m, b)
+}
+func (m *ListAPIKeysRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAPIKeysRequest.Marshal(b, m, deterministic)
+}
+func (m *ListAPIKeysRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAPIKeysRequest.Merge(m, src)
+}
+func (m *ListAPIKeysRequest) XXX_Size() int {
+	return xxx_messageInfo_ListAPIKeysRequest.Size(m)
+}
+func (m *ListAPIKeysRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAPIKeysRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAPIKeysRequest proto.InternalMessageInfo
+
+func (m *ListAPIKeysRequest) GetOrgCode() string {
+	if m != nil {
+		return m.OrgCode
+	}
+	return ""
+}
+
+type ListAPIKeysResponse struct {
+	Ok                   bool                        `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	ErrorCode            int32                       `protobuf:"varint,2,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
+	ErrorMessage         string                      `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	ErrorReason          string                      `protobuf:"bytes,5,opt,name=errorReason,proto3" json:"errorReason,omitempty"`
+	Violations           []*Violation                `protobuf:"bytes,6,rep,name=violations,proto3" json:"violations,omitempty"`
+	Result               *ListAPIKeysResponse_Result `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *ListAPIKeysResponse) Reset()         { *m = ListAPIKeysResponse{} }
+func (m *ListAPIKeysResponse) String() string { return proto.CompactTextString(m) }
+func (*ListAPIKeysResponse) ProtoMessage()    {}
+func (*ListAPIKeysResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{17}
+}
+
+func (m *ListAPIKeysResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAPIKeysResponse.Unmarshal(m, b)
+}
+func (m *ListAPIKeysResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAPIKeysResponse.Marshal(b, m, deterministic)
+}
+func (m *ListAPIKeysResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAPIKeysResponse.Merge(m, src)
+}
+func (m *ListAPIKeysResponse) XXX_Size() int {
+	return xxx_messageInfo_ListAPIKeysResponse.Size(m)
+}
+func (m *ListAPIKeysResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAPIKeysResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAPIKeysResponse proto.InternalMessageInfo
+
+func (m *ListAPIKeysResponse) GetOk() bool {
+	if m != nil {
+		return m.Ok
+	}
+	return false
+}
+
+func (m *ListAPIKeysResponse) GetErrorCode() int32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *ListAPIKeysResponse) GetErrorMessage() string {
+	if m != nil {
+		return m.ErrorMessage
+	}
+	return ""
+}
+
+func (m *ListAPIKeysResponse) GetErrorReason() string {
+	if m != nil {
+		return m.ErrorReason
+	}
+	return ""
+}
+
+func (m *ListAPIKeysResponse) GetViolations() []*Violation {
+	if m != nil {
+		return m.Violations
+	}
+	return nil
+}
+
+func (m *ListAPIKeysResponse) GetResult() *ListAPIKeysResponse_Result {
+	if m != nil {
+		return m.Result
+	}
+	return nil
+}
+
+type ListAPIKeysResponse_Result struct {
+	ApiKeys              []*APIKey `protobuf:"bytes,1,rep,name=apiKeys,proto3" json:"apiKeys,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *ListAPIKeysResponse_Result) Reset()         { *m = ListAPIKeysResponse_Result{} }
+func (m *ListAPIKeysResponse_Result) String() string { return proto.CompactTextString(m) }
+func (*ListAPIKeysResponse_Result) ProtoMessage()    {}
+func (*ListAPIKeysResponse_Result) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{17, 0}
+}
+
+func (m *ListAPIKeysResponse_Result) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAPIKeysResponse_Result.Unmarshal(m, b)
+}
+func (m *ListAPIKeysResponse_Result) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAPIKeysResponse_Result.Marshal(b, m, deterministic)
+}
+func (m *ListAPIKeysResponse_Result) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAPIKeysResponse_Result.Merge(m, src)
+}
+func (m *ListAPIKeysResponse_Result) XXX_Size() int {
+	return xxx_messageInfo_ListAPIKeysResponse_Result.Size(m)
+}
+func (m *ListAPIKeysResponse_Result) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAPIKeysResponse_Result.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAPIKeysResponse_Result proto.InternalMessageInfo
+
+func (m *ListAPIKeysResponse_Result) GetApiKeys() []*APIKey {
+	if m != nil {
+		return m.ApiKeys
+	}
+	return nil
+}
+
+type RevokeAPIKeyRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeAPIKeyRequest) Reset()         { *m = RevokeAPIKeyRequest{} }
+func (m *RevokeAPIKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*RevokeAPIKeyRequest) ProtoMessage()    {}
+func (*RevokeAPIKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{18}
+}
+
+func (m *RevokeAPIKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeAPIKeyRequest.Unmarshal(m, b)
+}
+func (m *RevokeAPIKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeAPIKeyRequest.Marshal(b, m, deterministic)
+}
+func (m *RevokeAPIKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeAPIKeyRequest.Merge(m, src)
+}
+func (m *RevokeAPIKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_RevokeAPIKeyRequest.Size(m)
+}
+func (m *RevokeAPIKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeAPIKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeAPIKeyRequest proto.InternalMessageInfo
+
+func (m *RevokeAPIKeyRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type RevokeAPIKeyResponse struct {
+	Ok                   bool                         `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	ErrorCode            int32                        `protobuf:"varint,2,opt,name=errorCode,proto3" json:"errorCode,omitempty"`
+	ErrorMessage         string                       `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	ErrorReason          string                       `protobuf:"bytes,5,opt,name=errorReason,proto3" json:"errorReason,omitempty"`
+	Violations           []*Violation                 `protobuf:"bytes,6,rep,name=violations,proto3" json:"violations,omitempty"`
+	Result               *RevokeAPIKeyResponse_Result `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
+	XXX_unrecognized     []byte                       `json:"-"`
+	XXX_sizecache        int32                        `json:"-"`
+}
+
+func (m *RevokeAPIKeyResponse) Reset()         { *m = RevokeAPIKeyResponse{} }
+func (m *RevokeAPIKeyResponse) String() string { return proto.CompactTextString(m) }
+func (*RevokeAPIKeyResponse) ProtoMessage()    {}
+func (*RevokeAPIKeyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{19}
+}
+
+func (m *RevokeAPIKeyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeAPIKeyResponse.Unmarshal(m, b)
+}
+func (m *RevokeAPIKeyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeAPIKeyResponse.Marshal(b, m, deterministic)
+}
+func (m *RevokeAPIKeyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeAPIKeyResponse.Merge(m, src)
+}
+func (m *RevokeAPIKeyResponse) XXX_Size() int {
+	return xxx_messageInfo_RevokeAPIKeyResponse.Size(m)
+}
+func (m *RevokeAPIKeyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeAPIKeyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeAPIKeyResponse proto.InternalMessageInfo
+
+func (m *RevokeAPIKeyResponse) GetOk() bool {
+	if m != nil {
+		return m.Ok
+	}
+	return false
+}
+
+func (m *RevokeAPIKeyResponse) GetErrorCode() int32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *RevokeAPIKeyResponse) GetErrorMessage() string {
+	if m != nil {
+		return m.ErrorMessage
+	}
+	return ""
+}
+
+func (m *RevokeAPIKeyResponse) GetErrorReason() string {
+	if m != nil {
+		return m.ErrorReason
+	}
+	return ""
+}
+
+func (m *RevokeAPIKeyResponse) GetViolations() []*Violation {
+	if m != nil {
+		return m.Violations
+	}
+	return nil
+}
+
+func (m *RevokeAPIKeyResponse) GetResult() *RevokeAPIKeyResponse_Result {
+	if m != nil {
+		return m.Result
+	}
+	return nil
+}
+
+type RevokeAPIKeyResponse_Result struct {
+	ApiKey               *APIKey  `protobuf:"bytes,1,opt,name=apiKey,proto3" json:"apiKey,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeAPIKeyResponse_Result) Reset()         { *m = RevokeAPIKeyResponse_Result{} }
+func (m *RevokeAPIKeyResponse_Result) String() string { return proto.CompactTextString(m) }
+func (*RevokeAPIKeyResponse_Result) ProtoMessage()    {}
+func (*RevokeAPIKeyResponse_Result) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fb7cc0a8d5129ab9, []int{19, 0}
+}
+
+func (m *RevokeAPIKeyResponse_Result) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeAPIKeyResponse_Result.Unmarshal(m, b)
+}
+func (m *RevokeAPIKeyResponse_Result) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeAPIKeyResponse_Result.Marshal(b, m, deterministic)
+}
+func (m *RevokeAPIKeyResponse_Result) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeAPIKeyResponse_Result.Merge(m, src)
+}
+func (m *RevokeAPIKeyResponse_Result) XXX_Size() int {
+	return xxx_messageInfo_RevokeAPIKeyResponse_Result.Size(m)
+}
+func (m *RevokeAPIKeyResponse_Result) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeAPIKeyResponse_Result.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeAPIKeyResponse_Result proto.InternalMessageInfo
+
+func (m *RevokeAPIKeyResponse_Result) GetApiKey() *APIKey {
+	if m != nil {
+		return m.ApiKey
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("docnogen.ImportMode", ImportMode_name, ImportMode_value)
+	proto.RegisterType((*Violation)(nil), "docnogen.Violation")
+	proto.RegisterType((*GenerateBulkDocNoFormatRequest)(nil), "docnogen.GenerateBulkDocNoFormatRequest")
+	proto.RegisterMapType((map[string]string)(nil), "docnogen.GenerateBulkDocNoFormatRequest.VariableMapEntry")
+	proto.RegisterType((*GenerateBulkDocNoFormatResponse)(nil), "docnogen.GenerateBulkDocNoFormatResponse")
+	proto.RegisterType((*GenerateBulkDocNoFormatResponse_Result)(nil), "docnogen.GenerateBulkDocNoFormatResponse.Result")
+	proto.RegisterType((*GenerateDocNoFormatRequest)(nil), "docnogen.GenerateDocNoFormatRequest")
+	proto.RegisterMapType((map[string]string)(nil), "docnogen.GenerateDocNoFormatRequest.VariableMapEntry")
+	proto.RegisterType((*GenerateDocNoFormatResponse)(nil), "docnogen.GenerateDocNoFormatResponse")
+	proto.RegisterType((*GenerateDocNoFormatResponse_Result)(nil), "docnogen.GenerateDocNoFormatResponse.Result")
+	proto.RegisterType((*GetNextDocNoRequest)(nil), "docnogen.GetNextDocNoRequest")
+	proto.RegisterMapType((map[string]string)(nil), "docnogen.GetNextDocNoRequest.VariableMapEntry")
+	proto.RegisterType((*GetNextDocNoResponse)(nil), "docnogen.GetNextDocNoResponse")
+	proto.RegisterType((*GetNextDocNoResponse_Result)(nil), "docnogen.GetNextDocNoResponse.Result")
+	proto.RegisterType((*ConsumeDocNoRequest)(nil), "docnogen.ConsumeDocNoRequest")
+	proto.RegisterType((*ConsumeDocNoResponse)(nil), "docnogen.ConsumeDocNoResponse")
+	proto.RegisterType((*ConsumeDocNoResponse_Result)(nil), "docnogen.ConsumeDocNoResponse.Result")
+	proto.RegisterType((*ExportRequest)(nil), "docnogen.ExportRequest")
+	proto.RegisterType((*ExportResponse)(nil), "docnogen.ExportResponse")
+	proto.RegisterType((*ExportResponse_Result)(nil), "docnogen.ExportResponse.Result")
+	proto.RegisterType((*ImportRequest)(nil), "docnogen.ImportRequest")
+	proto.RegisterType((*ImportResponse)(nil), "docnogen.ImportResponse")
+	proto.RegisterType((*ImportResponse_Change)(nil), "docnogen.ImportResponse.Change")
+	proto.RegisterType((*ImportResponse_Result)(nil), "docnogen.ImportResponse.Result")
+	proto.RegisterType((*APIKey)(nil), "docnogen.APIKey")
+	proto.RegisterType((*CreateAPIKeyRequest)(nil), "docnogen.CreateAPIKeyRequest")
+	proto.RegisterType((*CreateAPIKeyResponse)(nil), "docnogen.CreateAPIKeyResponse")
+	proto.RegisterType((*CreateAPIKeyResponse_Result)(nil), "docnogen.CreateAPIKeyResponse.Result")
+	proto.RegisterType((*ListAPIKeysRequest)(nil), "docnogen.ListAPIKeysRequest")
+	proto.RegisterType((*ListAPIKeysResponse)(nil), "docnogen.ListAPIKeysResponse")
+	proto.RegisterType((*ListAPIKeysResponse_Result)(nil), "docnogen.ListAPIKeysResponse.Result")
+	proto.RegisterType((*RevokeAPIKeyRequest)(nil), "docnogen.RevokeAPIKeyRequest")
+	proto.RegisterType((*RevokeAPIKeyResponse)(nil), "docnogen.RevokeAPIKeyResponse")
+	proto.RegisterType((*RevokeAPIKeyResponse_Result)(nil), "docnogen.RevokeAPIKeyResponse.Result")
+}
+
+func init() { proto.RegisterFile("docnogen.proto", fileDescriptor_fb7cc0a8d5129ab9) }
+
+var fileDescriptor_fb7cc0a8d5129ab9 = []byte{
+	// 1330 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xdc, 0x59, 0x4f, 0x6f, 0xdc, 0x44,
+	0x14, 0xaf, 0xed, 0xfd, 0x93, 0x7d, 0x69, 0xd2, 0x68, 0x36, 0x6a, 0xad, 0xa5, 0x4d, 0x23, 0xab,
+	0x45, 0x4b, 0x55, 0x45, 0x28, 0x05, 0x41, 0x11, 0x45, 0x4a, 0xd3, 0x25, 0x5a, 0xda, 0xa4, 0xd5,
+	0xb4, 0x6a, 0x91, 0x38, 0x39, 0xf6, 0x34, 0xb5, 0x76, 0xed, 0x71, 0xc7, 0xf6, 0x2a, 0xfb, 0x2d,
+	0x40, 0x7c, 0x00, 0xc4, 0x91, 0x03, 0x17, 0x4e, 0x5c, 0x91, 0x90, 0x10, 0x12, 0x9f, 0x85, 0x2b,
+	0xe2, 0x86, 0x66, 0xc6, 0xe3, 0x7f, 0x59, 0x6f, 0x17, 0x41, 0xa4, 0x26, 0xa7, 0xec, 0x7b, 0xcf,
+	0xf3, 0xe6, 0xfd, 0x7e, 0xef, 0x37, 0x33, 0x1e, 0x07, 0x56, 0x5d, 0xea, 0x04, 0xf4, 0x88, 0x04,
+	0x5b, 0x21, 0xa3, 0x31, 0x45, 0x4b, 0xca, 0xb6, 0x76, 0xa1, 0xf3, 0xdc, 0xa3, 0x63, 0x3b, 0xf6,
+	0x68, 0x80, 0xd6, 0xa1, 0xf9, 0xd2, 0x23, 0x63, 0xd7, 0xd4, 0x36, 0xb5, 0x7e, 0x07, 0x4b, 0x03,
+	0x6d, 0xc2, 0xb2, 0x4b, 0x22, 0x87, 0x79, 0x21, 0x7f, 0xc8, 0xd4, 0x45, 0xac, 0xe8, 0xb2, 0x7e,
+	0xd7, 0x61, 0x63, 0x8f, 0x04, 0x84, 0xd9, 0x31, 0xb9, 0x9f, 0x8c, 0x47, 0x0f, 0xa8, 0x73, 0x40,
+	0x3f, 0xa7, 0xcc, 0xb7, 0x63, 0x4c, 0x5e, 0x27, 0x24, 0x8a, 0x91, 0x09, 0x6d, 0x97, 0x3a, 0xbb,
+	0xd4, 0x25, 0x69, 0x72, 0x65, 0xf2, 0x08, 0x65, 0x47, 0x22, 0x22, 0x53, 0x2b, 0x13, 0x21, 0x68,
+	0x84, 0x76, 0xfc, 0xca, 0x34, 0x84, 0x5b, 0xfc, 0x46, 0x5f, 0xc1, 0xf2, 0xc4, 0x66, 0x9e, 0x7d,
+	0x38, 0x26, 0xfb, 0x76, 0x68, 0x36, 0x36, 0x8d, 0xfe, 0xf2, 0xf6, 0xdd, 0xad, 0x0c, 0xdf, 0xfc,
+	0x32, 0xb6, 0x9e, 0xe7, 0x63, 0x07, 0x41, 0xcc, 0xa6, 0xb8, 0x98, 0x0d, 0x6d, 0x00, 0x1c, 0x26,
+	0xe3, 0xd1, 0x41, 0xe2, 0x1f, 0x12, 0x66, 0x36, 0x37, 0xb5, 0xfe, 0x0a, 0x2e, 0x78, 0x90, 0x05,
+	0x17, 0x9d, 0x24, 0x8a, 0xa9, 0x2f, 0x93, 0x9a, 0x2d, 0x51, 0x58, 0xc9, 0xd7, 0xfb, 0x0c, 0xd6,
+	0xaa, 0x93, 0xa0, 0x35, 0x30, 0x46, 0x64, 0x9a, 0x02, 0xe7, 0x3f, 0x39, 0xd3, 0x13, 0x7b, 0x9c,
+	0x28, 0xc8, 0xd2, 0xf8, 0x44, 0xff, 0x58, 0xb3, 0x7e, 0x30, 0xe0, 0x7a, 0x2d, 0x88, 0x28, 0xa4,
+	0x41, 0x44, 0xd0, 0x2a, 0xe8, 0x74, 0x24, 0xd2, 0x2d, 0x61, 0x9d, 0x8e, 0xd0, 0x55, 0xe8, 0x10,
+	0xc6, 0x28, 0xcb, 0x48, 0x6c, 0xe2, 0xdc, 0xc1, 0xab, 0x16, 0xc6, 0x3e, 0x89, 0x22, 0xfb, 0x88,
+	0xa4, 0x74, 0x96, 0x7c, 0xbc, 0xc7, 0xc2, 0xc6, 0xc4, 0x8e, 0x68, 0x20, 0xa0, 0x77, 0x70, 0xd1,
+	0x85, 0xee, 0x00, 0x4c, 0x94, 0x50, 0x22, 0xb3, 0x25, 0x78, 0xef, 0xe6, 0xbc, 0x67, 0x22, 0xc2,
+	0x85, 0xc7, 0xd0, 0x17, 0xd0, 0x66, 0x24, 0x4a, 0xc6, 0x71, 0x94, 0x76, 0xea, 0xfd, 0x05, 0x3a,
+	0x25, 0x41, 0x6e, 0x61, 0x31, 0x10, 0xab, 0x04, 0xbd, 0xaf, 0x35, 0x68, 0x49, 0x9f, 0x50, 0x24,
+	0x1f, 0xf1, 0x34, 0x66, 0x5e, 0x70, 0x94, 0xf2, 0x5a, 0x74, 0x71, 0x46, 0x02, 0x72, 0x1c, 0x3f,
+	0x25, 0xaf, 0x0f, 0xa8, 0x60, 0x64, 0x05, 0xe7, 0x0e, 0x74, 0x1b, 0x2e, 0x31, 0xe2, 0x50, 0xe6,
+	0x3e, 0xf3, 0x7c, 0x12, 0xc5, 0xb6, 0x1f, 0x0a, 0x52, 0x8c, 0xfb, 0xba, 0xa9, 0xe1, 0x6a, 0x88,
+	0x0b, 0x74, 0x42, 0x58, 0xc4, 0xb5, 0xdf, 0xe0, 0x4f, 0x61, 0x65, 0x5a, 0xdf, 0xeb, 0xd0, 0x53,
+	0x30, 0x4e, 0x51, 0xf3, 0x2f, 0x66, 0x69, 0xfe, 0xc3, 0x93, 0x4c, 0xfe, 0x6b, 0xbd, 0x57, 0xf5,
+	0xdc, 0x3c, 0x05, 0x3d, 0x7f, 0x67, 0xc0, 0x3b, 0x33, 0x0b, 0x3c, 0x6b, 0x5a, 0x7e, 0x00, 0x2d,
+	0x29, 0x45, 0xa1, 0x82, 0xe5, 0xed, 0xdb, 0x6f, 0x68, 0x40, 0x59, 0xc6, 0xe9, 0xd8, 0xb7, 0x51,
+	0xc5, 0xdf, 0xea, 0xd0, 0xdd, 0x23, 0xf1, 0x01, 0x39, 0x8e, 0x05, 0x80, 0xff, 0x5b, 0xbe, 0x4f,
+	0x66, 0xc9, 0x77, 0xab, 0xc8, 0xde, 0x89, 0xb9, 0xdf, 0x02, 0xdd, 0x7e, 0x63, 0xc0, 0x7a, 0xb9,
+	0xb2, 0xb3, 0x26, 0xd8, 0x7b, 0x15, 0xc1, 0xde, 0xac, 0xa3, 0xfc, 0xcc, 0x28, 0xf5, 0x17, 0x0d,
+	0xba, 0xbb, 0x34, 0x88, 0x12, 0x9f, 0x9c, 0x8a, 0x52, 0x7b, 0xb0, 0xe4, 0x24, 0x4c, 0x82, 0x68,
+	0x08, 0x10, 0x99, 0x3d, 0x0b, 0x43, 0x73, 0x21, 0x0c, 0xad, 0x32, 0x86, 0x3f, 0x75, 0x58, 0x2f,
+	0x63, 0x38, 0x47, 0xba, 0x9a, 0x05, 0xac, 0xaa, 0xab, 0x20, 0x93, 0x55, 0x49, 0x34, 0xda, 0x02,
+	0xa2, 0xd1, 0x17, 0x22, 0xdc, 0x28, 0x13, 0xbe, 0x03, 0x2b, 0x83, 0xe3, 0x90, 0xb2, 0xe2, 0xb1,
+	0xac, 0x34, 0xa1, 0x95, 0x35, 0x71, 0x19, 0x5a, 0x2f, 0xe5, 0x8e, 0x22, 0xc5, 0x92, 0x5a, 0xd6,
+	0x1f, 0x3a, 0xac, 0xaa, 0x1c, 0x67, 0xad, 0x5b, 0x1f, 0x55, 0xba, 0x75, 0x3d, 0x1f, 0x50, 0x86,
+	0x54, 0xed, 0xd3, 0x97, 0x59, 0x9f, 0x72, 0x5a, 0xb4, 0x22, 0x2d, 0x7c, 0x09, 0xb9, 0x76, 0x6c,
+	0xa7, 0x64, 0x89, 0xdf, 0x62, 0x6b, 0xa6, 0x49, 0x10, 0x13, 0xb6, 0xcb, 0xff, 0x08, 0xa4, 0x2b,
+	0xb8, 0xe4, 0xb3, 0x7e, 0xd4, 0x60, 0x65, 0xe8, 0xff, 0xa7, 0x96, 0x64, 0x73, 0x1b, 0x85, 0xb9,
+	0xfb, 0xd0, 0xf0, 0x79, 0x0a, 0x0e, 0x74, 0x75, 0x7b, 0x3d, 0x07, 0x2a, 0x27, 0xdb, 0xa7, 0x2e,
+	0xc1, 0x0d, 0x3f, 0xcd, 0xea, 0xb2, 0x29, 0x4e, 0x24, 0xcd, 0x4b, 0x38, 0xb5, 0xc4, 0x05, 0x88,
+	0x32, 0x87, 0x88, 0x45, 0xbb, 0x84, 0xa5, 0x61, 0xfd, 0xd5, 0x80, 0xd5, 0xa1, 0x5f, 0xe4, 0xea,
+	0x5c, 0xb4, 0x7f, 0xe8, 0xcf, 0x6b, 0xff, 0xcf, 0x1a, 0xb4, 0x76, 0x5f, 0xd9, 0xc1, 0x11, 0x99,
+	0xb3, 0xbd, 0xaa, 0x4d, 0x54, 0x2f, 0x6c, 0xa2, 0x97, 0xa1, 0x65, 0x3b, 0xb1, 0x5a, 0x88, 0x1d,
+	0x9c, 0x5a, 0x9c, 0xa2, 0x97, 0x8c, 0xfa, 0xf9, 0xee, 0x6a, 0xe0, 0xdc, 0xc1, 0xe7, 0x88, 0xa9,
+	0x8c, 0x35, 0xe5, 0xfa, 0x8d, 0x69, 0x16, 0xb1, 0xc3, 0x70, 0xec, 0x11, 0x37, 0xed, 0x8a, 0x32,
+	0x85, 0x36, 0x6c, 0x6f, 0x4c, 0x5c, 0xb3, 0x2d, 0xbb, 0x28, 0xad, 0xde, 0x6f, 0x5a, 0x51, 0xba,
+	0x69, 0xa3, 0xb5, 0x52, 0xa3, 0x4d, 0x68, 0x3b, 0x8c, 0xd8, 0x31, 0x71, 0xd3, 0xd3, 0x4a, 0x99,
+	0x3c, 0x92, 0x84, 0xae, 0x88, 0x48, 0xed, 0x2a, 0x93, 0x03, 0x48, 0x02, 0x47, 0x50, 0xe2, 0xa6,
+	0xc7, 0x43, 0xee, 0x10, 0x24, 0x91, 0x31, 0xe1, 0xe3, 0xe4, 0xc5, 0x51, 0x99, 0xe8, 0x2e, 0xb4,
+	0xe5, 0x43, 0xaa, 0x69, 0xf5, 0x3d, 0x90, 0x84, 0x63, 0xf5, 0xbc, 0xc5, 0x9b, 0xb0, 0xf3, 0x64,
+	0xf8, 0x90, 0x4c, 0xb9, 0xe2, 0x3c, 0x75, 0x31, 0xd7, 0x3d, 0x97, 0x53, 0x1f, 0xd8, 0xbe, 0x3a,
+	0xd6, 0xc4, 0x6f, 0x7e, 0x7e, 0xa5, 0xeb, 0x26, 0x32, 0x8d, 0x4d, 0xa3, 0xdf, 0xc1, 0x99, 0xcd,
+	0xa5, 0xcd, 0xe8, 0x98, 0xc8, 0x8b, 0x58, 0x07, 0x4b, 0x83, 0x63, 0x4a, 0x81, 0xef, 0xc4, 0x29,
+	0xf1, 0xb9, 0x83, 0x47, 0xc9, 0x71, 0xe8, 0x31, 0x12, 0xed, 0xc4, 0xe9, 0x39, 0x96, 0x3b, 0x78,
+	0x94, 0x91, 0x09, 0x1d, 0x89, 0xb1, 0x6d, 0x19, 0xcd, 0x1c, 0xd6, 0x14, 0xba, 0xbb, 0x22, 0x91,
+	0xac, 0x5f, 0xad, 0x74, 0x55, 0xb6, 0x56, 0x53, 0xb6, 0x5e, 0x57, 0xb6, 0x51, 0x29, 0x3b, 0x2f,
+	0xac, 0x51, 0x29, 0xcc, 0xfa, 0x95, 0x1f, 0xb1, 0xa5, 0xb9, 0xcf, 0xd3, 0x11, 0x3b, 0x03, 0x58,
+	0x75, 0xed, 0x3e, 0xc8, 0xf4, 0x7f, 0xf2, 0x8d, 0xb7, 0x0f, 0x2d, 0x3b, 0xf4, 0x1e, 0x92, 0xa9,
+	0x00, 0xbc, 0xbc, 0xbd, 0x96, 0xa7, 0x4e, 0x93, 0xa6, 0x71, 0x6b, 0x0b, 0xd0, 0x23, 0x2f, 0x8a,
+	0xa5, 0x37, 0x7a, 0xe3, 0x56, 0x6d, 0xfd, 0xa4, 0x43, 0xb7, 0x34, 0xe0, 0xac, 0xb1, 0xfe, 0x69,
+	0x85, 0xf5, 0x1b, 0xf9, 0x80, 0x19, 0xb8, 0xaa, 0xa4, 0x7f, 0x90, 0x91, 0x7e, 0x0b, 0xda, 0x92,
+	0xc2, 0xc8, 0xd4, 0x36, 0x8d, 0x99, 0x1c, 0xab, 0x07, 0xac, 0x9b, 0xd0, 0xc5, 0x62, 0xcd, 0x94,
+	0x97, 0x49, 0x65, 0xb5, 0x73, 0x6e, 0xd7, 0xcb, 0xcf, 0x9d, 0x23, 0x49, 0xcf, 0x02, 0x56, 0x65,
+	0x77, 0x3b, 0x63, 0x37, 0x17, 0xb0, 0x36, 0x5f, 0xc0, 0xb7, 0xde, 0x05, 0xc8, 0x4f, 0x7e, 0xd4,
+	0x81, 0xe6, 0xfe, 0x00, 0xef, 0x0d, 0xd6, 0x2e, 0xa0, 0x15, 0xe8, 0x3c, 0x7e, 0x3e, 0xc0, 0x2f,
+	0xf0, 0xf0, 0xd9, 0x60, 0x4d, 0xdb, 0xfe, 0xbb, 0x09, 0x97, 0xc4, 0x2b, 0xeb, 0x1e, 0x09, 0x9e,
+	0x12, 0x36, 0xf1, 0x1c, 0x82, 0x42, 0xb8, 0x52, 0xf3, 0x81, 0x0a, 0xf5, 0x17, 0xfd, 0xda, 0xd8,
+	0x7b, 0x6f, 0xe1, 0xaf, 0x5d, 0xd6, 0x05, 0xe4, 0x42, 0x57, 0x3d, 0x54, 0x9c, 0xed, 0xc6, 0x22,
+	0xdf, 0x79, 0x7a, 0x37, 0x17, 0xfa, 0x18, 0x61, 0x5d, 0x40, 0x8f, 0xe1, 0x62, 0xf1, 0xf2, 0x87,
+	0xae, 0xcd, 0xbd, 0x87, 0xf7, 0x36, 0xe6, 0xdf, 0x19, 0x65, 0xc2, 0xe2, 0x5b, 0x7f, 0x31, 0xe1,
+	0x8c, 0xab, 0x5a, 0x6f, 0xa3, 0x2e, 0x9c, 0x25, 0xbc, 0x07, 0x2d, 0xf9, 0x62, 0x8a, 0xae, 0x9c,
+	0x7c, 0x55, 0x95, 0x49, 0xcc, 0xba, 0x77, 0x58, 0x39, 0x7c, 0xe8, 0x57, 0x87, 0x0f, 0xfd, 0x9a,
+	0xe1, 0xe5, 0xf3, 0x37, 0x85, 0x53, 0xd8, 0x61, 0x4b, 0x70, 0x4e, 0x1e, 0x67, 0xbd, 0x8d, 0xba,
+	0x70, 0x96, 0xf0, 0x11, 0x2c, 0x17, 0x36, 0x0f, 0x74, 0xb5, 0x66, 0x4f, 0x91, 0xe9, 0xae, 0xcd,
+	0xdd, 0x71, 0x64, 0x79, 0xc5, 0xd5, 0x52, 0x2c, 0x6f, 0xc6, 0x36, 0xd2, 0xdb, 0xa8, 0x0b, 0xab,
+	0x84, 0x87, 0x2d, 0xf1, 0x0f, 0x81, 0x3b, 0xff, 0x0c, 0x00, 0xe5, 0x73, 0x30, 0x29, 0x22, 0x18,
+	0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// DocNoGenServiceClient is the client API for DocNoGenService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type DocNoGenServiceClient interface {
+	GenerateBulkDocNoFormat(ctx context.Context, in *GenerateBulkDocNoFormatRequest, opts ...grpc.CallOption) (*GenerateBulkDocNoFormatResponse, error)
+	GenerateDocNoFormat(ctx context.Context, in *GenerateDocNoFormatRequest, opts ...grpc.CallOption) (*GenerateDocNoFormatResponse, error)
+	GetNextDocNo(ctx context.Context, in *GetNextDocNoRequest, opts ...grpc.CallOption) (*GetNextDocNoResponse, error)
+	ConsumeDocNo(ctx context.Context, in *ConsumeDocNoRequest, opts ...grpc.CallOption) (*ConsumeDocNoResponse, error)
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ExportResponse, error)
+	Import(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (*ImportResponse, error)
+	CreateAPIKey(ctx context.Context, in *CreateAPIKeyRequest, opts ...grpc.CallOption) (*CreateAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, in *ListAPIKeysRequest, opts ...grpc.CallOption) (*ListAPIKeysResponse, error)
+	RevokeAPIKey(ctx context.Context, in *RevokeAPIKeyRequest, opts ...grpc.CallOption) (*RevokeAPIKeyResponse, error)
+}
+
+type docNoGenServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewDocNoGenServiceClient(cc *grpc.ClientConn) DocNoGenServiceClient {
+	return &docNoGenServiceClient{cc}
+}
+
+func (c *docNoGenServiceClient) GenerateBulkDocNoFormat(ctx context.Context, in *GenerateBulkDocNoFormatRequest, opts ...grpc.CallOption) (*GenerateBulkDocNoFormatResponse, error) {
+	out := new(GenerateBulkDocNoFormatResponse)
+	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/GenerateBulkDocNoFormat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *docNoGenServiceClient) GenerateDocNoFormat(ctx context.Context, in *GenerateDocNoFormatRequest, opts ...grpc.CallOption) (*GenerateDocNoFormatResponse, error) {
+	out := new(GenerateDocNoFormatResponse)
+	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/GenerateDocNoFormat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *docNoGenServiceClient) GetNextDocNo(ctx context.Context, in *GetNextDocNoRequest, opts ...grpc.CallOption) (*GetNextDocNoResponse, error) {
+	out := new(GetNextDocNoResponse)
+	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/GetNextDocNo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *docNoGenServiceClient) ConsumeDocNo(ctx context.Context, in *ConsumeDocNoRequest, opts ...grpc.CallOption) (*ConsumeDocNoResponse, error) {
+	out := new(ConsumeDocNoResponse)
+	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/ConsumeDocNo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *docNoGenServiceClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ExportResponse, error) {
+	out := new(ExportResponse)
+	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/Export", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *docNoGenServiceClient) Import(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (*ImportResponse, error) {
+	out := new(ImportResponse)
+	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/Import", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *docNoGenServiceClient) CreateAPIKey(ctx context.Context, in *CreateAPIKeyRequest, opts ...grpc.CallOption) (*CreateAPIKeyResponse, error) {
+	out := new(CreateAPIKeyResponse)
+	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/CreateAPIKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *docNoGenServiceClient) ListAPIKeys(ctx context.Context, in *ListAPIKeysRequest, opts ...grpc.CallOption) (*ListAPIKeysResponse, error) {
+	out := new(ListAPIKeysResponse)
+	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/ListAPIKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *docNoGenServiceClient) RevokeAPIKey(ctx context.Context, in *RevokeAPIKeyRequest, opts ...grpc.CallOption) (*RevokeAPIKeyResponse, error) {
+	out := new(RevokeAPIKeyResponse)
+	err := c.cc.Invoke(ctx, "/docnogen.DocNoGenService/RevokeAPIKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DocNoGenServiceServer is the server API for DocNoGenService service.
+type DocNoGenServiceServer interface {
+	GenerateBulkDocNoFormat(context.Context, *GenerateBulkDocNoFormatRequest) (*GenerateBulkDocNoFormatResponse, error)
+	GenerateDocNoFormat(context.Context, *GenerateDocNoFormatRequest) (*GenerateDocNoFormatResponse, error)
+	GetNextDocNo(context.Context, *GetNextDocNoRequest) (*GetNextDocNoResponse, error)
+	ConsumeDocNo(context.Context, *ConsumeDocNoRequest) (*ConsumeDocNoResponse, error)
+	Export(context.Context, *ExportRequest) (*ExportResponse, error)
+	Import(context.Context, *ImportRequest) (*ImportResponse, error)
+	CreateAPIKey(context.Context, *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+	ListAPIKeys(context.Context, *ListAPIKeysRequest) (*ListAPIKeysResponse, error)
+	RevokeAPIKey(context.Context, *RevokeAPIKeyRequest) (*RevokeAPIKeyResponse, error)
+}
+
+func RegisterDocNoGenServiceServer(s *grpc.Server, srv DocNoGenServiceServer) {
+	s.RegisterService(&_DocNoGenService_serviceDesc, srv)
+}
+
+func _DocNoGenService_GenerateBulkDocNoFormat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateBulkDocNoFormatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocNoGenServiceServer).GenerateBulkDocNoFormat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/docnogen.DocNoGenService/GenerateBulkDocNoFormat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocNoGenServiceServer).GenerateBulkDocNoFormat(ctx, req.(*GenerateBulkDocNoFormatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DocNoGenService_GenerateDocNoFormat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateDocNoFormatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocNoGenServiceServer).GenerateDocNoFormat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/docnogen.DocNoGenService/GenerateDocNoFormat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocNoGenServiceServer).GenerateDocNoFormat(ctx, req.(*GenerateDocNoFormatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DocNoGenService_GetNextDocNo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNextDocNoRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -989,6 +2386,96 @@ func _DocNoGenService_ConsumeDocNo_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DocNoGenService_Export_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocNoGenServiceServer).Export(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/docnogen.DocNoGenService/Export",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocNoGenServiceServer).Export(ctx, req.(*ExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DocNoGenService_Import_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocNoGenServiceServer).Import(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/docnogen.DocNoGenService/Import",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocNoGenServiceServer).Import(ctx, req.(*ImportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DocNoGenService_CreateAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAPIKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocNoGenServiceServer).CreateAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/docnogen.DocNoGenService/CreateAPIKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocNoGenServiceServer).CreateAPIKey(ctx, req.(*CreateAPIKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DocNoGenService_ListAPIKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAPIKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocNoGenServiceServer).ListAPIKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/docnogen.DocNoGenService/ListAPIKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocNoGenServiceServer).ListAPIKeys(ctx, req.(*ListAPIKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DocNoGenService_RevokeAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeAPIKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocNoGenServiceServer).RevokeAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/docnogen.DocNoGenService/RevokeAPIKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocNoGenServiceServer).RevokeAPIKey(ctx, req.(*RevokeAPIKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _DocNoGenService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "docnogen.DocNoGenService",
 	HandlerType: (*DocNoGenServiceServer)(nil),
@@ -1009,6 +2496,26 @@ var _DocNoGenService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ConsumeDocNo",
 			Handler:    _DocNoGenService_ConsumeDocNo_Handler,
 		},
+		{
+			MethodName: "Export",
+			Handler:    _DocNoGenService_Export_Handler,
+		},
+		{
+			MethodName: "Import",
+			Handler:    _DocNoGenService_Import_Handler,
+		},
+		{
+			MethodName: "CreateAPIKey",
+			Handler:    _DocNoGenService_CreateAPIKey_Handler,
+		},
+		{
+			MethodName: "ListAPIKeys",
+			Handler:    _DocNoGenService_ListAPIKeys_Handler,
+		},
+		{
+			MethodName: "RevokeAPIKey",
+			Handler:    _DocNoGenService_RevokeAPIKey_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "docnogen.proto",
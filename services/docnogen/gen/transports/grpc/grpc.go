@@ -3,8 +3,10 @@ package docnogen_grpctransport
 import (
 	"fmt"
 
+	jwt "github.com/go-kit/kit/auth/jwt"
 	"github.com/go-kit/kit/log"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/howlun/go-kit-documentnogen/common"
 	endpoints "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/endpoints"
 	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
 	context "golang.org/x/net/context"
@@ -15,53 +17,100 @@ var _ = fmt.Errorf
 
 func MakeGRPCServer(_ context.Context, endpoints endpoints.Endpoints, logger log.Logger) pb.DocNoGenServiceServer {
 	options := []grpctransport.ServerOption{
+		grpctransport.ServerBefore(common.TraceGRPCToContext(), common.RequestIDGRPCToContext(logger), jwt.GRPCToContext(), common.APIKeyGRPCToContext(), common.RateLimitGRPCToContext()),
+		grpctransport.ServerFinalizer(common.RateLimitGRPCHeaders(), common.RequestIDGRPCHeader(), common.TraceGRPCFinalizer()),
 		grpctransport.ServerErrorLogger(logger),
 	}
 
 	return &grpcServer{
 
-		generatebulkdocnoformat: grpctransport.NewServer(
+		generatebulkdocnoformatHandler: grpctransport.NewServer(
 			endpoints.GenerateBulkDocNoFormatEndpoint,
 			decodeGenerateBulkDocNoFormatRequest,
 			encodeGenerateBulkDocNoFormatResponse,
 			options...,
 		),
 
-		generatedocnoformat: grpctransport.NewServer(
+		generatedocnoformatHandler: grpctransport.NewServer(
 			endpoints.GenerateDocNoFormatEndpoint,
 			decodeGenerateDocNoFormatRequest,
 			encodeGenerateDocNoFormatResponse,
 			options...,
 		),
 
-		getnextdocno: grpctransport.NewServer(
+		getnextdocnoHandler: grpctransport.NewServer(
 			endpoints.GetNextDocNoEndpoint,
 			decodeGetNextDocNoRequest,
 			encodeGetNextDocNoResponse,
 			options...,
 		),
 
-		consumedocno: grpctransport.NewServer(
+		consumedocnoHandler: grpctransport.NewServer(
 			endpoints.ConsumeDocNoEndpoint,
 			decodeConsumeDocNoRequest,
 			encodeConsumeDocNoResponse,
 			options...,
 		),
+
+		exportHandler: grpctransport.NewServer(
+			endpoints.ExportEndpoint,
+			decodeExportRequest,
+			encodeExportResponse,
+			options...,
+		),
+
+		importHandler: grpctransport.NewServer(
+			endpoints.ImportEndpoint,
+			decodeImportRequest,
+			encodeImportResponse,
+			options...,
+		),
+
+		createapikeyHandler: grpctransport.NewServer(
+			endpoints.CreateAPIKeyEndpoint,
+			decodeCreateAPIKeyRequest,
+			encodeCreateAPIKeyResponse,
+			options...,
+		),
+
+		listapikeysHandler: grpctransport.NewServer(
+			endpoints.ListAPIKeysEndpoint,
+			decodeListAPIKeysRequest,
+			encodeListAPIKeysResponse,
+			options...,
+		),
+
+		revokeapikeyHandler: grpctransport.NewServer(
+			endpoints.RevokeAPIKeyEndpoint,
+			decodeRevokeAPIKeyRequest,
+			encodeRevokeAPIKeyResponse,
+			options...,
+		),
 	}
 }
 
 type grpcServer struct {
-	generatebulkdocnoformat grpctransport.Handler
+	generatebulkdocnoformatHandler grpctransport.Handler
+
+	generatedocnoformatHandler grpctransport.Handler
+
+	getnextdocnoHandler grpctransport.Handler
+
+	consumedocnoHandler grpctransport.Handler
 
-	generatedocnoformat grpctransport.Handler
+	exportHandler grpctransport.Handler
 
-	getnextdocno grpctransport.Handler
+	importHandler grpctransport.Handler
 
-	consumedocno grpctransport.Handler
+	createapikeyHandler grpctransport.Handler
+
+	listapikeysHandler grpctransport.Handler
+
+	revokeapikeyHandler grpctransport.Handler
 }
 
 func (s *grpcServer) GenerateBulkDocNoFormat(ctx context.Context, req *pb.GenerateBulkDocNoFormatRequest) (*pb.GenerateBulkDocNoFormatResponse, error) {
-	_, rep, err := s.generatebulkdocnoformat.ServeGRPC(ctx, req)
+	_, rep, err := s.generatebulkdocnoformatHandler.ServeGRPC(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -74,11 +123,14 @@ func decodeGenerateBulkDocNoFormatRequest(_ context.Context, grpcReq interface{}
 
 func encodeGenerateBulkDocNoFormatResponse(_ context.Context, response interface{}) (interface{}, error) {
 	resp := response.(*pb.GenerateBulkDocNoFormatResponse)
+	if err := common.ResponseStatus(resp); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
 func (s *grpcServer) GenerateDocNoFormat(ctx context.Context, req *pb.GenerateDocNoFormatRequest) (*pb.GenerateDocNoFormatResponse, error) {
-	_, rep, err := s.generatedocnoformat.ServeGRPC(ctx, req)
+	_, rep, err := s.generatedocnoformatHandler.ServeGRPC(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -91,11 +143,14 @@ func decodeGenerateDocNoFormatRequest(_ context.Context, grpcReq interface{}) (i
 
 func encodeGenerateDocNoFormatResponse(_ context.Context, response interface{}) (interface{}, error) {
 	resp := response.(*pb.GenerateDocNoFormatResponse)
+	if err := common.ResponseStatus(resp); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
 func (s *grpcServer) GetNextDocNo(ctx context.Context, req *pb.GetNextDocNoRequest) (*pb.GetNextDocNoResponse, error) {
-	_, rep, err := s.getnextdocno.ServeGRPC(ctx, req)
+	_, rep, err := s.getnextdocnoHandler.ServeGRPC(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -108,11 +163,14 @@ func decodeGetNextDocNoRequest(_ context.Context, grpcReq interface{}) (interfac
 
 func encodeGetNextDocNoResponse(_ context.Context, response interface{}) (interface{}, error) {
 	resp := response.(*pb.GetNextDocNoResponse)
+	if err := common.ResponseStatus(resp); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
 func (s *grpcServer) ConsumeDocNo(ctx context.Context, req *pb.ConsumeDocNoRequest) (*pb.ConsumeDocNoResponse, error) {
-	_, rep, err := s.consumedocno.ServeGRPC(ctx, req)
+	_, rep, err := s.consumedocnoHandler.ServeGRPC(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -125,6 +183,109 @@ func decodeConsumeDocNoRequest(_ context.Context, grpcReq interface{}) (interfac
 
 func encodeConsumeDocNoResponse(_ context.Context, response interface{}) (interface{}, error) {
 	resp := response.(*pb.ConsumeDocNoResponse)
+	if err := common.ResponseStatus(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) Export(ctx context.Context, req *pb.ExportRequest) (*pb.ExportResponse, error) {
+	_, rep, err := s.exportHandler.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*pb.ExportResponse), nil
+}
+
+func decodeExportRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	return grpcReq, nil
+}
+
+func encodeExportResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(*pb.ExportResponse)
+	if err := common.ResponseStatus(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) Import(ctx context.Context, req *pb.ImportRequest) (*pb.ImportResponse, error) {
+	_, rep, err := s.importHandler.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*pb.ImportResponse), nil
+}
+
+func decodeImportRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	return grpcReq, nil
+}
+
+func encodeImportResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(*pb.ImportResponse)
+	if err := common.ResponseStatus(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyResponse, error) {
+	_, rep, err := s.createapikeyHandler.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*pb.CreateAPIKeyResponse), nil
+}
+
+func decodeCreateAPIKeyRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	return grpcReq, nil
+}
+
+func encodeCreateAPIKeyResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(*pb.CreateAPIKeyResponse)
+	if err := common.ResponseStatus(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	_, rep, err := s.listapikeysHandler.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*pb.ListAPIKeysResponse), nil
+}
+
+func decodeListAPIKeysRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	return grpcReq, nil
+}
+
+func encodeListAPIKeysResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(*pb.ListAPIKeysResponse)
+	if err := common.ResponseStatus(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyResponse, error) {
+	_, rep, err := s.revokeapikeyHandler.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*pb.RevokeAPIKeyResponse), nil
+}
+
+func decodeRevokeAPIKeyRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	return grpcReq, nil
+}
+
+func encodeRevokeAPIKeyResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(*pb.RevokeAPIKeyResponse)
+	if err := common.ResponseStatus(resp); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
@@ -3,14 +3,15 @@ package docnogen_httptransport
 import (
 	"encoding/json"
 	"errors"
-	stdLog "log"
 	"net/http"
 
 	context "golang.org/x/net/context"
 
+	jwt "github.com/go-kit/kit/auth/jwt"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/howlun/go-kit-documentnogen/common"
 	endpoints "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/endpoints"
 	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
 )
@@ -22,6 +23,9 @@ import (
 func MakeGenerateBulkDocNoFormatHandler(_ context.Context, svc pb.DocNoGenServiceServer, endpoint endpoint.Endpoint, logger log.Logger) *httptransport.Server {
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(errorEncoder),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, common.TraceHTTPToContext(), common.RequestIDHTTPToContext(logger), jwt.HTTPToContext(), common.APIKeyHTTPToContext(), common.RateLimitHTTPToContext()),
+		httptransport.ServerAfter(common.RateLimitHTTPHeaders(), common.RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(common.TraceHTTPFinalizer()),
 		httptransport.ServerErrorLogger(logger),
 	}
 
@@ -35,7 +39,7 @@ func MakeGenerateBulkDocNoFormatHandler(_ context.Context, svc pb.DocNoGenServic
 
 func decodeGenerateBulkDocNoFormatRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var req pb.GenerateBulkDocNoFormatRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := common.DecodeHTTPRequest(r, &req); err != nil {
 		return nil, err
 	}
 	return &req, nil
@@ -46,13 +50,20 @@ func encodeGenerateBulkDocNoFormatResponse(ctx context.Context, w http.ResponseW
 		errorEncoder(ctx, f.Failed(), w)
 		return nil
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	return json.NewEncoder(w).Encode(response)
+	status := http.StatusOK
+	if e := common.ResponseError(response); e != nil {
+		status = e.Reason.HTTPStatus()
+	}
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	return common.EncodeHTTPResponse(w, accept, status, response.(*pb.GenerateBulkDocNoFormatResponse))
 }
 
 func MakeGenerateDocNoFormatHandler(_ context.Context, svc pb.DocNoGenServiceServer, endpoint endpoint.Endpoint, logger log.Logger) *httptransport.Server {
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(errorEncoder),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, common.TraceHTTPToContext(), common.RequestIDHTTPToContext(logger), jwt.HTTPToContext(), common.APIKeyHTTPToContext(), common.RateLimitHTTPToContext()),
+		httptransport.ServerAfter(common.RateLimitHTTPHeaders(), common.RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(common.TraceHTTPFinalizer()),
 		httptransport.ServerErrorLogger(logger),
 	}
 
@@ -66,7 +77,7 @@ func MakeGenerateDocNoFormatHandler(_ context.Context, svc pb.DocNoGenServiceSer
 
 func decodeGenerateDocNoFormatRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var req pb.GenerateDocNoFormatRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := common.DecodeHTTPRequest(r, &req); err != nil {
 		return nil, err
 	}
 	return &req, nil
@@ -77,13 +88,20 @@ func encodeGenerateDocNoFormatResponse(ctx context.Context, w http.ResponseWrite
 		errorEncoder(ctx, f.Failed(), w)
 		return nil
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	return json.NewEncoder(w).Encode(response)
+	status := http.StatusOK
+	if e := common.ResponseError(response); e != nil {
+		status = e.Reason.HTTPStatus()
+	}
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	return common.EncodeHTTPResponse(w, accept, status, response.(*pb.GenerateDocNoFormatResponse))
 }
 
 func MakeGetNextDocNoHandler(_ context.Context, svc pb.DocNoGenServiceServer, endpoint endpoint.Endpoint, logger log.Logger) *httptransport.Server {
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(errorEncoder),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, common.TraceHTTPToContext(), common.RequestIDHTTPToContext(logger), jwt.HTTPToContext(), common.APIKeyHTTPToContext(), common.RateLimitHTTPToContext()),
+		httptransport.ServerAfter(common.RateLimitHTTPHeaders(), common.RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(common.TraceHTTPFinalizer()),
 		httptransport.ServerErrorLogger(logger),
 	}
 
@@ -97,7 +115,7 @@ func MakeGetNextDocNoHandler(_ context.Context, svc pb.DocNoGenServiceServer, en
 
 func decodeGetNextDocNoRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var req pb.GetNextDocNoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := common.DecodeHTTPRequest(r, &req); err != nil {
 		return nil, err
 	}
 	return &req, nil
@@ -108,13 +126,20 @@ func encodeGetNextDocNoResponse(ctx context.Context, w http.ResponseWriter, resp
 		errorEncoder(ctx, f.Failed(), w)
 		return nil
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	return json.NewEncoder(w).Encode(response)
+	status := http.StatusOK
+	if e := common.ResponseError(response); e != nil {
+		status = e.Reason.HTTPStatus()
+	}
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	return common.EncodeHTTPResponse(w, accept, status, response.(*pb.GetNextDocNoResponse))
 }
 
 func MakeConsumeDocNoHandler(_ context.Context, svc pb.DocNoGenServiceServer, endpoint endpoint.Endpoint, logger log.Logger) *httptransport.Server {
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(errorEncoder),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, common.TraceHTTPToContext(), common.RequestIDHTTPToContext(logger), jwt.HTTPToContext(), common.APIKeyHTTPToContext(), common.RateLimitHTTPToContext()),
+		httptransport.ServerAfter(common.RateLimitHTTPHeaders(), common.RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(common.TraceHTTPFinalizer()),
 		httptransport.ServerErrorLogger(logger),
 	}
 
@@ -128,7 +153,7 @@ func MakeConsumeDocNoHandler(_ context.Context, svc pb.DocNoGenServiceServer, en
 
 func decodeConsumeDocNoRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var req pb.ConsumeDocNoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := common.DecodeHTTPRequest(r, &req); err != nil {
 		return nil, err
 	}
 	return &req, nil
@@ -139,40 +164,252 @@ func encodeConsumeDocNoResponse(ctx context.Context, w http.ResponseWriter, resp
 		errorEncoder(ctx, f.Failed(), w)
 		return nil
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	return json.NewEncoder(w).Encode(response)
+	status := http.StatusOK
+	if e := common.ResponseError(response); e != nil {
+		status = e.Reason.HTTPStatus()
+	}
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	return common.EncodeHTTPResponse(w, accept, status, response.(*pb.ConsumeDocNoResponse))
+}
+
+func MakeExportHandler(_ context.Context, svc pb.DocNoGenServiceServer, endpoint endpoint.Endpoint, logger log.Logger) *httptransport.Server {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(errorEncoder),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, common.TraceHTTPToContext(), common.RequestIDHTTPToContext(logger), jwt.HTTPToContext(), common.APIKeyHTTPToContext(), common.RateLimitHTTPToContext()),
+		httptransport.ServerAfter(common.RateLimitHTTPHeaders(), common.RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(common.TraceHTTPFinalizer()),
+		httptransport.ServerErrorLogger(logger),
+	}
+
+	return httptransport.NewServer(
+		endpoint,
+		decodeExportRequest,
+		encodeExportResponse,
+		options...,
+	)
+}
+
+func decodeExportRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req pb.ExportRequest
+	if err := common.DecodeHTTPRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func encodeExportResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		errorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	status := http.StatusOK
+	if e := common.ResponseError(response); e != nil {
+		status = e.Reason.HTTPStatus()
+	}
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	return common.EncodeHTTPResponse(w, accept, status, response.(*pb.ExportResponse))
+}
+
+func MakeImportHandler(_ context.Context, svc pb.DocNoGenServiceServer, endpoint endpoint.Endpoint, logger log.Logger) *httptransport.Server {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(errorEncoder),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, common.TraceHTTPToContext(), common.RequestIDHTTPToContext(logger), jwt.HTTPToContext(), common.APIKeyHTTPToContext(), common.RateLimitHTTPToContext()),
+		httptransport.ServerAfter(common.RateLimitHTTPHeaders(), common.RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(common.TraceHTTPFinalizer()),
+		httptransport.ServerErrorLogger(logger),
+	}
+
+	return httptransport.NewServer(
+		endpoint,
+		decodeImportRequest,
+		encodeImportResponse,
+		options...,
+	)
+}
+
+func decodeImportRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req pb.ImportRequest
+	if err := common.DecodeHTTPRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func encodeImportResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		errorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	status := http.StatusOK
+	if e := common.ResponseError(response); e != nil {
+		status = e.Reason.HTTPStatus()
+	}
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	return common.EncodeHTTPResponse(w, accept, status, response.(*pb.ImportResponse))
+}
+
+func MakeCreateAPIKeyHandler(_ context.Context, svc pb.DocNoGenServiceServer, endpoint endpoint.Endpoint, logger log.Logger) *httptransport.Server {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(errorEncoder),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, common.TraceHTTPToContext(), common.RequestIDHTTPToContext(logger), jwt.HTTPToContext(), common.APIKeyHTTPToContext(), common.RateLimitHTTPToContext()),
+		httptransport.ServerAfter(common.RateLimitHTTPHeaders(), common.RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(common.TraceHTTPFinalizer()),
+		httptransport.ServerErrorLogger(logger),
+	}
+
+	return httptransport.NewServer(
+		endpoint,
+		decodeCreateAPIKeyRequest,
+		encodeCreateAPIKeyResponse,
+		options...,
+	)
+}
+
+func decodeCreateAPIKeyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req pb.CreateAPIKeyRequest
+	if err := common.DecodeHTTPRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func encodeCreateAPIKeyResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		errorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	status := http.StatusOK
+	if e := common.ResponseError(response); e != nil {
+		status = e.Reason.HTTPStatus()
+	}
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	return common.EncodeHTTPResponse(w, accept, status, response.(*pb.CreateAPIKeyResponse))
+}
+
+func MakeListAPIKeysHandler(_ context.Context, svc pb.DocNoGenServiceServer, endpoint endpoint.Endpoint, logger log.Logger) *httptransport.Server {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(errorEncoder),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, common.TraceHTTPToContext(), common.RequestIDHTTPToContext(logger), jwt.HTTPToContext(), common.APIKeyHTTPToContext(), common.RateLimitHTTPToContext()),
+		httptransport.ServerAfter(common.RateLimitHTTPHeaders(), common.RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(common.TraceHTTPFinalizer()),
+		httptransport.ServerErrorLogger(logger),
+	}
+
+	return httptransport.NewServer(
+		endpoint,
+		decodeListAPIKeysRequest,
+		encodeListAPIKeysResponse,
+		options...,
+	)
+}
+
+func decodeListAPIKeysRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req pb.ListAPIKeysRequest
+	if err := common.DecodeHTTPRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func encodeListAPIKeysResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		errorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	status := http.StatusOK
+	if e := common.ResponseError(response); e != nil {
+		status = e.Reason.HTTPStatus()
+	}
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	return common.EncodeHTTPResponse(w, accept, status, response.(*pb.ListAPIKeysResponse))
+}
+
+func MakeRevokeAPIKeyHandler(_ context.Context, svc pb.DocNoGenServiceServer, endpoint endpoint.Endpoint, logger log.Logger) *httptransport.Server {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(errorEncoder),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, common.TraceHTTPToContext(), common.RequestIDHTTPToContext(logger), jwt.HTTPToContext(), common.APIKeyHTTPToContext(), common.RateLimitHTTPToContext()),
+		httptransport.ServerAfter(common.RateLimitHTTPHeaders(), common.RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(common.TraceHTTPFinalizer()),
+		httptransport.ServerErrorLogger(logger),
+	}
+
+	return httptransport.NewServer(
+		endpoint,
+		decodeRevokeAPIKeyRequest,
+		encodeRevokeAPIKeyResponse,
+		options...,
+	)
+}
+
+func decodeRevokeAPIKeyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req pb.RevokeAPIKeyRequest
+	if err := common.DecodeHTTPRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func encodeRevokeAPIKeyResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		errorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	status := http.StatusOK
+	if e := common.ResponseError(response); e != nil {
+		status = e.Reason.HTTPStatus()
+	}
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	return common.EncodeHTTPResponse(w, accept, status, response.(*pb.RevokeAPIKeyResponse))
 }
 
 func RegisterHandlers(ctx context.Context, svc pb.DocNoGenServiceServer, mux *http.ServeMux, endpoints endpoints.Endpoints, logger log.Logger) error {
 
-	stdLog.Println("new HTTP endpoint: \"/GenerateBulkDocNoFormat\" (service=Docnogen)")
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/GenerateBulkDocNoFormat", "service", "Docnogen")
 	mux.Handle("/GenerateBulkDocNoFormat", MakeGenerateBulkDocNoFormatHandler(ctx, svc, endpoints.GenerateBulkDocNoFormatEndpoint, logger))
 
-	stdLog.Println("new HTTP endpoint: \"/GenerateDocNoFormat\" (service=Docnogen)")
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/GenerateDocNoFormat", "service", "Docnogen")
 	mux.Handle("/GenerateDocNoFormat", MakeGenerateDocNoFormatHandler(ctx, svc, endpoints.GenerateDocNoFormatEndpoint, logger))
 
-	stdLog.Println("new HTTP endpoint: \"/GetNextDocNo\" (service=Docnogen)")
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/GetNextDocNo", "service", "Docnogen")
 	mux.Handle("/GetNextDocNo", MakeGetNextDocNoHandler(ctx, svc, endpoints.GetNextDocNoEndpoint, logger))
 
-	stdLog.Println("new HTTP endpoint: \"/ConsumeDocNo\" (service=Docnogen)")
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/ConsumeDocNo", "service", "Docnogen")
 	mux.Handle("/ConsumeDocNo", MakeConsumeDocNoHandler(ctx, svc, endpoints.ConsumeDocNoEndpoint, logger))
 
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/Export", "service", "Docnogen")
+	mux.Handle("/Export", MakeExportHandler(ctx, svc, endpoints.ExportEndpoint, logger))
+
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/Import", "service", "Docnogen")
+	mux.Handle("/Import", MakeImportHandler(ctx, svc, endpoints.ImportEndpoint, logger))
+
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/CreateAPIKey", "service", "Docnogen")
+	mux.Handle("/CreateAPIKey", MakeCreateAPIKeyHandler(ctx, svc, endpoints.CreateAPIKeyEndpoint, logger))
+
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/ListAPIKeys", "service", "Docnogen")
+	mux.Handle("/ListAPIKeys", MakeListAPIKeysHandler(ctx, svc, endpoints.ListAPIKeysEndpoint, logger))
+
+	common.LogInfo(logger).Log("msg", "new HTTP endpoint", "path", "/RevokeAPIKey", "service", "Docnogen")
+	mux.Handle("/RevokeAPIKey", MakeRevokeAPIKeyHandler(ctx, svc, endpoints.RevokeAPIKeyEndpoint, logger))
+
 	return nil
 }
 
-func errorEncoder(_ context.Context, err error, w http.ResponseWriter) {
-	w.WriteHeader(err2code(err))
-	json.NewEncoder(w).Encode(errorWrapper{Error: err.Error()})
+func errorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
+	code := err2code(err)
+	common.SetRateLimitHeaders(ctx, w.Header())
+	common.SetRequestIDHeader(ctx, w.Header())
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(errorWrapper{
+		Error:        err.Error(),
+		ErrorCode:    int32(code),
+		ErrorMessage: err.Error(),
+		ErrorReason:  string(common.ReasonOf(err)),
+	})
 }
 
 func err2code(err error) int {
-	/*
-		switch err {
-		case addservice.ErrTwoZeroes, addservice.ErrMaxSizeExceeded, addservice.ErrIntOverflow:
-			return http.StatusBadRequest
-		}
-	*/
-	return http.StatusInternalServerError
+	return common.ReasonOf(err).HTTPStatus()
 }
 
 func errorDecoder(r *http.Response) error {
@@ -180,9 +417,16 @@ func errorDecoder(r *http.Response) error {
 	if err := json.NewDecoder(r.Body).Decode(&w); err != nil {
 		return err
 	}
+	if w.ErrorReason != "" {
+		return common.NewError(common.Reason(w.ErrorReason), w.Error)
+	}
 	return errors.New(w.Error)
 }
 
+// errorWrapper carries the legacy body fields next to error, so that clients reading either keep working
 type errorWrapper struct {
-	Error string `json:"error"`
+	Error        string `json:"error"`
+	ErrorCode    int32  `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+	ErrorReason  string `json:"errorReason"`
 }
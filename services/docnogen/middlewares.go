@@ -1,10 +1,12 @@
 package docnogensvc
 
 import (
+	"math"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
+	"github.com/howlun/go-kit-documentnogen/common"
 	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
 	context "golang.org/x/net/context"
 )
@@ -12,6 +14,28 @@ import (
 // Middleware describes a service (as opposed to endpoint) middleware.
 type Middleware func(DocnogenService) DocnogenService
 
+// Middlewares names the service middlewares a chain can be built from, e.g. "logging" and "instrumenting", and
+// those of other teams such as auditing. A name mapped to nil is known but disabled.
+type Middlewares map[string]Middleware
+
+// Chain returns the middleware applying the ones named, the first outermost. Unknown or repeated names are an error.
+func (m Middlewares) Chain(names []string) (Middleware, error) {
+	enabled := make(map[string]bool, len(m))
+	for name, mw := range m {
+		enabled[name] = mw != nil
+	}
+	names, err := common.ChainNames("service", names, enabled)
+	if err != nil {
+		return nil, err
+	}
+	return func(next DocnogenService) DocnogenService {
+		for i := len(names) - 1; i >= 0; i-- {
+			next = m[names[i]](next)
+		}
+		return next
+	}, nil
+}
+
 // LoggingMiddleware takes a logger as a dependency
 // and returns a service middleware.
 type loggingMiddleware struct {
@@ -56,49 +80,143 @@ func (mw loggingMiddleware) ConsumeDocNo(ctx context.Context, in *pb.ConsumeDocN
 	return mw.next.ConsumeDocNo(ctx, in)
 }
 
-// InstrumentingMiddleware returns a service middleware that instruments
-// the number of integers summed and characters concatenated over the lifetime of
-// the service.
+func (mw loggingMiddleware) Export(ctx context.Context, in *pb.ExportRequest) (out *pb.ExportResponse, err error) {
+	defer func(begin time.Time) {
+		mw.logger.Log("method", "Export", "took", time.Since(begin), "err", err)
+	}(time.Now())
+	return mw.next.Export(ctx, in)
+}
+
+func (mw loggingMiddleware) Import(ctx context.Context, in *pb.ImportRequest) (out *pb.ImportResponse, err error) {
+	defer func(begin time.Time) {
+		mw.logger.Log("method", "Import", "took", time.Since(begin), "err", err)
+	}(time.Now())
+	return mw.next.Import(ctx, in)
+}
+
+func (mw loggingMiddleware) CreateAPIKey(ctx context.Context, in *pb.CreateAPIKeyRequest) (out *pb.CreateAPIKeyResponse, err error) {
+	defer func(begin time.Time) {
+		mw.logger.Log("method", "CreateAPIKey", "took", time.Since(begin), "err", err)
+	}(time.Now())
+	return mw.next.CreateAPIKey(ctx, in)
+}
+
+func (mw loggingMiddleware) ListAPIKeys(ctx context.Context, in *pb.ListAPIKeysRequest) (out *pb.ListAPIKeysResponse, err error) {
+	defer func(begin time.Time) {
+		mw.logger.Log("method", "ListAPIKeys", "took", time.Since(begin), "err", err)
+	}(time.Now())
+	return mw.next.ListAPIKeys(ctx, in)
+}
+
+func (mw loggingMiddleware) RevokeAPIKey(ctx context.Context, in *pb.RevokeAPIKeyRequest) (out *pb.RevokeAPIKeyResponse, err error) {
+	defer func(begin time.Time) {
+		mw.logger.Log("method", "RevokeAPIKey", "took", time.Since(begin), "err", err)
+	}(time.Now())
+	return mw.next.RevokeAPIKey(ctx, in)
+}
+
+// The actions counted by the numbers metric of InstrumentingMiddleware
+const (
+	ActionIssued   = "issued"
+	ActionPeeked   = "peeked"
+	ActionConsumed = "consumed"
+)
+
+// instrumentingMiddleware records the document numbers handed out by the service, and where each counter stands.
+// The methods that do not move counters, such as Export, are those of the embedded service.
 type instrumentingMiddleware struct {
-	ints  metrics.Counter
-	chars metrics.Counter
-	next  DocnogenService
+	numbers   metrics.Counter
+	bulkSize  metrics.Histogram
+	nextSeqNo metrics.Gauge
+	remaining metrics.Gauge
+	DocnogenService
 }
 
-func InstrumentingMiddleware(ints, chars metrics.Counter) Middleware {
+// InstrumentingMiddleware returns a service middleware counting the numbers issued, peeked and consumed with labels
+// "action", "org" and "doc_code", observing the size of every bulk request served with label "org", and setting the
+// NextSeqNo and the remaining capacity of the counter last used with labels "org" and "doc_code". The path of the
+// counter is left out, since callers choose it. The remaining capacity also has label "limit": "pad_width" for the
+// numbers left before the sequence outgrows its zero padding, "max_value" for those left before NextSeqNo overflows.
+// The gauges also follow the counters written by Import.
+func InstrumentingMiddleware(numbers metrics.Counter, bulkSize metrics.Histogram, nextSeqNo, remaining metrics.Gauge) Middleware {
 	return func(next DocnogenService) DocnogenService {
 		return instrumentingMiddleware{
-			//ints:  ints,
-			//chars: chars,
-			next: next,
+			numbers:   numbers,
+			bulkSize:  bulkSize,
+			nextSeqNo: nextSeqNo,
+			remaining: remaining,
+
+			DocnogenService: next,
 		}
 	}
 }
 
-func (mw instrumentingMiddleware) GenerateBulkDocNoFormat(ctx context.Context, in *pb.GenerateBulkDocNoFormatRequest) (out *pb.GenerateBulkDocNoFormatResponse, err error) {
-	v, err := mw.next.GenerateBulkDocNoFormat(ctx, in)
-	// TODO: implement instrumenting logic here
+// count adds n numbers of action, then sets the gauges of the counter from its NextSeqNo
+func (mw instrumentingMiddleware) count(action, org, docCode string, n int, nextSeqNo uint32) {
+	if n > 0 {
+		mw.numbers.With("action", action, "org", org, "doc_code", docCode).Add(float64(n))
+	}
+	mw.nextSeqNo.With("org", org, "doc_code", docCode).Set(float64(nextSeqNo))
+	padWidth, maxValue := RemainingCapacity(nextSeqNo)
+	mw.remaining.With("org", org, "doc_code", docCode, "limit", "pad_width").Set(float64(padWidth))
+	mw.remaining.With("org", org, "doc_code", docCode, "limit", "max_value").Set(float64(maxValue))
+}
+
+// RemainingCapacity returns how many numbers a counter at nextSeqNo can still issue before the sequence number
+// needs more than common.DefaultSeqNoLength digits, and before it exceeds the largest NextSeqNo
+func RemainingCapacity(nextSeqNo uint32) (padWidth, maxValue uint64) {
+	limit := uint64(1)
+	for i := 0; i < common.DefaultSeqNoLength; i++ {
+		limit *= 10
+	}
+	if uint64(nextSeqNo) < limit {
+		padWidth = limit - uint64(nextSeqNo)
+	}
+	return padWidth, math.MaxUint32 - uint64(nextSeqNo)
+}
 
+func (mw instrumentingMiddleware) GenerateBulkDocNoFormat(ctx context.Context, in *pb.GenerateBulkDocNoFormatRequest) (out *pb.GenerateBulkDocNoFormatResponse, err error) {
+	v, err := mw.DocnogenService.GenerateBulkDocNoFormat(ctx, in)
+	if err == nil && v != nil && v.Ok && len(v.Results) > 0 {
+		mw.bulkSize.With("org", in.OrgCode).Observe(float64(len(v.Results)))
+		last := v.Results[len(v.Results)-1]
+		mw.count(ActionIssued, in.OrgCode, in.DocCode, len(v.Results), last.NextSeqNo)
+	}
 	return v, err
 }
 
 func (mw instrumentingMiddleware) GenerateDocNoFormat(ctx context.Context, in *pb.GenerateDocNoFormatRequest) (out *pb.GenerateDocNoFormatResponse, err error) {
-	v, err := mw.next.GenerateDocNoFormat(ctx, in)
-	// TODO: implement instrumenting logic here
-
+	v, err := mw.DocnogenService.GenerateDocNoFormat(ctx, in)
+	if err == nil && v != nil && v.Ok && v.Result != nil {
+		mw.count(ActionIssued, in.OrgCode, in.DocCode, 1, v.Result.NextSeqNo)
+	}
 	return v, err
 }
 
 func (mw instrumentingMiddleware) GetNextDocNo(ctx context.Context, in *pb.GetNextDocNoRequest) (out *pb.GetNextDocNoResponse, err error) {
-	v, err := mw.next.GetNextDocNo(ctx, in)
-	// TODO: implement instrumenting logic here
-
+	v, err := mw.DocnogenService.GetNextDocNo(ctx, in)
+	if err == nil && v != nil && v.Ok && v.Result != nil {
+		mw.count(ActionPeeked, in.OrgCode, in.DocCode, 1, v.Result.NextSeqNo)
+	}
 	return v, err
 }
 
 func (mw instrumentingMiddleware) ConsumeDocNo(ctx context.Context, in *pb.ConsumeDocNoRequest) (out *pb.ConsumeDocNoResponse, err error) {
-	v, err := mw.next.ConsumeDocNo(ctx, in)
-	// TODO: implement instrumenting logic here
+	v, err := mw.DocnogenService.ConsumeDocNo(ctx, in)
+	if err == nil && v != nil && v.Ok && v.Result != nil {
+		mw.count(ActionConsumed, in.OrgCode, in.DocCode, 1, v.Result.NextSeqNo)
+	}
+	return v, err
+}
 
+func (mw instrumentingMiddleware) Import(ctx context.Context, in *pb.ImportRequest) (out *pb.ImportResponse, err error) {
+	v, err := mw.DocnogenService.Import(ctx, in)
+	if err == nil && v != nil && v.Ok && v.Result != nil && !v.Result.DryRun {
+		for _, change := range v.Result.Changes {
+			if change.Action == ImportActionCreate || change.Action == ImportActionUpdate {
+				mw.count("", in.OrgCode, change.DocCode, 0, uint32(change.ToSeqNo))
+			}
+		}
+	}
 	return v, err
 }
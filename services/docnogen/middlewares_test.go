@@ -0,0 +1,161 @@
+package docnogensvc
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+)
+
+// testMetric records the value of every label set: summed by Add and Observe, replaced by Set
+type testMetric struct {
+	mtx    *sync.Mutex
+	labels []string
+	values map[string]float64
+}
+
+func newTestMetric() *testMetric {
+	return &testMetric{mtx: &sync.Mutex{}, values: map[string]float64{}}
+}
+
+func (m *testMetric) with(labelValues []string) *testMetric {
+	return &testMetric{mtx: m.mtx, labels: append(append([]string{}, m.labels...), labelValues...), values: m.values}
+}
+
+func (m *testMetric) update(f func(key string)) {
+	m.mtx.Lock()
+	f(strings.Join(m.labels, ","))
+	m.mtx.Unlock()
+}
+
+func (m *testMetric) value(labelValues ...string) float64 {
+	return m.values[strings.Join(labelValues, ",")]
+}
+
+type testCounterVec struct{ *testMetric }
+
+func (c testCounterVec) With(labelValues ...string) metrics.Counter {
+	return testCounterVec{c.with(labelValues)}
+}
+func (c testCounterVec) Add(delta float64) { c.update(func(k string) { c.values[k] += delta }) }
+
+type testGaugeVec struct{ *testMetric }
+
+func (g testGaugeVec) With(labelValues ...string) metrics.Gauge {
+	return testGaugeVec{g.with(labelValues)}
+}
+func (g testGaugeVec) Set(value float64) { g.update(func(k string) { g.values[k] = value }) }
+func (g testGaugeVec) Add(delta float64) { g.update(func(k string) { g.values[k] += delta }) }
+
+type testHistogramVec struct{ *testMetric }
+
+func (h testHistogramVec) With(labelValues ...string) metrics.Histogram {
+	return testHistogramVec{h.with(labelValues)}
+}
+func (h testHistogramVec) Observe(value float64) { h.update(func(k string) { h.values[k] += value }) }
+
+func Test_InstrumentingMiddleware(t *testing.T) {
+	Convey("Given an instrumented service", t, func() {
+		numbers, bulkSize, nextSeqNo, remaining := newTestMetric(), newTestMetric(), newTestMetric(), newTestMetric()
+		svc := InstrumentingMiddleware(testCounterVec{numbers}, testHistogramVec{bulkSize}, testGaugeVec{nextSeqNo}, testGaugeVec{remaining})(newTestService())
+		ctx := context.Background()
+		counter := []string{"org", "MAT", "doc_code", "AP"}
+
+		Convey("Issued numbers are counted and the counter gauges follow NextSeqNo", func() {
+			out, err := svc.GenerateDocNoFormat(ctx, &pb.GenerateDocNoFormatRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			bulk, err := svc.GenerateBulkDocNoFormat(ctx, &pb.GenerateBulkDocNoFormatRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, BulkNumber: 3, CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+			So(err, ShouldBeNil)
+			So(bulk.Ok, ShouldBeTrue)
+
+			So(numbers.value("action", "issued", "org", "MAT", "doc_code", "AP"), ShouldEqual, 4)
+			So(bulkSize.value("org", "MAT"), ShouldEqual, 3)
+			So(nextSeqNo.value(counter...), ShouldEqual, 5)
+			So(remaining.value(append(counter, "limit", "pad_width")...), ShouldEqual, 99995)
+			So(remaining.value(append(counter, "limit", "max_value")...), ShouldEqual, math.MaxUint32-5)
+		})
+
+		Convey("A failed bulk request is not observed", func() {
+			bulk, err := svc.GenerateBulkDocNoFormat(ctx, &pb.GenerateBulkDocNoFormatRequest{OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, BulkNumber: 3, CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+			So(err, ShouldBeNil)
+			So(bulk.Ok, ShouldBeFalse)
+			So(bulkSize.value("org", "MAT"), ShouldEqual, 0)
+		})
+
+		Convey("Peeked and consumed numbers are counted apart", func() {
+			peek, err := svc.GetNextDocNo(ctx, &pb.GetNextDocNoRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+			So(err, ShouldBeNil)
+			So(peek.Ok, ShouldBeTrue)
+			in := &pb.ConsumeDocNoRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", CurSeqNo: peek.Result.NextSeqNo, Version: peek.Result.Version}
+			out, err := svc.ConsumeDocNo(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			out, err = svc.ConsumeDocNo(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+
+			So(numbers.value("action", "peeked", "org", "MAT", "doc_code", "AP"), ShouldEqual, 1)
+			So(numbers.value("action", "consumed", "org", "MAT", "doc_code", "AP"), ShouldEqual, 1)
+			So(numbers.value("action", "issued", "org", "MAT", "doc_code", "AP"), ShouldEqual, 0)
+			So(nextSeqNo.value(counter...), ShouldEqual, 2)
+		})
+
+		Convey("The counter gauges follow an import, but not its dry run", func() {
+			data := `{"version": 1, "orgCode": "MAT", "counters": [{"docCode": "AP", "path": "AP/PO/HQ/19", "nextSeqNo": 100}]}`
+			out, err := svc.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: data, DryRun: true})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			So(nextSeqNo.value(counter...), ShouldEqual, 0)
+
+			out, err = svc.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: data})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			So(nextSeqNo.value(counter...), ShouldEqual, 100)
+			So(remaining.value(append(counter, "limit", "pad_width")...), ShouldEqual, 99900)
+		})
+	})
+
+	Convey("The capacity left runs out at the pad width, then at the largest NextSeqNo", t, func() {
+		padWidth, maxValue := RemainingCapacity(99999)
+		So(padWidth, ShouldEqual, 1)
+		So(maxValue, ShouldEqual, math.MaxUint32-99999)
+		padWidth, maxValue = RemainingCapacity(math.MaxUint32)
+		So(padWidth, ShouldEqual, 0)
+		So(maxValue, ShouldEqual, 0)
+	})
+}
+
+func Test_MiddlewareChain(t *testing.T) {
+	Convey("Service middlewares are chained by name, the first outermost", t, func() {
+		numbers := newTestMetric()
+		var buf bytes.Buffer
+		middlewares := Middlewares{
+			"logging":       LoggingMiddleware(log.NewLogfmtLogger(&buf)),
+			"instrumenting": InstrumentingMiddleware(testCounterVec{numbers}, testHistogramVec{newTestMetric()}, testGaugeVec{newTestMetric()}, testGaugeVec{newTestMetric()}),
+			"audit":         nil,
+		}
+		chain, err := middlewares.Chain([]string{"logging", "audit", "instrumenting"})
+		So(err, ShouldBeNil)
+		svc := chain(newTestService())
+		out, err := svc.GenerateDocNoFormat(context.Background(), &pb.GenerateDocNoFormatRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+		So(err, ShouldBeNil)
+		So(out.Ok, ShouldBeTrue)
+		So(numbers.value("action", "issued", "org", "MAT", "doc_code", "AP"), ShouldEqual, 1)
+		So(buf.String(), ShouldContainSubstring, "method=GenerateDocNoFormat")
+
+		_, err = middlewares.Chain([]string{"instrumenting", "cache"})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "audit, instrumenting, logging")
+		_, err = middlewares.Chain([]string{"logging", "logging"})
+		So(err, ShouldNotBeNil)
+	})
+}
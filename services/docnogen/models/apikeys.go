@@ -0,0 +1,173 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	context "golang.org/x/net/context"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+)
+
+// APIKeyCollection holds the API keys, it is never treated as an org collection
+const APIKeyCollection = "_apikeys"
+
+// apiKeyIndex makes the hashes unique and backs the lookup of every request
+var apiKeyIndex = mgo.Index{
+	Key:        []string{"hash"},
+	Unique:     true,
+	Background: true,
+	Name:       "hash_unique",
+}
+
+// APIKey is a stored API key, only the hash of its secret is kept
+type APIKey struct {
+	ID        string   `bson:"_id"`
+	Name      string   `bson:"name"`
+	Hash      string   `bson:"hash"` // see HashAPIKey
+	Orgs      []string `bson:"orgs"`
+	Roles     []string `bson:"roles"`
+	CreatedAt int64    `bson:"createdat"` // Unix timestamp
+	ExpiresAt int64    `bson:"expiresat"` // Unix timestamp, 0 when the key does not expire
+	RevokedAt int64    `bson:"revokedat"` // Unix timestamp, 0 when the key is not revoked
+}
+
+// Active tells whether the key can be used at Unix time now
+func (k *APIKey) Active(now int64) bool {
+	return k.RevokedAt == 0 && (k.ExpiresAt == 0 || now < k.ExpiresAt)
+}
+
+// HashAPIKey returns the hash an API key is stored and looked up by. Keys are random, so a plain SHA-256 is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) (err error)
+	// GetByHash returns nil without error when no key has the hash
+	GetByHash(ctx context.Context, hash string) (key *APIKey, err error)
+	// List returns the keys of orgCode, including the keys for every organization, or every key when orgCode is empty
+	List(ctx context.Context, orgCode string) (keys []*APIKey, err error)
+	// Revoke marks the key revoked at Unix time at, a revoked key keeps its first revocation time
+	Revoke(ctx context.Context, id string, at int64) (key *APIKey, err error)
+}
+
+type apiKeyRepository struct {
+	DB DBClient
+}
+
+func NewAPIKeyRepository(dbClient DBClient) (r APIKeyRepository) {
+	r = &apiKeyRepository{
+		DB: dbClient,
+	}
+	return r
+}
+
+// collection returns the API key collection and its session, which the caller must close
+func (r *apiKeyRepository) collection(ctx context.Context) (*mgo.Collection, *mgo.Session, error) {
+	if r.DB == nil {
+		return nil, nil, errors.New("DB Client is Nil")
+	}
+	s := r.DB.CurrentSession()
+	if s == nil {
+		return nil, nil, fmt.Errorf("DB Session is nil")
+	}
+	// bound the Mongo operations by the deadline of the request
+	if err := boundSession(ctx, s); err != nil {
+		s.Close()
+		return nil, nil, err
+	}
+	return r.DB.CurrentDB(s).C(APIKeyCollection), s, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *APIKey) (err error) {
+	collection, s, err := r.collection(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err = ensureAPIKeyIndex(collection); err != nil {
+		return err
+	}
+	if err = collection.Insert(key); err != nil {
+		return fmt.Errorf("Error inserting API key %s Error=%s", key.ID, err.Error())
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (key *APIKey, err error) {
+	collection, s, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	key = &APIKey{}
+	err = collection.Find(bson.M{"hash": hash}).One(key)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error finding API key Error=%s", err.Error())
+	}
+	return key, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, orgCode string) (keys []*APIKey, err error) {
+	collection, s, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	selector := bson.M{}
+	if orgCode != "" {
+		selector = bson.M{"orgs": bson.M{"$in": []string{orgCode, common.AllOrgs}}}
+	}
+	keys = []*APIKey{}
+	if err = collection.Find(selector).Sort("createdat", "_id").All(&keys); err != nil {
+		return nil, fmt.Errorf("Error listing API keys Error=%s", err.Error())
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id string, at int64) (key *APIKey, err error) {
+	collection, s, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	err = collection.Update(bson.M{"_id": id, "revokedat": 0}, bson.M{"$set": bson.M{"revokedat": at}})
+	if err != nil && err != mgo.ErrNotFound {
+		return nil, fmt.Errorf("Error revoking API key %s Error=%s", id, err.Error())
+	}
+
+	// not found is either a key revoked already or no key at all
+	key = &APIKey{}
+	err = collection.FindId(id).One(key)
+	if err == mgo.ErrNotFound {
+		return nil, common.Errorf(common.ReasonNotFound, "API key %s does not exist", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error finding API key %s Error=%s", id, err.Error())
+	}
+	return key, nil
+}
+
+func ensureAPIKeyIndex(collection *mgo.Collection) error {
+	if err := collection.EnsureIndex(apiKeyIndex); err != nil {
+		return fmt.Errorf("Error creating index on collection %s Error=%s", collection.Name, err.Error())
+	}
+	return nil
+}
+
+func migrateAPIKeyIndex(db *mgo.Database) error {
+	return ensureAPIKeyIndex(db.C(APIKeyCollection))
+}
@@ -0,0 +1,420 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	context "golang.org/x/net/context"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// OrgLayoutCollection records the layout of each organization, under defaultLayoutID LayoutShared once every
+	// organization is in the shared layout, and under newOrgLayoutID the layout of new organizations while
+	// MigrateToSharedLayout runs
+	OrgLayoutCollection = "_layouts"
+	defaultLayoutID     = "*"
+	newOrgLayoutID      = "$new"
+
+	// layoutMigrating is recorded for an organization while MigrateToSharedLayout moves its documents
+	layoutMigrating = "migrating"
+)
+
+type orgLayoutRecord struct {
+	ID     string `bson:"_id"`
+	Layout string `bson:"layout"`
+}
+
+// layoutDocNoRepository serves each organization from the layout recorded for it in OrgLayoutCollection, so that
+// every instance writes a counter to the same place whatever layout it is configured with. The first instance serving
+// an organization records its layout: that of newOrgLayoutID when recorded, else the configured layout.
+//
+// The layouts read are remembered. A document of an org collection is marked moved before it is copied to
+// SharedCollection, and only served from there since, so an instance finding a moved document forgets the layout of
+// its organization, and a document inserted in an org collection is moved at once unless its organization is still
+// in the collection layout.
+type layoutDocNoRepository struct {
+	DB         DBClient
+	layout     string
+	collection *docNoRepository
+	shared     DocNoRepository
+
+	// the shared layout is final, the collection layout is forgotten once a moved document is found
+	allShared int32
+	layouts   sync.Map
+}
+
+// NewDocNoRepositoryWithLayout returns the Mongo repository of the organizations recorded in OrgLayoutCollection,
+// which records layout, LayoutCollection or LayoutShared, for new ones. LayoutShared is recorded as the default of a
+// database without org collections, and refused for one with org collections MigrateToSharedLayout has not moved.
+func NewDocNoRepositoryWithLayout(dbClient DBClient, layout string) (r DocNoRepository, err error) {
+	if layout == "" {
+		layout = LayoutCollection
+	}
+	if layout != LayoutCollection && layout != LayoutShared {
+		return nil, fmt.Errorf("Mongo layout %s is not supported, use %s or %s", layout, LayoutCollection, LayoutShared)
+	}
+
+	if dbClient == nil {
+		return nil, errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := dbClient.CurrentSession()
+	if s == nil {
+		return nil, fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	db := dbClient.CurrentDB(s)
+	layouts, err := readOrgLayouts(db, defaultLayoutID, newOrgLayoutID)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &layoutDocNoRepository{
+		DB:         dbClient,
+		layout:     layout,
+		collection: &docNoRepository{DB: dbClient},
+		shared:     NewSharedDocNoRepository(dbClient),
+	}
+	if layouts[defaultLayoutID] == LayoutShared {
+		repo.allShared = 1
+		return repo, nil
+	}
+
+	if layout == LayoutShared {
+		orgs, err := orgCollectionNames(db)
+		if err != nil {
+			return nil, err
+		}
+		if len(orgs) > 0 || layouts[newOrgLayoutID] != "" {
+			return nil, fmt.Errorf("Mongo layout %s needs the organization collections to be moved first, run migrate-layout", LayoutShared)
+		}
+		if err = writeOrgLayout(db, defaultLayoutID, LayoutShared); err != nil {
+			return nil, err
+		}
+		repo.allShared = 1
+	}
+	return repo, nil
+}
+
+// orgLayout returns the layout serving the organization: LayoutCollection, LayoutShared or layoutMigrating
+func (d *layoutDocNoRepository) orgLayout(ctx context.Context, orgCode string) (string, error) {
+	if atomic.LoadInt32(&d.allShared) == 1 {
+		return LayoutShared, nil
+	}
+	if layout, ok := d.layouts.Load(orgCode); ok {
+		return layout.(string), nil
+	}
+	return d.readOrgLayout(ctx, orgCode)
+}
+
+// readOrgLayout reads the layout of the organization, recording it if it has none, and remembers it unless it is
+// being migrated
+func (d *layoutDocNoRepository) readOrgLayout(ctx context.Context, orgCode string) (string, error) {
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return "", fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err := boundSession(ctx, s); err != nil {
+		return "", err
+	}
+
+	db := d.DB.CurrentDB(s)
+	layouts, err := readOrgLayouts(db, orgCode, defaultLayoutID, newOrgLayoutID)
+	if err != nil {
+		return "", err
+	}
+	if layouts[defaultLayoutID] == LayoutShared {
+		// every organization has been moved once the default is shared
+		atomic.StoreInt32(&d.allShared, 1)
+		return LayoutShared, nil
+	}
+
+	layout, ok := layouts[orgCode]
+	if !ok {
+		layout = d.layout
+		if newLayout, ok := layouts[newOrgLayoutID]; ok {
+			layout = newLayout
+		}
+		// the first instance serving the organization decides its layout, the others follow
+		if layout, err = insertOrgLayout(db, orgCode, layout); err != nil {
+			return "", err
+		}
+	}
+
+	if layout == layoutMigrating {
+		d.layouts.Delete(orgCode)
+	} else {
+		d.layouts.Store(orgCode, layout)
+	}
+	return layout, nil
+}
+
+// checkCollectionLayout reads the layout of the organization again, and returns errDocNoMoved unless it is still in
+// the collection layout
+func (d *layoutDocNoRepository) checkCollectionLayout(ctx context.Context, orgCode string) error {
+	layout, err := d.readOrgLayout(ctx, orgCode)
+	if err != nil {
+		return err
+	}
+	if layout != LayoutCollection {
+		return errDocNoMoved
+	}
+	return nil
+}
+
+// moveDocNo forgets the layout of the organization, which is being or has been moved, and moves the document to
+// SharedCollection unless it is there already, to be served from there
+func (d *layoutDocNoRepository) moveDocNo(ctx context.Context, orgCode string, docCode string, path string) error {
+	d.layouts.Delete(orgCode)
+
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err := boundSession(ctx, s); err != nil {
+		return err
+	}
+	return moveDocNo(d.DB.CurrentDB(s), orgCode, docCode, path)
+}
+
+func (d *layoutDocNoRepository) GetByPath(ctx context.Context, docCode string, orgCode string, path string) (doc *DocNo, err error) {
+	if orgCode == "" {
+		// refused by the repository
+		return d.collection.GetByPath(ctx, docCode, orgCode, path)
+	}
+
+	layout, err := d.orgLayout(ctx, orgCode)
+	if err != nil {
+		return nil, err
+	}
+	if layout == LayoutShared {
+		return d.shared.GetByPath(ctx, docCode, orgCode, path)
+	}
+
+	doc, created, err := d.collection.getByPath(ctx, docCode, orgCode, path, func() error {
+		return d.checkCollectionLayout(ctx, orgCode)
+	})
+	if err == nil && created {
+		// the organization may have started moving since the check, and the new document then moves with it
+		err = d.checkCollectionLayout(ctx, orgCode)
+	}
+	if err == errDocNoMoved {
+		if err = d.moveDocNo(ctx, orgCode, docCode, path); err != nil {
+			return nil, err
+		}
+		return d.shared.GetByPath(ctx, docCode, orgCode, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (d *layoutDocNoRepository) UpdateByPath(ctx context.Context, orgCode string, doc *DocNo, curSeqNo int64, curVersion int64) (updated *DocNo, err error) {
+	if orgCode == "" || doc == nil {
+		// refused by the repository
+		return d.collection.UpdateByPath(ctx, orgCode, doc, curSeqNo, curVersion)
+	}
+
+	layout, err := d.orgLayout(ctx, orgCode)
+	if err != nil {
+		return nil, err
+	}
+	if layout == LayoutShared {
+		return d.shared.UpdateByPath(ctx, orgCode, doc, curSeqNo, curVersion)
+	}
+
+	updated, err = d.collection.UpdateByPath(ctx, orgCode, doc, curSeqNo, curVersion)
+	if err == errDocNoMoved {
+		// the copy keeps the number and version of the document, so the update applies to it as it would have here
+		if err = d.moveDocNo(ctx, orgCode, doc.Prefix, doc.Path); err != nil {
+			return nil, err
+		}
+		return d.shared.UpdateByPath(ctx, orgCode, doc, curSeqNo, curVersion)
+	}
+	return updated, err
+}
+
+func (d *layoutDocNoRepository) ListByOrg(ctx context.Context, orgCode string) (docs []*DocNo, err error) {
+	if orgCode == "" {
+		// refused by the repository
+		return d.collection.ListByOrg(ctx, orgCode)
+	}
+
+	layout, err := d.orgLayout(ctx, orgCode)
+	if err != nil {
+		return nil, err
+	}
+	if layout == LayoutShared {
+		return d.shared.ListByOrg(ctx, orgCode)
+	}
+
+	// an organization only has moved documents once it is migrating, so they are all listed while it is in the
+	// collection layout
+	docs, err = d.collection.listByOrg(ctx, orgCode, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = d.checkCollectionLayout(ctx, orgCode); err != errDocNoMoved {
+		return docs, err
+	}
+
+	// a moved document is final until copied, and the copy then wins
+	moved, err := d.shared.ListByOrg(ctx, orgCode)
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]*DocNo, len(docs)+len(moved))
+	for _, doc := range append(docs, moved...) {
+		byPath[doc.Prefix+"\x00"+doc.Path] = doc
+	}
+	docs = make([]*DocNo, 0, len(byPath))
+	for _, doc := range byPath {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Prefix != docs[j].Prefix {
+			return docs[i].Prefix < docs[j].Prefix
+		}
+		return docs[i].Path < docs[j].Path
+	})
+	return docs, nil
+}
+
+func (d *layoutDocNoRepository) SaveByPath(ctx context.Context, orgCode string, doc *DocNo) (saved *DocNo, err error) {
+	if orgCode == "" || doc == nil {
+		// refused by the repository
+		return d.collection.SaveByPath(ctx, orgCode, doc)
+	}
+
+	layout, err := d.orgLayout(ctx, orgCode)
+	if err != nil {
+		return nil, err
+	}
+	if layout == LayoutShared {
+		return d.shared.SaveByPath(ctx, orgCode, doc)
+	}
+
+	// the save may insert the document, so the layout is checked before and after it as for GetByPath
+	err = d.checkCollectionLayout(ctx, orgCode)
+	if err == nil {
+		saved, err = d.collection.SaveByPath(ctx, orgCode, doc)
+	}
+	if err == nil {
+		err = d.checkCollectionLayout(ctx, orgCode)
+	}
+	if err == errDocNoMoved {
+		if err = d.moveDocNo(ctx, orgCode, doc.Prefix, doc.Path); err != nil {
+			return nil, err
+		}
+		return d.shared.SaveByPath(ctx, orgCode, doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (d *layoutDocNoRepository) DeleteByPath(ctx context.Context, orgCode string, docCode string, path string) (err error) {
+	if orgCode == "" {
+		// refused by the repository
+		return d.collection.DeleteByPath(ctx, orgCode, docCode, path)
+	}
+
+	layout, err := d.orgLayout(ctx, orgCode)
+	if err != nil {
+		return err
+	}
+	if layout == LayoutShared {
+		return d.shared.DeleteByPath(ctx, orgCode, docCode, path)
+	}
+
+	if err = d.collection.DeleteByPath(ctx, orgCode, docCode, path); err != nil {
+		return err
+	}
+	if err = d.checkCollectionLayout(ctx, orgCode); err != errDocNoMoved {
+		return err
+	}
+
+	// the document may have moved: it is removed from the org collection first, so that it is not copied again
+	if err = d.removeMovedDocNo(ctx, orgCode, docCode, path); err != nil {
+		return err
+	}
+	return d.shared.DeleteByPath(ctx, orgCode, docCode, path)
+}
+
+// removeMovedDocNo removes the document from the org collection, moved or not
+func (d *layoutDocNoRepository) removeMovedDocNo(ctx context.Context, orgCode string, docCode string, path string) error {
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err := boundSession(ctx, s); err != nil {
+		return err
+	}
+
+	if _, err := d.DB.CurrentDB(s).C(orgCode).RemoveAll(bson.M{"prefix": docCode, "path": path}); err != nil {
+		return fmt.Errorf("Error deleting document with Prefix=%s Path=%s Error=%s", docCode, path, err.Error())
+	}
+	return nil
+}
+
+// readOrgLayouts returns the layouts recorded for the ids, by id
+func readOrgLayouts(db *mgo.Database, ids ...string) (map[string]string, error) {
+	var records []orgLayoutRecord
+	err := db.C(OrgLayoutCollection).Find(bson.M{"_id": bson.M{"$in": ids}}).All(&records)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading organization layouts Error=%s", err.Error())
+	}
+
+	layouts := make(map[string]string, len(records))
+	for _, record := range records {
+		layouts[record.ID] = record.Layout
+	}
+	return layouts, nil
+}
+
+func writeOrgLayout(db *mgo.Database, id string, layout string) error {
+	_, err := db.C(OrgLayoutCollection).UpsertId(id, bson.M{"$set": bson.M{"layout": layout}})
+	if err != nil {
+		return fmt.Errorf("Error recording layout %s of %s Error=%s", layout, id, err.Error())
+	}
+	return nil
+}
+
+// insertOrgLayout records the layout of id unless it has one, and returns the layout recorded
+func insertOrgLayout(db *mgo.Database, id string, layout string) (string, error) {
+	var record orgLayoutRecord
+	_, err := db.C(OrgLayoutCollection).FindId(id).Apply(mgo.Change{
+		Update:    bson.M{"$setOnInsert": bson.M{"layout": layout}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &record)
+	if mgo.IsDup(err) {
+		// a concurrent first call has recorded it
+		err = db.C(OrgLayoutCollection).FindId(id).One(&record)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error recording layout %s of %s Error=%s", layout, id, err.Error())
+	}
+	return record.Layout, nil
+}
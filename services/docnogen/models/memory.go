@@ -0,0 +1,324 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+)
+
+// memoryDocNoRepository is an in-memory reference implementation of DocNoRepository.
+// It keeps the same semantics as the Mongo repository and is used by the service tests
+// and the repository conformance suite (see models/repotest)
+type memoryDocNoRepository struct {
+	mtx  sync.Mutex
+	docs map[string]map[string]*DocNo // orgCode -> prefix/path -> document
+}
+
+func NewMemoryDocNoRepository() (r DocNoRepository) {
+	r = &memoryDocNoRepository{
+		docs: make(map[string]map[string]*DocNo),
+	}
+	return r
+}
+
+func (d *memoryDocNoRepository) GetByPath(ctx context.Context, docCode string, orgCode string, path string) (doc *DocNo, err error) {
+	if docCode == "" {
+		return nil, errors.New("Doc Code is empty")
+	}
+
+	if orgCode == "" {
+		return nil, errors.New("Organization Code is empty")
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	// the document is group by organization code, same as the collection in Mongo
+	org, ok := d.docs[orgCode]
+	if !ok {
+		org = make(map[string]*DocNo)
+		d.docs[orgCode] = org
+	}
+
+	stored, ok := org[memoryKey(docCode, path)]
+	if !ok {
+		// create new document and start with 1
+		stored = &DocNo{
+			Prefix:          docCode,
+			Path:            path,
+			NextSeqNo:       1,
+			RecordTimestamp: time.Now().Unix(),
+			Version:         1,
+		}
+		org[memoryKey(docCode, path)] = stored
+	}
+
+	// always hand out a copy so callers cannot alter the stored document
+	doc = &DocNo{}
+	*doc = *stored
+	return doc, nil
+}
+
+func (d *memoryDocNoRepository) UpdateByPath(ctx context.Context, orgCode string, doc *DocNo, curSeqNo int64, curVersion int64) (updated *DocNo, err error) {
+	if doc == nil {
+		return nil, errors.New("Document to be updated is nil")
+	}
+
+	if orgCode == "" {
+		return nil, errors.New("Organization Code is empty")
+	}
+
+	if doc.Prefix == "" {
+		return nil, errors.New("Document Prefix is empty")
+	}
+
+	if doc.NextSeqNo == 0 {
+		return nil, errors.New("Document Next Sequence No is empty")
+	}
+
+	if curSeqNo == 0 {
+		return nil, errors.New("Current Sequence Number for concurrency check cannot be zero")
+	}
+
+	// version 0 is that of a legacy document, written without one
+	if curVersion < 0 {
+		return nil, errors.New("Current Version for concurrency check cannot be less than zero")
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	// Check if record exists
+	stored, ok := d.docs[orgCode][memoryKey(doc.Prefix, doc.Path)]
+	if !ok {
+		return nil, common.Errorf(common.ReasonNotFound, "Error finding document with Prefix=%s Path=%s Error=%s", doc.Prefix, doc.Path, "not found")
+	}
+
+	// check if record has been altered before update
+	if stored.NextSeqNo != curSeqNo || stored.Version != curVersion {
+		return nil, common.ConcurrencyUpdateError
+	}
+
+	stored.NextSeqNo = doc.NextSeqNo
+	stored.RecordTimestamp = doc.RecordTimestamp
+	stored.Version = curVersion + 1
+
+	updated = &DocNo{}
+	*updated = *stored
+	return updated, nil
+}
+
+func (d *memoryDocNoRepository) ListByOrg(ctx context.Context, orgCode string) (docs []*DocNo, err error) {
+	if orgCode == "" {
+		return nil, errors.New("Organization Code is empty")
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	docs = []*DocNo{}
+	for _, stored := range d.docs[orgCode] {
+		doc := &DocNo{}
+		*doc = *stored
+		docs = append(docs, doc)
+	}
+
+	// same order as the Mongo repository: by prefix, then path
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Prefix != docs[j].Prefix {
+			return docs[i].Prefix < docs[j].Prefix
+		}
+		return docs[i].Path < docs[j].Path
+	})
+	return docs, nil
+}
+
+func (d *memoryDocNoRepository) SaveByPath(ctx context.Context, orgCode string, doc *DocNo) (saved *DocNo, err error) {
+	if doc == nil {
+		return nil, errors.New("Document to be saved is nil")
+	}
+
+	if orgCode == "" {
+		return nil, errors.New("Organization Code is empty")
+	}
+
+	if doc.Prefix == "" {
+		return nil, errors.New("Document Prefix is empty")
+	}
+
+	if doc.NextSeqNo == 0 {
+		return nil, errors.New("Document Next Sequence No is empty")
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	org, ok := d.docs[orgCode]
+	if !ok {
+		org = make(map[string]*DocNo)
+		d.docs[orgCode] = org
+	}
+
+	// the version is bumped as for any other write, so pending conditional updates fail
+	var version int64
+	if existing, ok := org[memoryKey(doc.Prefix, doc.Path)]; ok {
+		version = existing.Version
+	}
+	stored := &DocNo{}
+	*stored = *doc
+	stored.Version = version + 1
+	org[memoryKey(doc.Prefix, doc.Path)] = stored
+
+	saved = &DocNo{}
+	*saved = *stored
+	return saved, nil
+}
+
+func (d *memoryDocNoRepository) DeleteByPath(ctx context.Context, orgCode string, docCode string, path string) (err error) {
+	if docCode == "" {
+		return errors.New("Doc Code is empty")
+	}
+
+	if orgCode == "" {
+		return errors.New("Organization Code is empty")
+	}
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	delete(d.docs[orgCode], memoryKey(docCode, path))
+	return nil
+}
+
+func memoryKey(prefix string, path string) string {
+	return prefix + "\x00" + path
+}
+
+// memoryAPIKeyRepository is an in-memory APIKeyRepository with the semantics of the Mongo one, for tests
+type memoryAPIKeyRepository struct {
+	mtx  sync.Mutex
+	keys map[string]*APIKey // id -> key
+}
+
+func NewMemoryAPIKeyRepository() (r APIKeyRepository) {
+	r = &memoryAPIKeyRepository{
+		keys: make(map[string]*APIKey),
+	}
+	return r
+}
+
+func (r *memoryAPIKeyRepository) Create(ctx context.Context, key *APIKey) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, stored := range r.keys {
+		if stored.ID == key.ID || stored.Hash == key.Hash {
+			return fmt.Errorf("Error inserting API key %s Error=duplicate key", key.ID)
+		}
+	}
+	r.keys[key.ID] = copyAPIKey(key)
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) GetByHash(ctx context.Context, hash string) (key *APIKey, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, stored := range r.keys {
+		if stored.Hash == hash {
+			return copyAPIKey(stored), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryAPIKeyRepository) List(ctx context.Context, orgCode string) (keys []*APIKey, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	keys = []*APIKey{}
+	for _, stored := range r.keys {
+		if orgCode == "" || containsString(stored.Orgs, orgCode) || containsString(stored.Orgs, common.AllOrgs) {
+			keys = append(keys, copyAPIKey(stored))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt != keys[j].CreatedAt {
+			return keys[i].CreatedAt < keys[j].CreatedAt
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (r *memoryAPIKeyRepository) Revoke(ctx context.Context, id string, at int64) (key *APIKey, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	stored, ok := r.keys[id]
+	if !ok {
+		return nil, common.Errorf(common.ReasonNotFound, "API key %s does not exist", id)
+	}
+	if stored.RevokedAt == 0 {
+		stored.RevokedAt = at
+	}
+	return copyAPIKey(stored), nil
+}
+
+func copyAPIKey(key *APIKey) *APIKey {
+	c := *key
+	c.Orgs = append([]string(nil), key.Orgs...)
+	c.Roles = append([]string(nil), key.Roles...)
+	return &c
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
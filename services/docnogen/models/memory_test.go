@@ -0,0 +1,20 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models/repotest"
+)
+
+func Test_MemoryDocNoRepository(t *testing.T) {
+	repotest.Run(t, func() models.DocNoRepository {
+		return models.NewMemoryDocNoRepository()
+	})
+}
+
+func Test_MemoryAPIKeyRepository(t *testing.T) {
+	repotest.RunAPIKeys(t, func() models.APIKeyRepository {
+		return models.NewMemoryAPIKeyRepository()
+	})
+}
@@ -3,8 +3,10 @@ package models
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	context "golang.org/x/net/context"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 
@@ -15,6 +17,7 @@ type DBClient interface {
 	CurrentSession() *mgo.Session
 	CurrentDB(s *mgo.Session) *mgo.Database
 	DialWithInfo() error
+	Ping(ctx context.Context) error
 	Close()
 }
 
@@ -73,24 +76,70 @@ func (m *mongoDBClient) DialWithInfo() error {
 	return nil
 }
 
+// Ping checks the Mongo server can be reached before the deadline of ctx
+func (m *mongoDBClient) Ping(ctx context.Context) error {
+	if m.DBSession == nil {
+		return errors.New("DB Session is nil")
+	}
+
+	s := m.CurrentSession()
+	defer s.Close()
+	if err := boundSession(ctx, s); err != nil {
+		return err
+	}
+	return s.Ping()
+}
+
 func (m *mongoDBClient) Close() {
 	m.DBSession.Close()
 }
 
+// boundSession limits the socket and server selection timeouts of the session to the deadline of ctx,
+// since mgo does not take a context. It returns ctx.Err() if ctx is done already.
+func boundSession(ctx context.Context, s *mgo.Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+		s.SetSocketTimeout(timeout)
+		s.SetSyncTimeout(timeout)
+	}
+	return nil
+}
+
 type DocNo struct {
 	Prefix          string `bson:"prefix"`
 	Path            string `bson:"path"`
 	NextSeqNo       int64  `bson:"nextseqno"`
-	RecordTimestamp int64  `bson:"recordtimestamp"` // Unix timestamp
+	RecordTimestamp int64  `bson:"recordtimestamp"` // Unix timestamp of the last write, informational only
+	Version         int64  `bson:"version"`         // checked and bumped by every write, starts with 1
 }
 
 type DocNoRepository interface {
-	GetByPath(docCode string, orgCode string, path string) (doc *DocNo, err error)
-	UpdateByPath(orgCode string, doc *DocNo, curSeqNo int64, recordTimestampCheck int64) (updated *DocNo, err error)
+	GetByPath(ctx context.Context, docCode string, orgCode string, path string) (doc *DocNo, err error)
+	UpdateByPath(ctx context.Context, orgCode string, doc *DocNo, curSeqNo int64, curVersion int64) (updated *DocNo, err error)
+	ListByOrg(ctx context.Context, orgCode string) (docs []*DocNo, err error)
+	SaveByPath(ctx context.Context, orgCode string, doc *DocNo) (saved *DocNo, err error)
+	DeleteByPath(ctx context.Context, orgCode string, docCode string, path string) (err error)
 }
 
+// errDocNoMoved is returned for a document of an org collection moved to the shared layout, which is only served from
+// there since
+var errDocNoMoved error = &common.Error{Reason: common.ReasonConcurrencyConflict, Message: "Concurrency update error: record has moved to the shared layout"}
+
+// notMoved selects the documents of an org collection not moved to the shared layout
+var notMoved = bson.M{"$exists": false}
+
 type docNoRepository struct {
 	DB DBClient
+
+	// the org collections given the unique index by this repository
+	indexed sync.Map
 }
 
 func NewDocNoRepository(dbClient DBClient) (r DocNoRepository) {
@@ -100,64 +149,99 @@ func NewDocNoRepository(dbClient DBClient) (r DocNoRepository) {
 	return r
 }
 
-func (d *docNoRepository) GetByPath(docCode string, orgCode string, path string) (doc *DocNo, err error) {
+func (d *docNoRepository) GetByPath(ctx context.Context, docCode string, orgCode string, path string) (doc *DocNo, err error) {
+	doc, _, err = d.getByPath(ctx, docCode, orgCode, path, nil)
+	return doc, err
+}
+
+// getByPath returns the document, created telling if it was inserted. beforeCreate, when set, is called before a new
+// document is inserted, and its error returned.
+func (d *docNoRepository) getByPath(ctx context.Context, docCode string, orgCode string, path string, beforeCreate func() error) (doc *DocNo, created bool, err error) {
 	if docCode == "" {
-		return nil, errors.New("Doc Code is empty")
+		return nil, false, errors.New("Doc Code is empty")
 	}
 
 	if orgCode == "" {
-		return nil, errors.New("Organization Code is empty")
+		return nil, false, errors.New("Organization Code is empty")
 	}
 
 	if d.DB == nil {
-		return nil, errors.New("DB Client is Nil")
+		return nil, false, errors.New("DB Client is Nil")
 	}
 
 	// Get Current DB Session
 	s := d.DB.CurrentSession()
 	if s == nil {
-		return nil, fmt.Errorf("DB Session is nil")
+		return nil, false, fmt.Errorf("DB Session is nil")
 	}
 	defer s.Close()
 
+	// bound the Mongo operations by the deadline of the request
+	if err = boundSession(ctx, s); err != nil {
+		return nil, false, err
+	}
+
 	// the document is group by collection (organization code)
 	// perform find doc by colletion and paramter: Path (no unique ID here)
 	collection := d.DB.CurrentDB(s).C(orgCode)
 	if collection == nil {
-		return nil, fmt.Errorf("Collection is nil with Org Code=%s", orgCode)
+		return nil, false, fmt.Errorf("Collection is nil with Org Code=%s", orgCode)
 	}
-	//fmt.Println(collection.FullName)
 
-	err = collection.Find(bson.M{"prefix": docCode, "path": path}).One(&doc)
+	err = collection.Find(bson.M{"prefix": docCode, "path": path, "moved": notMoved}).One(&doc)
 	// if has error and error not equal to document Not Found
 	if err != nil && err.Error() != "not found" {
-		return nil, fmt.Errorf("Error finding document with Path=%s Error=%s", path, err.Error())
+		return nil, false, fmt.Errorf("Error finding document with Path=%s Error=%s", path, err.Error())
 	}
 	// if no document found, we create new
-	fmt.Println("if no document found, we create new")
 	if doc == nil {
 		// create new document and start with 1
-		fmt.Println("create new document and start with 1")
+		common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "creating document", "org", orgCode, "docCode", docCode, "path", path)
 		doc = &DocNo{
 			Prefix:          docCode,
 			Path:            path,
 			NextSeqNo:       1,
 			RecordTimestamp: time.Now().Unix(),
+			Version:         1,
+		}
+
+		if beforeCreate != nil {
+			if err = beforeCreate(); err != nil {
+				return nil, false, err
+			}
+		}
+
+		// a new org collection gets the unique {prefix, path} index before its first document
+		err = d.ensureIndex(collection)
+		if err != nil {
+			return nil, false, err
 		}
 
 		// insert the new document to collection
-		fmt.Println("insert the new document to collection")
 		err = collection.Insert(doc)
-		if err != nil {
-			return nil, fmt.Errorf("Error inserting document with Prefix=%s Path=%s Error=%s", docCode, path, err.Error())
+		created = err == nil
+		if err != nil && mgo.IsDup(err) {
+			// a concurrent first call has inserted the document already, use that one, unless it is the moved one
+			doc = nil
+			err = collection.Find(bson.M{"prefix": docCode, "path": path, "moved": notMoved}).One(&doc)
+			if err == mgo.ErrNotFound {
+				return nil, false, errDocNoMoved
+			}
+			if err != nil {
+				return nil, false, fmt.Errorf("Error finding document with Path=%s Error=%s", path, err.Error())
+			}
+		} else if err != nil {
+			return nil, false, fmt.Errorf("Error inserting document with Prefix=%s Path=%s Error=%s", docCode, path, err.Error())
 		}
 
 	}
-	fmt.Println(doc)
-	return doc, nil
+	common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "found document", "org", orgCode, "docCode", doc.Prefix, "path", doc.Path, "nextSeqNo", doc.NextSeqNo, "version", doc.Version)
+	return doc, created, nil
 }
 
-func (d *docNoRepository) UpdateByPath(orgCode string, doc *DocNo, curSeqNo int64, recordTimestampCheck int64) (updated *DocNo, err error) {
+// UpdateByPath writes NextSeqNo and RecordTimestamp of the document if its NextSeqNo and Version are still curSeqNo and curVersion,
+// and bumps the Version. Otherwise common.ConcurrencyUpdateError is returned. A document without a version has Version 0.
+func (d *docNoRepository) UpdateByPath(ctx context.Context, orgCode string, doc *DocNo, curSeqNo int64, curVersion int64) (updated *DocNo, err error) {
 	if doc == nil {
 		return nil, errors.New("Document to be updated is nil")
 	}
@@ -178,8 +262,9 @@ func (d *docNoRepository) UpdateByPath(orgCode string, doc *DocNo, curSeqNo int6
 		return nil, errors.New("Current Sequence Number for concurrency check cannot be zero")
 	}
 
-	if recordTimestampCheck <= 0 {
-		return nil, errors.New("Record timestamp for concurrency check cannot be zero or less than zero")
+	// version 0 is that of a legacy document, written without one
+	if curVersion < 0 {
+		return nil, errors.New("Current Version for concurrency check cannot be less than zero")
 	}
 
 	// Get Current DB Session
@@ -189,6 +274,11 @@ func (d *docNoRepository) UpdateByPath(orgCode string, doc *DocNo, curSeqNo int6
 	}
 	defer s.Close()
 
+	// bound the Mongo operations by the deadline of the request
+	if err = boundSession(ctx, s); err != nil {
+		return nil, err
+	}
+
 	// the document is group by collection (organization code)
 	// perform find doc by colletion and paramter: Path (no unique ID here)
 	collection := d.DB.CurrentDB(s).C(orgCode)
@@ -197,30 +287,197 @@ func (d *docNoRepository) UpdateByPath(orgCode string, doc *DocNo, curSeqNo int6
 	}
 
 	// Check if record exists
-	fmt.Println("check if record exist with Prefix and Path")
-	err = collection.Find(bson.M{"prefix": doc.Prefix, "path": doc.Path}).One(&updated)
+	err = collection.Find(bson.M{"prefix": doc.Prefix, "path": doc.Path, "moved": notMoved}).One(&updated)
 	// if has error: either error finding record, or record "not found"
+	if err == mgo.ErrNotFound {
+		if moved, countErr := collection.Find(bson.M{"prefix": doc.Prefix, "path": doc.Path}).Count(); countErr == nil && moved > 0 {
+			return nil, errDocNoMoved
+		}
+		return nil, common.Errorf(common.ReasonNotFound, "Error finding document with Prefix=%s Path=%s Error=%s", doc.Prefix, doc.Path, err.Error())
+	}
 	if err != nil {
 		return nil, fmt.Errorf("Error finding document with Prefix=%s Path=%s Error=%s", doc.Prefix, doc.Path, err.Error())
 	}
 
 	// check if record has been altered before update
-	fmt.Println("check if record has been altered before update with CurSeqNo")
 	if updated != nil && updated.NextSeqNo != curSeqNo {
 		return nil, common.ConcurrencyUpdateError
 	}
-	fmt.Println("check if record has been altered before update with RecordTimestamp")
-	if updated != nil && updated.RecordTimestamp != recordTimestampCheck {
+	if updated != nil && updated.Version != curVersion {
 		return nil, common.ConcurrencyUpdateError
 	}
 
-	// partial update the document to collection if record found
-	fmt.Println("update the document to collection")
-	err = collection.Update(bson.M{"prefix": doc.Prefix, "path": doc.Path, "recordtimestamp": recordTimestampCheck}, bson.M{"$set": bson.M{"nextseqno": doc.NextSeqNo, "recordtimestamp": doc.RecordTimestamp}})
+	// partial update the document to collection if record is still the same, bumping its version,
+	// and not moved to the shared layout since
+	err = collection.Update(bson.M{"prefix": doc.Prefix, "path": doc.Path, "nextseqno": curSeqNo, "version": versionSelector(curVersion), "moved": notMoved}, bson.M{"$set": bson.M{"nextseqno": doc.NextSeqNo, "recordtimestamp": doc.RecordTimestamp, "version": curVersion + 1}})
+	if err == mgo.ErrNotFound {
+		// altered between the check and the update
+		return nil, common.ConcurrencyUpdateError
+	}
 	if err != nil {
-		return nil, fmt.Errorf("Error updating document with Prefix=%s Path=%s RecordTimestamp=%d  Error=%s", doc.Prefix, doc.Path, recordTimestampCheck, err.Error())
+		return nil, fmt.Errorf("Error updating document with Prefix=%s Path=%s Version=%d  Error=%s", doc.Prefix, doc.Path, curVersion, err.Error())
 	}
-	updated = doc
-	fmt.Println(doc)
+	updated = &DocNo{}
+	*updated = *doc
+	updated.Version = curVersion + 1
+	common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "updated document", "org", orgCode, "docCode", updated.Prefix, "path", updated.Path, "nextSeqNo", updated.NextSeqNo, "version", updated.Version)
 	return updated, nil
 }
+
+// ensureIndex gives the org collection the unique {prefix, path} index, once
+func (d *docNoRepository) ensureIndex(collection *mgo.Collection) error {
+	if _, ok := d.indexed.Load(collection.Name); ok {
+		return nil
+	}
+	if err := ensureDocNoIndex(collection); err != nil {
+		return err
+	}
+	d.indexed.Store(collection.Name, true)
+	return nil
+}
+
+// versionSelector matches the version of a document. Version 0 is that of a legacy document, without the field, such
+// as those inserted by a build older than schema version 3 or copied from one; its first update sets version 1.
+func versionSelector(version int64) interface{} {
+	if version == 0 {
+		// null also matches a missing field
+		return bson.M{"$in": []interface{}{nil, 0}}
+	}
+	return version
+}
+
+func (d *docNoRepository) ListByOrg(ctx context.Context, orgCode string) (docs []*DocNo, err error) {
+	return d.listByOrg(ctx, orgCode, bson.M{"moved": notMoved})
+}
+
+// listByOrg returns the documents of the org collection matching selector
+func (d *docNoRepository) listByOrg(ctx context.Context, orgCode string, selector bson.M) (docs []*DocNo, err error) {
+	if orgCode == "" {
+		return nil, errors.New("Organization Code is empty")
+	}
+
+	if d.DB == nil {
+		return nil, errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return nil, fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err = boundSession(ctx, s); err != nil {
+		return nil, err
+	}
+
+	collection := d.DB.CurrentDB(s).C(orgCode)
+	if collection == nil {
+		return nil, fmt.Errorf("Collection is nil with Org Code=%s", orgCode)
+	}
+
+	docs = []*DocNo{}
+	err = collection.Find(selector).Sort("prefix", "path").All(&docs)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing documents with Org Code=%s Error=%s", orgCode, err.Error())
+	}
+	return docs, nil
+}
+
+// SaveByPath creates or replaces the document without any concurrency check, it is meant for administrative tasks
+func (d *docNoRepository) SaveByPath(ctx context.Context, orgCode string, doc *DocNo) (saved *DocNo, err error) {
+	if doc == nil {
+		return nil, errors.New("Document to be saved is nil")
+	}
+
+	if orgCode == "" {
+		return nil, errors.New("Organization Code is empty")
+	}
+
+	if doc.Prefix == "" {
+		return nil, errors.New("Document Prefix is empty")
+	}
+
+	if doc.NextSeqNo == 0 {
+		return nil, errors.New("Document Next Sequence No is empty")
+	}
+
+	if d.DB == nil {
+		return nil, errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return nil, fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err = boundSession(ctx, s); err != nil {
+		return nil, err
+	}
+
+	collection := d.DB.CurrentDB(s).C(orgCode)
+	if collection == nil {
+		return nil, fmt.Errorf("Collection is nil with Org Code=%s", orgCode)
+	}
+
+	err = d.ensureIndex(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	// the version is bumped as for any other write, so pending conditional updates fail. A document moved to the
+	// shared layout is not matched, and the upsert then fails on the unique index.
+	_, err = collection.Find(bson.M{"prefix": doc.Prefix, "path": doc.Path, "moved": notMoved}).Apply(mgo.Change{
+		Update:    bson.M{"$set": bson.M{"nextseqno": doc.NextSeqNo, "recordtimestamp": doc.RecordTimestamp}, "$inc": bson.M{"version": 1}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &saved)
+	if mgo.IsDup(err) {
+		return nil, errDocNoMoved
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error saving document with Prefix=%s Path=%s Error=%s", doc.Prefix, doc.Path, err.Error())
+	}
+	return saved, nil
+}
+
+func (d *docNoRepository) DeleteByPath(ctx context.Context, orgCode string, docCode string, path string) (err error) {
+	if docCode == "" {
+		return errors.New("Doc Code is empty")
+	}
+
+	if orgCode == "" {
+		return errors.New("Organization Code is empty")
+	}
+
+	if d.DB == nil {
+		return errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err = boundSession(ctx, s); err != nil {
+		return err
+	}
+
+	collection := d.DB.CurrentDB(s).C(orgCode)
+	if collection == nil {
+		return fmt.Errorf("Collection is nil with Org Code=%s", orgCode)
+	}
+
+	_, err = collection.RemoveAll(bson.M{"prefix": docCode, "path": path, "moved": notMoved})
+	if err != nil {
+		return fmt.Errorf("Error deleting document with Prefix=%s Path=%s Error=%s", docCode, path, err.Error())
+	}
+	return nil
+}
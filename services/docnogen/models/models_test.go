@@ -0,0 +1,287 @@
+package models_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models/repotest"
+)
+
+// Test_DocNoRepository runs the conformance suite against a real Mongo server.
+// It is skipped unless DOCNOGEN_TEST_MONGOADDR is set, e.g. DOCNOGEN_TEST_MONGOADDR=localhost:27017
+func Test_DocNoRepository(t *testing.T) {
+	addr := os.Getenv("DOCNOGEN_TEST_MONGOADDR")
+	if addr == "" {
+		t.Skip("DOCNOGEN_TEST_MONGOADDR is not set")
+	}
+
+	dbclient := models.NewDBClient(addr, "docnogen_test", os.Getenv("DOCNOGEN_TEST_MONGOAUTHUSERNAME"), os.Getenv("DOCNOGEN_TEST_MONGOAUTHPASSWORD"))
+	if err := dbclient.DialWithInfo(); err != nil {
+		t.Fatalf("Failed to establish connection to Mongo Server: %s", err.Error())
+	}
+	defer dbclient.Close()
+
+	repotest.Run(t, func() models.DocNoRepository {
+		return models.NewDocNoRepository(dbclient)
+	})
+}
+
+// Test_SharedDocNoRepository runs the conformance suite against the shared layout, see Test_DocNoRepository
+func Test_SharedDocNoRepository(t *testing.T) {
+	addr := os.Getenv("DOCNOGEN_TEST_MONGOADDR")
+	if addr == "" {
+		t.Skip("DOCNOGEN_TEST_MONGOADDR is not set")
+	}
+
+	dbclient := models.NewDBClient(addr, "docnogen_test", os.Getenv("DOCNOGEN_TEST_MONGOAUTHUSERNAME"), os.Getenv("DOCNOGEN_TEST_MONGOAUTHPASSWORD"))
+	if err := dbclient.DialWithInfo(); err != nil {
+		t.Fatalf("Failed to establish connection to Mongo Server: %s", err.Error())
+	}
+	defer dbclient.Close()
+
+	repotest.Run(t, func() models.DocNoRepository {
+		return models.NewSharedDocNoRepository(dbclient)
+	})
+}
+
+// Test_APIKeyRepository runs the API key conformance suite against a real Mongo server, see Test_DocNoRepository
+func Test_APIKeyRepository(t *testing.T) {
+	addr := os.Getenv("DOCNOGEN_TEST_MONGOADDR")
+	if addr == "" {
+		t.Skip("DOCNOGEN_TEST_MONGOADDR is not set")
+	}
+
+	dbclient := models.NewDBClient(addr, "docnogen_test", os.Getenv("DOCNOGEN_TEST_MONGOAUTHUSERNAME"), os.Getenv("DOCNOGEN_TEST_MONGOAUTHPASSWORD"))
+	if err := dbclient.DialWithInfo(); err != nil {
+		t.Fatalf("Failed to establish connection to Mongo Server: %s", err.Error())
+	}
+	defer dbclient.Close()
+
+	repotest.RunAPIKeys(t, func() models.APIKeyRepository {
+		return models.NewAPIKeyRepository(dbclient)
+	})
+}
+
+func Test_MigrateToSharedLayout(t *testing.T) {
+	addr := os.Getenv("DOCNOGEN_TEST_MONGOADDR")
+	if addr == "" {
+		t.Skip("DOCNOGEN_TEST_MONGOADDR is not set")
+	}
+
+	dbclient := models.NewDBClient(addr, "docnogen_layout_test", os.Getenv("DOCNOGEN_TEST_MONGOAUTHUSERNAME"), os.Getenv("DOCNOGEN_TEST_MONGOAUTHPASSWORD"))
+	if err := dbclient.DialWithInfo(); err != nil {
+		t.Fatalf("Failed to establish connection to Mongo Server: %s", err.Error())
+	}
+	defer dbclient.Close()
+
+	Convey("Given two organizations in the collection layout", t, func() {
+		ctx := context.Background()
+		s := dbclient.CurrentSession()
+		defer s.Close()
+		So(dbclient.CurrentDB(s).DropDatabase(), ShouldBeNil)
+
+		legacy, err := models.NewDocNoRepositoryWithLayout(dbclient, models.LayoutCollection)
+		So(err, ShouldBeNil)
+		_, err = legacy.SaveByPath(ctx, "MAT", &models.DocNo{Prefix: "AP", Path: "AP/PO", NextSeqNo: 7, RecordTimestamp: time.Now().Unix()})
+		So(err, ShouldBeNil)
+		_, err = legacy.SaveByPath(ctx, "NOVA", &models.DocNo{Prefix: "AR", Path: "AR/INV", NextSeqNo: 3, RecordTimestamp: time.Now().Unix()})
+		So(err, ShouldBeNil)
+		pending, err := legacy.GetByPath(ctx, "AP", "MAT", "AP/PO")
+		So(err, ShouldBeNil)
+
+		Convey("The shared layout is refused until they are moved", func() {
+			_, err := models.NewDocNoRepositoryWithLayout(dbclient, models.LayoutShared)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Migrating moves every counter", func() {
+			result, err := models.MigrateToSharedLayout(dbclient, false, 0)
+			So(err, ShouldBeNil)
+			So(result.Orgs, ShouldEqual, 2)
+			So(result.Created, ShouldEqual, 2)
+
+			docs, err := models.NewSharedDocNoRepository(dbclient).ListByOrg(ctx, "NOVA")
+			So(err, ShouldBeNil)
+			So(len(docs), ShouldEqual, 1)
+			So(docs[0].NextSeqNo, ShouldEqual, 3)
+
+			Convey("an update of a moved document under way fails", func() {
+				pending.NextSeqNo++
+				_, err := models.NewDocNoRepository(dbclient).UpdateByPath(ctx, "MAT", pending, 7, pending.Version)
+				So(common.ReasonOf(err), ShouldEqual, common.ReasonConcurrencyConflict)
+			})
+
+			Convey("the org collection no longer returns the moved documents", func() {
+				docs, err := models.NewDocNoRepository(dbclient).ListByOrg(ctx, "MAT")
+				So(err, ShouldBeNil)
+				So(len(docs), ShouldEqual, 0)
+				_, err = models.NewDocNoRepository(dbclient).GetByPath(ctx, "AP", "MAT", "AP/PO")
+				So(common.ReasonOf(err), ShouldEqual, common.ReasonConcurrencyConflict)
+			})
+
+			Convey("instances of either layout serve the moved counters from the shared collection", func() {
+				_, err := legacy.SaveByPath(ctx, "MAT", &models.DocNo{Prefix: "AP", Path: "AP/PO", NextSeqNo: 9, RecordTimestamp: time.Now().Unix()})
+				So(err, ShouldBeNil)
+				shared, err := models.NewDocNoRepositoryWithLayout(dbclient, models.LayoutShared)
+				So(err, ShouldBeNil)
+				doc, err := shared.GetByPath(ctx, "AP", "MAT", "AP/PO")
+				So(err, ShouldBeNil)
+				So(doc.NextSeqNo, ShouldEqual, 9)
+
+				_, err = legacy.GetByPath(ctx, "AP", "NEW", "AP/PO")
+				So(err, ShouldBeNil)
+				docs, err := models.NewSharedDocNoRepository(dbclient).ListByOrg(ctx, "NEW")
+				So(err, ShouldBeNil)
+				So(len(docs), ShouldEqual, 1)
+			})
+
+			Convey("a server remembering the collection layout updates a moved counter in the shared collection", func() {
+				doc, err := legacy.GetByPath(ctx, "AP", "MAT", "AP/PO")
+				So(err, ShouldBeNil)
+				So(doc.NextSeqNo, ShouldEqual, 7)
+				_, err = legacy.UpdateByPath(ctx, "MAT", &models.DocNo{Prefix: "AP", Path: "AP/PO", NextSeqNo: 8}, 7, doc.Version)
+				So(err, ShouldBeNil)
+
+				docs, err := models.NewSharedDocNoRepository(dbclient).ListByOrg(ctx, "MAT")
+				So(err, ShouldBeNil)
+				So(docs[0].NextSeqNo, ShouldEqual, 8)
+			})
+
+			Convey("and running it again only drops the org collections", func() {
+				result, err := models.MigrateToSharedLayout(dbclient, true, 0)
+				So(err, ShouldBeNil)
+				So(result.Orgs, ShouldEqual, 0)
+				So(result.Dropped, ShouldEqual, 2)
+
+				doc, err := legacy.GetByPath(ctx, "AP", "MAT", "AP/PO")
+				So(err, ShouldBeNil)
+				So(doc.NextSeqNo, ShouldEqual, 7)
+			})
+		})
+	})
+}
+
+// Test_LegacyVersion updates documents written without a version, e.g. by an older build during a rolling deploy,
+// see Test_DocNoRepository
+func Test_LegacyVersion(t *testing.T) {
+	addr := os.Getenv("DOCNOGEN_TEST_MONGOADDR")
+	if addr == "" {
+		t.Skip("DOCNOGEN_TEST_MONGOADDR is not set")
+	}
+
+	dbclient := models.NewDBClient(addr, "docnogen_test", os.Getenv("DOCNOGEN_TEST_MONGOAUTHUSERNAME"), os.Getenv("DOCNOGEN_TEST_MONGOAUTHPASSWORD"))
+	if err := dbclient.DialWithInfo(); err != nil {
+		t.Fatalf("Failed to establish connection to Mongo Server: %s", err.Error())
+	}
+	defer dbclient.Close()
+
+	Convey("Given documents without a version in both layouts", t, func() {
+		ctx := context.Background()
+		s := dbclient.CurrentSession()
+		defer s.Close()
+		db := dbclient.CurrentDB(s)
+		orgCode := fmt.Sprintf("LEGACY%d", time.Now().UnixNano())
+		So(db.C(orgCode).Insert(bson.M{"prefix": "AP", "path": "AP/PO", "nextseqno": 7, "recordtimestamp": time.Now().Unix()}), ShouldBeNil)
+		So(db.C(models.SharedCollection).Insert(bson.M{"org": orgCode, "prefix": "AP", "path": "AP/PO", "nextseqno": 7, "recordtimestamp": time.Now().Unix()}), ShouldBeNil)
+
+		for name, repo := range map[string]models.DocNoRepository{"collection": models.NewDocNoRepository(dbclient), "shared": models.NewSharedDocNoRepository(dbclient)} {
+			Convey("the "+name+" layout updates them once, setting version 1", func() {
+				doc, err := repo.GetByPath(ctx, "AP", orgCode, "AP/PO")
+				So(err, ShouldBeNil)
+				So(doc.Version, ShouldEqual, 0)
+
+				doc.NextSeqNo++
+				updated, err := repo.UpdateByPath(ctx, orgCode, doc, 7, 0)
+				So(err, ShouldBeNil)
+				So(updated.Version, ShouldEqual, 1)
+
+				doc.NextSeqNo++
+				_, err = repo.UpdateByPath(ctx, orgCode, doc, 8, 0)
+				So(err, ShouldEqual, common.ConcurrencyUpdateError)
+			})
+		}
+	})
+}
+
+func Test_SchemaMigrator(t *testing.T) {
+	addr := os.Getenv("DOCNOGEN_TEST_MONGOADDR")
+	if addr == "" {
+		t.Skip("DOCNOGEN_TEST_MONGOADDR is not set")
+	}
+
+	dbclient := models.NewDBClient(addr, "docnogen_schema_test", os.Getenv("DOCNOGEN_TEST_MONGOAUTHUSERNAME"), os.Getenv("DOCNOGEN_TEST_MONGOAUTHPASSWORD"))
+	if err := dbclient.DialWithInfo(); err != nil {
+		t.Fatalf("Failed to establish connection to Mongo Server: %s", err.Error())
+	}
+	defer dbclient.Close()
+
+	Convey("Given a store with duplicate documents and no schema version", t, func() {
+		s := dbclient.CurrentSession()
+		defer s.Close()
+		db := dbclient.CurrentDB(s)
+		So(db.DropDatabase(), ShouldBeNil)
+		for _, seqNo := range []int64{3, 9, 5} {
+			So(db.C("MAT").Insert(&models.DocNo{Prefix: "AP", Path: "AP/PO", NextSeqNo: seqNo, RecordTimestamp: time.Now().Unix()}), ShouldBeNil)
+		}
+
+		ctx := context.Background()
+		migrator := models.NewSchemaMigrator(dbclient)
+		version, err := migrator.Version()
+		So(err, ShouldBeNil)
+		So(version, ShouldEqual, 0)
+		So(models.CheckSchemaVersion(ctx, dbclient), ShouldNotBeNil)
+		So(dbclient.Ping(ctx), ShouldBeNil)
+
+		Convey("Migrate keeps the document with the highest NextSeqNo and records the version", func() {
+			from, to, err := migrator.Migrate()
+			So(err, ShouldBeNil)
+			So(from, ShouldEqual, 0)
+			So(to, ShouldEqual, models.SchemaVersion)
+
+			docs, err := models.NewDocNoRepository(dbclient).ListByOrg(ctx, "MAT")
+			So(err, ShouldBeNil)
+			So(len(docs), ShouldEqual, 1)
+			So(docs[0].NextSeqNo, ShouldEqual, 9)
+
+			version, err := migrator.Version()
+			So(err, ShouldBeNil)
+			So(version, ShouldEqual, models.SchemaVersion)
+			So(models.CheckSchemaVersion(ctx, dbclient), ShouldBeNil)
+
+			Convey("and the unique index refuses another duplicate", func() {
+				err := db.C("MAT").Insert(&models.DocNo{Prefix: "AP", Path: "AP/PO", NextSeqNo: 1, RecordTimestamp: time.Now().Unix()})
+				So(mgo.IsDup(err), ShouldBeTrue)
+			})
+
+			Convey("and running it again is a no-op", func() {
+				from, to, err := migrator.Migrate()
+				So(err, ShouldBeNil)
+				So(from, ShouldEqual, models.SchemaVersion)
+				So(to, ShouldEqual, models.SchemaVersion)
+			})
+
+			Convey("and a store migrated further by a newer build is still usable", func() {
+				So(db.C(models.SchemaCollection).UpdateId("docnogen", bson.M{"$set": bson.M{"version": models.SchemaVersion + 1}}), ShouldBeNil)
+				So(models.CheckSchemaVersion(ctx, dbclient), ShouldBeNil)
+				_, _, err := migrator.Migrate()
+				So(err, ShouldBeNil)
+
+				Convey("unless a migration of that build is breaking", func() {
+					So(db.C(models.SchemaCollection).UpdateId("docnogen", bson.M{"$set": bson.M{"minversion": models.SchemaVersion + 1}}), ShouldBeNil)
+					So(models.CheckSchemaVersion(ctx, dbclient), ShouldNotBeNil)
+					_, _, err := migrator.Migrate()
+					So(err, ShouldNotBeNil)
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,108 @@
+package repotest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+// RunAPIKeys executes the conformance suite of models.APIKeyRepository against the repositories returned by newRepo.
+// newRepo is called once per test case.
+func RunAPIKeys(t *testing.T, newRepo func() models.APIKeyRepository) {
+	Convey("Given an APIKeyRepository", t, func() {
+		ctx := context.Background()
+		repo := newRepo()
+		orgCode := newOrgCode()
+		now := time.Now().Unix()
+		newKey := func(name string, orgs ...string) *models.APIKey {
+			id := newOrgCode()
+			return &models.APIKey{
+				ID:        id,
+				Name:      name,
+				Hash:      models.HashAPIKey("secret of " + id),
+				Orgs:      orgs,
+				Roles:     []string{"peek"},
+				CreatedAt: now,
+			}
+		}
+
+		Convey("A created key is found by its hash only", func() {
+			key := newKey("reporting", orgCode)
+			So(repo.Create(ctx, key), ShouldBeNil)
+
+			found, err := repo.GetByHash(ctx, key.Hash)
+			So(err, ShouldBeNil)
+			So(found, ShouldResemble, key)
+
+			found, err = repo.GetByHash(ctx, models.HashAPIKey("unknown"))
+			So(err, ShouldBeNil)
+			So(found, ShouldBeNil)
+		})
+
+		Convey("Keys with the same hash are refused", func() {
+			key := newKey("reporting", orgCode)
+			So(repo.Create(ctx, key), ShouldBeNil)
+			duplicate := newKey("copy", orgCode)
+			duplicate.Hash = key.Hash
+			So(repo.Create(ctx, duplicate), ShouldNotBeNil)
+		})
+
+		Convey("Keys are listed by organization, keys for every organization included", func() {
+			first := newKey("first", orgCode)
+			other := newKey("other", newOrgCode())
+			second := newKey("second", "ANOTHER", orgCode)
+			second.CreatedAt = now + 1
+			for _, key := range []*models.APIKey{first, other, second} {
+				So(repo.Create(ctx, key), ShouldBeNil)
+			}
+
+			keys, err := repo.List(ctx, orgCode)
+			So(err, ShouldBeNil)
+			So(keys, ShouldResemble, []*models.APIKey{first, second})
+
+			all := newKey("all", common.AllOrgs)
+			So(repo.Create(ctx, all), ShouldBeNil)
+			keys, err = repo.List(ctx, orgCode)
+			So(err, ShouldBeNil)
+			So(len(keys), ShouldEqual, 3)
+
+			keys, err = repo.List(ctx, "")
+			So(err, ShouldBeNil)
+			ids := map[string]bool{}
+			for _, key := range keys {
+				ids[key.ID] = true
+			}
+			So(ids[first.ID] && ids[other.ID] && ids[second.ID] && ids[all.ID], ShouldBeTrue)
+		})
+
+		Convey("A revoked key keeps its first revocation time", func() {
+			key := newKey("reporting", orgCode)
+			So(repo.Create(ctx, key), ShouldBeNil)
+			So(key.Active(now), ShouldBeTrue)
+
+			revoked, err := repo.Revoke(ctx, key.ID, now+10)
+			So(err, ShouldBeNil)
+			So(revoked.RevokedAt, ShouldEqual, now+10)
+			So(revoked.Active(now+10), ShouldBeFalse)
+
+			revoked, err = repo.Revoke(ctx, key.ID, now+20)
+			So(err, ShouldBeNil)
+			So(revoked.RevokedAt, ShouldEqual, now+10)
+
+			found, err := repo.GetByHash(ctx, key.Hash)
+			So(err, ShouldBeNil)
+			So(found.RevokedAt, ShouldEqual, now+10)
+		})
+
+		Convey("Revoking an unknown key is NOT_FOUND", func() {
+			_, err := repo.Revoke(ctx, fmt.Sprintf("UNKNOWN%d", now), now)
+			So(common.ReasonOf(err), ShouldEqual, common.ReasonNotFound)
+		})
+	})
+}
@@ -0,0 +1,396 @@
+// Package repotest provides a conformance test suite that every
+// models.DocNoRepository implementation is expected to pass.
+//
+// Usage from an implementation's test file:
+//
+//	func TestMyRepository(t *testing.T) {
+//		repotest.Run(t, func() models.DocNoRepository {
+//			return NewMyRepository(...)
+//		})
+//	}
+package repotest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+var (
+	// Concurrency is the number of goroutines used by the concurrent increment test
+	Concurrency = 8
+	// IncrementsPerWorker is the number of sequence numbers each goroutine consumes
+	IncrementsPerWorker = 25
+	// MaxAttempts bounds the retries of a single increment, so a broken repository fails instead of hanging
+	MaxAttempts = 1000
+)
+
+var orgSeq int64
+
+// newOrgCode returns an organization code that is unique within the process,
+// so suites running against a shared database do not see each other's documents
+func newOrgCode() string {
+	return fmt.Sprintf("REPOTEST%d%d", time.Now().UnixNano(), atomic.AddInt64(&orgSeq, 1))
+}
+
+// Increment consumes the next sequence number of the document with the
+// conditional update of the repository, retrying on common.ConcurrencyUpdateError.
+// It returns the sequence number that was consumed.
+func Increment(ctx context.Context, repo models.DocNoRepository, docCode string, orgCode string, path string) (int64, error) {
+	for attempt := 0; attempt < MaxAttempts; attempt++ {
+		doc, err := repo.GetByPath(ctx, docCode, orgCode, path)
+		if err != nil {
+			return 0, err
+		}
+		if doc == nil {
+			return 0, fmt.Errorf("GetByPath returned no document with DocCode=%s OrgCode=%s Path=%s", docCode, orgCode, path)
+		}
+
+		currSeqNo := doc.NextSeqNo
+		doc.NextSeqNo++
+		doc.RecordTimestamp = time.Now().Unix()
+
+		_, err = repo.UpdateByPath(ctx, orgCode, doc, currSeqNo, doc.Version)
+		if err == common.ConcurrencyUpdateError {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return currSeqNo, nil
+	}
+
+	return 0, fmt.Errorf("Gave up consuming sequence number after %d attempts", MaxAttempts)
+}
+
+// Run executes the conformance suite against the repositories returned by newRepo.
+// newRepo is called once per test case.
+func Run(t *testing.T, newRepo func() models.DocNoRepository) {
+	Convey("Given a DocNoRepository", t, func() {
+		ctx := context.Background()
+		repo := newRepo()
+		orgCode := newOrgCode()
+		docCode := "AP"
+		path := "AP/PO/HQ/19"
+
+		Convey("GetByPath rejects an empty Doc Code or Organization Code", func() {
+			doc, err := repo.GetByPath(ctx, "", orgCode, path)
+			So(err, ShouldNotBeNil)
+			So(doc, ShouldBeNil)
+
+			doc, err = repo.GetByPath(ctx, docCode, "", path)
+			So(err, ShouldNotBeNil)
+			So(doc, ShouldBeNil)
+		})
+
+		Convey("Operations with a done context are refused with the context error", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			_, err := repo.GetByPath(cancelled, docCode, orgCode, path)
+			So(err, ShouldEqual, context.Canceled)
+
+			expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+			defer cancel()
+			_, err = repo.ListByOrg(expired, orgCode)
+			So(err, ShouldResemble, context.DeadlineExceeded)
+
+			docs, err := repo.ListByOrg(ctx, orgCode)
+			So(err, ShouldBeNil)
+			So(docs, ShouldBeEmpty)
+		})
+
+		Convey("When a path is used for the first time", func() {
+			doc, err := repo.GetByPath(ctx, docCode, orgCode, path)
+
+			Convey("A document starting with sequence number 1 is created", func() {
+				So(err, ShouldBeNil)
+				So(doc, ShouldNotBeNil)
+				So(doc.Prefix, ShouldEqual, docCode)
+				So(doc.Path, ShouldEqual, path)
+				So(doc.NextSeqNo, ShouldEqual, 1)
+				So(doc.RecordTimestamp, ShouldBeGreaterThan, 0)
+				So(doc.Version, ShouldEqual, 1)
+			})
+
+			Convey("Reading it again returns the same document instead of creating another one", func() {
+				again, err := repo.GetByPath(ctx, docCode, orgCode, path)
+				So(err, ShouldBeNil)
+				So(again, ShouldResemble, doc)
+			})
+
+			Convey("Documents are kept apart by organization, doc code and path", func() {
+				_, err := Increment(ctx, repo, docCode, orgCode, path)
+				So(err, ShouldBeNil)
+
+				other, err := repo.GetByPath(ctx, docCode, newOrgCode(), path)
+				So(err, ShouldBeNil)
+				So(other.NextSeqNo, ShouldEqual, 1)
+
+				other, err = repo.GetByPath(ctx, "AR", orgCode, path)
+				So(err, ShouldBeNil)
+				So(other.NextSeqNo, ShouldEqual, 1)
+
+				other, err = repo.GetByPath(ctx, docCode, orgCode, path+"/01")
+				So(err, ShouldBeNil)
+				So(other.NextSeqNo, ShouldEqual, 1)
+			})
+		})
+
+		Convey("Concurrent first use of a path creates a single document", func() {
+			var (
+				wg   sync.WaitGroup
+				mtx  sync.Mutex
+				docs []*models.DocNo
+				errs []error
+			)
+			for w := 0; w < Concurrency; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					doc, err := repo.GetByPath(ctx, docCode, orgCode, path)
+					mtx.Lock()
+					if err != nil {
+						errs = append(errs, err)
+					} else {
+						docs = append(docs, doc)
+					}
+					mtx.Unlock()
+				}()
+			}
+			wg.Wait()
+
+			So(errs, ShouldBeEmpty)
+			for _, doc := range docs {
+				So(doc.NextSeqNo, ShouldEqual, 1)
+			}
+
+			stored, err := repo.ListByOrg(ctx, orgCode)
+			So(err, ShouldBeNil)
+			So(len(stored), ShouldEqual, 1)
+		})
+
+		Convey("When updating a document conditionally", func() {
+			doc, err := repo.GetByPath(ctx, docCode, orgCode, path)
+			So(err, ShouldBeNil)
+			currSeqNo := doc.NextSeqNo
+			currRecordTimestamp := doc.RecordTimestamp
+			currVersion := doc.Version
+
+			Convey("An update with the current values succeeds and bumps the version", func() {
+				doc.NextSeqNo++
+				doc.RecordTimestamp = currRecordTimestamp + 1
+				updated, err := repo.UpdateByPath(ctx, orgCode, doc, currSeqNo, currVersion)
+				So(err, ShouldBeNil)
+				So(updated, ShouldNotBeNil)
+				So(updated.NextSeqNo, ShouldEqual, currSeqNo+1)
+				So(updated.Version, ShouldEqual, currVersion+1)
+
+				stored, err := repo.GetByPath(ctx, docCode, orgCode, path)
+				So(err, ShouldBeNil)
+				So(stored.NextSeqNo, ShouldEqual, currSeqNo+1)
+				So(stored.RecordTimestamp, ShouldEqual, currRecordTimestamp+1)
+				So(stored.Version, ShouldEqual, currVersion+1)
+			})
+
+			Convey("Updates within the same second are still told apart by the version", func() {
+				first := *doc
+				first.NextSeqNo++
+				_, err := repo.UpdateByPath(ctx, orgCode, &first, currSeqNo, currVersion)
+				So(err, ShouldBeNil)
+
+				stored, err := repo.GetByPath(ctx, docCode, orgCode, path)
+				So(err, ShouldBeNil)
+				second := *stored
+				second.NextSeqNo++
+				_, err = repo.UpdateByPath(ctx, orgCode, &second, stored.NextSeqNo, stored.Version)
+				So(err, ShouldBeNil)
+
+				// the current NextSeqNo, but the version of the first update
+				stale := *doc
+				stale.NextSeqNo = currSeqNo + 3
+				_, err = repo.UpdateByPath(ctx, orgCode, &stale, currSeqNo+2, currVersion+1)
+				So(err, ShouldEqual, common.ConcurrencyUpdateError)
+			})
+
+			Convey("An update with a stale sequence number returns common.ConcurrencyUpdateError", func() {
+				doc.NextSeqNo = currSeqNo + 2
+				doc.RecordTimestamp = currRecordTimestamp + 1
+				updated, err := repo.UpdateByPath(ctx, orgCode, doc, currSeqNo+1, currVersion)
+				So(err, ShouldEqual, common.ConcurrencyUpdateError)
+				So(updated, ShouldBeNil)
+
+				stored, err := repo.GetByPath(ctx, docCode, orgCode, path)
+				So(err, ShouldBeNil)
+				So(stored.NextSeqNo, ShouldEqual, currSeqNo)
+				So(stored.RecordTimestamp, ShouldEqual, currRecordTimestamp)
+				So(stored.Version, ShouldEqual, currVersion)
+			})
+
+			Convey("An update with a stale version returns common.ConcurrencyUpdateError", func() {
+				doc.NextSeqNo = currSeqNo + 1
+				doc.RecordTimestamp = currRecordTimestamp + 2
+				updated, err := repo.UpdateByPath(ctx, orgCode, doc, currSeqNo, currVersion+1)
+				So(err, ShouldEqual, common.ConcurrencyUpdateError)
+				So(updated, ShouldBeNil)
+
+				stored, err := repo.GetByPath(ctx, docCode, orgCode, path)
+				So(err, ShouldBeNil)
+				So(stored.NextSeqNo, ShouldEqual, currSeqNo)
+			})
+
+			Convey("A second update based on the same read loses", func() {
+				first := *doc
+				first.NextSeqNo++
+				first.RecordTimestamp = currRecordTimestamp + 1
+				_, err := repo.UpdateByPath(ctx, orgCode, &first, currSeqNo, currVersion)
+				So(err, ShouldBeNil)
+
+				second := *doc
+				second.NextSeqNo++
+				second.RecordTimestamp = currRecordTimestamp + 2
+				_, err = repo.UpdateByPath(ctx, orgCode, &second, currSeqNo, currVersion)
+				So(err, ShouldEqual, common.ConcurrencyUpdateError)
+			})
+
+			Convey("Invalid update arguments are rejected", func() {
+				_, err := repo.UpdateByPath(ctx, orgCode, nil, currSeqNo, currVersion)
+				So(err, ShouldNotBeNil)
+
+				_, err = repo.UpdateByPath(ctx, "", doc, currSeqNo, currVersion)
+				So(err, ShouldNotBeNil)
+
+				_, err = repo.UpdateByPath(ctx, orgCode, doc, 0, currVersion)
+				So(err, ShouldNotBeNil)
+
+				_, err = repo.UpdateByPath(ctx, orgCode, doc, currSeqNo, 0)
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("Documents can be listed, saved and deleted by organization", func() {
+			docs, err := repo.ListByOrg(ctx, orgCode)
+			So(err, ShouldBeNil)
+			So(docs, ShouldBeEmpty)
+
+			_, err = repo.GetByPath(ctx, "AR", orgCode, path)
+			So(err, ShouldBeNil)
+			saved, err := repo.SaveByPath(ctx, orgCode, &models.DocNo{Prefix: docCode, Path: path, NextSeqNo: 42, RecordTimestamp: time.Now().Unix()})
+			So(err, ShouldBeNil)
+			So(saved.NextSeqNo, ShouldEqual, 42)
+			So(saved.Version, ShouldEqual, 1)
+
+			docs, err = repo.ListByOrg(ctx, orgCode)
+			So(err, ShouldBeNil)
+			So(len(docs), ShouldEqual, 2)
+			So(docs[0].Prefix, ShouldEqual, docCode)
+			So(docs[0].NextSeqNo, ShouldEqual, 42)
+			So(docs[1].Prefix, ShouldEqual, "AR")
+			So(docs[1].NextSeqNo, ShouldEqual, 1)
+
+			Convey("saving a document again bumps its version", func() {
+				saved, err := repo.SaveByPath(ctx, orgCode, &models.DocNo{Prefix: docCode, Path: path, NextSeqNo: 50, RecordTimestamp: time.Now().Unix()})
+				So(err, ShouldBeNil)
+				So(saved.Version, ShouldEqual, 2)
+			})
+
+			Convey("a saved document keeps working with conditional updates", func() {
+				seqNo, err := Increment(ctx, repo, docCode, orgCode, path)
+				So(err, ShouldBeNil)
+				So(seqNo, ShouldEqual, 42)
+			})
+
+			Convey("a deleted document starts over on next use", func() {
+				err := repo.DeleteByPath(ctx, orgCode, docCode, path)
+				So(err, ShouldBeNil)
+
+				docs, err := repo.ListByOrg(ctx, orgCode)
+				So(err, ShouldBeNil)
+				So(len(docs), ShouldEqual, 1)
+
+				doc, err := repo.GetByPath(ctx, docCode, orgCode, path)
+				So(err, ShouldBeNil)
+				So(doc.NextSeqNo, ShouldEqual, 1)
+			})
+		})
+
+		Convey("Sequence numbers are monotonic", func() {
+			var consumed []int64
+			for i := 0; i < 20; i++ {
+				seqNo, err := Increment(ctx, repo, docCode, orgCode, path)
+				So(err, ShouldBeNil)
+				consumed = append(consumed, seqNo)
+			}
+
+			for i := 1; i < len(consumed); i++ {
+				So(consumed[i], ShouldEqual, consumed[i-1]+1)
+			}
+
+			stored, err := repo.GetByPath(ctx, docCode, orgCode, path)
+			So(err, ShouldBeNil)
+			So(stored.NextSeqNo, ShouldEqual, 21)
+
+			Convey("and cannot be moved backwards with a stale read", func() {
+				stale := *stored
+				stale.NextSeqNo = 1
+				_, err := repo.UpdateByPath(ctx, orgCode, &stale, 1, stored.Version)
+				So(err, ShouldEqual, common.ConcurrencyUpdateError)
+
+				stored, err := repo.GetByPath(ctx, docCode, orgCode, path)
+				So(err, ShouldBeNil)
+				So(stored.NextSeqNo, ShouldEqual, 21)
+			})
+		})
+
+		Convey("Concurrent increments neither duplicate nor lose sequence numbers", func() {
+			// create the document up front, concurrent first use is a separate concern of the store
+			_, err := repo.GetByPath(ctx, docCode, orgCode, path)
+			So(err, ShouldBeNil)
+
+			var (
+				wg       sync.WaitGroup
+				mtx      sync.Mutex
+				consumed []int64
+				errs     []error
+			)
+			for w := 0; w < Concurrency; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < IncrementsPerWorker; i++ {
+						seqNo, err := Increment(ctx, repo, docCode, orgCode, path)
+						mtx.Lock()
+						if err != nil {
+							errs = append(errs, err)
+						} else {
+							consumed = append(consumed, seqNo)
+						}
+						mtx.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			So(errs, ShouldBeEmpty)
+			total := Concurrency * IncrementsPerWorker
+			So(len(consumed), ShouldEqual, total)
+
+			sort.Slice(consumed, func(i, j int) bool { return consumed[i] < consumed[j] })
+			for i, seqNo := range consumed {
+				So(seqNo, ShouldEqual, int64(i+1))
+			}
+
+			stored, err := repo.GetByPath(ctx, docCode, orgCode, path)
+			So(err, ShouldBeNil)
+			So(stored.NextSeqNo, ShouldEqual, int64(total+1))
+		})
+	})
+}
@@ -0,0 +1,305 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	context "golang.org/x/net/context"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// SchemaCollection holds the schema version record, it is never treated as an org collection
+	SchemaCollection = "_schema"
+	schemaRecordID   = "docnogen"
+)
+
+// docNoIndex makes {prefix, path} unique within an org collection
+var docNoIndex = mgo.Index{
+	Key:        []string{"prefix", "path"},
+	Unique:     true,
+	Background: true,
+	Name:       "prefix_path_unique",
+}
+
+// Migration upgrades the Mongo store from Version-1 to Version.
+// Migrations must be safe to run again, since several instances may start at the same time.
+// A Breaking migration leaves a store that builds expecting an older version cannot use, so that they refuse it.
+type Migration struct {
+	Version     int
+	Description string
+	Breaking    bool
+	Up          func(db *mgo.Database) error
+}
+
+// Migrations lists every schema migration in version order, the last one is the current schema version
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "remove duplicate {prefix, path} documents keeping the highest NextSeqNo, and create the unique {prefix, path} index",
+		Up:          migrateUniqueDocNoIndex,
+	},
+	{
+		Version:     2,
+		Description: "create the unique {org, prefix, path} index of the shared collection",
+		Up:          migrateSharedDocNoIndex,
+	},
+	{
+		Version:     3,
+		Description: "set version 1 on documents written before the version field existed",
+		Up:          migrateDocNoVersion,
+	},
+	{
+		Version:     4,
+		Description: "create the unique hash index of the API key collection",
+		Up:          migrateAPIKeyIndex,
+	},
+}
+
+// SchemaVersion is the schema version this build expects
+var SchemaVersion = Migrations[len(Migrations)-1].Version
+
+type SchemaMigrator interface {
+	Version() (version int, err error)
+	Migrate() (from int, to int, err error)
+}
+
+type mongoSchemaMigrator struct {
+	DB DBClient
+}
+
+func NewSchemaMigrator(dbClient DBClient) (m SchemaMigrator) {
+	m = &mongoSchemaMigrator{
+		DB: dbClient,
+	}
+	return m
+}
+
+type schemaRecord struct {
+	ID      string `bson:"_id"`
+	Version int    `bson:"version"`
+	// MinVersion is the version of the last breaking migration, builds expecting an older one cannot use the store
+	MinVersion int   `bson:"minversion,omitempty"`
+	UpdatedAt  int64 `bson:"updatedat"` // Unix timestamp
+}
+
+// Version returns the schema version recorded in the store, 0 if no migration has ever run
+func (m *mongoSchemaMigrator) Version() (version int, err error) {
+	if m.DB == nil {
+		return 0, errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := m.DB.CurrentSession()
+	if s == nil {
+		return 0, fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	record, err := readSchemaRecord(m.DB.CurrentDB(s))
+	return record.Version, err
+}
+
+// Migrate runs every migration newer than the recorded schema version, recording the version after each one.
+// A store migrated further by a newer build is left as is, unless one of its migrations is breaking.
+func (m *mongoSchemaMigrator) Migrate() (from int, to int, err error) {
+	if m.DB == nil {
+		return 0, 0, errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := m.DB.CurrentSession()
+	if s == nil {
+		return 0, 0, fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	db := m.DB.CurrentDB(s)
+	record, err := readSchemaRecord(db)
+	if err != nil {
+		return 0, 0, err
+	}
+	from = record.Version
+	if err = checkMinVersion(record); err != nil {
+		return from, from, err
+	}
+
+	to = from
+	for _, migration := range Migrations {
+		if migration.Version <= from {
+			continue
+		}
+
+		err = migration.Up(db)
+		if err != nil {
+			return from, to, fmt.Errorf("Error migrating schema to version %d (%s) Error=%s", migration.Version, migration.Description, err.Error())
+		}
+
+		// $max so that an instance running an older migration never lowers the version
+		max := bson.M{"version": migration.Version}
+		if migration.Breaking {
+			max["minversion"] = migration.Version
+		}
+		_, err = db.C(SchemaCollection).UpsertId(schemaRecordID, bson.M{"$max": max, "$set": bson.M{"updatedat": time.Now().Unix()}})
+		if err != nil {
+			return from, to, fmt.Errorf("Error recording schema version %d Error=%s", migration.Version, err.Error())
+		}
+		to = migration.Version
+	}
+
+	return from, to, nil
+}
+
+// CheckSchemaVersion returns an error when the store is not yet migrated to the schema version this build expects, or
+// was migrated by a newer build through a breaking migration, reading it before the deadline of ctx. A store migrated
+// further without breaking migrations is fine, so that older instances stay ready during a rolling upgrade.
+func CheckSchemaVersion(ctx context.Context, dbClient DBClient) error {
+	if dbClient == nil {
+		return errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := dbClient.CurrentSession()
+	if s == nil {
+		return fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+	if err := boundSession(ctx, s); err != nil {
+		return err
+	}
+
+	record, err := readSchemaRecord(dbClient.CurrentDB(s))
+	if err != nil {
+		return err
+	}
+	if record.Version < SchemaVersion {
+		return fmt.Errorf("Schema version is %d, this build expects %d", record.Version, SchemaVersion)
+	}
+	return checkMinVersion(record)
+}
+
+// checkMinVersion returns an error when a breaking migration newer than this build has run
+func checkMinVersion(record schemaRecord) error {
+	if record.MinVersion > SchemaVersion {
+		return fmt.Errorf("Schema version %d needs a build expecting version %d or newer, this build expects %d", record.Version, record.MinVersion, SchemaVersion)
+	}
+	return nil
+}
+
+// readSchemaRecord returns the recorded schema version, version 0 if no migration has ever run
+func readSchemaRecord(db *mgo.Database) (schemaRecord, error) {
+	var record schemaRecord
+	err := db.C(SchemaCollection).FindId(schemaRecordID).One(&record)
+	if err == mgo.ErrNotFound {
+		return schemaRecord{}, nil
+	}
+	if err != nil {
+		return schemaRecord{}, fmt.Errorf("Error reading schema version Error=%s", err.Error())
+	}
+	return record, nil
+}
+
+// orgCollectionNames returns the names of the collections holding documents of an organization
+func orgCollectionNames(db *mgo.Database) ([]string, error) {
+	names, err := db.CollectionNames()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing collections Error=%s", err.Error())
+	}
+
+	orgs := []string{}
+	for _, name := range names {
+		if !IsOrgCollectionName(name) {
+			continue
+		}
+		orgs = append(orgs, name)
+	}
+	return orgs, nil
+}
+
+// ensureDocNoIndex creates the unique {prefix, path} index on an org collection
+func ensureDocNoIndex(collection *mgo.Collection) error {
+	if err := collection.EnsureIndex(docNoIndex); err != nil {
+		return fmt.Errorf("Error creating index on collection %s Error=%s", collection.Name, err.Error())
+	}
+	return nil
+}
+
+func migrateUniqueDocNoIndex(db *mgo.Database) error {
+	orgs, err := orgCollectionNames(db)
+	if err != nil {
+		return err
+	}
+
+	for _, orgCode := range orgs {
+		collection := db.C(orgCode)
+		if err := removeDuplicateDocNos(collection); err != nil {
+			return err
+		}
+		if err := ensureDocNoIndex(collection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeDuplicateDocNos keeps one document per {prefix, path}: the one with the highest NextSeqNo,
+// so no sequence number that may have been handed out is issued again
+func removeDuplicateDocNos(collection *mgo.Collection) error {
+	var groups []struct {
+		ID struct {
+			Prefix string `bson:"prefix"`
+			Path   string `bson:"path"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+	err := collection.Pipe([]bson.M{
+		{"$group": bson.M{"_id": bson.M{"prefix": "$prefix", "path": "$path"}, "count": bson.M{"$sum": 1}}},
+		{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+	}).AllowDiskUse().All(&groups)
+	if err != nil {
+		return fmt.Errorf("Error finding duplicate documents in collection %s Error=%s", collection.Name, err.Error())
+	}
+
+	for _, group := range groups {
+		var dups []struct {
+			ID interface{} `bson:"_id"`
+		}
+		err = collection.Find(bson.M{"prefix": group.ID.Prefix, "path": group.ID.Path}).Sort("-nextseqno", "-recordtimestamp").Select(bson.M{"_id": 1}).All(&dups)
+		if err != nil {
+			return fmt.Errorf("Error reading duplicate documents with Prefix=%s Path=%s Error=%s", group.ID.Prefix, group.ID.Path, err.Error())
+		}
+
+		if len(dups) < 2 {
+			continue
+		}
+		for _, dup := range dups[1:] {
+			err = collection.RemoveId(dup.ID)
+			if err != nil && err != mgo.ErrNotFound {
+				return fmt.Errorf("Error removing duplicate document with Prefix=%s Path=%s Error=%s", group.ID.Prefix, group.ID.Path, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+func migrateSharedDocNoIndex(db *mgo.Database) error {
+	return ensureSharedDocNoIndex(db.C(SharedCollection))
+}
+
+func migrateDocNoVersion(db *mgo.Database) error {
+	collections, err := orgCollectionNames(db)
+	if err != nil {
+		return err
+	}
+	collections = append(collections, SharedCollection)
+
+	for _, name := range collections {
+		_, err := db.C(name).UpdateAll(bson.M{"version": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"version": 1}})
+		if err != nil {
+			return fmt.Errorf("Error setting version on collection %s Error=%s", name, err.Error())
+		}
+	}
+	return nil
+}
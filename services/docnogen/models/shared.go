@@ -0,0 +1,503 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	context "golang.org/x/net/context"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+)
+
+const (
+	// LayoutCollection keeps the documents of each organization in a collection named after the org code
+	LayoutCollection = "collection"
+	// LayoutShared keeps the documents of every organization in SharedCollection
+	LayoutShared = "shared"
+
+	// SharedCollection holds the documents of every organization in the shared layout
+	SharedCollection = "_docnos"
+)
+
+// sharedDocNoIndex makes {org, prefix, path} unique, with org first so that it can back a shard key
+var sharedDocNoIndex = mgo.Index{
+	Key:        []string{"org", "prefix", "path"},
+	Unique:     true,
+	Background: true,
+	Name:       "org_prefix_path_unique",
+}
+
+type sharedDocNo struct {
+	OrgCode string `bson:"org"`
+	DocNo   `bson:",inline"`
+}
+
+type sharedDocNoRepository struct {
+	DB DBClient
+}
+
+// NewSharedDocNoRepository keeps the documents of every organization in SharedCollection
+func NewSharedDocNoRepository(dbClient DBClient) (r DocNoRepository) {
+	r = &sharedDocNoRepository{
+		DB: dbClient,
+	}
+	return r
+}
+
+func sharedSelector(orgCode string, docCode string, path string) bson.M {
+	return bson.M{"org": orgCode, "prefix": docCode, "path": path}
+}
+
+func (d *sharedDocNoRepository) GetByPath(ctx context.Context, docCode string, orgCode string, path string) (doc *DocNo, err error) {
+	if docCode == "" {
+		return nil, errors.New("Doc Code is empty")
+	}
+
+	if orgCode == "" {
+		return nil, errors.New("Organization Code is empty")
+	}
+
+	if d.DB == nil {
+		return nil, errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return nil, fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err = boundSession(ctx, s); err != nil {
+		return nil, err
+	}
+
+	collection := d.DB.CurrentDB(s).C(SharedCollection)
+
+	var stored sharedDocNo
+	err = collection.Find(sharedSelector(orgCode, docCode, path)).One(&stored)
+	if err == nil {
+		return &stored.DocNo, nil
+	}
+	if err != mgo.ErrNotFound {
+		return nil, fmt.Errorf("Error finding document with Org Code=%s Path=%s Error=%s", orgCode, path, err.Error())
+	}
+
+	// create new document and start with 1
+	stored = sharedDocNo{
+		OrgCode: orgCode,
+		DocNo: DocNo{
+			Prefix:          docCode,
+			Path:            path,
+			NextSeqNo:       1,
+			RecordTimestamp: time.Now().Unix(),
+			Version:         1,
+		},
+	}
+
+	err = ensureSharedDocNoIndex(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	err = collection.Insert(&stored)
+	if err != nil && mgo.IsDup(err) {
+		// a concurrent first call has inserted the document already, use that one
+		stored = sharedDocNo{}
+		err = collection.Find(sharedSelector(orgCode, docCode, path)).One(&stored)
+		if err != nil {
+			return nil, fmt.Errorf("Error finding document with Org Code=%s Path=%s Error=%s", orgCode, path, err.Error())
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("Error inserting document with Org Code=%s Prefix=%s Path=%s Error=%s", orgCode, docCode, path, err.Error())
+	}
+
+	return &stored.DocNo, nil
+}
+
+func (d *sharedDocNoRepository) UpdateByPath(ctx context.Context, orgCode string, doc *DocNo, curSeqNo int64, curVersion int64) (updated *DocNo, err error) {
+	if doc == nil {
+		return nil, errors.New("Document to be updated is nil")
+	}
+
+	if orgCode == "" {
+		return nil, errors.New("Organization Code is empty")
+	}
+
+	if doc.Prefix == "" {
+		return nil, errors.New("Document Prefix is empty")
+	}
+
+	if doc.NextSeqNo == 0 {
+		return nil, errors.New("Document Next Sequence No is empty")
+	}
+
+	if curSeqNo == 0 {
+		return nil, errors.New("Current Sequence Number for concurrency check cannot be zero")
+	}
+
+	// version 0 is that of a legacy document, written without one
+	if curVersion < 0 {
+		return nil, errors.New("Current Version for concurrency check cannot be less than zero")
+	}
+
+	if d.DB == nil {
+		return nil, errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return nil, fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err = boundSession(ctx, s); err != nil {
+		return nil, err
+	}
+
+	collection := d.DB.CurrentDB(s).C(SharedCollection)
+
+	// the concurrency check and the update are a single conditional update
+	selector := sharedSelector(orgCode, doc.Prefix, doc.Path)
+	selector["nextseqno"] = curSeqNo
+	selector["version"] = versionSelector(curVersion)
+	err = collection.Update(selector, bson.M{"$set": bson.M{"nextseqno": doc.NextSeqNo, "recordtimestamp": doc.RecordTimestamp, "version": curVersion + 1}})
+	if err == mgo.ErrNotFound {
+		// either the document does not exist or it has been altered
+		n, cerr := collection.Find(sharedSelector(orgCode, doc.Prefix, doc.Path)).Count()
+		if cerr != nil {
+			return nil, fmt.Errorf("Error finding document with Org Code=%s Prefix=%s Path=%s Error=%s", orgCode, doc.Prefix, doc.Path, cerr.Error())
+		}
+		if n == 0 {
+			return nil, common.Errorf(common.ReasonNotFound, "Error finding document with Org Code=%s Prefix=%s Path=%s Error=%s", orgCode, doc.Prefix, doc.Path, err.Error())
+		}
+		return nil, common.ConcurrencyUpdateError
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error updating document with Org Code=%s Prefix=%s Path=%s Version=%d Error=%s", orgCode, doc.Prefix, doc.Path, curVersion, err.Error())
+	}
+
+	updated = &DocNo{}
+	*updated = *doc
+	updated.Version = curVersion + 1
+	return updated, nil
+}
+
+func (d *sharedDocNoRepository) ListByOrg(ctx context.Context, orgCode string) (docs []*DocNo, err error) {
+	if orgCode == "" {
+		return nil, errors.New("Organization Code is empty")
+	}
+
+	if d.DB == nil {
+		return nil, errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return nil, fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err = boundSession(ctx, s); err != nil {
+		return nil, err
+	}
+
+	var stored []sharedDocNo
+	err = d.DB.CurrentDB(s).C(SharedCollection).Find(bson.M{"org": orgCode}).Sort("prefix", "path").All(&stored)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing documents with Org Code=%s Error=%s", orgCode, err.Error())
+	}
+
+	docs = []*DocNo{}
+	for i := range stored {
+		docs = append(docs, &stored[i].DocNo)
+	}
+	return docs, nil
+}
+
+// SaveByPath creates or replaces the document without any concurrency check, it is meant for administrative tasks
+func (d *sharedDocNoRepository) SaveByPath(ctx context.Context, orgCode string, doc *DocNo) (saved *DocNo, err error) {
+	if doc == nil {
+		return nil, errors.New("Document to be saved is nil")
+	}
+
+	if orgCode == "" {
+		return nil, errors.New("Organization Code is empty")
+	}
+
+	if doc.Prefix == "" {
+		return nil, errors.New("Document Prefix is empty")
+	}
+
+	if doc.NextSeqNo == 0 {
+		return nil, errors.New("Document Next Sequence No is empty")
+	}
+
+	if d.DB == nil {
+		return nil, errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return nil, fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err = boundSession(ctx, s); err != nil {
+		return nil, err
+	}
+
+	collection := d.DB.CurrentDB(s).C(SharedCollection)
+
+	err = ensureSharedDocNoIndex(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	// the version is bumped as for any other write, so pending conditional updates fail
+	var stored sharedDocNo
+	_, err = collection.Find(sharedSelector(orgCode, doc.Prefix, doc.Path)).Apply(mgo.Change{
+		Update:    bson.M{"$set": bson.M{"nextseqno": doc.NextSeqNo, "recordtimestamp": doc.RecordTimestamp}, "$inc": bson.M{"version": 1}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("Error saving document with Org Code=%s Prefix=%s Path=%s Error=%s", orgCode, doc.Prefix, doc.Path, err.Error())
+	}
+	return &stored.DocNo, nil
+}
+
+func (d *sharedDocNoRepository) DeleteByPath(ctx context.Context, orgCode string, docCode string, path string) (err error) {
+	if docCode == "" {
+		return errors.New("Doc Code is empty")
+	}
+
+	if orgCode == "" {
+		return errors.New("Organization Code is empty")
+	}
+
+	if d.DB == nil {
+		return errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := d.DB.CurrentSession()
+	if s == nil {
+		return fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	// bound the Mongo operations by the deadline of the request
+	if err = boundSession(ctx, s); err != nil {
+		return err
+	}
+
+	_, err = d.DB.CurrentDB(s).C(SharedCollection).RemoveAll(sharedSelector(orgCode, docCode, path))
+	if err != nil {
+		return fmt.Errorf("Error deleting document with Org Code=%s Prefix=%s Path=%s Error=%s", orgCode, docCode, path, err.Error())
+	}
+	return nil
+}
+
+// IsOrgCollectionName tells if the org code can be, and is allowed to be, the name of an org collection: it is not a
+// collection of the store or of Mongo
+func IsOrgCollectionName(orgCode string) bool {
+	if orgCode == SchemaCollection || orgCode == SharedCollection || orgCode == APIKeyCollection || orgCode == OrgLayoutCollection || strings.HasPrefix(orgCode, "system.") {
+		return false
+	}
+	return !strings.ContainsAny(orgCode, "$\x00")
+}
+
+func ensureSharedDocNoIndex(collection *mgo.Collection) error {
+	if err := collection.EnsureIndex(sharedDocNoIndex); err != nil {
+		return fmt.Errorf("Error creating index on collection %s Error=%s", collection.Name, err.Error())
+	}
+	return nil
+}
+
+// LayoutMigrationResult counts what MigrateToSharedLayout did with the organizations and their documents
+type LayoutMigrationResult struct {
+	Orgs      int
+	Created   int
+	Advanced  int
+	Unchanged int
+	Dropped   int
+}
+
+// MigrateToSharedLayout moves the documents of every org collection into SharedCollection while instances of either
+// layout serve requests. New organizations are recorded in the shared layout from the start, after requestWait,
+// longer than the slowest request, the organizations already recorded are moved one at a time:
+//
+//  1. the organization is recorded as migrating
+//  2. its documents are marked moved, so that the writes of every instance fail on them from then on
+//  3. its documents are copied, only ever raising NextSeqNo; an instance finding a moved document copies it the same
+//     way, and serves it from SharedCollection since
+//  4. it is recorded with the shared layout
+//
+// Once every organization is moved, the shared layout is recorded as the default. An interrupted migration can be
+// run again. With dropSource the org collections are dropped once moved.
+func MigrateToSharedLayout(dbClient DBClient, dropSource bool, requestWait time.Duration) (result *LayoutMigrationResult, err error) {
+	if dbClient == nil {
+		return nil, errors.New("DB Client is Nil")
+	}
+
+	// Get Current DB Session
+	s := dbClient.CurrentSession()
+	if s == nil {
+		return nil, fmt.Errorf("DB Session is nil")
+	}
+	defer s.Close()
+
+	db := dbClient.CurrentDB(s)
+	shared := db.C(SharedCollection)
+	if err = ensureSharedDocNoIndex(shared); err != nil {
+		return nil, err
+	}
+
+	layouts, err := readOrgLayouts(db, defaultLayoutID)
+	if err != nil {
+		return nil, err
+	}
+	if layouts[defaultLayoutID] != LayoutShared {
+		// the existing organizations stay where they are until moved, and new ones are created in the shared layout
+		orgs, err := orgCollectionNames(db)
+		if err != nil {
+			return nil, err
+		}
+		for _, orgCode := range orgs {
+			if _, err = insertOrgLayout(db, orgCode, LayoutCollection); err != nil {
+				return nil, err
+			}
+		}
+		if err = writeOrgLayout(db, newOrgLayoutID, LayoutShared); err != nil {
+			return nil, err
+		}
+
+		// an instance records the layout of a new organization within a request, from the layout it read for new ones
+		time.Sleep(requestWait)
+	}
+
+	orgs, err := unmovedOrgs(db)
+	if err != nil {
+		return nil, err
+	}
+	result = &LayoutMigrationResult{}
+	for _, orgCode := range orgs {
+		if err = moveOrgToSharedLayout(db, orgCode, result); err != nil {
+			return result, err
+		}
+		result.Orgs++
+	}
+
+	if dropSource {
+		names, err := orgCollectionNames(db)
+		if err != nil {
+			return result, err
+		}
+		for _, orgCode := range names {
+			if err := db.C(orgCode).DropCollection(); err != nil {
+				return result, fmt.Errorf("Error dropping collection %s Error=%s", orgCode, err.Error())
+			}
+			result.Dropped++
+		}
+	}
+
+	return result, writeOrgLayout(db, defaultLayoutID, LayoutShared)
+}
+
+// unmovedOrgs returns the organizations recorded in a layout other than the shared one
+func unmovedOrgs(db *mgo.Database) ([]string, error) {
+	var records []orgLayoutRecord
+	err := db.C(OrgLayoutCollection).Find(bson.M{
+		"_id":    bson.M{"$nin": []string{defaultLayoutID, newOrgLayoutID}},
+		"layout": bson.M{"$ne": LayoutShared},
+	}).Sort("_id").All(&records)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading organization layouts Error=%s", err.Error())
+	}
+
+	orgs := make([]string, 0, len(records))
+	for _, record := range records {
+		orgs = append(orgs, record.ID)
+	}
+	return orgs, nil
+}
+
+// moveOrgToSharedLayout marks the documents of the organization moved and copies them into SharedCollection, then
+// records it with the shared layout
+func moveOrgToSharedLayout(db *mgo.Database, orgCode string, result *LayoutMigrationResult) error {
+	if err := writeOrgLayout(db, orgCode, layoutMigrating); err != nil {
+		return err
+	}
+	collection := db.C(orgCode)
+	if _, err := collection.UpdateAll(bson.M{"moved": notMoved}, bson.M{"$set": bson.M{"moved": true}}); err != nil {
+		return fmt.Errorf("Error marking the documents of collection %s moved Error=%s", orgCode, err.Error())
+	}
+
+	shared := db.C(SharedCollection)
+	iter := collection.Find(nil).Iter()
+	for {
+		var doc DocNo
+		if !iter.Next(&doc) {
+			break
+		}
+		info, err := copyDocNo(shared, orgCode, doc)
+		if err != nil {
+			iter.Close()
+			return err
+		}
+		switch {
+		case info.UpsertedId != nil:
+			result.Created++
+		case info.Updated > 0:
+			result.Advanced++
+		default:
+			result.Unchanged++
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return fmt.Errorf("Error reading collection %s Error=%s", orgCode, err.Error())
+	}
+
+	return writeOrgLayout(db, orgCode, LayoutShared)
+}
+
+// moveDocNo marks a document of the org collection moved, and copies it into SharedCollection
+func moveDocNo(db *mgo.Database, orgCode string, docCode string, path string) error {
+	collection := db.C(orgCode)
+	err := collection.Update(bson.M{"prefix": docCode, "path": path, "moved": notMoved}, bson.M{"$set": bson.M{"moved": true}})
+	if err != nil && err != mgo.ErrNotFound {
+		return fmt.Errorf("Error marking document with Prefix=%s Path=%s moved Error=%s", docCode, path, err.Error())
+	}
+
+	var doc DocNo
+	err = collection.Find(bson.M{"prefix": docCode, "path": path}).One(&doc)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error finding document with Prefix=%s Path=%s Error=%s", docCode, path, err.Error())
+	}
+	_, err = copyDocNo(db.C(SharedCollection), orgCode, doc)
+	return err
+}
+
+// copyDocNo copies a moved document into SharedCollection, only ever raising its NextSeqNo and Version there
+func copyDocNo(shared *mgo.Collection, orgCode string, doc DocNo) (*mgo.ChangeInfo, error) {
+	info, err := shared.Upsert(sharedSelector(orgCode, doc.Prefix, doc.Path), bson.M{"$max": bson.M{"nextseqno": doc.NextSeqNo, "recordtimestamp": doc.RecordTimestamp, "version": doc.Version}})
+	if err != nil {
+		return nil, fmt.Errorf("Error copying document from collection %s with Prefix=%s Path=%s Error=%s", orgCode, doc.Prefix, doc.Path, err.Error())
+	}
+	return info, nil
+}
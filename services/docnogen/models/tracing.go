@@ -0,0 +1,122 @@
+package models
+
+import (
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+)
+
+type tracingDocNoRepository struct {
+	next   DocNoRepository
+	system string
+}
+
+// NewTracingDocNoRepository starts a client span around every operation of next, with the db.type tag,
+// e.g. mongodb or memory
+func NewTracingDocNoRepository(next DocNoRepository, system string) (r DocNoRepository) {
+	r = &tracingDocNoRepository{next: next, system: system}
+	return r
+}
+
+func (r *tracingDocNoRepository) start(ctx context.Context, operation string, orgCode string) (opentracing.Span, context.Context) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "DocNoRepository."+operation)
+	ext.SpanKindRPCClient.Set(span)
+	ext.DBType.Set(span, r.system)
+	span.SetTag("db.operation", operation)
+	span.SetTag("org", orgCode)
+	return span, ctx
+}
+
+func (r *tracingDocNoRepository) GetByPath(ctx context.Context, docCode string, orgCode string, path string) (doc *DocNo, err error) {
+	span, ctx := r.start(ctx, "GetByPath", orgCode)
+	span.SetTag("doc_code", docCode)
+	span.SetTag("path", path)
+	defer func() { common.FinishSpan(span, err) }()
+	return r.next.GetByPath(ctx, docCode, orgCode, path)
+}
+
+func (r *tracingDocNoRepository) UpdateByPath(ctx context.Context, orgCode string, doc *DocNo, curSeqNo int64, curVersion int64) (updated *DocNo, err error) {
+	span, ctx := r.start(ctx, "UpdateByPath", orgCode)
+	if doc != nil {
+		span.SetTag("doc_code", doc.Prefix)
+		span.SetTag("path", doc.Path)
+	}
+	span.SetTag("cur_seq_no", curSeqNo)
+	span.SetTag("cur_version", curVersion)
+	defer func() { common.FinishSpan(span, err) }()
+	return r.next.UpdateByPath(ctx, orgCode, doc, curSeqNo, curVersion)
+}
+
+func (r *tracingDocNoRepository) ListByOrg(ctx context.Context, orgCode string) (docs []*DocNo, err error) {
+	span, ctx := r.start(ctx, "ListByOrg", orgCode)
+	defer func() {
+		span.SetTag("count", len(docs))
+		common.FinishSpan(span, err)
+	}()
+	return r.next.ListByOrg(ctx, orgCode)
+}
+
+func (r *tracingDocNoRepository) SaveByPath(ctx context.Context, orgCode string, doc *DocNo) (saved *DocNo, err error) {
+	span, ctx := r.start(ctx, "SaveByPath", orgCode)
+	if doc != nil {
+		span.SetTag("doc_code", doc.Prefix)
+		span.SetTag("path", doc.Path)
+	}
+	defer func() { common.FinishSpan(span, err) }()
+	return r.next.SaveByPath(ctx, orgCode, doc)
+}
+
+func (r *tracingDocNoRepository) DeleteByPath(ctx context.Context, orgCode string, docCode string, path string) (err error) {
+	span, ctx := r.start(ctx, "DeleteByPath", orgCode)
+	span.SetTag("doc_code", docCode)
+	span.SetTag("path", path)
+	defer func() { common.FinishSpan(span, err) }()
+	return r.next.DeleteByPath(ctx, orgCode, docCode, path)
+}
+
+type tracingAPIKeyRepository struct {
+	next   APIKeyRepository
+	system string
+}
+
+// NewTracingAPIKeyRepository starts a client span around every operation of next, as NewTracingDocNoRepository
+func NewTracingAPIKeyRepository(next APIKeyRepository, system string) (r APIKeyRepository) {
+	r = &tracingAPIKeyRepository{next: next, system: system}
+	return r
+}
+
+func (r *tracingAPIKeyRepository) start(ctx context.Context, operation string) (opentracing.Span, context.Context) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "APIKeyRepository."+operation)
+	ext.SpanKindRPCClient.Set(span)
+	ext.DBType.Set(span, r.system)
+	span.SetTag("db.operation", operation)
+	return span, ctx
+}
+
+func (r *tracingAPIKeyRepository) Create(ctx context.Context, key *APIKey) (err error) {
+	span, ctx := r.start(ctx, "Create")
+	defer func() { common.FinishSpan(span, err) }()
+	return r.next.Create(ctx, key)
+}
+
+func (r *tracingAPIKeyRepository) GetByHash(ctx context.Context, hash string) (key *APIKey, err error) {
+	span, ctx := r.start(ctx, "GetByHash")
+	defer func() { common.FinishSpan(span, err) }()
+	return r.next.GetByHash(ctx, hash)
+}
+
+func (r *tracingAPIKeyRepository) List(ctx context.Context, orgCode string) (keys []*APIKey, err error) {
+	span, ctx := r.start(ctx, "List")
+	span.SetTag("org", orgCode)
+	defer func() { common.FinishSpan(span, err) }()
+	return r.next.List(ctx, orgCode)
+}
+
+func (r *tracingAPIKeyRepository) Revoke(ctx context.Context, id string, at int64) (key *APIKey, err error) {
+	span, ctx := r.start(ctx, "Revoke")
+	span.SetTag("id", id)
+	defer func() { common.FinishSpan(span, err) }()
+	return r.next.Revoke(ctx, id, at)
+}
@@ -0,0 +1,31 @@
+package docnogensvc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	openapi "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/openapi"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+)
+
+func Test_OpenAPI(t *testing.T) {
+	Convey("The generated OpenAPI document", t, func() {
+		var spec struct {
+			OpenAPI string                            `json:"openapi"`
+			Paths   map[string]map[string]interface{} `json:"paths"`
+		}
+		So(json.Unmarshal([]byte(openapi.Spec), &spec), ShouldBeNil)
+		So(spec.OpenAPI, ShouldStartWith, "3.")
+
+		Convey("has a POST route for every method of the service", func() {
+			server := reflect.TypeOf((*pb.DocNoGenServiceServer)(nil)).Elem()
+			So(len(spec.Paths), ShouldEqual, server.NumMethod())
+			for i := 0; i < server.NumMethod(); i++ {
+				So(spec.Paths["/"+server.Method(i).Name], ShouldContainKey, "post")
+			}
+		})
+	})
+}
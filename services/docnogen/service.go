@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/go-kit/kit/metrics"
 	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
 	context "golang.org/x/net/context"
 
@@ -16,15 +17,40 @@ type DocnogenService interface {
 	GenerateDocNoFormat(ctx context.Context, in *pb.GenerateDocNoFormatRequest) (out *pb.GenerateDocNoFormatResponse, err error)
 	GetNextDocNo(ctx context.Context, in *pb.GetNextDocNoRequest) (out *pb.GetNextDocNoResponse, err error)
 	ConsumeDocNo(ctx context.Context, in *pb.ConsumeDocNoRequest) (out *pb.ConsumeDocNoResponse, err error)
+	Export(ctx context.Context, in *pb.ExportRequest) (out *pb.ExportResponse, err error)
+	Import(ctx context.Context, in *pb.ImportRequest) (out *pb.ImportResponse, err error)
+	CreateAPIKey(ctx context.Context, in *pb.CreateAPIKeyRequest) (out *pb.CreateAPIKeyResponse, err error)
+	ListAPIKeys(ctx context.Context, in *pb.ListAPIKeysRequest) (out *pb.ListAPIKeysResponse, err error)
+	RevokeAPIKey(ctx context.Context, in *pb.RevokeAPIKeyRequest) (out *pb.RevokeAPIKeyResponse, err error)
+}
+
+// RetryConfig bounds the retries of GenerateDocNoFormat and GenerateBulkDocNoFormat on concurrency update errors.
+// Retries counts every retry and Exhausted every request given up on, both with label "method"; either may be nil.
+type RetryConfig struct {
+	Policy    common.RetryPolicy
+	Retries   metrics.Counter
+	Exhausted metrics.Counter
 }
 
 type docnogenService struct {
 	DocNoRepo      models.DocNoRepository
 	DocNoFormatter DocnoformatterService
+	Retry          RetryConfig
+	APIKeyRepo     models.APIKeyRepository
 }
 
 func NewDocnogenService(repo models.DocNoRepository, formatter DocnoformatterService) (s pb.DocNoGenServiceServer) {
-	s = &docnogenService{DocNoRepo: repo, DocNoFormatter: formatter}
+	return NewDocnogenServiceWithRetry(repo, formatter, RetryConfig{Policy: common.DefaultRetryPolicy})
+}
+
+func NewDocnogenServiceWithRetry(repo models.DocNoRepository, formatter DocnoformatterService, retry RetryConfig) (s pb.DocNoGenServiceServer) {
+	s = &docnogenService{DocNoRepo: repo, DocNoFormatter: formatter, Retry: retry}
+	return s
+}
+
+// NewDocnogenServiceWithAPIKeys also manages the API keys in apiKeys
+func NewDocnogenServiceWithAPIKeys(repo models.DocNoRepository, formatter DocnoformatterService, retry RetryConfig, apiKeys models.APIKeyRepository) (s pb.DocNoGenServiceServer) {
+	s = &docnogenService{DocNoRepo: repo, DocNoFormatter: formatter, Retry: retry, APIKeyRepo: apiKeys}
 	return s
 }
 
@@ -36,35 +62,19 @@ func (s *docnogenService) GenerateBulkDocNoFormat(ctx context.Context, in *pb.Ge
 			Ok:           false,
 			ErrorCode:    500,
 			ErrorMessage: fmt.Sprint("Document Number Repository or Document Number Formatter is nil"),
+			ErrorReason:  string(common.ReasonInternal),
 			Results:      []*pb.GenerateBulkDocNoFormatResponse_Result{},
 		}
 	} else {
-		var preCondiErr error
-		// check if DocCode is empty
-		if in.DocCode == "" {
-			preCondiErr = fmt.Errorf("Doc Code is empty")
-		}
-
-		// check if OrgCode is empty
-		if in.OrgCode == "" {
-			preCondiErr = fmt.Errorf("Organisation Code is empty")
-		}
-
-		// check if Path is empty
-		if in.Path == "" {
-			preCondiErr = fmt.Errorf("Path is empty")
-		}
-
-		// check if BulkNumber is at least 1 and not more than 99
-		if in.BulkNumber < 1 || in.BulkNumber > 99 {
-			preCondiErr = fmt.Errorf("Bulk Number must be at least 1 and not more than 99")
-		}
+		// check the request against its rules, every violation is reported
+		violations := generateBulkDocNoFormatRules.Validate(in)
 
 		// check if Format string is empty
-		format := s.getFormatString(in.OrgCode, in.DocCode, in.Path, in.CustomFormat)
+		format := s.getFormatString(ctx, in.OrgCode, in.DocCode, in.Path, in.CustomFormat)
 		if format == "" {
-			preCondiErr = fmt.Errorf("Format is empty")
+			violations = append(violations, common.Violation{Field: "customFormat", Description: "Format is empty"})
 		}
+		preCondiErr := violations.Err()
 
 		// if no error for preconditions
 		if preCondiErr == nil {
@@ -73,17 +83,30 @@ func (s *docnogenService) GenerateBulkDocNoFormat(ctx context.Context, in *pb.Ge
 			var updatedDoc *models.DocNo
 			results := []*pb.GenerateBulkDocNoFormatResponse_Result{}
 			for x := 0; x < int(in.BulkNumber); x++ {
-				fmt.Printf("trying to generate doc number for %d/%d...\n", x+1, in.BulkNumber)
+				common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "generating document number", "number", x+1, "of", in.BulkNumber)
 				// try get and consume the sequence number until successful, else if error because concurrency update detechted... keep trying
 				updateSuccess := false
-				for {
-					// this loop will loop infinite until updateSuccess = true
-					docNo, err = s.DocNoRepo.GetByPath(in.DocCode, in.OrgCode, in.Path)
+				for attempt := 1; ; attempt++ {
+					// this loop will loop until updateSuccess = true, or the retries on concurrency update error run out
+					if err = ctx.Err(); err != nil {
+						out = &pb.GenerateBulkDocNoFormatResponse{
+							Ok:           false,
+							ErrorCode:    common.ContextErrorCode(err),
+							ErrorMessage: err.Error(),
+							ErrorReason:  string(common.ReasonOf(err)),
+							Results:      results,
+						}
+
+						break
+					}
+
+					docNo, err = s.DocNoRepo.GetByPath(ctx, in.DocCode, in.OrgCode, in.Path)
 					if err != nil {
 						out = &pb.GenerateBulkDocNoFormatResponse{
 							Ok:           false,
-							ErrorCode:    500,
+							ErrorCode:    errorCode(ctx, 500),
 							ErrorMessage: err.Error(),
+							ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
 							Results:      results,
 						}
 
@@ -92,13 +115,14 @@ func (s *docnogenService) GenerateBulkDocNoFormat(ctx context.Context, in *pb.Ge
 						// if no error, and document not nil, assign result to response
 						if docNo != nil {
 							// generate Sequence Number string
-							seqNoStr := s.DocNoFormatter.GenerateSeqNoStr(in.OrgCode, in.DocCode, in.Path, docNo.NextSeqNo)
+							seqNoStr := s.DocNoFormatter.GenerateSeqNoStr(ctx, in.OrgCode, in.DocCode, in.Path, docNo.NextSeqNo)
 							if seqNoStr == "" {
 								err = fmt.Errorf("Sequence Number String is empty")
 								out = &pb.GenerateBulkDocNoFormatResponse{
 									Ok:           false,
 									ErrorCode:    400,
 									ErrorMessage: err.Error(),
+									ErrorReason:  string(common.ReasonInvalidArgument),
 									Results:      results,
 								}
 
@@ -107,12 +131,13 @@ func (s *docnogenService) GenerateBulkDocNoFormat(ctx context.Context, in *pb.Ge
 
 							// generate Document Number string
 							var docNoStr string
-							docNoStr, err = s.DocNoFormatter.GenerateFormatString(format, in.DocCode, seqNoStr, in.VariableMap)
+							docNoStr, err = s.DocNoFormatter.GenerateFormatString(ctx, format, in.DocCode, seqNoStr, in.VariableMap)
 							if err != nil {
 								out = &pb.GenerateBulkDocNoFormatResponse{
 									Ok:           false,
 									ErrorCode:    400,
 									ErrorMessage: err.Error(),
+									ErrorReason:  string(common.ReasonInvalidArgument),
 									Results:      results,
 								}
 
@@ -122,25 +147,38 @@ func (s *docnogenService) GenerateBulkDocNoFormat(ctx context.Context, in *pb.Ge
 							var r pb.GenerateBulkDocNoFormatResponse_Result
 
 							// consume the sequence number
-							// increase sequence number by 1 and set a new record timestamp to mark record has been altered
+							// increase sequence number by 1, the repository bumps the version to mark record has been altered
 							currSeqNo := docNo.NextSeqNo
-							currRecordTimestamp := docNo.RecordTimestamp
+							currVersion := docNo.Version
 							docNo.NextSeqNo++
 							docNo.RecordTimestamp = time.Now().Unix()
 							// update the doc to db with concurrency update control
-							updatedDoc, err = s.DocNoRepo.UpdateByPath(in.OrgCode, docNo, currSeqNo, currRecordTimestamp)
+							updatedDoc, err = s.DocNoRepo.UpdateByPath(ctx, in.OrgCode, docNo, currSeqNo, currVersion)
 							if err != nil && err != common.ConcurrencyUpdateError {
 								out = &pb.GenerateBulkDocNoFormatResponse{
 									Ok:           false,
-									ErrorCode:    500,
+									ErrorCode:    errorCode(ctx, 500),
 									ErrorMessage: err.Error(),
+									ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
 									Results:      results,
 								}
 
 								break
 							} else if err != nil && err == common.ConcurrencyUpdateError {
 								// concurrency update error detected...
-								// loop again
+								// back off and loop again, unless the retries have run out
+								var code int32
+								if code, err = s.waitRetry(ctx, "GenerateBulkDocNoFormat", attempt); err != nil {
+									out = &pb.GenerateBulkDocNoFormatResponse{
+										Ok:           false,
+										ErrorCode:    code,
+										ErrorMessage: err.Error(),
+										ErrorReason:  string(common.ReasonOf(err)),
+										Results:      results,
+									}
+
+									break
+								}
 							} else {
 								// no error, update successful
 								if updatedDoc != nil {
@@ -148,6 +186,7 @@ func (s *docnogenService) GenerateBulkDocNoFormat(ctx context.Context, in *pb.Ge
 										DocNoString:     docNoStr,
 										NextSeqNo:       uint32(updatedDoc.NextSeqNo),
 										RecordTimestamp: updatedDoc.RecordTimestamp,
+										Version:         updatedDoc.Version,
 									}
 								}
 								// add r to results
@@ -161,6 +200,7 @@ func (s *docnogenService) GenerateBulkDocNoFormat(ctx context.Context, in *pb.Ge
 								Ok:           false,
 								ErrorCode:    500,
 								ErrorMessage: "Something is wrong with the system...",
+								ErrorReason:  string(common.ReasonInternal),
 								Results:      results,
 							}
 							break
@@ -169,15 +209,19 @@ func (s *docnogenService) GenerateBulkDocNoFormat(ctx context.Context, in *pb.Ge
 
 					if updateSuccess {
 						// if consume success, break from the loop
-						fmt.Println(results)
 						break
 					}
 				}
 
+				if !updateSuccess {
+					// do not generate the remaining numbers after an error
+					break
+				}
+
 			}
 			// end of loop
 
-			fmt.Printf("out=%v err=%v", out, err)
+			common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "generated document numbers", "count", len(results), "err", err)
 			if err == nil {
 				// genereate OK response
 				out = &pb.GenerateBulkDocNoFormatResponse{
@@ -194,6 +238,8 @@ func (s *docnogenService) GenerateBulkDocNoFormat(ctx context.Context, in *pb.Ge
 				Ok:           false,
 				ErrorCode:    400,
 				ErrorMessage: preCondiErr.Error(),
+				ErrorReason:  string(common.ReasonInvalidArgument),
+				Violations:   pbViolations(violations),
 				Results:      []*pb.GenerateBulkDocNoFormatResponse_Result{},
 			}
 		}
@@ -209,43 +255,45 @@ func (s *docnogenService) GenerateDocNoFormat(ctx context.Context, in *pb.Genera
 			Ok:           false,
 			ErrorCode:    500,
 			ErrorMessage: fmt.Sprint("Document Number Repository or Document Number Formatter is nil"),
+			ErrorReason:  string(common.ReasonInternal),
 			Result:       nil,
 		}
 	} else {
-		var preCondiErr error
-		// check if DocCode is empty
-		if in.DocCode == "" {
-			preCondiErr = fmt.Errorf("Doc Code is empty")
-		}
-
-		// check if OrgCode is empty
-		if in.OrgCode == "" {
-			preCondiErr = fmt.Errorf("Organisation Code is empty")
-		}
-
-		// check if Path is empty
-		if in.Path == "" {
-			preCondiErr = fmt.Errorf("Path is empty")
-		}
+		// check the request against its rules, every violation is reported
+		violations := generateDocNoFormatRules.Validate(in)
 
 		// check if Format string is empty
-		format := s.getFormatString(in.OrgCode, in.DocCode, in.Path, in.CustomFormat)
+		format := s.getFormatString(ctx, in.OrgCode, in.DocCode, in.Path, in.CustomFormat)
 		if format == "" {
-			preCondiErr = fmt.Errorf("Format is empty")
+			violations = append(violations, common.Violation{Field: "customFormat", Description: "Format is empty"})
 		}
+		preCondiErr := violations.Err()
 
 		// if no error for preconditions
 		if preCondiErr == nil {
 			// try get and consume the sequence number until successful, else if error because concurrency update detechted... keep trying
 			updateSuccess := false
-			for {
-				// this loop will loop infinite until updateSuccess = true
-				docNo, err := s.DocNoRepo.GetByPath(in.DocCode, in.OrgCode, in.Path)
+			for attempt := 1; ; attempt++ {
+				// this loop will loop until updateSuccess = true, or the retries on concurrency update error run out
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					out = &pb.GenerateDocNoFormatResponse{
+						Ok:           false,
+						ErrorCode:    common.ContextErrorCode(ctxErr),
+						ErrorMessage: ctxErr.Error(),
+						ErrorReason:  string(common.ReasonOf(ctxErr)),
+						Result:       nil,
+					}
+
+					break
+				}
+
+				docNo, err := s.DocNoRepo.GetByPath(ctx, in.DocCode, in.OrgCode, in.Path)
 				if err != nil {
 					out = &pb.GenerateDocNoFormatResponse{
 						Ok:           false,
-						ErrorCode:    500,
+						ErrorCode:    errorCode(ctx, 500),
 						ErrorMessage: err.Error(),
+						ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
 						Result:       nil,
 					}
 
@@ -254,12 +302,13 @@ func (s *docnogenService) GenerateDocNoFormat(ctx context.Context, in *pb.Genera
 					// if no error, and document not nil, assign result to response
 					if docNo != nil {
 						// generate Sequence Number string
-						seqNoStr := s.DocNoFormatter.GenerateSeqNoStr(in.OrgCode, in.DocCode, in.Path, docNo.NextSeqNo)
+						seqNoStr := s.DocNoFormatter.GenerateSeqNoStr(ctx, in.OrgCode, in.DocCode, in.Path, docNo.NextSeqNo)
 						if seqNoStr == "" {
 							out = &pb.GenerateDocNoFormatResponse{
 								Ok:           false,
 								ErrorCode:    400,
 								ErrorMessage: "Sequence Number String is empty",
+								ErrorReason:  string(common.ReasonInvalidArgument),
 								Result:       nil,
 							}
 
@@ -267,12 +316,13 @@ func (s *docnogenService) GenerateDocNoFormat(ctx context.Context, in *pb.Genera
 						}
 
 						// generate Document Number string
-						docNoStr, err := s.DocNoFormatter.GenerateFormatString(format, in.DocCode, seqNoStr, in.VariableMap)
+						docNoStr, err := s.DocNoFormatter.GenerateFormatString(ctx, format, in.DocCode, seqNoStr, in.VariableMap)
 						if err != nil {
 							out = &pb.GenerateDocNoFormatResponse{
 								Ok:           false,
 								ErrorCode:    400,
 								ErrorMessage: err.Error(),
+								ErrorReason:  string(common.ReasonInvalidArgument),
 								Result:       nil,
 							}
 
@@ -282,25 +332,37 @@ func (s *docnogenService) GenerateDocNoFormat(ctx context.Context, in *pb.Genera
 						var result pb.GenerateDocNoFormatResponse_Result
 
 						// consume the sequence number
-						// increase sequence number by 1 and set a new record timestamp to mark record has been altered
+						// increase sequence number by 1, the repository bumps the version to mark record has been altered
 						currSeqNo := docNo.NextSeqNo
-						currRecordTimestamp := docNo.RecordTimestamp
+						currVersion := docNo.Version
 						docNo.NextSeqNo++
 						docNo.RecordTimestamp = time.Now().Unix()
 						// update the doc to db with concurrency update control
-						updatedDoc, err := s.DocNoRepo.UpdateByPath(in.OrgCode, docNo, currSeqNo, currRecordTimestamp)
+						updatedDoc, err := s.DocNoRepo.UpdateByPath(ctx, in.OrgCode, docNo, currSeqNo, currVersion)
 						if err != nil && err != common.ConcurrencyUpdateError {
 							out = &pb.GenerateDocNoFormatResponse{
 								Ok:           false,
-								ErrorCode:    500,
+								ErrorCode:    errorCode(ctx, 500),
 								ErrorMessage: err.Error(),
+								ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
 								Result:       nil,
 							}
 
 							break
 						} else if err != nil && err == common.ConcurrencyUpdateError {
 							// concurrency update error detected...
-							// loop again
+							// back off and loop again, unless the retries have run out
+							if code, retryErr := s.waitRetry(ctx, "GenerateDocNoFormat", attempt); retryErr != nil {
+								out = &pb.GenerateDocNoFormatResponse{
+									Ok:           false,
+									ErrorCode:    code,
+									ErrorMessage: retryErr.Error(),
+									ErrorReason:  string(common.ReasonOf(retryErr)),
+									Result:       nil,
+								}
+
+								break
+							}
 						} else {
 							// no error, update successful
 							if updatedDoc != nil {
@@ -308,6 +370,7 @@ func (s *docnogenService) GenerateDocNoFormat(ctx context.Context, in *pb.Genera
 									DocNoString:     docNoStr,
 									NextSeqNo:       uint32(updatedDoc.NextSeqNo),
 									RecordTimestamp: updatedDoc.RecordTimestamp,
+									Version:         updatedDoc.Version,
 								}
 							}
 
@@ -326,6 +389,7 @@ func (s *docnogenService) GenerateDocNoFormat(ctx context.Context, in *pb.Genera
 							Ok:           false,
 							ErrorCode:    500,
 							ErrorMessage: "Something is wrong with the system...",
+							ErrorReason:  string(common.ReasonInternal),
 							Result:       nil,
 						}
 						break
@@ -343,6 +407,8 @@ func (s *docnogenService) GenerateDocNoFormat(ctx context.Context, in *pb.Genera
 				Ok:           false,
 				ErrorCode:    400,
 				ErrorMessage: preCondiErr.Error(),
+				ErrorReason:  string(common.ReasonInvalidArgument),
+				Violations:   pbViolations(violations),
 				Result:       nil,
 			}
 		}
@@ -357,41 +423,31 @@ func (s *docnogenService) GetNextDocNo(ctx context.Context, in *pb.GetNextDocNoR
 			Ok:           false,
 			ErrorCode:    500,
 			ErrorMessage: fmt.Sprint("Document Number Repository or Document Number Formatter is nil"),
+			ErrorReason:  string(common.ReasonInternal),
 			Result:       nil,
 		}
 	} else {
-		var preCondiErr error
-		// check if DocCode is empty
-		if in.DocCode == "" {
-			preCondiErr = fmt.Errorf("Doc Code is empty")
-		}
-
-		// check if OrgCode is empty
-		if in.OrgCode == "" {
-			preCondiErr = fmt.Errorf("Organisation Code is empty")
-		}
-
-		// check if Path is empty
-		if in.Path == "" {
-			preCondiErr = fmt.Errorf("Path is empty")
-		}
+		// check the request against its rules, every violation is reported
+		violations := getNextDocNoRules.Validate(in)
 
 		// check if Format string is empty
-		format := s.getFormatString(in.OrgCode, in.DocCode, in.Path, in.CustomFormat)
+		format := s.getFormatString(ctx, in.OrgCode, in.DocCode, in.Path, in.CustomFormat)
 		if format == "" {
-			preCondiErr = fmt.Errorf("Format is empty")
+			violations = append(violations, common.Violation{Field: "customFormat", Description: "Format is empty"})
 		}
+		preCondiErr := violations.Err()
 
 		// if no error for preconditions
 		if preCondiErr == nil {
 			// Call GetByPath to get document
 			var result pb.GetNextDocNoResponse_Result
-			docNo, err := s.DocNoRepo.GetByPath(in.DocCode, in.OrgCode, in.Path)
+			docNo, err := s.DocNoRepo.GetByPath(ctx, in.DocCode, in.OrgCode, in.Path)
 			if err != nil {
 				out = &pb.GetNextDocNoResponse{
 					Ok:           false,
-					ErrorCode:    500,
+					ErrorCode:    errorCode(ctx, 500),
 					ErrorMessage: err.Error(),
+					ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
 					Result:       nil,
 				}
 			} else {
@@ -399,14 +455,13 @@ func (s *docnogenService) GetNextDocNo(ctx context.Context, in *pb.GetNextDocNoR
 				if docNo != nil {
 					var docNoStr string
 					// generate Sequence Number string
-					seqNoStr := s.DocNoFormatter.GenerateSeqNoStr(in.OrgCode, in.DocCode, in.Path, docNo.NextSeqNo)
+					seqNoStr := s.DocNoFormatter.GenerateSeqNoStr(ctx, in.OrgCode, in.DocCode, in.Path, docNo.NextSeqNo)
 					if seqNoStr == "" {
 						err = fmt.Errorf("Sequence Number String is empty")
 					} else {
 						// generate Document Number string
-						docNoStr, err = s.DocNoFormatter.GenerateFormatString(format, in.DocCode, seqNoStr, in.VariableMap)
-						fmt.Printf("docNoStr=%s err=%v\n", docNoStr, err)
-
+						docNoStr, err = s.DocNoFormatter.GenerateFormatString(ctx, format, in.DocCode, seqNoStr, in.VariableMap)
+						common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "formatted next document number", "docNo", docNoStr, "err", err)
 					}
 
 					if err != nil {
@@ -414,6 +469,7 @@ func (s *docnogenService) GetNextDocNo(ctx context.Context, in *pb.GetNextDocNoR
 							Ok:           false,
 							ErrorCode:    400,
 							ErrorMessage: err.Error(),
+							ErrorReason:  string(common.ReasonInvalidArgument),
 							Result:       nil,
 						}
 					} else {
@@ -422,6 +478,7 @@ func (s *docnogenService) GetNextDocNo(ctx context.Context, in *pb.GetNextDocNoR
 							DocNoString:     docNoStr,
 							NextSeqNo:       uint32(docNo.NextSeqNo),
 							RecordTimestamp: docNo.RecordTimestamp,
+							Version:         docNo.Version,
 						}
 
 						out = &pb.GetNextDocNoResponse{
@@ -439,6 +496,8 @@ func (s *docnogenService) GetNextDocNo(ctx context.Context, in *pb.GetNextDocNoR
 				Ok:           false,
 				ErrorCode:    400,
 				ErrorMessage: preCondiErr.Error(),
+				ErrorReason:  string(common.ReasonInvalidArgument),
+				Violations:   pbViolations(violations),
 				Result:       nil,
 			}
 		}
@@ -453,35 +512,26 @@ func (s *docnogenService) ConsumeDocNo(ctx context.Context, in *pb.ConsumeDocNoR
 			Ok:           false,
 			ErrorCode:    500,
 			ErrorMessage: fmt.Sprint("Document Number Repository is nil"),
+			ErrorReason:  string(common.ReasonInternal),
 			Result:       nil,
 		}
 	} else {
-		var preCondiErr error
-		// check if DocCode is empty
-		if in.DocCode == "" {
-			preCondiErr = fmt.Errorf("Doc Code is empty")
-		}
-
-		// check if OrgCode is empty
-		if in.OrgCode == "" {
-			preCondiErr = fmt.Errorf("Organisation Code is empty")
-		}
+		// check the request against its rules, every violation is reported
+		violations := consumeDocNoRules.Validate(in)
 
-		// check if Path is empty
-		if in.Path == "" {
-			preCondiErr = fmt.Errorf("Path is empty")
-		}
+		preCondiErr := violations.Err()
 
 		// if no error for preconditions
 		if preCondiErr == nil {
 			// Call GetByPath to get document
 			var result pb.ConsumeDocNoResponse_Result
-			docNo, err := s.DocNoRepo.GetByPath(in.DocCode, in.OrgCode, in.Path)
+			docNo, err := s.DocNoRepo.GetByPath(ctx, in.DocCode, in.OrgCode, in.Path)
 			if err != nil {
 				out = &pb.ConsumeDocNoResponse{
 					Ok:           false,
-					ErrorCode:    500,
+					ErrorCode:    errorCode(ctx, 500),
 					ErrorMessage: err.Error(),
+					ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
 					Result:       nil,
 				}
 			} else {
@@ -491,6 +541,7 @@ func (s *docnogenService) ConsumeDocNo(ctx context.Context, in *pb.ConsumeDocNoR
 						Ok:           false,
 						ErrorCode:    500,
 						ErrorMessage: fmt.Sprintf("No document found with OrgCode=%s DocCode=%s Path=%s", in.OrgCode, in.DocCode, in.Path),
+						ErrorReason:  string(common.ReasonNotFound),
 						Result:       nil,
 					}
 					//err = fmt.Errorf("No document found with OrgCode=%s DocCode=%s Path=%s", in.OrgCode, in.DocCode, in.Path)
@@ -500,30 +551,42 @@ func (s *docnogenService) ConsumeDocNo(ctx context.Context, in *pb.ConsumeDocNoR
 						Ok:           false,
 						ErrorCode:    400,
 						ErrorMessage: fmt.Sprintf("Concurreny Update error with OrgCode=%s DocCode=%s Path=%s UserSeqNumber=%d SystemSeqNumber=%d", in.OrgCode, in.DocCode, in.Path, in.CurSeqNo, docNo.NextSeqNo),
+						ErrorReason:  string(common.ReasonConcurrencyConflict),
 						Result:       nil,
 					}
 					//err = fmt.Errorf("Concurreny Update error with OrgCode=%s DocCode=%s Path=%s UserSeqNumber=%d SystemSeqNumber=%d", in.OrgCode, in.DocCode, in.Path, in.CurSeqNo, docNo.NextSeqNo)
-				} else if docNo.RecordTimestamp != in.RecordTimestamp {
-					// compare record timestamp, if not the same, record has been altered and throw error concurrency update
+				} else if in.Version != 0 && docNo.Version != in.Version {
+					// compare record version, if not the same, record has been altered and throw error concurrency update
+					out = &pb.ConsumeDocNoResponse{
+						Ok:           false,
+						ErrorCode:    400,
+						ErrorMessage: fmt.Sprintf("Concurreny Update error with OrgCode=%s DocCode=%s Path=%s UserVersion=%d SystemVersion=%d", in.OrgCode, in.DocCode, in.Path, in.Version, docNo.Version),
+						ErrorReason:  string(common.ReasonConcurrencyConflict),
+						Result:       nil,
+					}
+				} else if in.Version == 0 && docNo.RecordTimestamp != in.RecordTimestamp {
+					// deprecated: compare record timestamp for clients not sending the version yet
 					out = &pb.ConsumeDocNoResponse{
 						Ok:           false,
 						ErrorCode:    400,
 						ErrorMessage: fmt.Sprintf("Concurreny Update error with OrgCode=%s DocCode=%s Path=%s UserSubmitted=%d System=%d", in.OrgCode, in.DocCode, in.Path, in.RecordTimestamp, docNo.RecordTimestamp),
+						ErrorReason:  string(common.ReasonConcurrencyConflict),
 						Result:       nil,
 					}
 					//err = fmt.Errorf("Concurreny Update error with OrgCode=%s DocCode=%s Path=%s UserSubmitted=%d System=%d", in.OrgCode, in.DocCode, in.Path, in.RecordTimestamp, docNo.RecordTimestamp)
 				} else {
-					// increase sequence number by 1 and set a new record timestamp to mark record has been altered
-					currRecordTimestamp := docNo.RecordTimestamp
+					// increase sequence number by 1, the repository bumps the version to mark record has been altered
+					currVersion := docNo.Version
 					docNo.NextSeqNo++
 					docNo.RecordTimestamp = time.Now().Unix()
 					// update the doc to db with concurrency update control
-					updatedDoc, err := s.DocNoRepo.UpdateByPath(in.OrgCode, docNo, int64(in.CurSeqNo), currRecordTimestamp)
+					updatedDoc, err := s.DocNoRepo.UpdateByPath(ctx, in.OrgCode, docNo, int64(in.CurSeqNo), currVersion)
 					if err != nil {
 						out = &pb.ConsumeDocNoResponse{
 							Ok:           false,
-							ErrorCode:    500,
+							ErrorCode:    errorCode(ctx, 500),
 							ErrorMessage: err.Error(),
+							ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
 							Result:       nil,
 						}
 					} else {
@@ -531,6 +594,7 @@ func (s *docnogenService) ConsumeDocNo(ctx context.Context, in *pb.ConsumeDocNoR
 							result = pb.ConsumeDocNoResponse_Result{
 								NextSeqNo:       uint32(updatedDoc.NextSeqNo),
 								RecordTimestamp: updatedDoc.RecordTimestamp,
+								Version:         updatedDoc.Version,
 							}
 						}
 
@@ -550,6 +614,73 @@ func (s *docnogenService) ConsumeDocNo(ctx context.Context, in *pb.ConsumeDocNoR
 				Ok:           false,
 				ErrorCode:    400,
 				ErrorMessage: preCondiErr.Error(),
+				ErrorReason:  string(common.ReasonInvalidArgument),
+				Violations:   pbViolations(violations),
+				Result:       nil,
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func (s *docnogenService) Export(ctx context.Context, in *pb.ExportRequest) (out *pb.ExportResponse, err error) {
+	// check if Repository has been initialized
+	if s.DocNoRepo == nil {
+		out = &pb.ExportResponse{
+			Ok:           false,
+			ErrorCode:    500,
+			ErrorMessage: fmt.Sprint("Document Number Repository is nil"),
+			ErrorReason:  string(common.ReasonInternal),
+			Result:       nil,
+		}
+	} else {
+		// check the request against its rules, every violation is reported
+		violations := exportRules.Validate(in)
+
+		// check if Format is supported
+		format, formatErr := NormalizeDumpFormat(in.Format)
+		if formatErr != nil {
+			violations = append(violations, common.Violation{Field: "format", Description: formatErr.Error()})
+		}
+		preCondiErr := violations.Err()
+
+		// if no error for preconditions
+		if preCondiErr == nil {
+			var data string
+			dump, err := s.exportOrg(ctx, in.OrgCode)
+			if err == nil {
+				data, err = EncodeOrgDump(dump, format)
+			}
+
+			if err != nil {
+				out = &pb.ExportResponse{
+					Ok:           false,
+					ErrorCode:    errorCode(ctx, 500),
+					ErrorMessage: err.Error(),
+					ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
+					Result:       nil,
+				}
+			} else {
+				out = &pb.ExportResponse{
+					Ok:           true,
+					ErrorCode:    0,
+					ErrorMessage: "",
+					Result: &pb.ExportResponse_Result{
+						Format:       format,
+						Data:         data,
+						CounterCount: uint32(len(dump.Counters)),
+					},
+				}
+			}
+		} else {
+			// preconditions have errors
+			out = &pb.ExportResponse{
+				Ok:           false,
+				ErrorCode:    400,
+				ErrorMessage: preCondiErr.Error(),
+				ErrorReason:  string(common.ReasonInvalidArgument),
+				Violations:   pbViolations(violations),
 				Result:       nil,
 			}
 		}
@@ -558,13 +689,150 @@ func (s *docnogenService) ConsumeDocNo(ctx context.Context, in *pb.ConsumeDocNoR
 	return out, nil
 }
 
+func (s *docnogenService) Import(ctx context.Context, in *pb.ImportRequest) (out *pb.ImportResponse, err error) {
+	// check if Repository has been initialized
+	if s.DocNoRepo == nil {
+		out = &pb.ImportResponse{
+			Ok:           false,
+			ErrorCode:    500,
+			ErrorMessage: fmt.Sprint("Document Number Repository is nil"),
+			ErrorReason:  string(common.ReasonInternal),
+			Result:       nil,
+		}
+	} else {
+		// check the request against its rules, every violation is reported
+		violations := importRules.Validate(in)
+
+		// check if Format is supported
+		if _, formatErr := NormalizeDumpFormat(in.Format); formatErr != nil {
+			violations = append(violations, common.Violation{Field: "format", Description: formatErr.Error()})
+		}
+
+		// check if Data can be decoded
+		var dump *OrgDump
+		if len(violations) == 0 {
+			var decodeErr error
+			if dump, decodeErr = DecodeOrgDump(in.Data, in.Format); decodeErr != nil {
+				violations = append(violations, common.Violation{Field: "data", Description: decodeErr.Error()})
+			}
+		}
+		preCondiErr := violations.Err()
+
+		// if no error for preconditions
+		if preCondiErr == nil {
+			var changes, previous []*ImportChange
+			for attempt := 1; ; attempt++ {
+				// work out the changes first, so nothing is written when any counter would move backwards
+				changes, err = s.planImport(ctx, in.OrgCode, dump, in.Mode == pb.ImportMode_OVERWRITE, in.Force)
+				changes = keepApplied(previous, changes)
+				previous = changes
+				if err == nil && !in.DryRun {
+					err = s.applyImport(ctx, in.OrgCode, changes)
+				}
+				if err != common.ConcurrencyUpdateError {
+					break
+				}
+
+				// a counter has changed since the plan, back off and plan again from what is stored now
+				if _, retryErr := s.waitRetry(ctx, "Import", attempt); retryErr != nil {
+					err = retryErr
+					break
+				}
+			}
+
+			if err != nil {
+				out = &pb.ImportResponse{
+					Ok:           false,
+					ErrorCode:    errorCode(ctx, errorCodeOf(err)),
+					ErrorMessage: err.Error(),
+					ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
+					Result:       importResult(in.DryRun, changes),
+				}
+			} else {
+				out = &pb.ImportResponse{
+					Ok:           true,
+					ErrorCode:    0,
+					ErrorMessage: "",
+					Result:       importResult(in.DryRun, changes),
+				}
+			}
+		} else {
+			// preconditions have errors
+			out = &pb.ImportResponse{
+				Ok:           false,
+				ErrorCode:    400,
+				ErrorMessage: preCondiErr.Error(),
+				ErrorReason:  string(common.ReasonInvalidArgument),
+				Violations:   pbViolations(violations),
+				Result:       nil,
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// waitRetry is called after an attempt failed with a concurrency update error. It backs off before the next attempt,
+// or returns the error code and error to respond with when the retries have run out or ctx is done.
+func (s *docnogenService) waitRetry(ctx context.Context, method string, attempt int) (int32, error) {
+	if attempt >= s.Retry.Policy.MaxAttempts {
+		if s.Retry.Exhausted != nil {
+			s.Retry.Exhausted.With("method", method).Add(1)
+		}
+		common.LogSpanEvent(ctx, "retries exhausted", "attempt", attempt)
+		return common.ErrorCodeContention, common.Errorf(common.ReasonContention, "Contention error: gave up after %d attempts because of concurrent updates, try again later", attempt)
+	}
+
+	if s.Retry.Retries != nil {
+		s.Retry.Retries.With("method", method).Add(1)
+	}
+	common.LogSpanEvent(ctx, "retry", "attempt", attempt, "reason", "concurrency update conflict")
+	if err := s.Retry.Policy.Wait(ctx, attempt); err != nil {
+		return common.ContextErrorCode(err), err
+	}
+	return 0, nil
+}
+
+// errorCode returns code, unless ctx is done, in which case the error is most likely caused by the cancellation or the deadline
+func errorCode(ctx context.Context, code int32) int32 {
+	if err := ctx.Err(); err != nil {
+		return common.ContextErrorCode(err)
+	}
+	return code
+}
+
+// errorCodeOf returns the legacy error code of err: 400 for a failed precondition or conflict, 500 for a storage
+// failure
+func errorCodeOf(err error) int32 {
+	switch reason := common.ReasonOf(err); reason {
+	case common.ReasonInvalidArgument, common.ReasonFailedPrecondition, common.ReasonConcurrencyConflict:
+		return 400
+	case common.ReasonContention:
+		return common.ErrorCodeContention
+	case common.ReasonCancelled, common.ReasonDeadlineExceeded:
+		return int32(reason.HTTPStatus())
+	}
+	return 500
+}
+
+// errorReason returns the reason of err, or fallback when err has none, unless ctx is done
+func errorReason(ctx context.Context, err error, fallback common.Reason) string {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return string(common.ReasonOf(ctxErr))
+	}
+	if e, ok := err.(*common.Error); ok {
+		return string(e.Reason)
+	}
+	return string(fallback)
+}
+
 // This internal function check if Custom Function is passed in from request, if yes, Custom Function will be return
-func (s *docnogenService) getFormatString(orgCode string, docCode string, path string, customFormat string) string {
+func (s *docnogenService) getFormatString(ctx context.Context, orgCode string, docCode string, path string, customFormat string) string {
 	if customFormat != "" {
-		fmt.Println("Custom Format is defined")
+		common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "custom format is defined")
 		return customFormat
 	}
 
-	fmt.Printf("Custom Format is not defined, system format is generated according to parameters: OrgCode=%s DocCode=%s Path=%s\n", orgCode, docCode, path)
-	return s.DocNoFormatter.GetFormatString(orgCode, docCode, path)
+	common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "custom format is not defined, using the system format", "org", orgCode, "docCode", docCode, "path", path)
+	return s.DocNoFormatter.GetFormatString(ctx, orgCode, docCode, path)
 }
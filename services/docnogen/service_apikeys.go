@@ -0,0 +1,304 @@
+package docnogensvc
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+// The roles of API keys and tokens. Admin can call every method, void is reserved for voiding issued numbers.
+const (
+	RolePeek    = "peek"
+	RoleIssue   = "issue"
+	RoleConsume = "consume"
+	RoleVoid    = "void"
+	RoleAdmin   = "admin"
+
+	// apiKeyPrefix starts every API key, so that leaked keys are easy to find
+	apiKeyPrefix = "dng_"
+)
+
+// Roles lists every role an API key can have
+var Roles = []string{RolePeek, RoleIssue, RoleConsume, RoleVoid, RoleAdmin}
+
+// Permissions are the roles each method needs. Managing API keys across organizations needs every organization.
+var Permissions = map[string]common.Permission{
+	"GenerateBulkDocNoFormat": {Roles: []string{RoleIssue, RoleAdmin}},
+	"GenerateDocNoFormat":     {Roles: []string{RoleIssue, RoleAdmin}},
+	"GetNextDocNo":            {Roles: []string{RolePeek, RoleAdmin}},
+	"ConsumeDocNo":            {Roles: []string{RoleConsume, RoleAdmin}},
+	"Export":                  {Roles: []string{RoleAdmin}},
+	"Import":                  {Roles: []string{RoleAdmin}},
+	"CreateAPIKey":            {Roles: []string{RoleAdmin}, AllOrgsWithoutOrg: true},
+	"ListAPIKeys":             {Roles: []string{RoleAdmin}, AllOrgsWithoutOrg: true},
+	"RevokeAPIKey":            {Roles: []string{RoleAdmin}, AllOrgsWithoutOrg: true},
+	// the admin endpoint of the circuit breakers
+	common.CircuitBreakersMethod: {Roles: []string{RoleAdmin}, AllOrgsWithoutOrg: true},
+}
+
+// newAPIKey returns a new random key and its id, the id is part of the key
+func newAPIKey() (key string, id string, err error) {
+	idBytes := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err = rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(idBytes)
+	return apiKeyPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(secret), id, nil
+}
+
+func pbAPIKey(key *models.APIKey) *pb.APIKey {
+	return &pb.APIKey{
+		Id:        key.ID,
+		Name:      key.Name,
+		OrgCodes:  key.Orgs,
+		Roles:     key.Roles,
+		CreatedAt: key.CreatedAt,
+		ExpiresAt: key.ExpiresAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
+
+func (s *docnogenService) CreateAPIKey(ctx context.Context, in *pb.CreateAPIKeyRequest) (out *pb.CreateAPIKeyResponse, err error) {
+	// check if Repository has been initialized
+	if s.APIKeyRepo == nil {
+		out = &pb.CreateAPIKeyResponse{
+			Ok:           false,
+			ErrorCode:    500,
+			ErrorMessage: fmt.Sprint("API Key Repository is nil"),
+			ErrorReason:  string(common.ReasonInternal),
+			Result:       nil,
+		}
+	} else {
+		// check the request against its rules, every violation is reported
+		violations := createAPIKeyRules.Validate(in)
+
+		// check if the key expires in the future
+		now := time.Now().Unix()
+		if in.ExpiresAt != 0 && in.ExpiresAt <= now {
+			violations = append(violations, common.Violation{Field: "expiresAt", Description: "Expiry is in the past"})
+		}
+		preCondiErr := violations.Err()
+
+		// if no error for preconditions
+		if preCondiErr == nil {
+			var key, id string
+			key, id, err = newAPIKey()
+			stored := &models.APIKey{
+				ID:        id,
+				Name:      in.Name,
+				Hash:      models.HashAPIKey(key),
+				Orgs:      in.OrgCodes,
+				Roles:     in.Roles,
+				CreatedAt: now,
+				ExpiresAt: in.ExpiresAt,
+			}
+			if err == nil {
+				err = s.APIKeyRepo.Create(ctx, stored)
+			}
+
+			if err != nil {
+				out = &pb.CreateAPIKeyResponse{
+					Ok:           false,
+					ErrorCode:    errorCode(ctx, 500),
+					ErrorMessage: err.Error(),
+					ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
+					Result:       nil,
+				}
+			} else {
+				out = &pb.CreateAPIKeyResponse{
+					Ok:           true,
+					ErrorCode:    0,
+					ErrorMessage: "",
+					Result: &pb.CreateAPIKeyResponse_Result{
+						Key:    key,
+						ApiKey: pbAPIKey(stored),
+					},
+				}
+			}
+		} else {
+			// preconditions have errors
+			out = &pb.CreateAPIKeyResponse{
+				Ok:           false,
+				ErrorCode:    400,
+				ErrorMessage: preCondiErr.Error(),
+				ErrorReason:  string(common.ReasonInvalidArgument),
+				Violations:   pbViolations(violations),
+				Result:       nil,
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func (s *docnogenService) ListAPIKeys(ctx context.Context, in *pb.ListAPIKeysRequest) (out *pb.ListAPIKeysResponse, err error) {
+	// check if Repository has been initialized
+	if s.APIKeyRepo == nil {
+		out = &pb.ListAPIKeysResponse{
+			Ok:           false,
+			ErrorCode:    500,
+			ErrorMessage: fmt.Sprint("API Key Repository is nil"),
+			ErrorReason:  string(common.ReasonInternal),
+			Result:       nil,
+		}
+	} else {
+		keys, err := s.APIKeyRepo.List(ctx, in.OrgCode)
+		if err != nil {
+			out = &pb.ListAPIKeysResponse{
+				Ok:           false,
+				ErrorCode:    errorCode(ctx, 500),
+				ErrorMessage: err.Error(),
+				ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
+				Result:       nil,
+			}
+		} else {
+			result := &pb.ListAPIKeysResponse_Result{ApiKeys: make([]*pb.APIKey, len(keys))}
+			for i, key := range keys {
+				result.ApiKeys[i] = pbAPIKey(key)
+			}
+			out = &pb.ListAPIKeysResponse{
+				Ok:           true,
+				ErrorCode:    0,
+				ErrorMessage: "",
+				Result:       result,
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func (s *docnogenService) RevokeAPIKey(ctx context.Context, in *pb.RevokeAPIKeyRequest) (out *pb.RevokeAPIKeyResponse, err error) {
+	// check if Repository has been initialized
+	if s.APIKeyRepo == nil {
+		out = &pb.RevokeAPIKeyResponse{
+			Ok:           false,
+			ErrorCode:    500,
+			ErrorMessage: fmt.Sprint("API Key Repository is nil"),
+			ErrorReason:  string(common.ReasonInternal),
+			Result:       nil,
+		}
+	} else {
+		// check the request against its rules, every violation is reported
+		violations := revokeAPIKeyRules.Validate(in)
+		preCondiErr := violations.Err()
+
+		// if no error for preconditions
+		if preCondiErr == nil {
+			key, err := s.APIKeyRepo.Revoke(ctx, in.Id, time.Now().Unix())
+			if err != nil {
+				code := int32(500)
+				if common.ReasonOf(err) == common.ReasonNotFound {
+					code = 404
+				}
+				out = &pb.RevokeAPIKeyResponse{
+					Ok:           false,
+					ErrorCode:    errorCode(ctx, code),
+					ErrorMessage: err.Error(),
+					ErrorReason:  errorReason(ctx, err, common.ReasonStorageUnavailable),
+					Result:       nil,
+				}
+			} else {
+				out = &pb.RevokeAPIKeyResponse{
+					Ok:           true,
+					ErrorCode:    0,
+					ErrorMessage: "",
+					Result:       &pb.RevokeAPIKeyResponse_Result{ApiKey: pbAPIKey(key)},
+				}
+			}
+		} else {
+			// preconditions have errors
+			out = &pb.RevokeAPIKeyResponse{
+				Ok:           false,
+				ErrorCode:    400,
+				ErrorMessage: preCondiErr.Error(),
+				ErrorReason:  string(common.ReasonInvalidArgument),
+				Violations:   pbViolations(violations),
+				Result:       nil,
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// apiKeyAuthenticator authenticates requests by the API key the transports put in the context
+type apiKeyAuthenticator struct {
+	repo models.APIKeyRepository
+	ttl  time.Duration
+
+	mtx   sync.Mutex
+	cache map[string]cachedAPIKey // hash -> key
+}
+
+type cachedAPIKey struct {
+	key      *models.APIKey
+	loadedAt time.Time
+}
+
+// NewAPIKeyAuthenticator returns the authenticator of the keys in repo. A key is read again at most every ttl, so a
+// revoked key may be accepted for up to ttl; unknown keys are not cached.
+func NewAPIKeyAuthenticator(repo models.APIKeyRepository, ttl time.Duration) common.Authenticator {
+	return &apiKeyAuthenticator{
+		repo:  repo,
+		ttl:   ttl,
+		cache: make(map[string]cachedAPIKey),
+	}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(ctx context.Context) (*common.Principal, error) {
+	key, ok := ctx.Value(common.APIKeyContextKey).(string)
+	if !ok || key == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, common.Errorf(common.ReasonUnauthenticated, "Invalid API key")
+	}
+
+	hash := models.HashAPIKey(key)
+	a.mtx.Lock()
+	cached, ok := a.cache[hash]
+	a.mtx.Unlock()
+	if !ok || time.Since(cached.loadedAt) >= a.ttl {
+		stored, err := a.repo.GetByHash(ctx, hash)
+		if err != nil {
+			if common.ReasonOf(err) == common.ReasonInternal {
+				err = common.Errorf(common.ReasonStorageUnavailable, "Error checking API key: %s", err.Error())
+			}
+			return nil, err
+		}
+		if stored == nil {
+			a.mtx.Lock()
+			delete(a.cache, hash)
+			a.mtx.Unlock()
+			return nil, common.Errorf(common.ReasonUnauthenticated, "Invalid API key")
+		}
+		cached = cachedAPIKey{key: stored, loadedAt: time.Now()}
+		a.mtx.Lock()
+		a.cache[hash] = cached
+		a.mtx.Unlock()
+	}
+
+	if !cached.key.Active(time.Now().Unix()) {
+		return nil, common.Errorf(common.ReasonUnauthenticated, "API key %s is revoked or expired", cached.key.ID)
+	}
+	return &common.Principal{
+		Subject: "apikey:" + cached.key.ID,
+		Orgs:    cached.key.Orgs,
+		Roles:   cached.key.Roles,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package docnogensvc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+func Test_APIKeys(t *testing.T) {
+	Convey("Given a service with API keys", t, func() {
+		ctx := context.Background()
+		apiKeys := models.NewMemoryAPIKeyRepository()
+		svc := NewDocnogenServiceWithAPIKeys(models.NewMemoryDocNoRepository(), NewDocnoformatterService(), RetryConfig{Policy: common.DefaultRetryPolicy}, apiKeys)
+
+		Convey("A created key is returned once, and only its hash is stored", func() {
+			out, err := svc.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "reporting", OrgCodes: []string{"MAT"}, Roles: []string{RolePeek}})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			So(strings.HasPrefix(out.Result.Key, apiKeyPrefix+out.Result.ApiKey.Id), ShouldBeTrue)
+
+			stored, err := apiKeys.GetByHash(ctx, models.HashAPIKey(out.Result.Key))
+			So(err, ShouldBeNil)
+			So(stored.ID, ShouldEqual, out.Result.ApiKey.Id)
+			So(stored.Hash, ShouldNotContainSubstring, out.Result.Key)
+
+			Convey("it is listed for its organization and revoked once", func() {
+				list, _ := svc.ListAPIKeys(ctx, &pb.ListAPIKeysRequest{OrgCode: "MAT"})
+				So(list.Ok, ShouldBeTrue)
+				So(len(list.Result.ApiKeys), ShouldEqual, 1)
+				list, _ = svc.ListAPIKeys(ctx, &pb.ListAPIKeysRequest{OrgCode: "OTHER"})
+				So(len(list.Result.ApiKeys), ShouldEqual, 0)
+
+				revoked, _ := svc.RevokeAPIKey(ctx, &pb.RevokeAPIKeyRequest{Id: out.Result.ApiKey.Id})
+				So(revoked.Ok, ShouldBeTrue)
+				So(revoked.Result.ApiKey.RevokedAt, ShouldNotEqual, 0)
+
+				missing, _ := svc.RevokeAPIKey(ctx, &pb.RevokeAPIKeyRequest{Id: "missing"})
+				So(missing.Ok, ShouldBeFalse)
+				So(missing.ErrorCode, ShouldEqual, 404)
+				So(missing.ErrorReason, ShouldEqual, string(common.ReasonNotFound))
+			})
+		})
+
+		Convey("Keys without organizations or roles, with unknown roles or expired are INVALID_ARGUMENT", func() {
+			for _, in := range []*pb.CreateAPIKeyRequest{
+				{Name: "reporting", Roles: []string{RolePeek}},
+				{Name: "reporting", OrgCodes: []string{"MAT"}},
+				{Name: "reporting", OrgCodes: []string{"MAT"}, Roles: []string{"root"}},
+				{Name: "reporting", OrgCodes: []string{"MAT"}, Roles: []string{RolePeek}, ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+			} {
+				out, err := svc.CreateAPIKey(ctx, in)
+				So(err, ShouldBeNil)
+				So(out.Ok, ShouldBeFalse)
+				So(out.ErrorCode, ShouldEqual, 400)
+				So(out.ErrorReason, ShouldEqual, string(common.ReasonInvalidArgument))
+			}
+		})
+
+		Convey("Requests are authorized by the roles and organizations of their key", func() {
+			middleware := common.NewAuthMiddleware(Permissions, NewAPIKeyAuthenticator(apiKeys, 0))
+			call := func(key string, method string, request interface{}) error {
+				next := func(ctx context.Context, request interface{}) (interface{}, error) {
+					return nil, nil
+				}
+				_, err := middleware(method, next)(context.WithValue(ctx, common.APIKeyContextKey, key), request)
+				return err
+			}
+			created, _ := svc.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "reporting", OrgCodes: []string{"MAT"}, Roles: []string{RolePeek}})
+			key := created.Result.Key
+
+			So(call(key, "GetNextDocNo", &pb.GetNextDocNoRequest{OrgCode: "MAT"}), ShouldBeNil)
+			So(common.ReasonOf(call(key, "GetNextDocNo", &pb.GetNextDocNoRequest{OrgCode: "OTHER"})), ShouldEqual, common.ReasonPermissionDenied)
+			So(common.ReasonOf(call(key, "GenerateDocNoFormat", &pb.GenerateDocNoFormatRequest{OrgCode: "MAT"})), ShouldEqual, common.ReasonPermissionDenied)
+			So(common.ReasonOf(call(key, "ListAPIKeys", &pb.ListAPIKeysRequest{})), ShouldEqual, common.ReasonPermissionDenied)
+			So(common.ReasonOf(call(apiKeyPrefix+"unknown", "GetNextDocNo", &pb.GetNextDocNoRequest{OrgCode: "MAT"})), ShouldEqual, common.ReasonUnauthenticated)
+			So(common.ReasonOf(call("unknown", "GetNextDocNo", &pb.GetNextDocNoRequest{OrgCode: "MAT"})), ShouldEqual, common.ReasonUnauthenticated)
+
+			svc.RevokeAPIKey(ctx, &pb.RevokeAPIKeyRequest{Id: created.Result.ApiKey.Id})
+			So(common.ReasonOf(call(key, "GetNextDocNo", &pb.GetNextDocNoRequest{OrgCode: "MAT"})), ShouldEqual, common.ReasonUnauthenticated)
+		})
+
+		Convey("Only admins of every organization manage keys across organizations", func() {
+			middleware := common.NewAuthMiddleware(Permissions, NewAPIKeyAuthenticator(apiKeys, 0))
+			call := func(key string, request interface{}) error {
+				next := func(ctx context.Context, request interface{}) (interface{}, error) {
+					return nil, nil
+				}
+				_, err := middleware("ListAPIKeys", next)(context.WithValue(ctx, common.APIKeyContextKey, key), request)
+				return err
+			}
+			orgAdmin, _ := svc.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "org admin", OrgCodes: []string{"MAT"}, Roles: []string{RoleAdmin}})
+			admin, _ := svc.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "admin", OrgCodes: []string{common.AllOrgs}, Roles: []string{RoleAdmin}})
+
+			So(call(orgAdmin.Result.Key, &pb.ListAPIKeysRequest{OrgCode: "MAT"}), ShouldBeNil)
+			So(common.ReasonOf(call(orgAdmin.Result.Key, &pb.ListAPIKeysRequest{})), ShouldEqual, common.ReasonPermissionDenied)
+			So(call(admin.Result.Key, &pb.ListAPIKeysRequest{}), ShouldBeNil)
+		})
+	})
+}
@@ -5,15 +5,17 @@ import (
 	"regexp"
 	"strings"
 
+	context "golang.org/x/net/context"
+
 	"github.com/howlun/go-kit-documentnogen/common"
 )
 
 type DocnoformatterService interface {
-	GetFormatString(orgCode string, docCode string, path string) string
-	GenerateSeqNoStr(orgCode string, docCode string, path string, seqNo int64) string
-	SplitFormatToArray(format string) []string
-	ValidateFormatString(format string, docCode string, seqNoStr string, variableMap map[string]string) (bool, error)
-	GenerateFormatString(format string, docCode string, seqNoStr string, variableMap map[string]string) (string, error)
+	GetFormatString(ctx context.Context, orgCode string, docCode string, path string) string
+	GenerateSeqNoStr(ctx context.Context, orgCode string, docCode string, path string, seqNo int64) string
+	SplitFormatToArray(ctx context.Context, format string) []string
+	ValidateFormatString(ctx context.Context, format string, docCode string, seqNoStr string, variableMap map[string]string) (bool, error)
+	GenerateFormatString(ctx context.Context, format string, docCode string, seqNoStr string, variableMap map[string]string) (string, error)
 }
 
 type docNoFormatterDefaultService struct {
@@ -24,15 +26,15 @@ func NewDocnoformatterService() (s DocnoformatterService) {
 	return s
 }
 
-func (df *docNoFormatterDefaultService) GetFormatString(orgCode string, docCode string, path string) string {
+func (df *docNoFormatterDefaultService) GetFormatString(ctx context.Context, orgCode string, docCode string, path string) string {
 	return common.DefaultDocFormat
 }
 
-func (df *docNoFormatterDefaultService) GenerateSeqNoStr(orgCode string, docCode string, path string, seqNo int64) string {
+func (df *docNoFormatterDefaultService) GenerateSeqNoStr(ctx context.Context, orgCode string, docCode string, path string, seqNo int64) string {
 	return fmt.Sprintf(common.DefaultSeqNoFormat, common.DefaultSeqNoLength, seqNo)
 }
 
-func (df *docNoFormatterDefaultService) SplitFormatToArray(format string) []string {
+func (df *docNoFormatterDefaultService) SplitFormatToArray(ctx context.Context, format string) []string {
 	re := regexp.MustCompile(common.MustCompilePatternStr)
 	arr := re.FindAllString(format, -1)
 
@@ -41,12 +43,12 @@ func (df *docNoFormatterDefaultService) SplitFormatToArray(format string) []stri
 		str = strings.Replace(str, "}}", "", -1)
 		arr[i] = str
 	}
-	fmt.Println(arr)
+	common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "format variables", "format", format, "variables", strings.Join(arr, ","))
 	return arr
 }
 
 // This function check if all the variables in the Variable Map able to map to the Format required
-func (df *docNoFormatterDefaultService) ValidateFormatString(format string, docCode string, seqNoStr string, variableMap map[string]string) (bool, error) {
+func (df *docNoFormatterDefaultService) ValidateFormatString(ctx context.Context, format string, docCode string, seqNoStr string, variableMap map[string]string) (bool, error) {
 	validateSuccess := true
 	hasFixedVarPrefix := false
 	hasFixedVarSeqNo := false
@@ -56,7 +58,7 @@ func (df *docNoFormatterDefaultService) ValidateFormatString(format string, docC
 	variableMap[common.FixedVarPrefix] = docCode
 	variableMap[common.FixedVarSeqNo] = seqNoStr
 
-	for _, varName := range df.SplitFormatToArray(format) {
+	for _, varName := range df.SplitFormatToArray(ctx, format) {
 		// check if the mandatory variables (PREFIX and SEQNO) are provided
 		if varName == common.FixedVarPrefix {
 			hasFixedVarPrefix = true
@@ -77,11 +79,11 @@ func (df *docNoFormatterDefaultService) ValidateFormatString(format string, docC
 		err = fmt.Errorf("The required variable {{%s}} and/or {{%s}} in Format is not provided or not found", common.FixedVarPrefix, common.FixedVarSeqNo)
 	}
 
-	fmt.Printf("Format is valid=%v err=%v\n", validateSuccess, err)
+	common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "validated format", "format", format, "valid", validateSuccess, "variableMap", variableMap, "err", err)
 	return validateSuccess, err
 }
 
-func (df *docNoFormatterDefaultService) GenerateFormatString(format string, docCode string, seqNoStr string, variableMap map[string]string) (string, error) {
+func (df *docNoFormatterDefaultService) GenerateFormatString(ctx context.Context, format string, docCode string, seqNoStr string, variableMap map[string]string) (string, error) {
 	if format == "" {
 		return "", fmt.Errorf("Format string is empty")
 	}
@@ -95,8 +97,7 @@ func (df *docNoFormatterDefaultService) GenerateFormatString(format string, docC
 	}
 
 	// Check if all required variables needed in Format is provided in variable Map
-	fmt.Println("Check if all required variables needed in Format is provided in variable Map")
-	formatIsValid, err := df.ValidateFormatString(format, docCode, seqNoStr, variableMap)
+	formatIsValid, err := df.ValidateFormatString(ctx, format, docCode, seqNoStr, variableMap)
 	if formatIsValid == false {
 		return "", fmt.Errorf("Format is not valid with Variable Map: %s", err.Error())
 	}
@@ -108,9 +109,9 @@ func (df *docNoFormatterDefaultService) GenerateFormatString(format string, docC
 	//initialize docNoString with format
 	docNoString := format
 	// Replace variable into Format string
-	for _, key := range df.SplitFormatToArray(format) {
+	for _, key := range df.SplitFormatToArray(ctx, format) {
 		docNoString = strings.Replace(docNoString, fmt.Sprintf("{{%s}}", key), variableMap[key], -1)
 	}
-	fmt.Printf("docNoString=%s err=%v\n", docNoString, err)
+	common.LogDebug(common.LoggerFromContext(ctx)).Log("msg", "formatted document number", "docNo", docNoString)
 	return docNoString, nil
 }
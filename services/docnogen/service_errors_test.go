@@ -0,0 +1,95 @@
+package docnogensvc
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+func Test_ErrorReasons(t *testing.T) {
+	Convey("Given a service", t, func() {
+		ctx := context.Background()
+		repo := &contendedRepository{DocNoRepository: models.NewMemoryDocNoRepository()}
+		policy := common.RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond}
+		svc := NewDocnogenServiceWithRetry(repo, NewDocnoformatterService(), RetryConfig{Policy: policy})
+		peekIn := &pb.GetNextDocNoRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, CustomFormat: "{{PREFIX}}{{SEQNO}}"}
+
+		Convey("A request failing validation is INVALID_ARGUMENT, keeping the legacy code", func() {
+			out, err := svc.GetNextDocNo(ctx, &pb.GetNextDocNoRequest{OrgCode: "MAT", Path: "AP/PO/HQ/19"})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, 400)
+			So(out.ErrorReason, ShouldEqual, string(common.ReasonInvalidArgument))
+		})
+
+		Convey("Consuming with a stale version is CONCURRENCY_CONFLICT", func() {
+			peek, _ := svc.GetNextDocNo(ctx, peekIn)
+			out, err := svc.ConsumeDocNo(ctx, &pb.ConsumeDocNoRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", CurSeqNo: peek.Result.NextSeqNo, Version: peek.Result.Version + 1})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, 400)
+			So(out.ErrorReason, ShouldEqual, string(common.ReasonConcurrencyConflict))
+		})
+
+		Convey("Running out of retries is CONTENTION", func() {
+			repo.Conflicts = 1
+			out, err := svc.GenerateDocNoFormat(ctx, &pb.GenerateDocNoFormatRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, common.ErrorCodeContention)
+			So(out.ErrorReason, ShouldEqual, string(common.ReasonContention))
+
+			Convey("which maps to ABORTED with the response attached, and to 409", func() {
+				st, ok := status.FromError(common.ResponseStatus(out))
+				So(ok, ShouldBeTrue)
+				So(st.Code(), ShouldEqual, codes.Aborted)
+				So(st.Message(), ShouldEqual, out.ErrorMessage)
+				So(len(st.Details()), ShouldEqual, 1)
+				detail, ok := st.Details()[0].(*pb.GenerateDocNoFormatResponse)
+				So(ok, ShouldBeTrue)
+				So(detail.ErrorCode, ShouldEqual, common.ErrorCodeContention)
+
+				So(common.ResponseError(out).Reason.HTTPStatus(), ShouldEqual, http.StatusConflict)
+			})
+		})
+
+		Convey("A cancelled request is CANCELLED", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			out, err := svc.GetNextDocNo(cancelled, peekIn)
+			So(err, ShouldBeNil)
+			So(out.ErrorReason, ShouldEqual, string(common.ReasonCancelled))
+			So(common.ResponseError(out).Reason.GRPCCode(), ShouldEqual, codes.Canceled)
+		})
+
+		Convey("Updating a missing counter is NOT_FOUND", func() {
+			_, err := repo.UpdateByPath(ctx, "MAT", &models.DocNo{Prefix: "AR", Path: "AR/HQ", NextSeqNo: 2}, 1, 1)
+			So(common.ReasonOf(err), ShouldEqual, common.ReasonNotFound)
+			So(common.ReasonOf(err).HTTPStatus(), ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("A successful response has no status error", func() {
+			out, _ := svc.GetNextDocNo(ctx, peekIn)
+			So(out.Ok, ShouldBeTrue)
+			So(out.ErrorReason, ShouldBeEmpty)
+			So(common.ResponseStatus(out), ShouldBeNil)
+		})
+	})
+
+	Convey("Responses without a reason fall back to their legacy code", t, func() {
+		e := common.ResponseError(&pb.ExportResponse{ErrorCode: 500, ErrorMessage: "down"})
+		So(e.Reason, ShouldEqual, common.ReasonInternal)
+		So(common.ReasonOf(common.ConcurrencyUpdateError), ShouldEqual, common.ReasonConcurrencyConflict)
+		So(common.ReasonStorageUnavailable.GRPCCode(), ShouldEqual, codes.Unavailable)
+		So(common.ReasonStorageUnavailable.HTTPStatus(), ShouldEqual, http.StatusServiceUnavailable)
+	})
+}
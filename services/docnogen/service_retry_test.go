@@ -0,0 +1,162 @@
+package docnogensvc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	basictracer "github.com/opentracing/basictracer-go"
+	opentracing "github.com/opentracing/opentracing-go"
+	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+// contendedRepository fails the first Conflicts conditional updates with common.ConcurrencyUpdateError
+type contendedRepository struct {
+	models.DocNoRepository
+	mtx       sync.Mutex
+	Conflicts int
+	Updates   int
+}
+
+func (r *contendedRepository) UpdateByPath(ctx context.Context, orgCode string, doc *models.DocNo, curSeqNo int64, curVersion int64) (*models.DocNo, error) {
+	r.mtx.Lock()
+	r.Updates++
+	conflict := r.Updates <= r.Conflicts
+	r.mtx.Unlock()
+	if conflict {
+		return nil, common.ConcurrencyUpdateError
+	}
+	return r.DocNoRepository.UpdateByPath(ctx, orgCode, doc, curSeqNo, curVersion)
+}
+
+// testCounter sums everything added to it, whatever the labels
+type testCounter struct {
+	mtx   sync.Mutex
+	value float64
+}
+
+func (c *testCounter) With(labelValues ...string) metrics.Counter { return c }
+
+func (c *testCounter) Add(delta float64) {
+	c.mtx.Lock()
+	c.value += delta
+	c.mtx.Unlock()
+}
+
+func Test_Retry(t *testing.T) {
+	Convey("Given a repository with conflicting updates", t, func() {
+		repo := &contendedRepository{DocNoRepository: models.NewMemoryDocNoRepository()}
+		retries, exhausted := &testCounter{}, &testCounter{}
+		policy := common.RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, Multiplier: 2, Jitter: 0.5}
+		svc := NewDocnogenServiceWithRetry(repo, NewDocnoformatterService(), RetryConfig{Policy: policy, Retries: retries, Exhausted: exhausted})
+		in := &pb.GenerateDocNoFormatRequest{
+			DocCode:      "AP",
+			OrgCode:      "MAT",
+			Path:         "AP/PO/HQ/19",
+			VariableMap:  map[string]string{},
+			CustomFormat: "{{PREFIX}}{{SEQNO}}",
+		}
+
+		Convey("A few conflicts are retried until the update succeeds", func() {
+			repo.Conflicts = 3
+			out, err := svc.GenerateDocNoFormat(context.Background(), in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			So(out.Result.DocNoString, ShouldEqual, "AP00001")
+			So(repo.Updates, ShouldEqual, 4)
+			So(retries.value, ShouldEqual, 3)
+			So(exhausted.value, ShouldEqual, 0)
+		})
+
+		Convey("Conflicts beyond the maximum attempts are a contention error", func() {
+			repo.Conflicts = 100
+			out, err := svc.GenerateDocNoFormat(context.Background(), in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, common.ErrorCodeContention)
+			So(repo.Updates, ShouldEqual, 4)
+			So(exhausted.value, ShouldEqual, 1)
+
+			Convey("and a bulk request stops at the first number given up on", func() {
+				out, err := svc.GenerateBulkDocNoFormat(context.Background(), &pb.GenerateBulkDocNoFormatRequest{
+					DocCode:      "AP",
+					OrgCode:      "MAT",
+					Path:         "AP/PO/HQ/19",
+					VariableMap:  map[string]string{},
+					BulkNumber:   5,
+					CustomFormat: "{{PREFIX}}{{SEQNO}}",
+				})
+				So(err, ShouldBeNil)
+				So(out.Ok, ShouldBeFalse)
+				So(out.ErrorCode, ShouldEqual, common.ErrorCodeContention)
+				So(repo.Updates, ShouldEqual, 8)
+			})
+		})
+
+		Convey("Retries are events of the current span, and every repository call a span of its own", func() {
+			var buf bytes.Buffer
+			tracer := common.NewTracer("docnogen", common.NewWriterSpanExporter(&buf), 1, nil)
+			opentracing.SetGlobalTracer(tracer)
+			defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+			svc := NewDocnogenServiceWithRetry(models.NewTracingDocNoRepository(repo, "memory"), NewDocnoformatterService(), RetryConfig{Policy: policy})
+			repo.Conflicts = 2
+			span, ctx := opentracing.StartSpanFromContext(context.Background(), "service")
+			out, err := svc.GenerateDocNoFormat(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			span.Finish()
+			So(tracer.Shutdown(context.Background()), ShouldBeNil)
+
+			spanID := fmt.Sprintf("%016x", span.Context().(basictracer.SpanContext).SpanID)
+			So(strings.Count(buf.String(), `"name":"retry","time"`), ShouldEqual, 2)
+			So(buf.String(), ShouldContainSubstring, `"attempt":"2"`)
+			So(strings.Count(buf.String(), `"name":"DocNoRepository.UpdateByPath"`), ShouldEqual, 3)
+			So(strings.Count(buf.String(), `"parentSpanId":"`+spanID+`"`), ShouldEqual, 6)
+		})
+
+		Convey("A cancelled request stops retrying", func() {
+			repo.Conflicts = 100
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			out, err := svc.GenerateDocNoFormat(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, common.ErrorCodeCancelled)
+			So(repo.Updates, ShouldEqual, 0)
+		})
+
+		Convey("A request past its deadline stops retrying", func() {
+			repo.Conflicts = 100
+			svc := NewDocnogenServiceWithRetry(repo, NewDocnoformatterService(), RetryConfig{Policy: common.RetryPolicy{MaxAttempts: 100, InitialBackoff: time.Hour}})
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			out, err := svc.GenerateDocNoFormat(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, common.ErrorCodeDeadlineExceeded)
+			So(repo.Updates, ShouldEqual, 1)
+		})
+	})
+
+	Convey("The backoff grows exponentially up to the maximum", t, func() {
+		policy := common.RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond, Multiplier: 2}
+		So(policy.Backoff(1), ShouldEqual, 10*time.Millisecond)
+		So(policy.Backoff(2), ShouldEqual, 20*time.Millisecond)
+		So(policy.Backoff(3), ShouldEqual, 40*time.Millisecond)
+		So(policy.Backoff(4), ShouldEqual, 50*time.Millisecond)
+
+		policy.Jitter = 0.5
+		for i := 0; i < 20; i++ {
+			So(policy.Backoff(2), ShouldBeBetweenOrEqual, 10*time.Millisecond, 20*time.Millisecond)
+		}
+	})
+}
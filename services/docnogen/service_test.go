@@ -1,31 +1,247 @@
 package docnogensvc
 
 import (
+	"sync"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
 )
 
+func newTestService() pb.DocNoGenServiceServer {
+	return NewDocnogenService(models.NewMemoryDocNoRepository(), NewDocnoformatterService())
+}
+
 func Test_GenerateBulkDocNoFormat(t *testing.T) {
-	// TODO: Implement testin for method logics here
+	Convey("Given a service backed by the in-memory repository", t, func() {
+		svc := newTestService()
+		ctx := context.Background()
 
-	Convey("This isn't yet implemented", nil)
+		Convey("A bulk request returns consecutive document numbers", func() {
+			out, err := svc.GenerateBulkDocNoFormat(ctx, &pb.GenerateBulkDocNoFormatRequest{
+				DocCode:      "AP",
+				OrgCode:      "MAT",
+				Path:         "AP/PO/HQ/19",
+				VariableMap:  map[string]string{},
+				BulkNumber:   3,
+				CustomFormat: "{{PREFIX}}{{SEQNO}}",
+			})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			So(len(out.Results), ShouldEqual, 3)
+			So(out.Results[0].DocNoString, ShouldEqual, "AP00001")
+			So(out.Results[1].DocNoString, ShouldEqual, "AP00002")
+			So(out.Results[2].DocNoString, ShouldEqual, "AP00003")
+			So(out.Results[2].NextSeqNo, ShouldEqual, 4)
+		})
+
+		Convey("A bulk number out of range is rejected", func() {
+			out, err := svc.GenerateBulkDocNoFormat(ctx, &pb.GenerateBulkDocNoFormatRequest{
+				DocCode:     "AP",
+				OrgCode:     "MAT",
+				Path:        "AP/PO/HQ/19",
+				VariableMap: map[string]string{},
+				BulkNumber:  100,
+			})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, 400)
+			So(out.Results, ShouldBeEmpty)
+		})
+	})
 }
 
 func Test_GenerateDocNoFormat(t *testing.T) {
-	// TODO: Implement testin for method logics here
+	Convey("Given a service backed by the in-memory repository", t, func() {
+		svc := newTestService()
+		ctx := context.Background()
+		newRequest := func() *pb.GenerateDocNoFormatRequest {
+			return &pb.GenerateDocNoFormatRequest{
+				DocCode:     "AP",
+				OrgCode:     "MAT",
+				Path:        "AP/PO/HQ/19",
+				VariableMap: map[string]string{"DOCTYPE": "PO", "BRHCD": "HQ", "YEAR": "19"},
+			}
+		}
+
+		Convey("Each call consumes the next sequence number with the default format", func() {
+			out, err := svc.GenerateDocNoFormat(ctx, newRequest())
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			So(out.Result.DocNoString, ShouldEqual, "APPOHQ1900001")
+			So(out.Result.NextSeqNo, ShouldEqual, 2)
+
+			out, err = svc.GenerateDocNoFormat(ctx, newRequest())
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			So(out.Result.DocNoString, ShouldEqual, "APPOHQ1900002")
+		})
+
+		Convey("A missing variable of the format is rejected", func() {
+			in := newRequest()
+			delete(in.VariableMap, "BRHCD")
+			out, err := svc.GenerateDocNoFormat(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, 400)
+		})
+
+		Convey("An empty Doc Code is rejected", func() {
+			in := newRequest()
+			in.DocCode = ""
+			out, err := svc.GenerateDocNoFormat(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, 400)
+		})
+
+		Convey("Concurrent calls never hand out the same document number", func() {
+			var (
+				wg   sync.WaitGroup
+				mtx  sync.Mutex
+				seen = map[string]int{}
+			)
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 10; i++ {
+						out, _ := svc.GenerateDocNoFormat(ctx, newRequest())
+						mtx.Lock()
+						if out != nil && out.Ok {
+							seen[out.Result.DocNoString]++
+						}
+						mtx.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
 
-	Convey("This isn't yet implemented", nil)
+			So(len(seen), ShouldEqual, 80)
+			for _, count := range seen {
+				So(count, ShouldEqual, 1)
+			}
+		})
+	})
 }
 
 func Test_GetNextDocNo(t *testing.T) {
-	// TODO: Implement testin for method logics here
+	Convey("Given a service backed by the in-memory repository", t, func() {
+		svc := newTestService()
+		ctx := context.Background()
+		in := &pb.GetNextDocNoRequest{
+			DocCode:      "AP",
+			OrgCode:      "MAT",
+			Path:         "AP/PO/HQ/19",
+			VariableMap:  map[string]string{},
+			CustomFormat: "{{PREFIX}}-{{SEQNO}}",
+		}
 
-	Convey("This isn't yet implemented", nil)
+		Convey("Peeking does not consume the sequence number", func() {
+			out, err := svc.GetNextDocNo(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			So(out.Result.DocNoString, ShouldEqual, "AP-00001")
+			So(out.Result.NextSeqNo, ShouldEqual, 1)
+
+			out, err = svc.GetNextDocNo(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Result.NextSeqNo, ShouldEqual, 1)
+		})
+
+		Convey("An empty Path is rejected", func() {
+			in.Path = ""
+			out, err := svc.GetNextDocNo(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, 400)
+		})
+
+		Convey("A cancelled request does not reach the repository", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			out, err := svc.GetNextDocNo(cancelled, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, common.ErrorCodeCancelled)
+		})
+	})
 }
 
 func Test_ConsumeDocNo(t *testing.T) {
-	// TODO: Implement testin for method logics here
+	Convey("Given a peeked sequence number", t, func() {
+		svc := newTestService()
+		ctx := context.Background()
+		peek, err := svc.GetNextDocNo(ctx, &pb.GetNextDocNoRequest{
+			DocCode:      "AP",
+			OrgCode:      "MAT",
+			Path:         "AP/PO/HQ/19",
+			VariableMap:  map[string]string{},
+			CustomFormat: "{{PREFIX}}{{SEQNO}}",
+		})
+		So(err, ShouldBeNil)
+		So(peek.Ok, ShouldBeTrue)
+		in := &pb.ConsumeDocNoRequest{
+			DocCode:  "AP",
+			OrgCode:  "MAT",
+			Path:     "AP/PO/HQ/19",
+			CurSeqNo: peek.Result.NextSeqNo,
+			Version:  peek.Result.Version,
+		}
+
+		Convey("Consuming it moves the sequence number forward and bumps the version", func() {
+			out, err := svc.ConsumeDocNo(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+			So(out.Result.NextSeqNo, ShouldEqual, peek.Result.NextSeqNo+1)
+			So(out.Result.Version, ShouldEqual, peek.Result.Version+1)
+
+			Convey("and consuming it again is a concurrency error", func() {
+				out, err := svc.ConsumeDocNo(ctx, in)
+				So(err, ShouldBeNil)
+				So(out.Ok, ShouldBeFalse)
+				So(out.ErrorCode, ShouldEqual, 400)
+			})
+		})
+
+		Convey("A stale version is a concurrency error", func() {
+			in.Version--
+			out, err := svc.ConsumeDocNo(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, 400)
+		})
+
+		Convey("Without a version or record timestamp the request is rejected", func() {
+			in.Version = 0
+			out, err := svc.ConsumeDocNo(ctx, in)
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, 400)
+		})
+
+		Convey("The deprecated record timestamp is still checked when no version is sent", func() {
+			in.Version = 0
+			in.RecordTimestamp = peek.Result.RecordTimestamp
+
+			Convey("a stale one is a concurrency error", func() {
+				in.RecordTimestamp--
+				out, err := svc.ConsumeDocNo(ctx, in)
+				So(err, ShouldBeNil)
+				So(out.Ok, ShouldBeFalse)
+				So(out.ErrorCode, ShouldEqual, 400)
+			})
 
-	Convey("This isn't yet implemented", nil)
+			Convey("the current one consumes the sequence number", func() {
+				out, err := svc.ConsumeDocNo(ctx, in)
+				So(err, ShouldBeNil)
+				So(out.Ok, ShouldBeTrue)
+				So(out.Result.NextSeqNo, ShouldEqual, peek.Result.NextSeqNo+1)
+			})
+		})
+	})
 }
@@ -0,0 +1,394 @@
+package docnogensvc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+const (
+	// OrgDumpVersion is the version written to every export, imports of other versions are refused
+	OrgDumpVersion = 1
+
+	DumpFormatJSON = "json"
+	DumpFormatCSV  = "csv"
+
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionDelete    = "delete"
+)
+
+var csvHeader = []string{"version", "record", "orgCode", "docCode", "path", "nextSeqNo", "recordTimestamp", "name", "value"}
+
+// OrgDump is the versioned representation of an organization's counters. Formats and settings are not stored per
+// organization, so they are not dumped.
+type OrgDump struct {
+	Version    int            `json:"version"`
+	OrgCode    string         `json:"orgCode"`
+	ExportedAt int64          `json:"exportedAt"` // Unix timestamp
+	Counters   []*CounterDump `json:"counters"`
+}
+
+type CounterDump struct {
+	DocCode         string `json:"docCode"`
+	Path            string `json:"path"`
+	NextSeqNo       int64  `json:"nextSeqNo"`
+	RecordTimestamp int64  `json:"recordTimestamp"` // Unix timestamp
+}
+
+// ImportChange describes what an import does (or would do on dry run) to a single counter
+type ImportChange struct {
+	DocCode   string
+	Path      string
+	Action    string
+	FromSeqNo int64
+	ToSeqNo   int64
+
+	// Applied is set once the change is written, Failed when its write failed the import
+	Applied bool
+	Failed  bool
+
+	// fromVersion is the Version of the counter when the change was planned, the change is only written if it still is
+	fromVersion int64
+}
+
+func (c *ImportChange) key() string {
+	return c.DocCode + "\x00" + c.Path
+}
+
+// NormalizeDumpFormat returns the dump format in lower case, defaults to json
+func NormalizeDumpFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", DumpFormatJSON:
+		return DumpFormatJSON, nil
+	case DumpFormatCSV:
+		return DumpFormatCSV, nil
+	}
+	return "", fmt.Errorf("Dump format %s is not supported, use %s or %s", format, DumpFormatJSON, DumpFormatCSV)
+}
+
+// EncodeOrgDump writes the dump in json or csv format
+func EncodeOrgDump(dump *OrgDump, format string) (string, error) {
+	format, err := NormalizeDumpFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	if format == DumpFormatJSON {
+		b, err := json.MarshalIndent(dump, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(b) + "\n", nil
+	}
+
+	// csv: one "meta" record and one record per counter
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	version := strconv.Itoa(dump.Version)
+	w.Write(csvHeader)
+	w.Write([]string{version, "meta", dump.OrgCode, "", "", "", "", "exportedAt", strconv.FormatInt(dump.ExportedAt, 10)})
+	for _, c := range dump.Counters {
+		w.Write([]string{version, "counter", dump.OrgCode, c.DocCode, c.Path, strconv.FormatInt(c.NextSeqNo, 10), strconv.FormatInt(c.RecordTimestamp, 10), "", ""})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// DecodeOrgDump reads a dump written by EncodeOrgDump and checks its version and counters
+func DecodeOrgDump(data string, format string) (*OrgDump, error) {
+	format, err := NormalizeDumpFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	var dump *OrgDump
+	if format == DumpFormatJSON {
+		dump = &OrgDump{}
+		if err := json.Unmarshal([]byte(data), dump); err != nil {
+			return nil, fmt.Errorf("Error decoding json dump: %s", err.Error())
+		}
+	} else {
+		dump, err = decodeCSVOrgDump(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dump.Version != OrgDumpVersion {
+		return nil, fmt.Errorf("Dump version %d is not supported, expected version %d", dump.Version, OrgDumpVersion)
+	}
+
+	seen := make(map[string]bool)
+	for i, c := range dump.Counters {
+		if c == nil || c.DocCode == "" {
+			return nil, fmt.Errorf("Counter %d in dump has no Doc Code", i+1)
+		}
+		if c.Path == "" {
+			return nil, fmt.Errorf("Counter %d DocCode=%s in dump has no Path", i+1, c.DocCode)
+		}
+		if c.NextSeqNo < 1 {
+			return nil, fmt.Errorf("Counter DocCode=%s Path=%s in dump has invalid NextSeqNo=%d", c.DocCode, c.Path, c.NextSeqNo)
+		}
+		key := c.DocCode + "\x00" + c.Path
+		if seen[key] {
+			return nil, fmt.Errorf("Counter DocCode=%s Path=%s appears more than once in dump", c.DocCode, c.Path)
+		}
+		seen[key] = true
+	}
+
+	return dump, nil
+}
+
+func decodeCSVOrgDump(data string) (*OrgDump, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.FieldsPerRecord = len(csvHeader)
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding csv dump header: %s", err.Error())
+	}
+	if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+		return nil, fmt.Errorf("Unexpected csv dump header: %s", strings.Join(header, ","))
+	}
+
+	dump := &OrgDump{Counters: []*CounterDump{}}
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error decoding csv dump: %s", err.Error())
+		}
+
+		version, err := strconv.Atoi(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid version on csv dump line %d: %s", line, record[0])
+		}
+		if dump.Version != 0 && dump.Version != version {
+			return nil, fmt.Errorf("Mixed versions in csv dump on line %d", line)
+		}
+		dump.Version = version
+		dump.OrgCode = record[2]
+
+		switch record[1] {
+		case "meta":
+			if record[7] == "exportedAt" {
+				dump.ExportedAt, _ = strconv.ParseInt(record[8], 10, 64)
+			}
+		case "counter":
+			nextSeqNo, err := strconv.ParseInt(record[5], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid nextSeqNo on csv dump line %d: %s", line, record[5])
+			}
+			recordTimestamp, _ := strconv.ParseInt(record[6], 10, 64)
+			dump.Counters = append(dump.Counters, &CounterDump{
+				DocCode:         record[3],
+				Path:            record[4],
+				NextSeqNo:       nextSeqNo,
+				RecordTimestamp: recordTimestamp,
+			})
+		default:
+			return nil, fmt.Errorf("Unknown record type on csv dump line %d: %s", line, record[1])
+		}
+	}
+
+	return dump, nil
+}
+
+// exportOrg reads all counters of the organization into a dump
+func (s *docnogenService) exportOrg(ctx context.Context, orgCode string) (*OrgDump, error) {
+	docs, err := s.DocNoRepo.ListByOrg(ctx, orgCode)
+	if err != nil {
+		return nil, err
+	}
+
+	dump := &OrgDump{
+		Version:    OrgDumpVersion,
+		OrgCode:    orgCode,
+		ExportedAt: time.Now().Unix(),
+		Counters:   []*CounterDump{},
+	}
+	for _, doc := range docs {
+		dump.Counters = append(dump.Counters, &CounterDump{
+			DocCode:         doc.Prefix,
+			Path:            doc.Path,
+			NextSeqNo:       doc.NextSeqNo,
+			RecordTimestamp: doc.RecordTimestamp,
+		})
+	}
+	return dump, nil
+}
+
+// planImport compares the dump with the counters stored for the organization.
+// With overwrite, counters that are not in the dump are deleted.
+// Moving any NextSeqNo backwards is refused unless force is set.
+func (s *docnogenService) planImport(ctx context.Context, orgCode string, dump *OrgDump, overwrite bool, force bool) ([]*ImportChange, error) {
+	docs, err := s.DocNoRepo.ListByOrg(ctx, orgCode)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]*models.DocNo)
+	for _, doc := range docs {
+		existing[doc.Prefix+"\x00"+doc.Path] = doc
+	}
+
+	changes := []*ImportChange{}
+	backwards := []string{}
+	for _, c := range dump.Counters {
+		key := c.DocCode + "\x00" + c.Path
+		doc, ok := existing[key]
+		delete(existing, key)
+
+		change := &ImportChange{DocCode: c.DocCode, Path: c.Path, ToSeqNo: c.NextSeqNo}
+		if ok {
+			change.FromSeqNo, change.fromVersion = doc.NextSeqNo, doc.Version
+		}
+		switch {
+		case !ok:
+			change.Action = ImportActionCreate
+		case change.FromSeqNo == c.NextSeqNo:
+			change.Action = ImportActionUnchanged
+		default:
+			change.Action = ImportActionUpdate
+			if c.NextSeqNo < change.FromSeqNo {
+				backwards = append(backwards, fmt.Sprintf("DocCode=%s Path=%s (%d to %d)", c.DocCode, c.Path, change.FromSeqNo, c.NextSeqNo))
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	if overwrite {
+		for _, doc := range docs {
+			if _, ok := existing[doc.Prefix+"\x00"+doc.Path]; ok {
+				changes = append(changes, &ImportChange{DocCode: doc.Prefix, Path: doc.Path, Action: ImportActionDelete, FromSeqNo: doc.NextSeqNo})
+			}
+		}
+	}
+
+	if len(backwards) > 0 && !force {
+		return changes, common.Errorf(common.ReasonFailedPrecondition, "Import would move NextSeqNo backwards for %s, use force to allow it", strings.Join(backwards, ", "))
+	}
+
+	return changes, nil
+}
+
+// keepApplied returns the changes planned again after a conflict, with the changes an earlier attempt wrote in place of
+// the counters the new plan finds unchanged, or no longer finds when deleted
+func keepApplied(previous, changes []*ImportChange) []*ImportChange {
+	planned := make(map[string]int, len(changes))
+	for i, change := range changes {
+		planned[change.key()] = i
+	}
+	for _, earlier := range previous {
+		if !earlier.Applied {
+			continue
+		}
+		i, ok := planned[earlier.key()]
+		switch {
+		case !ok:
+			changes = append(changes, earlier)
+		case changes[i].Action == ImportActionUnchanged:
+			changes[i] = earlier
+		}
+	}
+	return changes
+}
+
+// applyImport writes the planned changes to the repository, marking each change written as Applied and the one
+// failing as Failed. Creates and updates are conditional updates of the counter as planned, so a number issued since
+// the plan fails them with common.ConcurrencyUpdateError instead of being overwritten, and the import has to be
+// planned again.
+func (s *docnogenService) applyImport(ctx context.Context, orgCode string, changes []*ImportChange) error {
+	for _, change := range changes {
+		if change.Applied {
+			continue
+		}
+		if err := s.applyChange(ctx, orgCode, change); err != nil {
+			change.Failed = true
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *docnogenService) applyChange(ctx context.Context, orgCode string, change *ImportChange) error {
+	switch change.Action {
+	case ImportActionUnchanged:
+		return nil
+	case ImportActionCreate, ImportActionUpdate:
+		curSeqNo, curVersion := change.FromSeqNo, change.fromVersion
+		if change.Action == ImportActionCreate {
+			// the counter starts at 1, unless it has been created and used since the plan
+			doc, err := s.DocNoRepo.GetByPath(ctx, change.DocCode, orgCode, change.Path)
+			if err != nil {
+				return err
+			}
+			if doc.NextSeqNo != 1 || doc.Version != 1 {
+				return common.ConcurrencyUpdateError
+			}
+			curSeqNo, curVersion = doc.NextSeqNo, doc.Version
+		}
+		_, err := s.DocNoRepo.UpdateByPath(ctx, orgCode, &models.DocNo{
+			Prefix:          change.DocCode,
+			Path:            change.Path,
+			NextSeqNo:       change.ToSeqNo,
+			RecordTimestamp: time.Now().Unix(),
+		}, curSeqNo, curVersion)
+		if err != nil {
+			return err
+		}
+	case ImportActionDelete:
+		if err := s.DocNoRepo.DeleteByPath(ctx, orgCode, change.DocCode, change.Path); err != nil {
+			return err
+		}
+	}
+	change.Applied = true
+	return nil
+}
+
+func importResult(dryRun bool, changes []*ImportChange) *pb.ImportResponse_Result {
+	result := &pb.ImportResponse_Result{
+		DryRun:  dryRun,
+		Changes: []*pb.ImportResponse_Change{},
+	}
+	for _, change := range changes {
+		switch change.Action {
+		case ImportActionCreate:
+			result.Created++
+		case ImportActionUpdate:
+			result.Updated++
+		case ImportActionUnchanged:
+			result.Unchanged++
+		case ImportActionDelete:
+			result.Deleted++
+		}
+		result.Changes = append(result.Changes, &pb.ImportResponse_Change{
+			DocCode:   change.DocCode,
+			Path:      change.Path,
+			Action:    change.Action,
+			FromSeqNo: change.FromSeqNo,
+			ToSeqNo:   change.ToSeqNo,
+			Applied:   change.Applied,
+			Failed:    change.Failed,
+		})
+	}
+	return result
+}
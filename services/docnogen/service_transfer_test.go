@@ -0,0 +1,243 @@
+package docnogensvc
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+func Test_ExportImport(t *testing.T) {
+	Convey("Given an organization with two counters", t, func() {
+		ctx := context.Background()
+		source := NewDocnogenService(models.NewMemoryDocNoRepository(), NewDocnoformatterService())
+		for _, path := range []string{"AP/PO/HQ/19", "AP/PO/HQ/20"} {
+			out, err := source.GenerateBulkDocNoFormat(ctx, &pb.GenerateBulkDocNoFormatRequest{
+				DocCode:      "AP",
+				OrgCode:      "MAT",
+				Path:         path,
+				VariableMap:  map[string]string{},
+				BulkNumber:   3,
+				CustomFormat: "{{PREFIX}}{{SEQNO}}",
+			})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeTrue)
+		}
+
+		for _, format := range []string{DumpFormatJSON, DumpFormatCSV} {
+			Convey("An "+format+" export can be imported into an empty store", func() {
+				exported, err := source.Export(ctx, &pb.ExportRequest{OrgCode: "MAT", Format: format})
+				So(err, ShouldBeNil)
+				So(exported.Ok, ShouldBeTrue)
+				So(exported.Result.CounterCount, ShouldEqual, 2)
+
+				targetRepo := models.NewMemoryDocNoRepository()
+				target := NewDocnogenService(targetRepo, NewDocnoformatterService())
+				imported, err := target.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Format: format, Data: exported.Result.Data})
+				So(err, ShouldBeNil)
+				So(imported.Ok, ShouldBeTrue)
+				So(imported.Result.Created, ShouldEqual, 2)
+
+				docs, err := targetRepo.ListByOrg(ctx, "MAT")
+				So(err, ShouldBeNil)
+				So(len(docs), ShouldEqual, 2)
+				So(docs[0].NextSeqNo, ShouldEqual, 4)
+				So(docs[1].NextSeqNo, ShouldEqual, 4)
+			})
+		}
+
+		Convey("With a dump taken before more numbers were issued", func() {
+			exported, err := source.Export(ctx, &pb.ExportRequest{OrgCode: "MAT"})
+			So(err, ShouldBeNil)
+			_, err = source.GenerateDocNoFormat(ctx, &pb.GenerateDocNoFormatRequest{
+				DocCode:      "AP",
+				OrgCode:      "MAT",
+				Path:         "AP/PO/HQ/19",
+				VariableMap:  map[string]string{},
+				CustomFormat: "{{PREFIX}}{{SEQNO}}",
+			})
+			So(err, ShouldBeNil)
+
+			Convey("importing it is refused because NextSeqNo would move backwards", func() {
+				imported, err := source.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: exported.Result.Data})
+				So(err, ShouldBeNil)
+				So(imported.Ok, ShouldBeFalse)
+				So(imported.ErrorCode, ShouldEqual, 400)
+				So(imported.ErrorReason, ShouldEqual, string(common.ReasonFailedPrecondition))
+
+				peek, _ := source.GetNextDocNo(ctx, &pb.GetNextDocNoRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+				So(peek.Result.NextSeqNo, ShouldEqual, 5)
+			})
+
+			Convey("importing it with force moves NextSeqNo backwards", func() {
+				imported, err := source.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: exported.Result.Data, Force: true})
+				So(err, ShouldBeNil)
+				So(imported.Ok, ShouldBeTrue)
+				So(imported.Result.Updated, ShouldEqual, 1)
+				So(imported.Result.Unchanged, ShouldEqual, 1)
+
+				peek, _ := source.GetNextDocNo(ctx, &pb.GetNextDocNoRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+				So(peek.Result.NextSeqNo, ShouldEqual, 4)
+			})
+		})
+
+		Convey("A dry run reports the changes without writing them", func() {
+			data := `{"version": 1, "orgCode": "MAT", "counters": [{"docCode": "AP", "path": "AP/PO/HQ/19", "nextSeqNo": 100}, {"docCode": "AR", "path": "AR/INV", "nextSeqNo": 7}]}`
+			imported, err := source.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: data, Mode: pb.ImportMode_OVERWRITE, DryRun: true})
+			So(err, ShouldBeNil)
+			So(imported.Ok, ShouldBeTrue)
+			So(imported.Result.DryRun, ShouldBeTrue)
+			So(imported.Result.Created, ShouldEqual, 1)
+			So(imported.Result.Updated, ShouldEqual, 1)
+			So(imported.Result.Deleted, ShouldEqual, 1)
+
+			exported, _ := source.Export(ctx, &pb.ExportRequest{OrgCode: "MAT"})
+			So(exported.Result.CounterCount, ShouldEqual, 2)
+
+			Convey("and overwrite applies them, deleting counters that are not in the dump", func() {
+				imported, err := source.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: data, Mode: pb.ImportMode_OVERWRITE})
+				So(err, ShouldBeNil)
+				So(imported.Ok, ShouldBeTrue)
+
+				exported, _ := source.Export(ctx, &pb.ExportRequest{OrgCode: "MAT", Format: DumpFormatCSV})
+				So(exported.Result.CounterCount, ShouldEqual, 2)
+				dump, err := DecodeOrgDump(exported.Result.Data, DumpFormatCSV)
+				So(err, ShouldBeNil)
+				So(dump.Counters[0].DocCode, ShouldEqual, "AP")
+				So(dump.Counters[0].NextSeqNo, ShouldEqual, 100)
+				So(dump.Counters[1].DocCode, ShouldEqual, "AR")
+			})
+
+			Convey("while merge keeps counters that are not in the dump", func() {
+				imported, err := source.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: data, Mode: pb.ImportMode_MERGE})
+				So(err, ShouldBeNil)
+				So(imported.Ok, ShouldBeTrue)
+				So(imported.Result.Deleted, ShouldEqual, 0)
+
+				exported, _ := source.Export(ctx, &pb.ExportRequest{OrgCode: "MAT"})
+				So(exported.Result.CounterCount, ShouldEqual, 3)
+			})
+		})
+
+		Convey("A storage failure is STORAGE_UNAVAILABLE with code 500, like the other methods", func() {
+			failing := NewDocnogenService(&failingListRepository{DocNoRepository: models.NewMemoryDocNoRepository()}, NewDocnoformatterService())
+			data := `{"version": 1, "orgCode": "MAT", "counters": [{"docCode": "AP", "path": "AP/PO/HQ/19", "nextSeqNo": 100}]}`
+			imported, err := failing.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: data})
+			So(err, ShouldBeNil)
+			So(imported.Ok, ShouldBeFalse)
+			So(imported.ErrorCode, ShouldEqual, 500)
+			So(imported.ErrorReason, ShouldEqual, string(common.ReasonStorageUnavailable))
+		})
+
+		Convey("A failed write reports the counters written before it, and the counter failing", func() {
+			failing := NewDocnogenService(&failingUpdateRepository{DocNoRepository: models.NewMemoryDocNoRepository(), Updates: 1}, NewDocnoformatterService())
+			data := `{"version": 1, "orgCode": "MAT", "counters": [{"docCode": "AP", "path": "AP/PO", "nextSeqNo": 10}, {"docCode": "AR", "path": "AR/INV", "nextSeqNo": 7}, {"docCode": "AR", "path": "AR/CN", "nextSeqNo": 3}]}`
+			imported, err := failing.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: data})
+			So(err, ShouldBeNil)
+			So(imported.Ok, ShouldBeFalse)
+			So(imported.ErrorCode, ShouldEqual, 500)
+			changes := imported.Result.Changes
+			So(changes[0].Applied, ShouldBeTrue)
+			So(changes[1].Applied, ShouldBeFalse)
+			So(changes[1].Failed, ShouldBeTrue)
+			So(changes[2].Applied || changes[2].Failed, ShouldBeFalse)
+		})
+
+		Convey("A counter without a path is refused", func() {
+			imported, err := source.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: `{"version": 1, "counters": [{"docCode": "AP", "nextSeqNo": 10}]}`})
+			So(err, ShouldBeNil)
+			So(imported.Ok, ShouldBeFalse)
+			So(imported.ErrorCode, ShouldEqual, 400)
+			So(imported.ErrorReason, ShouldEqual, string(common.ReasonInvalidArgument))
+		})
+
+		Convey("A dump of an unknown version is refused", func() {
+			imported, err := source.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: `{"version": 2, "counters": []}`})
+			So(err, ShouldBeNil)
+			So(imported.Ok, ShouldBeFalse)
+			So(imported.ErrorCode, ShouldEqual, 400)
+		})
+	})
+
+	Convey("Given numbers issued between the plan and the writes of an import", t, func() {
+		ctx := context.Background()
+		repo := &issuingRepository{DocNoRepository: models.NewMemoryDocNoRepository()}
+		svc := NewDocnogenService(repo, NewDocnoformatterService())
+		generate := &pb.GenerateDocNoFormatRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, CustomFormat: "{{PREFIX}}{{SEQNO}}"}
+		for i := 0; i < 3; i++ {
+			svc.GenerateDocNoFormat(ctx, generate)
+		}
+		repo.issue = func() {
+			svc.GenerateDocNoFormat(ctx, generate)
+			svc.GenerateDocNoFormat(ctx, generate)
+		}
+
+		Convey("the import is planned again and refused, as NextSeqNo would now move backwards", func() {
+			data := `{"version": 1, "orgCode": "MAT", "counters": [{"docCode": "AP", "path": "AP/PO/HQ/19", "nextSeqNo": 5}]}`
+			imported, err := svc.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: data})
+			So(err, ShouldBeNil)
+			So(imported.Ok, ShouldBeFalse)
+			So(imported.ErrorReason, ShouldEqual, string(common.ReasonFailedPrecondition))
+
+			docs, _ := repo.ListByOrg(ctx, "MAT")
+			So(docs[0].NextSeqNo, ShouldEqual, 6)
+		})
+
+		Convey("a counter still moving forward is written from the new plan", func() {
+			data := `{"version": 1, "orgCode": "MAT", "counters": [{"docCode": "AP", "path": "AP/PO/HQ/19", "nextSeqNo": 100}, {"docCode": "AR", "path": "AR/INV", "nextSeqNo": 7}]}`
+			imported, err := svc.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Data: data})
+			So(err, ShouldBeNil)
+			So(imported.Ok, ShouldBeTrue)
+			So(imported.Result.Changes[0].FromSeqNo, ShouldEqual, 6)
+			So(imported.Result.Changes[0].Applied, ShouldBeTrue)
+			So(imported.Result.Changes[1].Applied, ShouldBeTrue)
+			So(imported.Result.Created, ShouldEqual, 1)
+
+			docs, _ := repo.ListByOrg(ctx, "MAT")
+			So(docs[0].NextSeqNo, ShouldEqual, 100)
+			So(docs[1].NextSeqNo, ShouldEqual, 7)
+		})
+	})
+}
+
+// issuingRepository calls issue once, before the first conditional update, as if numbers were issued concurrently
+type issuingRepository struct {
+	models.DocNoRepository
+	issue func()
+}
+
+func (r *issuingRepository) UpdateByPath(ctx context.Context, orgCode string, doc *models.DocNo, curSeqNo int64, curVersion int64) (*models.DocNo, error) {
+	if issue := r.issue; issue != nil {
+		r.issue = nil
+		issue()
+	}
+	return r.DocNoRepository.UpdateByPath(ctx, orgCode, doc, curSeqNo, curVersion)
+}
+
+// failingListRepository fails to list the counters of an organization, as if Mongo were down
+type failingListRepository struct {
+	models.DocNoRepository
+}
+
+func (r *failingListRepository) ListByOrg(ctx context.Context, orgCode string) ([]*models.DocNo, error) {
+	return nil, errors.New("no reachable servers")
+}
+
+// failingUpdateRepository fails the conditional updates after the first Updates
+type failingUpdateRepository struct {
+	models.DocNoRepository
+	Updates int
+}
+
+func (r *failingUpdateRepository) UpdateByPath(ctx context.Context, orgCode string, doc *models.DocNo, curSeqNo int64, curVersion int64) (*models.DocNo, error) {
+	if r.Updates == 0 {
+		return nil, errors.New("no reachable servers")
+	}
+	r.Updates--
+	return r.DocNoRepository.UpdateByPath(ctx, orgCode, doc, curSeqNo, curVersion)
+}
@@ -0,0 +1,87 @@
+package docnogensvc
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+func Test_Validation(t *testing.T) {
+	Convey("Given a service", t, func() {
+		ctx := context.Background()
+		svc := NewDocnogenService(models.NewMemoryDocNoRepository(), NewDocnoformatterService())
+
+		Convey("A request with an empty docCode and an empty path reports both", func() {
+			out, err := svc.GenerateDocNoFormat(ctx, &pb.GenerateDocNoFormatRequest{OrgCode: "MAT", CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+			So(err, ShouldBeNil)
+			So(out.Ok, ShouldBeFalse)
+			So(out.ErrorCode, ShouldEqual, 400)
+			So(out.ErrorReason, ShouldEqual, string(common.ReasonInvalidArgument))
+			So(out.ErrorMessage, ShouldEqual, "Doc Code is empty; Path is empty")
+			So(out.Violations, ShouldResemble, []*pb.Violation{
+				{Field: "docCode", Description: "Doc Code is empty"},
+				{Field: "path", Description: "Path is empty"},
+			})
+		})
+
+		Convey("A bulk request out of range reports the bulk number", func() {
+			out, _ := svc.GenerateBulkDocNoFormat(ctx, &pb.GenerateBulkDocNoFormatRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", BulkNumber: 100, CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+			So(out.Ok, ShouldBeFalse)
+			So(len(out.Violations), ShouldEqual, 1)
+			So(out.Violations[0].Field, ShouldEqual, "bulkNumber")
+		})
+
+		Convey("A consume request takes the deprecated recordTimestamp in place of the version", func() {
+			out, _ := svc.ConsumeDocNo(ctx, &pb.ConsumeDocNoRequest{CurSeqNo: 1})
+			So(len(out.Violations), ShouldEqual, 4)
+			So(out.Violations[3].Field, ShouldEqual, "version")
+
+			out, _ = svc.ConsumeDocNo(ctx, &pb.ConsumeDocNoRequest{CurSeqNo: 1, RecordTimestamp: 1})
+			So(len(out.Violations), ShouldEqual, 3)
+		})
+
+		Convey("A consume request without a version reports it next to the other violations", func() {
+			out, _ := svc.ConsumeDocNo(ctx, &pb.ConsumeDocNoRequest{DocCode: "AP", OrgCode: "MAT", CurSeqNo: 1})
+			So(out.ErrorCode, ShouldEqual, 400)
+			So(out.ErrorMessage, ShouldEqual, "Path is empty; Version is empty")
+		})
+
+		Convey("An import request reports an unknown mode and format next to missing data", func() {
+			out, _ := svc.Import(ctx, &pb.ImportRequest{OrgCode: "MAT", Format: "xml", Mode: 7})
+			So(out.Ok, ShouldBeFalse)
+			So(out.Violations, ShouldResemble, []*pb.Violation{
+				{Field: "data", Description: "Data is empty"},
+				{Field: "mode", Description: "Import Mode 7 is not supported"},
+				{Field: "format", Description: "Dump format xml is not supported, use json or csv"},
+			})
+		})
+
+		Convey("A request for a reserved or invalid org code is refused", func() {
+			for _, orgCode := range []string{"_apikeys", "_schema", "_docnos", "_layouts", "system.users", "MA$T", "MA\x00T"} {
+				out, _ := svc.GetNextDocNo(ctx, &pb.GetNextDocNoRequest{DocCode: "AP", OrgCode: orgCode, Path: "AP/PO"})
+				So(out.Violations, ShouldResemble, []*pb.Violation{{Field: "orgCode", Description: fmt.Sprintf("Organisation Code %q is reserved or not valid", orgCode)}})
+			}
+
+			withKeys := NewDocnogenServiceWithAPIKeys(models.NewMemoryDocNoRepository(), NewDocnoformatterService(), RetryConfig{Policy: common.DefaultRetryPolicy}, models.NewMemoryAPIKeyRepository())
+			out, _ := withKeys.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "ci", OrgCodes: []string{"MAT", "_apikeys"}, Roles: []string{RolePeek}})
+			So(out.Violations, ShouldResemble, []*pb.Violation{{Field: "orgCodes", Description: `Organisation Code "_apikeys" is reserved or not valid`}})
+		})
+
+		Convey("A valid request has no violations", func() {
+			out, _ := svc.GetNextDocNo(ctx, &pb.GetNextDocNoRequest{DocCode: "AP", OrgCode: "MAT", Path: "AP/PO/HQ/19", VariableMap: map[string]string{}, CustomFormat: "{{PREFIX}}{{SEQNO}}"})
+			So(out.Ok, ShouldBeTrue)
+			So(out.Violations, ShouldBeEmpty)
+		})
+	})
+
+	Convey("A rule on a field the request does not have is reported as a violation", t, func() {
+		violations := common.Rules{common.Required("nope", "Nope is empty")}.Validate(&pb.ExportRequest{})
+		So(violations, ShouldResemble, common.Violations{{Field: "nope", Description: "Request has no field nope"}})
+	})
+}
@@ -0,0 +1,392 @@
+// Package docnogen_resttransport serves the counters as REST resources, next to the RPC style routes of the http transport:
+//
+//	GET  /v1/orgs/{org}/doccodes/{doc}/counters/{path}/next     peek, GetNextDocNo
+//	POST /v1/orgs/{org}/doccodes/{doc}/counters/{path}/issue    issue, GenerateDocNoFormat or GenerateBulkDocNoFormat with ?count=
+//	POST /v1/orgs/{org}/doccodes/{doc}/counters/{path}/consume  consume, ConsumeDocNo
+//
+// {path} may span several segments, e.g. AP/PO/HQ/19, or be escaped as one. The format and the variables of the
+// document number are given by the query parameters customFormat and var.{NAME}, or by the body of a POST, which is
+// the request message of the API as JSON or protobuf.
+package docnogen_resttransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	jwt "github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/golang/protobuf/proto"
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	endpoints "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/endpoints"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+)
+
+const (
+	// Prefix is the path every REST route starts with
+	Prefix = "/v1/orgs/"
+
+	ActionNext    = "next"
+	ActionIssue   = "issue"
+	ActionConsume = "consume"
+)
+
+// OpenAPIPaths describes the REST routes in OpenAPI 3, with the schemas of the generated document
+const OpenAPIPaths = `{
+  "/v1/orgs/{org}/doccodes/{doc}/counters/{path}/next": {
+    "parameters": [
+      {"name": "org", "in": "path", "required": true, "schema": {"type": "string"}},
+      {"name": "doc", "in": "path", "required": true, "schema": {"type": "string"}},
+      {"name": "path", "in": "path", "required": true, "description": "May span several segments, or be escaped as one", "schema": {"type": "string"}}
+    ],
+    "get": {
+      "operationId": "RESTNext",
+      "description": "Returns the next document number without issuing it, as GetNextDocNo. The variables are given by var.{NAME} query parameters.",
+      "parameters": [
+        {"name": "customFormat", "in": "query", "schema": {"type": "string"}}
+      ],
+      "responses": {
+        "200": {
+          "description": "Succeeded",
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/GetNextDocNoResponse"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/GetNextDocNoResponse"}}
+          }
+        },
+        "default": {
+          "description": "Failed, the status and errorReason tell why",
+          "content": {"application/json": {"schema": {"oneOf": [
+            {"$ref": "#/components/schemas/GetNextDocNoResponse"},
+            {"$ref": "#/components/schemas/TransportError"}
+          ]}}}
+        }
+      }
+    }
+  },
+  "/v1/orgs/{org}/doccodes/{doc}/counters/{path}/issue": {
+    "parameters": [
+      {"name": "org", "in": "path", "required": true, "schema": {"type": "string"}},
+      {"name": "doc", "in": "path", "required": true, "schema": {"type": "string"}},
+      {"name": "path", "in": "path", "required": true, "description": "May span several segments, or be escaped as one", "schema": {"type": "string"}}
+    ],
+    "post": {
+      "operationId": "RESTIssue",
+      "description": "Issues a document number, as GenerateDocNoFormat, or count of them, as GenerateBulkDocNoFormat. The variables are given by var.{NAME} query parameters or by the body, whose counter fields are ignored.",
+      "parameters": [
+        {"name": "customFormat", "in": "query", "schema": {"type": "string"}},
+        {"name": "count", "in": "query", "schema": {"type": "integer", "format": "uint32"}}
+      ],
+      "requestBody": {
+        "content": {
+          "application/json": {"schema": {"$ref": "#/components/schemas/GenerateDocNoFormatRequest"}},
+          "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/GenerateDocNoFormatRequest"}}
+        }
+      },
+      "responses": {
+        "201": {
+          "description": "Issued",
+          "content": {"application/json": {"schema": {"oneOf": [
+            {"$ref": "#/components/schemas/GenerateDocNoFormatResponse"},
+            {"$ref": "#/components/schemas/GenerateBulkDocNoFormatResponse"}
+          ]}}}
+        },
+        "default": {
+          "description": "Failed, the status and errorReason tell why",
+          "content": {"application/json": {"schema": {"oneOf": [
+            {"$ref": "#/components/schemas/GenerateDocNoFormatResponse"},
+            {"$ref": "#/components/schemas/GenerateBulkDocNoFormatResponse"},
+            {"$ref": "#/components/schemas/TransportError"}
+          ]}}}
+        }
+      }
+    }
+  },
+  "/v1/orgs/{org}/doccodes/{doc}/counters/{path}/consume": {
+    "parameters": [
+      {"name": "org", "in": "path", "required": true, "schema": {"type": "string"}},
+      {"name": "doc", "in": "path", "required": true, "schema": {"type": "string"}},
+      {"name": "path", "in": "path", "required": true, "description": "May span several segments, or be escaped as one", "schema": {"type": "string"}}
+    ],
+    "post": {
+      "operationId": "RESTConsume",
+      "description": "Consumes the current document number, as ConsumeDocNo, given by the query parameters or by the body, whose counter fields are ignored.",
+      "parameters": [
+        {"name": "curSeqNo", "in": "query", "schema": {"type": "integer", "format": "uint32"}},
+        {"name": "version", "in": "query", "schema": {"type": "integer", "format": "int64"}}
+      ],
+      "requestBody": {
+        "content": {
+          "application/json": {"schema": {"$ref": "#/components/schemas/ConsumeDocNoRequest"}},
+          "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/ConsumeDocNoRequest"}}
+        }
+      },
+      "responses": {
+        "200": {
+          "description": "Succeeded",
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/ConsumeDocNoResponse"}},
+            "application/x-protobuf": {"schema": {"$ref": "#/components/schemas/ConsumeDocNoResponse"}}
+          }
+        },
+        "default": {
+          "description": "Failed, the status and errorReason tell why",
+          "content": {"application/json": {"schema": {"oneOf": [
+            {"$ref": "#/components/schemas/ConsumeDocNoResponse"},
+            {"$ref": "#/components/schemas/TransportError"}
+          ]}}}
+        }
+      }
+    }
+  }
+}`
+
+// CounterRef is the counter a REST route refers to
+type CounterRef struct {
+	OrgCode string
+	DocCode string
+	Path    string
+	Action  string
+}
+
+// ParseCounterPath splits /v1/orgs/{org}/doccodes/{doc}/counters/{path}/{action}, unescaping every segment
+func ParseCounterPath(escapedPath string) (*CounterRef, error) {
+	if !strings.HasPrefix(escapedPath, Prefix) {
+		return nil, common.Errorf(common.ReasonNotFound, "Route %s is not found", escapedPath)
+	}
+	segments := strings.Split(strings.TrimPrefix(escapedPath, Prefix), "/")
+	// org, "doccodes", doc, "counters", at least one path segment, action
+	if len(segments) < 6 || segments[1] != "doccodes" || segments[3] != "counters" {
+		return nil, common.Errorf(common.ReasonNotFound, "Route %s is not found", escapedPath)
+	}
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, common.Errorf(common.ReasonInvalidArgument, "Route %s is not a valid path: %s", escapedPath, err.Error())
+		}
+		segments[i] = unescaped
+	}
+	return &CounterRef{
+		OrgCode: segments[0],
+		DocCode: segments[2],
+		Path:    strings.Join(segments[4:len(segments)-1], "/"),
+		Action:  segments[len(segments)-1],
+	}, nil
+}
+
+type router struct {
+	next      http.Handler
+	issue     http.Handler
+	issueBulk http.Handler
+	consume   http.Handler
+}
+
+// NewHandler returns the handler of every route under Prefix
+func NewHandler(endpoints endpoints.Endpoints, logger log.Logger) http.Handler {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(errorEncoder),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, common.TraceHTTPToContext(), common.RequestIDHTTPToContext(logger), jwt.HTTPToContext(), common.APIKeyHTTPToContext(), common.RateLimitHTTPToContext()),
+		httptransport.ServerAfter(common.RateLimitHTTPHeaders(), common.RequestIDHTTPHeader()),
+		httptransport.ServerFinalizer(common.TraceHTTPFinalizer()),
+		httptransport.ServerErrorLogger(logger),
+	}
+	return &router{
+		next:      httptransport.NewServer(endpoints.GetNextDocNoEndpoint, decodeNextRequest, encodeResponse(http.StatusOK), options...),
+		issue:     httptransport.NewServer(endpoints.GenerateDocNoFormatEndpoint, decodeIssueRequest, encodeResponse(http.StatusCreated), options...),
+		issueBulk: httptransport.NewServer(endpoints.GenerateBulkDocNoFormatEndpoint, decodeIssueBulkRequest, encodeResponse(http.StatusCreated), options...),
+		consume:   httptransport.NewServer(endpoints.ConsumeDocNoEndpoint, decodeConsumeRequest, encodeResponse(http.StatusOK), options...),
+	}
+}
+
+// RegisterHandlers mounts the REST routes on mux
+func RegisterHandlers(mux *http.ServeMux, endpoints endpoints.Endpoints, logger log.Logger) {
+	mux.Handle(Prefix, NewHandler(endpoints, logger))
+}
+
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ref, err := ParseCounterPath(r.URL.EscapedPath())
+	if err != nil {
+		errorEncoder(r.Context(), err, w)
+		return
+	}
+
+	var handler http.Handler
+	method := http.MethodPost
+	switch ref.Action {
+	case ActionNext:
+		handler, method = rt.next, http.MethodGet
+	case ActionIssue:
+		handler = rt.issue
+		if r.URL.Query().Get("count") != "" {
+			handler = rt.issueBulk
+		}
+	case ActionConsume:
+		handler = rt.consume
+	default:
+		errorEncoder(r.Context(), common.Errorf(common.ReasonNotFound, "Action %s is not found, use %s, %s or %s", ref.Action, ActionNext, ActionIssue, ActionConsume), w)
+		return
+	}
+
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		errorEncoder(r.Context(), common.Errorf(common.ReasonMethodNotAllowed, "Method %s is not allowed, use %s", r.Method, method), w)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+func decodeNextRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	ref, err := ParseCounterPath(r.URL.EscapedPath())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := common.NegotiateContentType(r.Header.Get("Accept")); err != nil {
+		return nil, err
+	}
+	return &pb.GetNextDocNoRequest{
+		DocCode:      ref.DocCode,
+		OrgCode:      ref.OrgCode,
+		Path:         ref.Path,
+		VariableMap:  variableMap(r.URL.Query(), nil),
+		CustomFormat: r.URL.Query().Get("customFormat"),
+	}, nil
+}
+
+func decodeIssueRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req pb.GenerateDocNoFormatRequest
+	ref, err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	req.DocCode, req.OrgCode, req.Path = ref.DocCode, ref.OrgCode, ref.Path
+	req.CustomFormat = queryOr(r.URL.Query(), "customFormat", req.CustomFormat)
+	req.VariableMap = variableMap(r.URL.Query(), req.VariableMap)
+	return &req, nil
+}
+
+func decodeIssueBulkRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req pb.GenerateBulkDocNoFormatRequest
+	ref, err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	count, err := queryUint(r.URL.Query(), "count", 32)
+	if err != nil {
+		return nil, err
+	}
+	req.DocCode, req.OrgCode, req.Path = ref.DocCode, ref.OrgCode, ref.Path
+	req.BulkNumber = uint32(count)
+	req.CustomFormat = queryOr(r.URL.Query(), "customFormat", req.CustomFormat)
+	req.VariableMap = variableMap(r.URL.Query(), req.VariableMap)
+	return &req, nil
+}
+
+func decodeConsumeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req pb.ConsumeDocNoRequest
+	ref, err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	req.DocCode, req.OrgCode, req.Path = ref.DocCode, ref.OrgCode, ref.Path
+
+	query := r.URL.Query()
+	if query.Get("curSeqNo") != "" {
+		curSeqNo, err := queryUint(query, "curSeqNo", 32)
+		if err != nil {
+			return nil, err
+		}
+		req.CurSeqNo = uint32(curSeqNo)
+	}
+	if query.Get("version") != "" {
+		version, err := queryUint(query, "version", 63)
+		if err != nil {
+			return nil, err
+		}
+		req.Version = int64(version)
+	}
+	return &req, nil
+}
+
+// decodeBody reads the counter from the path and the rest of the request from the body, JSON or protobuf.
+// The fields of the counter are taken from the path, whatever the body says.
+func decodeBody(r *http.Request, req proto.Message) (*CounterRef, error) {
+	ref, err := ParseCounterPath(r.URL.EscapedPath())
+	if err != nil {
+		return nil, err
+	}
+	if err := common.DecodeHTTPRequest(r, req); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// variableMap adds the var.{NAME} query parameters to vars, the result is never nil
+func variableMap(query url.Values, vars map[string]string) map[string]string {
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	for key, values := range query {
+		if strings.HasPrefix(key, "var.") && len(values) > 0 {
+			vars[strings.TrimPrefix(key, "var.")] = values[0]
+		}
+	}
+	return vars
+}
+
+func queryOr(query url.Values, name string, fallback string) string {
+	if value := query.Get(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func queryUint(query url.Values, name string, bitSize int) (uint64, error) {
+	n, err := strconv.ParseUint(query.Get(name), 10, bitSize)
+	if err != nil {
+		return 0, common.Errorf(common.ReasonInvalidArgument, "Query parameter %s=%s is not a valid number", name, query.Get(name))
+	}
+	return n, nil
+}
+
+// encodeResponse writes the response with status ok, or with the status of its reason when it failed
+func encodeResponse(ok int) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		if f, isFailer := response.(endpoint.Failer); isFailer && f.Failed() != nil {
+			errorEncoder(ctx, f.Failed(), w)
+			return nil
+		}
+		status := ok
+		if e := common.ResponseError(response); e != nil {
+			status = e.Reason.HTTPStatus()
+		}
+		accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+		return common.EncodeHTTPResponse(w, accept, status, response.(proto.Message))
+	}
+}
+
+func errorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
+	code := common.ReasonOf(err).HTTPStatus()
+	common.SetRateLimitHeaders(ctx, w.Header())
+	common.SetRequestIDHeader(ctx, w.Header())
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(errorWrapper{
+		Error:        err.Error(),
+		ErrorCode:    int32(code),
+		ErrorMessage: err.Error(),
+		ErrorReason:  string(common.ReasonOf(err)),
+	})
+}
+
+// errorWrapper has the same fields as the one of the http transport
+type errorWrapper struct {
+	Error        string `json:"error"`
+	ErrorCode    int32  `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+	ErrorReason  string `json:"errorReason"`
+}
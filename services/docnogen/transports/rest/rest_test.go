@@ -0,0 +1,176 @@
+package docnogen_resttransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	. "github.com/smartystreets/goconvey/convey"
+	context "golang.org/x/net/context"
+
+	"github.com/howlun/go-kit-documentnogen/common"
+	docnogensvc "github.com/howlun/go-kit-documentnogen/services/docnogen"
+	endpoints "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/endpoints"
+	openapi "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/openapi"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+func serve(handler http.Handler, method string, target string, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	out := map[string]interface{}{}
+	json.Unmarshal(w.Body.Bytes(), &out)
+	return w, out
+}
+
+func Test_REST(t *testing.T) {
+	Convey("Given the REST routes of a service", t, func() {
+		svc := docnogensvc.NewDocnogenService(models.NewMemoryDocNoRepository(), docnogensvc.NewDocnoformatterService())
+		e := endpoints.Endpoints{
+			GenerateBulkDocNoFormatEndpoint: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return svc.GenerateBulkDocNoFormat(ctx, in.(*pb.GenerateBulkDocNoFormatRequest))
+			},
+			GenerateDocNoFormatEndpoint: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return svc.GenerateDocNoFormat(ctx, in.(*pb.GenerateDocNoFormatRequest))
+			},
+			GetNextDocNoEndpoint: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return svc.GetNextDocNo(ctx, in.(*pb.GetNextDocNoRequest))
+			},
+			ConsumeDocNoEndpoint: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return svc.ConsumeDocNo(ctx, in.(*pb.ConsumeDocNoRequest))
+			},
+		}
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, e, log.NewNopLogger())
+		counter := "/v1/orgs/MAT/doccodes/AP/counters/AP/PO/HQ/19"
+
+		Convey("GET next peeks with the format and variables of the query", func() {
+			w, out := serve(mux, "GET", counter+"/next?customFormat={{PREFIX}}-{{BRANCH}}-{{SEQNO}}&var.BRANCH=HQ", "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(out["ok"], ShouldEqual, true)
+			result := out["result"].(map[string]interface{})
+			So(result["docNoString"], ShouldEqual, "AP-HQ-00001")
+		})
+
+		Convey("POST issue issues one number with 201", func() {
+			w, out := serve(mux, "POST", counter+"/issue", `{"customFormat": "{{PREFIX}}{{SEQNO}}"}`)
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(out["result"].(map[string]interface{})["docNoString"], ShouldEqual, "AP00001")
+
+			Convey("and with count several", func() {
+				w, out := serve(mux, "POST", counter+"/issue?count=3&customFormat={{PREFIX}}{{SEQNO}}", "")
+				So(w.Code, ShouldEqual, http.StatusCreated)
+				So(len(out["results"].([]interface{})), ShouldEqual, 3)
+			})
+		})
+
+		Convey("POST consume takes the sequence number and version from the query", func() {
+			_, peek := serve(mux, "GET", counter+"/next?customFormat={{PREFIX}}{{SEQNO}}", "")
+			result := peek["result"].(map[string]interface{})
+			w, out := serve(mux, "POST", counter+"/consume?curSeqNo=1&version=1", "")
+			So(result["version"], ShouldEqual, "1")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(out["ok"], ShouldEqual, true)
+
+			Convey("and a stale version is a 409", func() {
+				w, out := serve(mux, "POST", counter+"/consume", `{"curSeqNo": 1, "version": 1}`)
+				So(w.Code, ShouldEqual, http.StatusConflict)
+				So(out["errorReason"], ShouldEqual, "CONCURRENCY_CONFLICT")
+			})
+		})
+
+		Convey("An escaped path is one path", func() {
+			w, out := serve(mux, "GET", "/v1/orgs/MAT/doccodes/AP/counters/AP%2FPO%2FHQ%2F19/next?customFormat={{PREFIX}}{{SEQNO}}", "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(out["ok"], ShouldEqual, true)
+		})
+
+		Convey("A wrong method is a 405 with Allow", func() {
+			w, out := serve(mux, "POST", counter+"/next", "")
+			So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+			So(w.Header().Get("Allow"), ShouldEqual, "GET")
+			So(out["errorCode"], ShouldEqual, http.StatusMethodNotAllowed)
+			So(out["errorReason"], ShouldEqual, string(common.ReasonMethodNotAllowed))
+			So(out["errorMessage"], ShouldEqual, "Method POST is not allowed, use GET")
+		})
+
+		Convey("An unknown action or route is a 404", func() {
+			w, out := serve(mux, "POST", counter+"/void", "")
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(out["errorReason"], ShouldEqual, "NOT_FOUND")
+
+			w, _ = serve(mux, "GET", "/v1/orgs/MAT/next", "")
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("An unknown body field is a 400 and an unsupported Accept a 406", func() {
+			w, out := serve(mux, "POST", counter+"/issue", `{"customFormat": "{{PREFIX}}{{SEQNO}}", "colour": "red"}`)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(out["errorMessage"], ShouldContainSubstring, "colour")
+
+			r := httptest.NewRequest("POST", counter+"/issue", strings.NewReader(""))
+			r.Header.Set("Accept", "text/html")
+			w = httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, http.StatusNotAcceptable)
+		})
+
+		Convey("A bad count or body is a 400", func() {
+			w, out := serve(mux, "POST", counter+"/issue?count=many", "")
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(out["errorReason"], ShouldEqual, "INVALID_ARGUMENT")
+
+			w, _ = serve(mux, "POST", counter+"/consume", `{"curSeqNo":`)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func Test_ParseCounterPath(t *testing.T) {
+	Convey("A counter path is split into org, doc code, path and action", t, func() {
+		ref, err := ParseCounterPath("/v1/orgs/MAT/doccodes/AP/counters/AP/PO/HQ/19/next")
+		So(err, ShouldBeNil)
+		So(ref, ShouldResemble, &CounterRef{OrgCode: "MAT", DocCode: "AP", Path: "AP/PO/HQ/19", Action: "next"})
+
+		_, err = ParseCounterPath("/v1/orgs/MAT/doccodes/AP/counters/next")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func Test_OpenAPIPaths(t *testing.T) {
+	Convey("The OpenAPI document with the REST paths", t, func() {
+		doc, err := openapi.WithPaths(OpenAPIPaths)
+		So(err, ShouldBeNil)
+		var spec struct {
+			Paths      map[string]map[string]interface{} `json:"paths"`
+			Components struct {
+				Schemas map[string]interface{} `json:"schemas"`
+			} `json:"components"`
+		}
+		So(json.Unmarshal([]byte(doc), &spec), ShouldBeNil)
+
+		Convey("describes every REST route next to the RPC style ones", func() {
+			So(spec.Paths[Prefix+"{org}/doccodes/{doc}/counters/{path}/"+ActionNext], ShouldContainKey, "get")
+			So(spec.Paths[Prefix+"{org}/doccodes/{doc}/counters/{path}/"+ActionIssue], ShouldContainKey, "post")
+			So(spec.Paths[Prefix+"{org}/doccodes/{doc}/counters/{path}/"+ActionConsume], ShouldContainKey, "post")
+			So(spec.Paths["/GetNextDocNo"], ShouldContainKey, "post")
+		})
+
+		Convey("refers only to schemas of the document", func() {
+			for _, ref := range strings.Split(doc, `"$ref": "`)[1:] {
+				name := strings.TrimPrefix(ref[:strings.Index(ref, `"`)], "#/components/schemas/")
+				So(spec.Components.Schemas, ShouldContainKey, name)
+			}
+		})
+
+		Convey("refuses a path already described", func() {
+			_, err := openapi.WithPaths(OpenAPIPaths, OpenAPIPaths)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
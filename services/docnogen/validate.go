@@ -0,0 +1,87 @@
+package docnogensvc
+
+import (
+	"github.com/howlun/go-kit-documentnogen/common"
+	pb "github.com/howlun/go-kit-documentnogen/services/docnogen/gen/pb"
+	"github.com/howlun/go-kit-documentnogen/services/docnogen/models"
+)
+
+// The rules of each request type, checked before anything else. Formats and import data are checked by the methods.
+// They are written here rather than generated from proto annotations: the toolchain has no validation options, and
+// rules such as validOrgCode or the recordTimestamp fallback depend on the store and on deprecated fields, which an
+// annotation could not name.
+var (
+	generateBulkDocNoFormatRules = common.Rules{
+		common.Required("docCode", "Doc Code is empty"),
+		common.Required("orgCode", "Organisation Code is empty"),
+		validOrgCode("orgCode"),
+		common.Required("path", "Path is empty"),
+		common.Between("bulkNumber", 1, 99, "Bulk Number must be at least 1 and not more than 99"),
+	}
+
+	generateDocNoFormatRules = common.Rules{
+		common.Required("docCode", "Doc Code is empty"),
+		common.Required("orgCode", "Organisation Code is empty"),
+		validOrgCode("orgCode"),
+		common.Required("path", "Path is empty"),
+	}
+
+	getNextDocNoRules = common.Rules{
+		common.Required("docCode", "Doc Code is empty"),
+		common.Required("orgCode", "Organisation Code is empty"),
+		validOrgCode("orgCode"),
+		common.Required("path", "Path is empty"),
+	}
+
+	// Record Timestamp is still accepted in place of Version but deprecated. As before these rules, a request without
+	// either fails with "Version is empty", but now next to the other violations instead of hiding them.
+	consumeDocNoRules = common.Rules{
+		common.Required("docCode", "Doc Code is empty"),
+		common.Required("orgCode", "Organisation Code is empty"),
+		validOrgCode("orgCode"),
+		common.Required("path", "Path is empty"),
+		common.RequiredWithout("version", "recordTimestamp", "Version is empty"),
+	}
+
+	exportRules = common.Rules{
+		common.Required("orgCode", "Organisation Code is empty"),
+		validOrgCode("orgCode"),
+	}
+
+	importRules = common.Rules{
+		common.Required("orgCode", "Organisation Code is empty"),
+		validOrgCode("orgCode"),
+		common.Required("data", "Data is empty"),
+		common.Enum("mode", pb.ImportMode_name, "Import Mode %d is not supported"),
+	}
+
+	createAPIKeyRules = common.Rules{
+		common.Required("name", "Name is empty"),
+		common.Required("orgCodes", "Organisation Codes are empty"),
+		validOrgCode("orgCodes"),
+		common.Required("roles", "Roles are empty"),
+		common.OneOf("roles", Roles, "Role %s is not supported"),
+	}
+
+	revokeAPIKeyRules = common.Rules{
+		common.Required("id", "Id is empty"),
+	}
+)
+
+// validOrgCode refuses the org codes naming a collection of the store or of Mongo, such as _apikeys or system.users,
+// or that Mongo does not allow in a collection name
+func validOrgCode(field string) common.Rule {
+	return common.Satisfies(field, models.IsOrgCollectionName, "Organisation Code %q is reserved or not valid")
+}
+
+// pbViolations converts violations for the responses
+func pbViolations(violations common.Violations) []*pb.Violation {
+	if len(violations) == 0 {
+		return nil
+	}
+	out := make([]*pb.Violation, len(violations))
+	for i, v := range violations {
+		out[i] = &pb.Violation{Field: v.Field, Description: v.Description}
+	}
+	return out
+}